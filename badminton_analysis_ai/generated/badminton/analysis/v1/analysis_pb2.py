@@ -24,7 +24,7 @@ _sym_db = _symbol_database.Default()
 
 
 
-DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(b'\n$badminton/analysis/v1/analysis.proto\x12\x15\x62\x61\x64minton.analysis.v1\"\xaf\x01\n\x12\x41nalyzeVideoHeader\x12\x12\n\nrequest_id\x18\x01 \x01(\t\x12\x0f\n\x07user_id\x18\x02 \x01(\t\x12\x10\n\x08\x66ilename\x18\x03 \x01(\t\x12+\n\x05skill\x18\x04 \x01(\x0e\x32\x1c.badminton.analysis.v1.Skill\x12\x35\n\nhandedness\x18\x05 \x01(\x0e\x32!.badminton.analysis.v1.Handedness\"k\n\x11\x41nalyzeVideoChunk\x12;\n\x06header\x18\x01 \x01(\x0b\x32).badminton.analysis.v1.AnalyzeVideoHeaderH\x00\x12\x0e\n\x04\x64\x61ta\x18\x02 \x01(\x0cH\x00\x42\t\n\x07payload\"Z\n\rGradingDetail\x12\x14\n\x0c\x63riterion_id\x18\x01 \x01(\t\x12\x13\n\x0b\x64\x65scription\x18\x02 \x01(\t\x12\r\n\x05grade\x18\x03 \x01(\x01\x12\x0f\n\x07maximum\x18\x04 \x01(\x01\"z\n\x0eGradingOutcome\x12\x13\n\x0btotal_grade\x18\x01 \x01(\x01\x12=\n\x0fgrading_details\x18\x02 \x03(\x0b\x32$.badminton.analysis.v1.GradingDetail\x12\x14\n\x0cscore_status\x18\x03 \x01(\t\"z\n\x0bPhaseMarker\x12\n\n\x02id\x18\x01 \x01(\t\x12\r\n\x05label\x18\x02 \x01(\t\x12\x18\n\x10normalized_frame\x18\x03 \x01(\x05\x12\x1b\n\x13normalized_position\x18\x04 \x01(\x01\x12\x19\n\x11timestamp_seconds\x18\x05 \x01(\x01\"\xbb\x01\n\x0b\x43oachingCue\x12\r\n\x05title\x18\x01 \x01(\t\x12\x10\n\x08\x66\x65\x65\x64\x62\x61\x63k\x18\x02 \x01(\t\x12\x18\n\x10normalized_frame\x18\x03 \x01(\x05\x12\x1b\n\x13normalized_position\x18\x04 \x01(\x01\x12!\n\x19student_timestamp_seconds\x18\x05 \x01(\x01\x12\x1e\n\x16pause_duration_seconds\x18\x06 \x01(\x01\x12\x11\n\tjoint_ids\x18\x07 \x03(\x05\"\xb1\x01\n\x0bStoredVideo\x12\x13\n\x0bobject_path\x18\x01 \x01(\t\x12\x0f\n\x07gcs_uri\x18\x02 \x01(\t\x12\x12\n\nsigned_url\x18\x03 \x01(\t\x12\"\n\x1asigned_url_expires_at_unix\x18\x04 \x01(\x03\x12\x18\n\x10\x64uration_seconds\x18\x05 \x01(\x01\x12\x0b\n\x03\x66ps\x18\x06 \x01(\x01\x12\r\n\x05width\x18\x07 \x01(\x05\x12\x0e\n\x06height\x18\x08 \x01(\x05\"\xc0\x01\n\x0b\x45xpertMatch\x12\x11\n\texpert_id\x18\x01 \x01(\t\x12\x14\n\x0c\x64isplay_name\x18\x02 \x01(\t\x12\x1b\n\x13\x63orrection_distance\x18\x03 \x01(\x01\x12\x31\n\x05video\x18\x04 \x01(\x0b\x32\".badminton.analysis.v1.StoredVideo\x12\x1c\n\x14motion_start_seconds\x18\x05 \x01(\x01\x12\x1a\n\x12motion_end_seconds\x18\x06 \x01(\x01\"-\n\x0f\x44iagnosticValue\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\x01\"\xfc\x03\n\x14\x41nalyzeVideoResponse\x12\x13\n\x0b\x61nalysis_id\x18\x01 \x01(\t\x12+\n\x05skill\x18\x02 \x01(\x0e\x32\x1c.badminton.analysis.v1.Skill\x12\x35\n\nhandedness\x18\x03 \x01(\x0e\x32!.badminton.analysis.v1.Handedness\x12\x34\n\x05grade\x18\x04 \x01(\x0b\x32%.badminton.analysis.v1.GradingOutcome\x12\x39\n\rstudent_video\x18\x05 \x01(\x0b\x32\".badminton.analysis.v1.StoredVideo\x12\x32\n\x06\x65xpert\x18\x06 \x01(\x0b\x32\".badminton.analysis.v1.ExpertMatch\x12\x34\n\x08timeline\x18\x07 \x03(\x0b\x32\".badminton.analysis.v1.PhaseMarker\x12;\n\x0b\x64iagnostics\x18\x08 \x03(\x0b\x32&.badminton.analysis.v1.DiagnosticValue\x12\x39\n\rcoaching_cues\x18\t \x03(\x0b\x32\".badminton.analysis.v1.CoachingCue\x12\x18\n\x10overall_feedback\x18\n \x01(\t\"2\n\x1aRefreshPlaybackUrlsRequest\x12\x14\n\x0cobject_paths\x18\x01 \x03(\t\"Q\n\x1bRefreshPlaybackUrlsResponse\x12\x32\n\x06videos\x18\x01 \x03(\x0b\x32\".badminton.analysis.v1.StoredVideo\"\x0f\n\rHealthRequest\"U\n\x0eHealthResponse\x12\x0e\n\x06status\x18\x01 \x01(\t\x12\x33\n\rloaded_skills\x18\x02 \x03(\x0e\x32\x1c.badminton.analysis.v1.Skill*a\n\x05Skill\x12\x15\n\x11SKILL_UNSPECIFIED\x10\x00\x12\x0f\n\x0bSKILL_SERVE\x10\x01\x12\x0e\n\nSKILL_LIFT\x10\x02\x12\x0f\n\x0bSKILL_CLEAR\x10\x03\x12\x0f\n\x0bSKILL_SMASH\x10\x04*h\n\nHandedness\x12\x1a\n\x16HANDEDNESS_UNSPECIFIED\x10\x00\x12\x13\n\x0fHANDEDNESS_AUTO\x10\x01\x12\x14\n\x10HANDEDNESS_RIGHT\x10\x02\x12\x13\n\x0fHANDEDNESS_LEFT\x10\x03\x32\xd1\x02\n\x11\x42\x61\x64mintonAnalysis\x12g\n\x0c\x41nalyzeVideo\x12(.badminton.analysis.v1.AnalyzeVideoChunk\x1a+.badminton.analysis.v1.AnalyzeVideoResponse(\x01\x12|\n\x13RefreshPlaybackUrls\x12\x31.badminton.analysis.v1.RefreshPlaybackUrlsRequest\x1a\x32.badminton.analysis.v1.RefreshPlaybackUrlsResponse\x12U\n\x06Health\x12$.badminton.analysis.v1.HealthRequest\x1a%.badminton.analysis.v1.HealthResponseBBZ@github.com/HeavenAQ/nstc-linebot-2025/api/analysis/v1;analysisv1b\x06proto3')
+DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(b'\n$badminton/analysis/v1/analysis.proto\x12\x15\x62\x61\x64minton.analysis.v1\"\xaf\x01\n\x12\x41nalyzeVideoHeader\x12\x12\n\nrequest_id\x18\x01 \x01(\t\x12\x0f\n\x07user_id\x18\x02 \x01(\t\x12\x10\n\x08\x66ilename\x18\x03 \x01(\t\x12+\n\x05skill\x18\x04 \x01(\x0e\x32\x1c.badminton.analysis.v1.Skill\x12\x35\n\nhandedness\x18\x05 \x01(\x0e\x32!.badminton.analysis.v1.Handedness\"k\n\x11\x41nalyzeVideoChunk\x12;\n\x06header\x18\x01 \x01(\x0b\x32).badminton.analysis.v1.AnalyzeVideoHeaderH\x00\x12\x0e\n\x04\x64\x61ta\x18\x02 \x01(\x0cH\x00\x42\t\n\x07payload\"Z\n\rGradingDetail\x12\x14\n\x0c\x63riterion_id\x18\x01 \x01(\t\x12\x13\n\x0b\x64\x65scription\x18\x02 \x01(\t\x12\r\n\x05grade\x18\x03 \x01(\x01\x12\x0f\n\x07maximum\x18\x04 \x01(\x01\"z\n\x0eGradingOutcome\x12\x13\n\x0btotal_grade\x18\x01 \x01(\x01\x12=\n\x0fgrading_details\x18\x02 \x03(\x0b\x32$.badminton.analysis.v1.GradingDetail\x12\x14\n\x0cscore_status\x18\x03 \x01(\t\"z\n\x0bPhaseMarker\x12\n\n\x02id\x18\x01 \x01(\t\x12\r\n\x05label\x18\x02 \x01(\t\x12\x18\n\x10normalized_frame\x18\x03 \x01(\x05\x12\x1b\n\x13normalized_position\x18\x04 \x01(\x01\x12\x19\n\x11timestamp_seconds\x18\x05 \x01(\x01\"\xbb\x01\n\x0b\x43oachingCue\x12\r\n\x05title\x18\x01 \x01(\t\x12\x10\n\x08\x66\x65\x65\x64\x62\x61\x63k\x18\x02 \x01(\t\x12\x18\n\x10normalized_frame\x18\x03 \x01(\x05\x12\x1b\n\x13normalized_position\x18\x04 \x01(\x01\x12!\n\x19student_timestamp_seconds\x18\x05 \x01(\x01\x12\x1e\n\x16pause_duration_seconds\x18\x06 \x01(\x01\x12\x11\n\tjoint_ids\x18\x07 \x03(\x05\"\xb1\x01\n\x0bStoredVideo\x12\x13\n\x0bobject_path\x18\x01 \x01(\t\x12\x0f\n\x07gcs_uri\x18\x02 \x01(\t\x12\x12\n\nsigned_url\x18\x03 \x01(\t\x12\"\n\x1asigned_url_expires_at_unix\x18\x04 \x01(\x03\x12\x18\n\x10\x64uration_seconds\x18\x05 \x01(\x01\x12\x0b\n\x03\x66ps\x18\x06 \x01(\x01\x12\r\n\x05width\x18\x07 \x01(\x05\x12\x0e\n\x06height\x18\x08 \x01(\x05\"\xc0\x01\n\x0b\x45xpertMatch\x12\x11\n\texpert_id\x18\x01 \x01(\t\x12\x14\n\x0c\x64isplay_name\x18\x02 \x01(\t\x12\x1b\n\x13\x63orrection_distance\x18\x03 \x01(\x01\x12\x31\n\x05video\x18\x04 \x01(\x0b\x32\".badminton.analysis.v1.StoredVideo\x12\x1c\n\x14motion_start_seconds\x18\x05 \x01(\x01\x12\x1a\n\x12motion_end_seconds\x18\x06 \x01(\x01\"-\n\x0f\x44iagnosticValue\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\r\n\x05value\x18\x02 \x01(\x01\"\xfc\x03\n\x14\x41nalyzeVideoResponse\x12\x13\n\x0b\x61nalysis_id\x18\x01 \x01(\t\x12+\n\x05skill\x18\x02 \x01(\x0e\x32\x1c.badminton.analysis.v1.Skill\x12\x35\n\nhandedness\x18\x03 \x01(\x0e\x32!.badminton.analysis.v1.Handedness\x12\x34\n\x05grade\x18\x04 \x01(\x0b\x32%.badminton.analysis.v1.GradingOutcome\x12\x39\n\rstudent_video\x18\x05 \x01(\x0b\x32\".badminton.analysis.v1.StoredVideo\x12\x32\n\x06\x65xpert\x18\x06 \x01(\x0b\x32\".badminton.analysis.v1.ExpertMatch\x12\x34\n\x08timeline\x18\x07 \x03(\x0b\x32\".badminton.analysis.v1.PhaseMarker\x12;\n\x0b\x64iagnostics\x18\x08 \x03(\x0b\x32&.badminton.analysis.v1.DiagnosticValue\x12\x39\n\rcoaching_cues\x18\t \x03(\x0b\x32\".badminton.analysis.v1.CoachingCue\x12\x18\n\x10overall_feedback\x18\n \x01(\t\"\x92\x01\n\x11\x41nalyzeVideoEvent\x12\x35\n\x05stage\x18\x01 \x01(\x0e\x32$.badminton.analysis.v1.AnalysisStageH\x00\x12=\n\x06result\x18\x02 \x01(\x0b\x32+.badminton.analysis.v1.AnalyzeVideoResponseH\x00\x42\x07\n\x05\x65vent\"2\n\x1aRefreshPlaybackUrlsRequest\x12\x14\n\x0cobject_paths\x18\x01 \x03(\t\"Q\n\x1bRefreshPlaybackUrlsResponse\x12\x32\n\x06videos\x18\x01 \x03(\x0b\x32\".badminton.analysis.v1.StoredVideo\"\x0f\n\rHealthRequest\"U\n\x0eHealthResponse\x12\x0e\n\x06status\x18\x01 \x01(\t\x12\x33\n\rloaded_skills\x18\x02 \x03(\x0e\x32\x1c.badminton.analysis.v1.Skill*a\n\x05Skill\x12\x15\n\x11SKILL_UNSPECIFIED\x10\x00\x12\x0f\n\x0bSKILL_SERVE\x10\x01\x12\x0e\n\nSKILL_LIFT\x10\x02\x12\x0f\n\x0bSKILL_CLEAR\x10\x03\x12\x0f\n\x0bSKILL_SMASH\x10\x04*h\n\nHandedness\x12\x1a\n\x16HANDEDNESS_UNSPECIFIED\x10\x00\x12\x13\n\x0fHANDEDNESS_AUTO\x10\x01\x12\x14\n\x10HANDEDNESS_RIGHT\x10\x02\x12\x13\n\x0fHANDEDNESS_LEFT\x10\x03*\xbb\x01\n\rAnalysisStage\x12\x1e\n\x1a\x41NALYSIS_STAGE_UNSPECIFIED\x10\x00\x12\x17\n\x13\x41NALYSIS_STAGE_POSE\x10\x01\x12\x1d\n\x19\x41NALYSIS_STAGE_CORRECTION\x10\x02\x12\x1a\n\x16\x41NALYSIS_STAGE_SCORING\x10\x03\x12\x1b\n\x17\x41NALYSIS_STAGE_COACHING\x10\x04\x12\x19\n\x15\x41NALYSIS_STAGE_RENDER\x10\x05\x32\xc5\x03\n\x11\x42\x61\x64mintonAnalysis\x12g\n\x0c\x41nalyzeVideo\x12(.badminton.analysis.v1.AnalyzeVideoChunk\x1a+.badminton.analysis.v1.AnalyzeVideoResponse(\x01\x12r\n\x18\x41nalyzeVideoWithProgress\x12(.badminton.analysis.v1.AnalyzeVideoChunk\x1a(.badminton.analysis.v1.AnalyzeVideoEvent(\x01\x30\x01\x12|\n\x13RefreshPlaybackUrls\x12\x31.badminton.analysis.v1.RefreshPlaybackUrlsRequest\x1a\x32.badminton.analysis.v1.RefreshPlaybackUrlsResponse\x12U\n\x06Health\x12$.badminton.analysis.v1.HealthRequest\x1a%.badminton.analysis.v1.HealthResponseBBZ@github.com/HeavenAQ/nstc-linebot-2025/api/analysis/v1;analysisv1b\x06proto3')
 
 _globals = globals()
 _builder.BuildMessageAndEnumDescriptors(DESCRIPTOR, _globals)
@@ -32,10 +32,12 @@ _builder.BuildTopDescriptorsAndMessages(DESCRIPTOR, 'badminton.analysis.v1.analy
 if not _descriptor._USE_C_DESCRIPTORS:
   _globals['DESCRIPTOR']._loaded_options = None
   _globals['DESCRIPTOR']._serialized_options = b'Z@github.com/HeavenAQ/nstc-linebot-2025/api/analysis/v1;analysisv1'
-  _globals['_SKILL']._serialized_start=2052
-  _globals['_SKILL']._serialized_end=2149
-  _globals['_HANDEDNESS']._serialized_start=2151
-  _globals['_HANDEDNESS']._serialized_end=2255
+  _globals['_SKILL']._serialized_start=2201
+  _globals['_SKILL']._serialized_end=2298
+  _globals['_HANDEDNESS']._serialized_start=2300
+  _globals['_HANDEDNESS']._serialized_end=2404
+  _globals['_ANALYSISSTAGE']._serialized_start=2407
+  _globals['_ANALYSISSTAGE']._serialized_end=2594
   _globals['_ANALYZEVIDEOHEADER']._serialized_start=64
   _globals['_ANALYZEVIDEOHEADER']._serialized_end=239
   _globals['_ANALYZEVIDEOCHUNK']._serialized_start=241
@@ -56,14 +58,16 @@ if not _descriptor._USE_C_DESCRIPTORS:
   _globals['_DIAGNOSTICVALUE']._serialized_end=1300
   _globals['_ANALYZEVIDEORESPONSE']._serialized_start=1303
   _globals['_ANALYZEVIDEORESPONSE']._serialized_end=1811
-  _globals['_REFRESHPLAYBACKURLSREQUEST']._serialized_start=1813
-  _globals['_REFRESHPLAYBACKURLSREQUEST']._serialized_end=1863
-  _globals['_REFRESHPLAYBACKURLSRESPONSE']._serialized_start=1865
-  _globals['_REFRESHPLAYBACKURLSRESPONSE']._serialized_end=1946
-  _globals['_HEALTHREQUEST']._serialized_start=1948
-  _globals['_HEALTHREQUEST']._serialized_end=1963
-  _globals['_HEALTHRESPONSE']._serialized_start=1965
-  _globals['_HEALTHRESPONSE']._serialized_end=2050
-  _globals['_BADMINTONANALYSIS']._serialized_start=2258
-  _globals['_BADMINTONANALYSIS']._serialized_end=2595
+  _globals['_ANALYZEVIDEOEVENT']._serialized_start=1814
+  _globals['_ANALYZEVIDEOEVENT']._serialized_end=1960
+  _globals['_REFRESHPLAYBACKURLSREQUEST']._serialized_start=1962
+  _globals['_REFRESHPLAYBACKURLSREQUEST']._serialized_end=2012
+  _globals['_REFRESHPLAYBACKURLSRESPONSE']._serialized_start=2014
+  _globals['_REFRESHPLAYBACKURLSRESPONSE']._serialized_end=2095
+  _globals['_HEALTHREQUEST']._serialized_start=2097
+  _globals['_HEALTHREQUEST']._serialized_end=2112
+  _globals['_HEALTHRESPONSE']._serialized_start=2114
+  _globals['_HEALTHRESPONSE']._serialized_end=2199
+  _globals['_BADMINTONANALYSIS']._serialized_start=2597
+  _globals['_BADMINTONANALYSIS']._serialized_end=3050
 # @@protoc_insertion_point(module_scope)
@@ -39,6 +39,11 @@ class BadmintonAnalysisStub(object):
                 request_serializer=badminton_dot_analysis_dot_v1_dot_analysis__pb2.AnalyzeVideoChunk.SerializeToString,
                 response_deserializer=badminton_dot_analysis_dot_v1_dot_analysis__pb2.AnalyzeVideoResponse.FromString,
                 _registered_method=True)
+        self.AnalyzeVideoWithProgress = channel.stream_stream(
+                '/badminton.analysis.v1.BadmintonAnalysis/AnalyzeVideoWithProgress',
+                request_serializer=badminton_dot_analysis_dot_v1_dot_analysis__pb2.AnalyzeVideoChunk.SerializeToString,
+                response_deserializer=badminton_dot_analysis_dot_v1_dot_analysis__pb2.AnalyzeVideoEvent.FromString,
+                _registered_method=True)
         self.RefreshPlaybackUrls = channel.unary_unary(
                 '/badminton.analysis.v1.BadmintonAnalysis/RefreshPlaybackUrls',
                 request_serializer=badminton_dot_analysis_dot_v1_dot_analysis__pb2.RefreshPlaybackUrlsRequest.SerializeToString,
@@ -60,6 +65,12 @@ class BadmintonAnalysisServicer(object):
         context.set_details('Method not implemented!')
         raise NotImplementedError('Method not implemented!')
 
+    def AnalyzeVideoWithProgress(self, request_iterator, context):
+        """Missing associated documentation comment in .proto file."""
+        context.set_code(grpc.StatusCode.UNIMPLEMENTED)
+        context.set_details('Method not implemented!')
+        raise NotImplementedError('Method not implemented!')
+
     def RefreshPlaybackUrls(self, request, context):
         """Missing associated documentation comment in .proto file."""
         context.set_code(grpc.StatusCode.UNIMPLEMENTED)
@@ -80,6 +91,11 @@ def add_BadmintonAnalysisServicer_to_server(servicer, server):
                     request_deserializer=badminton_dot_analysis_dot_v1_dot_analysis__pb2.AnalyzeVideoChunk.FromString,
                     response_serializer=badminton_dot_analysis_dot_v1_dot_analysis__pb2.AnalyzeVideoResponse.SerializeToString,
             ),
+            'AnalyzeVideoWithProgress': grpc.stream_stream_rpc_method_handler(
+                    servicer.AnalyzeVideoWithProgress,
+                    request_deserializer=badminton_dot_analysis_dot_v1_dot_analysis__pb2.AnalyzeVideoChunk.FromString,
+                    response_serializer=badminton_dot_analysis_dot_v1_dot_analysis__pb2.AnalyzeVideoEvent.SerializeToString,
+            ),
             'RefreshPlaybackUrls': grpc.unary_unary_rpc_method_handler(
                     servicer.RefreshPlaybackUrls,
                     request_deserializer=badminton_dot_analysis_dot_v1_dot_analysis__pb2.RefreshPlaybackUrlsRequest.FromString,
@@ -128,6 +144,33 @@ class BadmintonAnalysis(object):
             metadata,
             _registered_method=True)
 
+    @staticmethod
+    def AnalyzeVideoWithProgress(request_iterator,
+            target,
+            options=(),
+            channel_credentials=None,
+            call_credentials=None,
+            insecure=False,
+            compression=None,
+            wait_for_ready=None,
+            timeout=None,
+            metadata=None):
+        return grpc.experimental.stream_stream(
+            request_iterator,
+            target,
+            '/badminton.analysis.v1.BadmintonAnalysis/AnalyzeVideoWithProgress',
+            badminton_dot_analysis_dot_v1_dot_analysis__pb2.AnalyzeVideoChunk.SerializeToString,
+            badminton_dot_analysis_dot_v1_dot_analysis__pb2.AnalyzeVideoEvent.FromString,
+            options,
+            channel_credentials,
+            insecure,
+            call_credentials,
+            compression,
+            wait_for_ready,
+            timeout,
+            metadata,
+            _registered_method=True)
+
     @staticmethod
     def RefreshPlaybackUrls(request,
             target,
@@ -19,6 +19,8 @@ class Settings:
     device: str
     openai_model: str
     coaching_pause_seconds: float
+    analyzer_version: str
+    queue_seconds: float
 
     @classmethod
     def from_env(cls) -> "Settings":
@@ -43,6 +45,8 @@ class Settings:
             device=os.getenv("SKELETON_DEVICE", "auto"),
             openai_model=os.getenv("OPENAI_COACHING_MODEL", "gpt-5.6-terra"),
             coaching_pause_seconds=float(os.getenv("COACHING_PAUSE_SECONDS", "2.0")),
+            analyzer_version=os.getenv("ANALYZER_VERSION", ""),
+            queue_seconds=float(os.getenv("ANALYSIS_QUEUE_SECONDS", "120")),
         )
         missing = [
             name
@@ -59,4 +63,6 @@ class Settings:
             raise ValueError("SIGNED_URL_MINUTES must be between 1 and 10080")
         if not 0.0 <= values.coaching_pause_seconds <= 10.0:
             raise ValueError("COACHING_PAUSE_SECONDS must be between 0 and 10")
+        if values.queue_seconds <= 0:
+            raise ValueError("ANALYSIS_QUEUE_SECONDS must be positive")
         return values
@@ -0,0 +1,45 @@
+from __future__ import annotations
+
+import grpc
+from google.protobuf import any_pb2
+from google.rpc import code_pb2, error_details_pb2, status_pb2
+from grpc_status import rpc_status
+
+# Reasons are sent as google.rpc.ErrorInfo so callers can tell failures apart
+# without parsing messages. Keep them in sync with the linebot client.
+ERROR_DOMAIN = "badminton-analysis"
+NO_PERSON_DETECTED = "NO_PERSON_DETECTED"
+WRONG_SKILL = "WRONG_SKILL"
+CLIP_TOO_SHORT = "CLIP_TOO_SHORT"
+VIDEO_UNREADABLE = "VIDEO_UNREADABLE"
+NO_MATCHING_EXPERT = "NO_MATCHING_EXPERT"
+SERVICE_OVERLOADED = "SERVICE_OVERLOADED"
+
+_REASON_CODES = {
+    NO_PERSON_DETECTED: code_pb2.FAILED_PRECONDITION,
+    WRONG_SKILL: code_pb2.FAILED_PRECONDITION,
+    CLIP_TOO_SHORT: code_pb2.FAILED_PRECONDITION,
+    VIDEO_UNREADABLE: code_pb2.INVALID_ARGUMENT,
+    NO_MATCHING_EXPERT: code_pb2.FAILED_PRECONDITION,
+    SERVICE_OVERLOADED: code_pb2.UNAVAILABLE,
+}
+
+
+class AnalysisRejected(ValueError):
+    """An analysis failure the student or the caller can act on."""
+
+    def __init__(self, reason: str, message: str) -> None:
+        if reason not in _REASON_CODES:
+            raise ValueError(f"unknown rejection reason: {reason}")
+        super().__init__(message)
+        self.reason = reason
+
+
+def rejection_status(exc: AnalysisRejected) -> grpc.Status:
+    detail = any_pb2.Any()
+    detail.Pack(error_details_pb2.ErrorInfo(reason=exc.reason, domain=ERROR_DOMAIN))
+    return rpc_status.to_status(
+        status_pb2.Status(
+            code=_REASON_CODES[exc.reason], message=str(exc), details=[detail]
+        )
+    )
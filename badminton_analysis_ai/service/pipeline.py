@@ -2,9 +2,10 @@ from __future__ import annotations
 
 import threading
 import time
+from contextlib import contextmanager
 from dataclasses import dataclass
 from pathlib import Path
-from typing import Any
+from typing import Any, Callable
 
 import numpy as np
 
@@ -20,8 +21,27 @@ from badminton_analysis.models.types import COCOKeypoints, Handedness, Skill, Tr
 from badminton_analysis.services.pose_detector import PoseDetector
 from badminton_analysis.services.video_processor import VideoProcessor
 
-from service.renderer import render_correction_video, source_fps
+from service.renderer import can_decode, render_correction_video, source_fps
 from service.coaching import CoachingGenerator
+from service.errors import (
+    CLIP_TOO_SHORT,
+    NO_MATCHING_EXPERT,
+    NO_PERSON_DETECTED,
+    SERVICE_OVERLOADED,
+    VIDEO_UNREADABLE,
+    WRONG_SKILL,
+    AnalysisRejected,
+)
+
+# Stages reported while analyze runs, in order.
+STAGE_POSE = "pose"
+STAGE_CORRECTION = "correction"
+STAGE_SCORING = "scoring"
+STAGE_COACHING = "coaching"
+STAGE_RENDER = "render"
+
+# tracking_to_normalized_sequence needs at least this many frames with a pose.
+_MIN_TRACKED_FRAMES = 5
 
 
 @dataclass(frozen=True)
@@ -101,6 +121,26 @@ def _resolve_handedness(tracking: TrackingData, requested: str) -> Handedness:
     return estimate.handedness
 
 
+def _require_player(tracking: TrackingData) -> None:
+    tracked = len(tracking["original_landmarks"])
+    if not tracked or not tracking.get("body_landmarks_2d"):
+        raise AnalysisRejected(NO_PERSON_DETECTED, "no player was detected in the video")
+    if tracked < _MIN_TRACKED_FRAMES:
+        raise AnalysisRejected(
+            CLIP_TOO_SHORT, f"the player is visible in only {tracked} frames"
+        )
+
+
+def _require_expert(backend: SkeletonCorrectionBackend, handedness: Handedness) -> None:
+    requested = str(handedness).lower()
+    if not np.any(np.asarray(backend.expert_reference_handedness) == requested):
+        raise AnalysisRejected(
+            NO_MATCHING_EXPERT,
+            f"no {requested}-handed expert reference is available "
+            f"for {backend.spec.slug}",
+        )
+
+
 def _populate_dominant_motion(
     tracking: TrackingData, handedness: Handedness
 ) -> None:
@@ -122,9 +162,11 @@ class SkeletonAnalysisPipeline:
         device: str = "auto",
         openai_model: str = "gpt-5.6-terra",
         pause_seconds: float = 2.0,
+        queue_seconds: float = 120.0,
     ) -> None:
         self.pose_detector = PoseDetector()
         self.lock = threading.Lock()
+        self.queue_seconds = queue_seconds
         self.backends: dict[Skill, SkeletonCorrectionBackend] = {}
         self.coaching = CoachingGenerator(openai_model)
         self.pause_seconds = pause_seconds
@@ -134,6 +176,17 @@ class SkeletonAnalysisPipeline:
                 model_root / f"{spec.model_stem}.pt", device=device
             )
 
+    @contextmanager
+    def _analysis_slot(self):
+        # One analysis runs at a time; a request that waits too long is told
+        # to come back instead of piling up behind the others.
+        if not self.lock.acquire(timeout=self.queue_seconds):
+            raise AnalysisRejected(SERVICE_OVERLOADED, "the analysis queue is full")
+        try:
+            yield
+        finally:
+            self.lock.release()
+
     @property
     def loaded_skills(self) -> tuple[Skill, ...]:
         return tuple(self.backends)
@@ -146,29 +199,48 @@ class SkeletonAnalysisPipeline:
         filename: str,
         skill: Skill,
         requested_handedness: str,
+        on_stage: Callable[[str], None] | None = None,
     ) -> AnalysisResult:
+        def report(stage: str) -> None:
+            if on_stage is not None:
+                on_stage(stage)
+
         pipeline_started = time.perf_counter()
-        with self.lock:
+        if not can_decode(video_path):
+            raise AnalysisRejected(VIDEO_UNREADABLE, "the video could not be decoded")
+        with self._analysis_slot():
+            report(STAGE_POSE)
             pose_started = time.perf_counter()
             processor = VideoProcessor(
                 str(video_path), filename, str(output_path.parent), self.pose_detector
             )
             tracking = processor.process_frames(None)
             pose_finished = time.perf_counter()
+            _require_player(tracking)
             handedness = _resolve_handedness(tracking, requested_handedness)
             _populate_dominant_motion(tracking, handedness)
             backend = self.backends[skill]
-            skeleton, confidence, window, phases = tracking_to_normalized_sequence(
-                tracking,
-                handedness,
-                skill=skill,
-                target_frames=backend.target_frames,
-            )
+            report(STAGE_CORRECTION)
+            try:
+                skeleton, confidence, window, phases = tracking_to_normalized_sequence(
+                    tracking,
+                    handedness,
+                    skill=skill,
+                    target_frames=backend.target_frames,
+                )
+            except ValueError as exc:
+                # The player was tracked long enough, so the motion of the
+                # selected skill was not found in the clip.
+                raise AnalysisRejected(
+                    WRONG_SKILL, f"no {skill} motion was found in the video: {exc}"
+                ) from exc
+            _require_expert(backend, handedness)
             preprocessing_finished = time.perf_counter()
             grade, corrected, diagnostics = backend.score_sequence(
                 skeleton, confidence, phases, handedness
             )
             scoring_finished = time.perf_counter()
+            report(STAGE_SCORING)
             fps = source_fps(video_path)
             spec: SkillCorrectionSpec = get_skill_spec(skill)
             criterion_values = phase_grading_details(
@@ -196,6 +268,7 @@ class SkeletonAnalysisPipeline:
                 fps=fps,
             )
             preview_finished = time.perf_counter()
+            report(STAGE_COACHING)
             coaching_payload = self.coaching.generate(
                 video_path=preview_path,
                 working_dir=output_path.parent,
@@ -207,6 +280,7 @@ class SkeletonAnalysisPipeline:
             )
             coaching_finished = time.perf_counter()
             problems = coaching_payload["analysis"]["problems"]
+            report(STAGE_RENDER)
             render_correction_video(
                 tracking=tracking,
                 original=skeleton,
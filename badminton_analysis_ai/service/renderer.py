@@ -207,6 +207,14 @@ def source_fps(video_path: Path) -> float:
         capture.release()
 
 
+def can_decode(video_path: Path) -> bool:
+    capture = cv2.VideoCapture(str(video_path))
+    try:
+        return capture.isOpened() and capture.read()[0]
+    finally:
+        capture.release()
+
+
 def probe_video(video_path: Path) -> dict[str, float | int]:
     capture = cv2.VideoCapture(str(video_path))
     try:
@@ -1,15 +1,18 @@
 from __future__ import annotations
 
 import logging
+import queue
 import re
 import secrets
 import signal
 import tempfile
+import threading
 import time
 import uuid
 from concurrent import futures
+from dataclasses import dataclass
 from pathlib import Path
-from typing import Iterable
+from typing import Callable, Iterable, Iterator
 
 import grpc
 
@@ -17,13 +20,23 @@ from badminton.analysis.v1 import analysis_pb2, analysis_pb2_grpc
 from badminton_analysis.models.types import Handedness, Skill
 
 from service.config import Settings
+from service.errors import NO_MATCHING_EXPERT, AnalysisRejected, rejection_status
 from service.expert_catalog import ExpertCatalog
-from service.pipeline import AnalysisResult, SkeletonAnalysisPipeline
+from service.pipeline import (
+    STAGE_COACHING,
+    STAGE_CORRECTION,
+    STAGE_POSE,
+    STAGE_RENDER,
+    STAGE_SCORING,
+    AnalysisResult,
+    SkeletonAnalysisPipeline,
+)
 from service.renderer import probe_video
 from service.storage import ObjectStorage, SignedObject
 
 LOGGER = logging.getLogger("badminton-analysis")
 _SAFE_SEGMENT = re.compile(r"[^A-Za-z0-9._-]+")
+_TRACEPARENT = re.compile(r"^[0-9a-f]{2}-([0-9a-f]{32})-[0-9a-f]{16}-[0-9a-f]{2}$")
 
 _PROTO_TO_SKILL = {
     analysis_pb2.SKILL_SERVE: Skill.SERVE,
@@ -42,12 +55,37 @@ _HANDEDNESS_TO_PROTO = {
     Handedness.LEFT: analysis_pb2.HANDEDNESS_LEFT,
 }
 
+_STAGE_TO_PROTO = {
+    STAGE_POSE: analysis_pb2.ANALYSIS_STAGE_POSE,
+    STAGE_CORRECTION: analysis_pb2.ANALYSIS_STAGE_CORRECTION,
+    STAGE_SCORING: analysis_pb2.ANALYSIS_STAGE_SCORING,
+    STAGE_COACHING: analysis_pb2.ANALYSIS_STAGE_COACHING,
+    STAGE_RENDER: analysis_pb2.ANALYSIS_STAGE_RENDER,
+}
+
+
+@dataclass(frozen=True)
+class _Upload:
+    header: analysis_pb2.AnalyzeVideoHeader
+    skill: Skill
+    handedness: str
+    input_path: Path
+    total_bytes: int
+    started: float
+
 
 def _safe_segment(value: str, fallback: str) -> str:
     cleaned = _SAFE_SEGMENT.sub("_", value).strip("._")
     return cleaned[:96] or fallback
 
 
+def _trace_id(context: grpc.ServicerContext) -> str:
+    """Returns the W3C trace ID the bot sent, so logs join its trace."""
+    traceparent = dict(context.invocation_metadata()).get("traceparent", "")
+    match = _TRACEPARENT.match(traceparent.strip().lower())
+    return match.group(1) if match else "-"
+
+
 class BadmintonAnalysisService(analysis_pb2_grpc.BadmintonAnalysisServicer):
     def __init__(self, settings: Settings) -> None:
         self.settings = settings
@@ -65,6 +103,7 @@ class BadmintonAnalysisService(analysis_pb2_grpc.BadmintonAnalysisServicer):
             device=settings.device,
             openai_model=settings.openai_model,
             pause_seconds=settings.coaching_pause_seconds,
+            queue_seconds=settings.queue_seconds,
         )
 
     def _authorize(self, context: grpc.ServicerContext) -> None:
@@ -87,103 +126,202 @@ class BadmintonAnalysisService(analysis_pb2_grpc.BadmintonAnalysisServicer):
             height=int(metadata.get("height", 0)),
         )
 
+    def _receive_upload(
+        self,
+        request_iterator: Iterable[analysis_pb2.AnalyzeVideoChunk],
+        context: grpc.ServicerContext,
+        temp_dir: Path,
+    ) -> _Upload:
+        started = time.perf_counter()
+        header = None
+        total_bytes = 0
+        input_path = temp_dir / "input.mp4"
+        with input_path.open("wb") as handle:
+            for chunk in request_iterator:
+                payload = chunk.WhichOneof("payload")
+                if payload == "header":
+                    if header is not None or total_bytes:
+                        context.abort(
+                            grpc.StatusCode.INVALID_ARGUMENT,
+                            "header must be the first and only header chunk",
+                        )
+                    header = chunk.header
+                elif payload == "data":
+                    if header is None:
+                        context.abort(
+                            grpc.StatusCode.INVALID_ARGUMENT,
+                            "video header must be sent first",
+                        )
+                    total_bytes += len(chunk.data)
+                    if total_bytes > self.settings.max_video_bytes:
+                        context.abort(
+                            grpc.StatusCode.RESOURCE_EXHAUSTED,
+                            "video exceeds configured size limit",
+                        )
+                    handle.write(chunk.data)
+                else:
+                    context.abort(grpc.StatusCode.INVALID_ARGUMENT, "empty chunk")
+        if header is None or total_bytes == 0:
+            context.abort(grpc.StatusCode.INVALID_ARGUMENT, "video is empty")
+        skill = _PROTO_TO_SKILL.get(header.skill)
+        handedness = _PROTO_TO_HANDEDNESS.get(header.handedness)
+        if skill is None or handedness is None:
+            context.abort(grpc.StatusCode.INVALID_ARGUMENT, "unsupported skill or handedness")
+        return _Upload(
+            header=header,
+            skill=skill,
+            handedness=handedness,
+            input_path=input_path,
+            total_bytes=total_bytes,
+            started=started,
+        )
+
+    def _analyze(
+        self,
+        analysis_id: str,
+        upload: _Upload,
+        on_stage: Callable[[str], None] | None = None,
+    ) -> analysis_pb2.AnalyzeVideoResponse:
+        output_path = upload.input_path.with_name("student_corrected.mp4")
+        result = self.pipeline.analyze(
+            video_path=upload.input_path,
+            output_path=output_path,
+            filename=upload.header.filename or "video.mp4",
+            skill=upload.skill,
+            requested_handedness=upload.handedness,
+            on_stage=on_stage,
+        )
+        catalog_started = time.perf_counter()
+        expert = self.catalog.get(str(upload.skill), result.expert_id)
+        if expert.handedness != str(result.handedness):
+            raise AnalysisRejected(
+                NO_MATCHING_EXPERT,
+                "selected expert handedness does not match the student: "
+                f"student={result.handedness}, expert={expert.handedness}"
+            )
+        catalog_finished = time.perf_counter()
+        user_segment = _safe_segment(upload.header.user_id, "anonymous")
+        request_segment = _safe_segment(upload.header.request_id, analysis_id)
+        object_path = (
+            f"analyses/v1/{user_segment}/{request_segment}/"
+            "student_corrected.mp4"
+        )
+        student_signed = self.storage.upload_file(
+            output_path, object_path, content_type="video/mp4"
+        )
+        expert_signed = self.storage.sign(expert.video_object_path)
+        storage_finished = time.perf_counter()
+        result.diagnostics.update(
+            {
+                "latency_catalog_seconds": catalog_finished - catalog_started,
+                "latency_storage_seconds": storage_finished - catalog_finished,
+                "latency_service_seconds": storage_finished - upload.started,
+                "input_video_bytes": float(upload.total_bytes),
+            }
+        )
+        return self._response(
+            analysis_id,
+            result,
+            student_signed,
+            expert_signed,
+            probe_video(output_path),
+            expert,
+        )
+
+    @staticmethod
+    def _log_started(
+        context: grpc.ServicerContext, analysis_id: str, upload: _Upload
+    ) -> None:
+        LOGGER.info(
+            "analysis started id=%s request_id=%s trace_id=%s bytes=%d",
+            analysis_id,
+            upload.header.request_id,
+            _trace_id(context),
+            upload.total_bytes,
+        )
+
+    def _finish(self, context: grpc.ServicerContext) -> None:
+        if self.settings.analyzer_version:
+            # Lets callers keep each grade with the checkpoints that produced it.
+            context.set_trailing_metadata(
+                (("x-analyzer-version", self.settings.analyzer_version),)
+            )
+
+    @staticmethod
+    def _abort(
+        context: grpc.ServicerContext, analysis_id: str, exc: Exception
+    ) -> None:
+        if isinstance(exc, AnalysisRejected):
+            LOGGER.warning(
+                "analysis rejected id=%s reason=%s error=%s",
+                analysis_id,
+                exc.reason,
+                exc,
+            )
+            context.abort_with_status(rejection_status(exc))
+        if isinstance(exc, (ValueError, KeyError)):
+            LOGGER.warning("analysis rejected id=%s error=%s", analysis_id, exc)
+            context.abort(grpc.StatusCode.FAILED_PRECONDITION, str(exc))
+        LOGGER.error("analysis failed id=%s", analysis_id, exc_info=exc)
+        context.abort(grpc.StatusCode.INTERNAL, "analysis failed")
+
     def AnalyzeVideo(
         self,
         request_iterator: Iterable[analysis_pb2.AnalyzeVideoChunk],
         context: grpc.ServicerContext,
     ) -> analysis_pb2.AnalyzeVideoResponse:
         self._authorize(context)
-        service_started = time.perf_counter()
-        header = None
-        total_bytes = 0
         analysis_id = uuid.uuid4().hex
         with tempfile.TemporaryDirectory(prefix="badminton-analysis-") as temp_value:
-            temp_dir = Path(temp_value)
-            input_path = temp_dir / "input.mp4"
-            with input_path.open("wb") as handle:
-                for chunk in request_iterator:
-                    payload = chunk.WhichOneof("payload")
-                    if payload == "header":
-                        if header is not None or total_bytes:
-                            context.abort(
-                                grpc.StatusCode.INVALID_ARGUMENT,
-                                "header must be the first and only header chunk",
-                            )
-                        header = chunk.header
-                    elif payload == "data":
-                        if header is None:
-                            context.abort(
-                                grpc.StatusCode.INVALID_ARGUMENT,
-                                "video header must be sent first",
-                            )
-                        total_bytes += len(chunk.data)
-                        if total_bytes > self.settings.max_video_bytes:
-                            context.abort(
-                                grpc.StatusCode.RESOURCE_EXHAUSTED,
-                                "video exceeds configured size limit",
-                            )
-                        handle.write(chunk.data)
-                    else:
-                        context.abort(grpc.StatusCode.INVALID_ARGUMENT, "empty chunk")
-            if header is None or total_bytes == 0:
-                context.abort(grpc.StatusCode.INVALID_ARGUMENT, "video is empty")
-            skill = _PROTO_TO_SKILL.get(header.skill)
-            handedness = _PROTO_TO_HANDEDNESS.get(header.handedness)
-            if skill is None or handedness is None:
-                context.abort(grpc.StatusCode.INVALID_ARGUMENT, "unsupported skill or handedness")
-
-            output_path = temp_dir / "student_corrected.mp4"
+            upload = self._receive_upload(request_iterator, context, Path(temp_value))
+            self._log_started(context, analysis_id, upload)
             try:
-                result = self.pipeline.analyze(
-                    video_path=input_path,
-                    output_path=output_path,
-                    filename=header.filename or "video.mp4",
-                    skill=skill,
-                    requested_handedness=handedness,
-                )
-                catalog_started = time.perf_counter()
-                expert = self.catalog.get(str(skill), result.expert_id)
-                if expert.handedness != str(result.handedness):
-                    raise ValueError(
-                        "selected expert handedness does not match the student: "
-                        f"student={result.handedness}, expert={expert.handedness}"
-                    )
-                catalog_finished = time.perf_counter()
-                user_segment = _safe_segment(header.user_id, "anonymous")
-                request_segment = _safe_segment(header.request_id, analysis_id)
-                object_path = (
-                    f"analyses/v1/{user_segment}/{request_segment}/"
-                    "student_corrected.mp4"
-                )
-                student_signed = self.storage.upload_file(
-                    output_path, object_path, content_type="video/mp4"
-                )
-                expert_signed = self.storage.sign(expert.video_object_path)
-                storage_finished = time.perf_counter()
-                result.diagnostics.update(
-                    {
-                        "latency_catalog_seconds": catalog_finished - catalog_started,
-                        "latency_storage_seconds": storage_finished - catalog_finished,
-                        "latency_service_seconds": storage_finished - service_started,
-                        "input_video_bytes": float(total_bytes),
-                    }
-                )
-                return self._response(
-                    analysis_id,
-                    result,
-                    student_signed,
-                    expert_signed,
-                    probe_video(output_path),
-                    expert,
-                )
-            except (ValueError, KeyError) as exc:
-                LOGGER.warning("analysis rejected id=%s error=%s", analysis_id, exc)
-                context.abort(grpc.StatusCode.FAILED_PRECONDITION, str(exc))
-            except Exception:
-                LOGGER.exception("analysis failed id=%s", analysis_id)
-                context.abort(grpc.StatusCode.INTERNAL, "analysis failed")
+                response = self._analyze(analysis_id, upload)
+            except Exception as exc:
+                self._abort(context, analysis_id, exc)
+            self._finish(context)
+            return response
         raise AssertionError("unreachable")
 
+    def AnalyzeVideoWithProgress(
+        self,
+        request_iterator: Iterable[analysis_pb2.AnalyzeVideoChunk],
+        context: grpc.ServicerContext,
+    ) -> Iterator[analysis_pb2.AnalyzeVideoEvent]:
+        self._authorize(context)
+        analysis_id = uuid.uuid4().hex
+        with tempfile.TemporaryDirectory(prefix="badminton-analysis-") as temp_value:
+            upload = self._receive_upload(request_iterator, context, Path(temp_value))
+            self._log_started(context, analysis_id, upload)
+            # The pipeline runs on a worker so its stages can be sent while it
+            # works; aborting stays on the handler thread.
+            events: queue.Queue[analysis_pb2.AnalyzeVideoEvent | Exception] = queue.Queue()
+
+            def report(stage: str) -> None:
+                events.put(analysis_pb2.AnalyzeVideoEvent(stage=_STAGE_TO_PROTO[stage]))
+
+            def run() -> None:
+                try:
+                    response = self._analyze(analysis_id, upload, report)
+                except Exception as exc:
+                    events.put(exc)
+                else:
+                    events.put(analysis_pb2.AnalyzeVideoEvent(result=response))
+
+            worker = threading.Thread(target=run, name=f"analysis-{analysis_id}", daemon=True)
+            worker.start()
+            while True:
+                event = events.get()
+                if isinstance(event, Exception):
+                    worker.join()
+                    self._abort(context, analysis_id, event)
+                if event.HasField("result"):
+                    worker.join()
+                    self._finish(context)
+                yield event
+                if event.HasField("result"):
+                    return
+
     def _response(
         self,
         analysis_id: str,
@@ -5,7 +5,7 @@ import React, { useEffect, useMemo, useState } from 'react'
 import { BarChart3, Columns2 } from 'lucide-react'
 
 import { useLiff } from '../LiffProvider'
-import type { GradingDetail, PlaybackResponse, UserData } from '@/types'
+import type { AnalysisJob, GradingDetail, PlaybackResponse, UserData } from '@/types'
 import { Card, CardContent, CardHeader, CardTitle } from '@/components/ui/card'
 import {
   ChartConfig,
@@ -22,6 +22,7 @@ import { Skill, SkillNameMap } from '@/lib/types'
 import { fetchUserDataSafe } from '@/lib/api/fetchUserDataSafe'
 import { fetchPlayback } from '@/lib/api/fetchPlayback'
 import VideoComparison from '@/components/VideoComparison'
+import AnalysisProgress from '@/components/AnalysisProgress'
 
 const TAB_OPTIONS = [
   { value: 'scores', label: '成績分析', icon: BarChart3 },
@@ -103,6 +104,8 @@ export default function PersonalPage() {
   const [playbackError, setPlaybackError] = useState('')
   const [playbackLoading, setPlaybackLoading] = useState(false)
   const [activeTab, setActiveTab] = useState<TabValue>('scores')
+  // Set when an analysis seen running finishes, to load and select its work
+  const [finished, setFinished] = useState<AnalysisJob | null>(null)
   const { liff, profile, liffError } = useLiff()
 
   useEffect(() => {
@@ -120,6 +123,13 @@ export default function PersonalPage() {
         if (!result.ok) throw result.error
         const data = result.data
         setUserData(data)
+        const newSkill = finished?.skill as Skill | undefined
+        const newDate = finished?.work_date
+        if (newSkill && newDate && data.portfolio[newSkill]?.[newDate]) {
+          setSelectedSkill(newSkill)
+          setSelectedDate(newDate)
+          return
+        }
         const firstSkill = (Object.keys(SkillNameMap) as Skill[]).find(
           skill => Object.keys(data.portfolio[skill]).length > 0
         )
@@ -136,7 +146,7 @@ export default function PersonalPage() {
     }
 
     fetchData()
-  }, [liff, liffError, profile])
+  }, [liff, liffError, profile, finished])
 
   const availableSkills = useMemo(
     () =>
@@ -205,9 +215,14 @@ export default function PersonalPage() {
     )
   }
 
+  const progress = profile?.userId && (
+    <AnalysisProgress userId={profile.userId} onDone={setFinished} />
+  )
+
   if (availableSkills.length === 0) {
     return (
-      <PageContainer className="pt-6">
+      <PageContainer className="space-y-4 pt-6">
+        {progress}
         <Alert title="還沒有動作分析">上傳一段練習影片，分析完成後這裡會顯示評分。</Alert>
       </PageContainer>
     )
@@ -223,6 +238,7 @@ export default function PersonalPage() {
   return (
     <PageContainer className="pt-6">
       <main className="space-y-6">
+        {progress}
         <div className="flex gap-3">
           <SelectField
             label="技能"
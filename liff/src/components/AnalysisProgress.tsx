@@ -0,0 +1,96 @@
+'use client'
+
+import React, { useEffect, useRef, useState } from 'react'
+
+import type { AnalysisJob } from '@/types'
+import { Alert } from '@/components/ui/alert'
+import { Card, CardContent, CardHeader, CardTitle } from '@/components/ui/card'
+import { fetchAnalysisJob } from '@/lib/api/fetchAnalysisJob'
+import { POLL_INTERVAL_MS, shouldShowJob, stageProgress } from '@/lib/analysisProgress'
+import { SkillNameMap, type Skill } from '@/lib/types'
+
+interface AnalysisProgressProps {
+  userId: string
+  /** Called once an analysis seen running here finishes, to load the new work. */
+  onDone: (job: AnalysisJob) => void
+}
+
+/**
+ * The latest upload's analysis while it runs, polled until it ends. A failed
+ * or interrupted one stays up for a while with what the student was told.
+ */
+export default function AnalysisProgress({ userId, onDone }: AnalysisProgressProps) {
+  const [job, setJob] = useState<AnalysisJob | null>(null)
+  const onDoneRef = useRef(onDone)
+  onDoneRef.current = onDone
+
+  useEffect(() => {
+    let cancelled = false
+    let timer: ReturnType<typeof setTimeout> | undefined
+    let sawRunning = false
+
+    const poll = async (): Promise<void> => {
+      try {
+        const latest = await fetchAnalysisJob(userId)
+        if (cancelled) return
+        setJob(latest)
+        if (latest?.state === 'running') {
+          sawRunning = true
+          timer = setTimeout(poll, POLL_INTERVAL_MS)
+          return
+        }
+        if (latest?.state === 'done' && sawRunning) onDoneRef.current(latest)
+      } catch (error) {
+        if (cancelled) return
+        if (error instanceof Error) console.error(error.message)
+        // Keep polling through a blip while an analysis is known to run
+        if (sawRunning) timer = setTimeout(poll, POLL_INTERVAL_MS)
+      }
+    }
+
+    poll()
+    return () => {
+      cancelled = true
+      clearTimeout(timer)
+    }
+  }, [userId])
+
+  if (!job || !shouldShowJob(job, Date.now())) return null
+
+  const skill = SkillNameMap[job.skill as Skill] ?? job.skill
+
+  if (job.state !== 'running') {
+    const title = job.state === 'interrupted' ? `${skill}分析被中斷` : `${skill}分析沒有完成`
+    return (
+      <Alert variant="warning" title={title}>
+        {job.message || '請再上傳一次影片。'}
+      </Alert>
+    )
+  }
+
+  const progress = stageProgress(job.stage)
+  return (
+    <Card role="status" aria-live="polite">
+      <CardHeader className="pb-2">
+        <CardTitle>{skill}影片分析中</CardTitle>
+      </CardHeader>
+      <CardContent>
+        <div className="flex items-baseline justify-between gap-3 text-sm">
+          <span>{progress.label}⋯</span>
+          <span className="num shrink-0 text-[13px] text-muted-foreground">
+            {progress.step} / {progress.total}
+          </span>
+        </div>
+        <div className="mt-2 h-1.5 w-full overflow-hidden rounded-full bg-muted">
+          <div
+            className="h-full rounded-full bg-primary transition-[width] duration-500"
+            style={{ width: `${(progress.step / progress.total) * 100}%` }}
+          />
+        </div>
+        <p className="mt-3 text-[13px] text-muted-foreground">
+          分析需要幾分鐘，完成後會自動顯示結果，LINE 也會通知你。
+        </p>
+      </CardContent>
+    </Card>
+  )
+}
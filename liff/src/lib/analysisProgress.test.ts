@@ -0,0 +1,28 @@
+import assert from 'node:assert/strict'
+import test from 'node:test'
+
+import { shouldShowJob, stageProgress } from './analysisProgress.ts'
+import type { AnalysisJob } from '@/schemas/analysisJob.schema.ts'
+
+const job = (state: AnalysisJob['state'], updatedAt: string): AnalysisJob => ({
+  request_id: '468789577898262530',
+  skill: 'serve',
+  state,
+  stage: 'scoring',
+  started_at: '2026-10-19T10:00:00Z',
+  updated_at: updatedAt
+})
+
+test('places each stage in order and unknown ones first', () => {
+  assert.deepEqual(stageProgress('upload'), { step: 1, total: 6, label: '上傳影片' })
+  assert.deepEqual(stageProgress('scoring'), { step: 4, total: 6, label: '評分' })
+  assert.deepEqual(stageProgress('warmup'), { step: 1, total: 6, label: '上傳影片' })
+})
+
+test('shows running jobs and recent failures only', () => {
+  const now = Date.parse('2026-10-19T10:05:00Z')
+  assert.equal(shouldShowJob(job('running', '2026-10-18T10:00:00Z'), now), true)
+  assert.equal(shouldShowJob(job('failed', '2026-10-19T10:01:00.123456789Z'), now), true)
+  assert.equal(shouldShowJob(job('interrupted', '2026-10-19T09:00:00Z'), now), false)
+  assert.equal(shouldShowJob(job('done', '2026-10-19T10:04:00Z'), now), false)
+})
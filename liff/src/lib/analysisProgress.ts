@@ -0,0 +1,44 @@
+import type { AnalysisJob } from '@/types'
+
+/** The stages the bot reports, in order; "upload" comes before the analyzer's own. */
+export const ANALYSIS_STAGES = [
+  { id: 'upload', label: '上傳影片' },
+  { id: 'pose', label: '辨識動作' },
+  { id: 'correction', label: '與專家動作比對' },
+  { id: 'scoring', label: '評分' },
+  { id: 'coaching', label: '撰寫教練建議' },
+  { id: 'render', label: '製作分析影片' }
+] as const
+
+/** How often a running analysis is polled. */
+export const POLL_INTERVAL_MS = 3000
+
+/** How long a failed or interrupted analysis stays on the page. */
+export const RECENT_MS = 10 * 60 * 1000
+
+export interface StageProgress {
+  /** 1-based step, for "step 2 of 6". */
+  step: number
+  total: number
+  label: string
+}
+
+/** Where a stage sits in the analysis. An unknown stage counts as the first. */
+export function stageProgress(stage: string): StageProgress {
+  const index = Math.max(
+    0,
+    ANALYSIS_STAGES.findIndex(s => s.id === stage)
+  )
+  return { step: index + 1, total: ANALYSIS_STAGES.length, label: ANALYSIS_STAGES[index].label }
+}
+
+/**
+ * Whether the job is worth showing: a running one always, a failed or
+ * interrupted one while it is recent. A finished one shows as the new work.
+ */
+export function shouldShowJob(job: AnalysisJob, now: number): boolean {
+  if (job.state === 'running') return true
+  if (job.state === 'done') return false
+  const updated = Date.parse(job.updated_at)
+  return Number.isFinite(updated) && now - updated < RECENT_MS
+}
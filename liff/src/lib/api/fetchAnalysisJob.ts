@@ -0,0 +1,17 @@
+import { AnalysisJobSchema, type AnalysisJob } from '@/schemas/analysisJob.schema'
+import { ErrorResponseSchema } from '@/schemas/error.schema'
+import { getBackendBaseUrl } from '@/utils/env'
+
+/** The user's latest analysis, or null before their first upload. */
+export async function fetchAnalysisJob(userId: string): Promise<AnalysisJob | null> {
+  const query = new URLSearchParams({ user_id: userId })
+  const response = await fetch(`${getBackendBaseUrl()}/api/db/analysis?${query.toString()}`, {
+    cache: 'no-store'
+  })
+  if (response.status === 404) return null
+  if (!response.ok) {
+    const parsed = ErrorResponseSchema.safeParse(await response.json().catch(() => null))
+    throw new Error(parsed.success ? parsed.data.error : `Unable to load analysis (${response.status})`)
+  }
+  return AnalysisJobSchema.parse(await response.json())
+}
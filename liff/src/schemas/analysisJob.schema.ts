@@ -0,0 +1,15 @@
+import { z } from 'zod'
+
+/** The latest upload's analysis, as served by `/api/db/analysis`. */
+export const AnalysisJobSchema = z.object({
+  request_id: z.string(),
+  skill: z.string(),
+  state: z.enum(['running', 'done', 'failed', 'interrupted']),
+  stage: z.string(),
+  message: z.string().optional(),
+  work_date: z.string().optional(),
+  started_at: z.string(),
+  updated_at: z.string()
+})
+
+export type AnalysisJob = z.infer<typeof AnalysisJobSchema>
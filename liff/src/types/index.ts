@@ -12,3 +12,4 @@ export type {
   UserData,
   Work
 } from '@/schemas/userData.schema'
+export type { AnalysisJob } from '@/schemas/analysisJob.schema'
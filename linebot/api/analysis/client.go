@@ -5,27 +5,34 @@ import (
 	"crypto/tls"
 	"errors"
 	"fmt"
+	"io"
 	"strings"
 	"time"
 
 	analysisv1 "github.com/HeavenAQ/nstc-linebot-2025/api/analysis/v1"
 	"github.com/HeavenAQ/nstc-linebot-2025/commons"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
 	insecurecredentials "google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/metadata"
-	"google.golang.org/grpc/status"
 )
 
 const chunkSize = 1024 * 1024
 
-var ErrNoMatchingExpert = errors.New("no same-handed expert is available")
+// analyzerVersionKey is the trailer the analysis service names its
+// checkpoints in, so a grade can be traced to what produced it.
+const analyzerVersionKey = "x-analyzer-version"
+
+var errVideoEmpty = errors.New("video is empty")
 
 type Client struct {
 	connection *grpc.ClientConn
 	service    analysisv1.BadmintonAnalysisClient
 	apiKey     string
+	retry      RetryPolicy
+	breaker    *Breaker
+	timeout    time.Duration
 }
 
 func NewClient(target, apiKey string, useInsecure bool) (*Client, error) {
@@ -47,6 +54,9 @@ func NewClient(target, apiKey string, useInsecure bool) (*Client, error) {
 	connection, err := grpc.NewClient(
 		target,
 		grpc.WithTransportCredentials(transport),
+		// Traces each call and sends its trace context in the metadata,
+		// alongside x-api-key.
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
 		grpc.WithDefaultCallOptions(
 			grpc.MaxCallRecvMsgSize(8*1024*1024),
 			grpc.MaxCallSendMsgSize(chunkSize+1024),
@@ -59,9 +69,34 @@ func NewClient(target, apiKey string, useInsecure bool) (*Client, error) {
 		connection: connection,
 		service:    analysisv1.NewBadmintonAnalysisClient(connection),
 		apiKey:     apiKey,
+		retry:      DefaultRetryPolicy(),
+		breaker:    NewBreaker(5, 30*time.Second),
+		timeout:    30 * time.Minute,
 	}, nil
 }
 
+// SetRetryPolicy replaces the default retries and breaker.
+func (c *Client) SetRetryPolicy(policy RetryPolicy, breaker *Breaker) {
+	c.retry = policy
+	c.breaker = breaker
+}
+
+// SetTimeout bounds each analysis, with its retries, to timeout.
+func (c *Client) SetTimeout(timeout time.Duration) {
+	c.timeout = timeout
+}
+
+// withTimeout bounds one analysis by the client's timeout.
+func (c *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.timeout)
+}
+
+// BreakerState reports whether calls are currently being refused.
+func (c *Client) BreakerState() BreakerState { return c.breaker.State() }
+
 func (c *Client) Close() error { return c.connection.Close() }
 
 func (c *Client) authorizedContext(ctx context.Context) context.Context {
@@ -96,13 +131,40 @@ func handednessValue(handedness string) (analysisv1.Handedness, error) {
 	}
 }
 
+// AnalyzeVideo streams video to the analysis service one chunk at a time, so
+// memory use stays at one chunk however long the upload is. A failed stream
+// is replayed if video can seek back to where it started, or if it failed
+// before more than the first chunk was read.
 func (c *Client) AnalyzeVideo(
 	ctx context.Context,
 	requestID, userID, filename, skill, handedness string,
-	video []byte,
+	video io.Reader,
 ) (*commons.AnalysisOutcome, error) {
-	if len(video) == 0 {
-		return nil, fmt.Errorf("video is empty")
+	return c.AnalyzeVideoWithProgress(ctx, requestID, userID, filename, skill, handedness, video, nil)
+}
+
+// AnalyzeVideoWithProgress is AnalyzeVideo that also sends progress each
+// stage the service starts. A stage is dropped rather than waited for when
+// progress is full, and progress is never closed. A replayed stream reports
+// its stages again. With a nil progress the plain AnalyzeVideo call is used,
+// which analysis services without progress events also serve.
+func (c *Client) AnalyzeVideoWithProgress(
+	ctx context.Context,
+	requestID, userID, filename, skill, handedness string,
+	video io.Reader,
+	progress chan<- Stage,
+) (*commons.AnalysisOutcome, error) {
+	if video == nil {
+		return nil, errVideoEmpty
+	}
+	rewind := rewinder(video)
+	chunk := make([]byte, chunkSize)
+	n, err := readChunk(video, chunk)
+	if errors.Is(err, io.EOF) {
+		return nil, errVideoEmpty
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read video: %w", err)
 	}
 	skillEnum, err := skillValue(skill)
 	if err != nil {
@@ -112,37 +174,139 @@ func (c *Client) AnalyzeVideo(
 	if err != nil {
 		return nil, err
 	}
-	ctx, cancel := context.WithTimeout(c.authorizedContext(ctx), 30*time.Minute)
+	header := &analysisv1.AnalyzeVideoHeader{
+		RequestId: requestID, UserId: userID, Filename: filename,
+		Skill: skillEnum, Handedness: handednessEnum,
+	}
+
+	ctx, cancel := c.withTimeout(c.authorizedContext(ctx))
 	defer cancel()
-	stream, err := c.service.AnalyzeVideo(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("start analysis stream: %w", err)
+	var result *commons.AnalysisOutcome
+	attempts, replayable := 0, true
+	err = c.call(ctx, func() error {
+		attempts++
+		if attempts > 1 && rewind != nil {
+			var err error
+			if n, err = rewind(chunk); err != nil {
+				return fmt.Errorf("rewind video: %w", err)
+			}
+		}
+		var readMore bool
+		var err error
+		result, readMore, err = c.streamAnalysis(ctx, header, video, chunk, n, progress)
+		replayable = rewind != nil || !readMore
+		return err
+	}, func() bool { return replayable })
+	return result, err
+}
+
+// streamAnalysis sends one analysis stream whose first n bytes are already in
+// chunk. readMore reports whether it read past them.
+func (c *Client) streamAnalysis(
+	ctx context.Context,
+	header *analysisv1.AnalyzeVideoHeader,
+	video io.Reader,
+	chunk []byte,
+	n int,
+	progress chan<- Stage,
+) (result *commons.AnalysisOutcome, readMore bool, err error) {
+	var trailer metadata.MD
+	var response *analysisv1.AnalyzeVideoResponse
+	if progress == nil {
+		stream, err := c.service.AnalyzeVideo(ctx, grpc.Trailer(&trailer))
+		if err != nil {
+			return nil, false, fmt.Errorf("start analysis stream: %w", err)
+		}
+		if readMore, err = sendVideo(stream, header, video, chunk, n); err != nil {
+			return nil, readMore, err
+		}
+		response, err = stream.CloseAndRecv()
+		if err != nil {
+			return nil, readMore, fmt.Errorf("receive analysis: %w", classify(err))
+		}
+	} else {
+		stream, err := c.service.AnalyzeVideoWithProgress(ctx, grpc.Trailer(&trailer))
+		if err != nil {
+			return nil, false, fmt.Errorf("start analysis stream: %w", err)
+		}
+		if readMore, err = sendVideo(stream, header, video, chunk, n); err != nil {
+			return nil, readMore, err
+		}
+		if err := stream.CloseSend(); err != nil {
+			return nil, readMore, fmt.Errorf("finish video upload: %w", err)
+		}
+		response, err = receiveEvents(stream, progress)
+		if err != nil {
+			return nil, readMore, fmt.Errorf("receive analysis: %w", classify(err))
+		}
 	}
+	result = outcome(response)
+	if values := trailer.Get(analyzerVersionKey); len(values) > 0 {
+		result.AnalyzerVersion = values[0]
+	}
+	return result, readMore, nil
+}
+
+// sendVideo sends the header and then video, whose first n bytes are already
+// in chunk. readMore reports whether it read past them.
+func sendVideo(
+	stream interface {
+		Send(*analysisv1.AnalyzeVideoChunk) error
+	},
+	header *analysisv1.AnalyzeVideoHeader,
+	video io.Reader,
+	chunk []byte,
+	n int,
+) (readMore bool, err error) {
 	if err := stream.Send(&analysisv1.AnalyzeVideoChunk{
-		Payload: &analysisv1.AnalyzeVideoChunk_Header{Header: &analysisv1.AnalyzeVideoHeader{
-			RequestId: requestID, UserId: userID, Filename: filename,
-			Skill: skillEnum, Handedness: handednessEnum,
-		}},
+		Payload: &analysisv1.AnalyzeVideoChunk_Header{Header: header},
 	}); err != nil {
-		return nil, fmt.Errorf("send analysis header: %w", err)
+		return false, fmt.Errorf("send analysis header: %w", err)
 	}
-	for start := 0; start < len(video); start += chunkSize {
-		end := min(start+chunkSize, len(video))
+	// Send marshals the message before it returns, so the chunk buffer can be
+	// refilled for the next one.
+	for n > 0 {
 		if err := stream.Send(&analysisv1.AnalyzeVideoChunk{
-			Payload: &analysisv1.AnalyzeVideoChunk_Data{Data: video[start:end]},
+			Payload: &analysisv1.AnalyzeVideoChunk_Data{Data: chunk[:n]},
 		}); err != nil {
-			return nil, fmt.Errorf("stream video: %w", err)
+			return readMore, fmt.Errorf("stream video: %w", err)
+		}
+		readMore = true
+		n, err = readChunk(video, chunk)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return readMore, fmt.Errorf("read video: %w", err)
 		}
 	}
-	response, err := stream.CloseAndRecv()
+	return readMore, nil
+}
+
+// rewinder returns how to start video over and read its first chunk again,
+// or nil if it cannot seek.
+func rewinder(video io.Reader) func(chunk []byte) (int, error) {
+	seeker, ok := video.(io.Seeker)
+	if !ok {
+		return nil
+	}
+	start, err := seeker.Seek(0, io.SeekCurrent)
 	if err != nil {
-		if status.Code(err) == codes.FailedPrecondition &&
-			strings.Contains(status.Convert(err).Message(), "expert reference") {
-			return nil, fmt.Errorf("%w: %s", ErrNoMatchingExpert, status.Convert(err).Message())
+		return nil
+	}
+	return func(chunk []byte) (int, error) {
+		if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+			return 0, err
 		}
-		return nil, fmt.Errorf("receive analysis: %w", err)
+		return readChunk(video, chunk)
 	}
-	return outcome(response), nil
+}
+
+// readChunk fills chunk from video. Only the final chunk is short, and
+// io.EOF is returned once nothing is left.
+func readChunk(video io.Reader, chunk []byte) (int, error) {
+	n, err := io.ReadFull(video, chunk)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return n, nil
+	}
+	return n, err
 }
 
 func media(value *analysisv1.StoredVideo) commons.MediaRef {
@@ -186,9 +350,15 @@ func outcome(response *analysisv1.AnalyzeVideoResponse) *commons.AnalysisOutcome
 }
 
 func (c *Client) RefreshPlaybackURLs(ctx context.Context, objectPaths ...string) ([]commons.MediaRef, error) {
-	ctx, cancel := context.WithTimeout(c.authorizedContext(ctx), 15*time.Second)
-	defer cancel()
-	response, err := c.service.RefreshPlaybackUrls(ctx, &analysisv1.RefreshPlaybackUrlsRequest{ObjectPaths: objectPaths})
+	ctx = c.authorizedContext(ctx)
+	var response *analysisv1.RefreshPlaybackUrlsResponse
+	err := c.call(ctx, func() error {
+		attemptCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
+		defer cancel()
+		var err error
+		response, err = c.service.RefreshPlaybackUrls(attemptCtx, &analysisv1.RefreshPlaybackUrlsRequest{ObjectPaths: objectPaths})
+		return err
+	}, func() bool { return true })
 	if err != nil {
 		return nil, fmt.Errorf("refresh playback URLs: %w", err)
 	}
@@ -200,14 +370,53 @@ func (c *Client) RefreshPlaybackURLs(ctx context.Context, objectPaths ...string)
 }
 
 func (c *Client) Health(ctx context.Context) error {
-	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
-	defer cancel()
-	response, err := c.service.Health(ctx, &analysisv1.HealthRequest{})
+	_, err := c.health(ctx)
+	return err
+}
+
+// skills are the skills the bot offers; the service must have an expert
+// reference loaded for each before it can take uploads.
+var skills = []string{"serve", "lift", "clear", "smash"}
+
+// Ready checks that the service is serving and has every skill loaded.
+func (c *Client) Ready(ctx context.Context) error {
+	response, err := c.health(ctx)
 	if err != nil {
 		return err
 	}
-	if response.Status != "serving" {
-		return fmt.Errorf("analysis service status: %s", response.Status)
+	loaded := make(map[analysisv1.Skill]bool, len(response.GetLoadedSkills()))
+	for _, skill := range response.GetLoadedSkills() {
+		loaded[skill] = true
+	}
+	var missing []string
+	for _, skill := range skills {
+		value, _ := skillValue(skill)
+		if !loaded[value] {
+			missing = append(missing, skill)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("analysis service has not loaded skills: %s", strings.Join(missing, ", "))
 	}
 	return nil
 }
+
+// healthTimeout bounds a health call whose caller set no deadline. A caller
+// waiting out a cold start sets a longer one.
+const healthTimeout = 10 * time.Second
+
+func (c *Client) health(ctx context.Context) (*analysisv1.HealthResponse, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, healthTimeout)
+		defer cancel()
+	}
+	response, err := c.service.Health(ctx, &analysisv1.HealthRequest{})
+	if err != nil {
+		return nil, err
+	}
+	if response.Status != "serving" {
+		return nil, fmt.Errorf("analysis service status: %s", response.Status)
+	}
+	return response, nil
+}
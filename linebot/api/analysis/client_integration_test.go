@@ -30,8 +30,9 @@ func TestLiveAnalysisRejectsOppositeHandFallback(t *testing.T) {
 	require.NotEmpty(t, videoPath)
 	require.NotEmpty(t, skill)
 
-	video, err := os.ReadFile(videoPath)
+	video, err := os.Open(videoPath)
 	require.NoError(t, err)
+	t.Cleanup(func() { video.Close() })
 	client, err := analysis.NewClient(target, apiKey, false)
 	require.NoError(t, err)
 	t.Cleanup(func() { require.NoError(t, client.Close()) })
@@ -69,8 +70,9 @@ func TestLiveAnalysisService(t *testing.T) {
 	require.NotEmpty(t, apiKey)
 	require.NotEmpty(t, videoPath)
 
-	video, err := os.ReadFile(videoPath)
+	video, err := os.Open(videoPath)
 	require.NoError(t, err)
+	t.Cleanup(func() { video.Close() })
 	client, err := analysis.NewClient(target, apiKey, os.Getenv("ANALYSIS_GRPC_INSECURE") == "true")
 	require.NoError(t, err)
 	t.Cleanup(func() { require.NoError(t, client.Close()) })
@@ -2,9 +2,15 @@ package analysis
 
 import (
 	"context"
+	"io"
+	"runtime"
 	"testing"
+	"time"
 
+	analysisv1 "github.com/HeavenAQ/nstc-linebot-2025/api/analysis/v1"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 func TestAnalyzeVideoRejectsEmptyInputBeforeStartingStream(t *testing.T) {
@@ -22,3 +28,131 @@ func TestAnalyzeVideoRejectsEmptyInputBeforeStartingStream(t *testing.T) {
 	require.Nil(t, result)
 	require.EqualError(t, err, "video is empty")
 }
+
+// patternReader yields size bytes without holding them in memory.
+type patternReader struct {
+	remaining int64
+}
+
+func (r *patternReader) Read(p []byte) (int, error) {
+	if r.remaining == 0 {
+		return 0, io.EOF
+	}
+	n := int(min(int64(len(p)), r.remaining))
+	for i := range p[:n] {
+		p[i] = byte(i)
+	}
+	r.remaining -= int64(n)
+	return n, nil
+}
+
+// countingStream discards chunks, counting what the server would receive.
+type countingStream struct {
+	grpc.ClientStream
+	headers int
+	chunks  int
+	bytes   int64
+}
+
+func (s *countingStream) Send(chunk *analysisv1.AnalyzeVideoChunk) error {
+	if chunk.GetHeader() != nil {
+		s.headers++
+		return nil
+	}
+	s.chunks++
+	s.bytes += int64(len(chunk.GetData()))
+	return nil
+}
+
+func (s *countingStream) CloseAndRecv() (*analysisv1.AnalyzeVideoResponse, error) {
+	return &analysisv1.AnalyzeVideoResponse{
+		AnalysisId: "analysis-id",
+		Grade:      &analysisv1.GradingOutcome{},
+		Expert:     &analysisv1.ExpertMatch{},
+	}, nil
+}
+
+type fakeService struct {
+	analysisv1.BadmintonAnalysisClient
+	stream  *countingStream
+	version string
+}
+
+func (s *fakeService) AnalyzeVideo(
+	_ context.Context, opts ...grpc.CallOption,
+) (grpc.ClientStreamingClient[analysisv1.AnalyzeVideoChunk, analysisv1.AnalyzeVideoResponse], error) {
+	for _, opt := range opts {
+		if trailer, ok := opt.(grpc.TrailerCallOption); ok && s.version != "" {
+			*trailer.TrailerAddr = metadata.Pairs(analyzerVersionKey, s.version)
+		}
+	}
+	return s.stream, nil
+}
+
+func TestAnalyzeVideoStreamsWithBoundedMemory(t *testing.T) {
+	const size = 64<<20 + 12345
+	stream := &countingStream{}
+	client := &Client{service: &fakeService{stream: stream, version: "2026.10-ckpt7"}}
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	result, err := client.AnalyzeVideo(
+		context.Background(), "request-id", "user-id", "video.mp4", "serve", "right",
+		&patternReader{remaining: size},
+	)
+	runtime.ReadMemStats(&after)
+
+	require.NoError(t, err)
+	require.Equal(t, "analysis-id", result.AnalysisID)
+	require.Equal(t, "2026.10-ckpt7", result.AnalyzerVersion)
+	require.Equal(t, 1, stream.headers)
+	require.Equal(t, 65, stream.chunks)
+	require.EqualValues(t, size, stream.bytes)
+	allocated := after.TotalAlloc - before.TotalAlloc
+	require.Less(t, allocated, uint64(4*chunkSize), "allocated %d bytes to stream %d", allocated, size)
+}
+
+type healthService struct {
+	analysisv1.BadmintonAnalysisClient
+	response *analysisv1.HealthResponse
+	deadline time.Time
+}
+
+func (s *healthService) Health(
+	ctx context.Context, _ *analysisv1.HealthRequest, _ ...grpc.CallOption,
+) (*analysisv1.HealthResponse, error) {
+	s.deadline, _ = ctx.Deadline()
+	return s.response, nil
+}
+
+func TestReadyRequiresEverySkillLoaded(t *testing.T) {
+	service := &healthService{response: &analysisv1.HealthResponse{
+		Status:       "serving",
+		LoadedSkills: []analysisv1.Skill{analysisv1.Skill_SKILL_SERVE, analysisv1.Skill_SKILL_CLEAR},
+	}}
+	client := &Client{service: service}
+
+	require.NoError(t, client.Health(context.Background()))
+	require.EqualError(t, client.Ready(context.Background()), "analysis service has not loaded skills: lift, smash")
+
+	service.response.LoadedSkills = append(service.response.LoadedSkills, analysisv1.Skill_SKILL_LIFT, analysisv1.Skill_SKILL_SMASH)
+	require.NoError(t, client.Ready(context.Background()))
+
+	service.response.Status = "loading"
+	require.EqualError(t, client.Ready(context.Background()), "analysis service status: loading")
+}
+
+func TestHealthKeepsTheCallersDeadline(t *testing.T) {
+	service := &healthService{response: &analysisv1.HealthResponse{Status: "serving"}}
+	client := &Client{service: service}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	require.NoError(t, client.Health(ctx))
+	deadline, _ := ctx.Deadline()
+	require.Equal(t, deadline, service.deadline)
+
+	require.NoError(t, client.Health(context.Background()))
+	require.WithinDuration(t, time.Now().Add(healthTimeout), service.deadline, time.Second)
+}
@@ -0,0 +1,55 @@
+package analysis
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+)
+
+// errorDomain is the ErrorInfo domain the analysis service names the reason
+// of a rejected video in.
+const errorDomain = "badminton-analysis"
+
+// Failures the student can fix by recording or choosing differently.
+var (
+	ErrNoPersonDetected = errors.New("no player was detected in the video")
+	ErrWrongSkill       = errors.New("the video does not show the selected skill")
+	ErrClipTooShort     = errors.New("the player is not visible long enough to analyze")
+	ErrVideoUnreadable  = errors.New("the video could not be decoded")
+	ErrNoMatchingExpert = errors.New("no same-handed expert is available")
+)
+
+var reasonErrors = map[string]error{
+	"NO_PERSON_DETECTED": ErrNoPersonDetected,
+	"WRONG_SKILL":        ErrWrongSkill,
+	"CLIP_TOO_SHORT":     ErrClipTooShort,
+	"VIDEO_UNREADABLE":   ErrVideoUnreadable,
+	"NO_MATCHING_EXPERT": ErrNoMatchingExpert,
+	"SERVICE_OVERLOADED": ErrServiceBusy,
+}
+
+// reasonError returns the error for the reason the analysis service attached
+// to err, or nil if it gave none this client knows.
+func reasonError(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return nil
+	}
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && info.GetDomain() == errorDomain {
+			return reasonErrors[info.GetReason()]
+		}
+	}
+	return nil
+}
+
+// classify wraps err with the error for its reason, keeping the status so
+// retries still see the code.
+func classify(err error) error {
+	if reason := reasonError(err); reason != nil {
+		return fmt.Errorf("%w: %w", reason, err)
+	}
+	return err
+}
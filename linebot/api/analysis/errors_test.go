@@ -0,0 +1,66 @@
+package analysis
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func rejection(t *testing.T, code codes.Code, domain, reason string) error {
+	st, err := status.New(code, "rejected").WithDetails(&errdetails.ErrorInfo{Domain: domain, Reason: reason})
+	require.NoError(t, err)
+	return st.Err()
+}
+
+func TestAnalyzeVideoMapsRejectionReasons(t *testing.T) {
+	for reason, want := range map[string]error{
+		"NO_PERSON_DETECTED": ErrNoPersonDetected,
+		"WRONG_SKILL":        ErrWrongSkill,
+		"CLIP_TOO_SHORT":     ErrClipTooShort,
+		"VIDEO_UNREADABLE":   ErrVideoUnreadable,
+		"NO_MATCHING_EXPERT": ErrNoMatchingExpert,
+	} {
+		t.Run(reason, func(t *testing.T) {
+			service := &flakyService{failures: []error{rejection(t, codes.FailedPrecondition, errorDomain, reason)}}
+			client := &Client{service: service, retry: quickRetry}
+
+			err := analyze(client, bytes.NewReader([]byte("x")))
+
+			require.ErrorIs(t, err, want)
+			require.Equal(t, codes.FailedPrecondition, status.Code(err))
+			require.Len(t, service.streams, 1)
+		})
+	}
+}
+
+func TestAnalyzeVideoRetriesOverloadedService(t *testing.T) {
+	overloaded := rejection(t, codes.Unavailable, errorDomain, "SERVICE_OVERLOADED")
+	service := &flakyService{failures: []error{overloaded, overloaded, overloaded}}
+	client := &Client{service: service, retry: quickRetry}
+
+	err := analyze(client, bytes.NewReader([]byte("x")))
+
+	require.ErrorIs(t, err, ErrServiceBusy)
+	require.Len(t, service.streams, 3)
+}
+
+func TestAnalyzeVideoIgnoresUnknownReasons(t *testing.T) {
+	for _, failure := range []error{
+		rejection(t, codes.FailedPrecondition, "other.example", "WRONG_SKILL"),
+		rejection(t, codes.FailedPrecondition, errorDomain, "SOMETHING_NEW"),
+		status.Error(codes.FailedPrecondition, "no expert reference is available"),
+	} {
+		client := &Client{service: &flakyService{failures: []error{failure}}, retry: quickRetry}
+
+		err := analyze(client, bytes.NewReader([]byte("x")))
+
+		require.Equal(t, codes.FailedPrecondition, status.Code(err))
+		for _, sentinel := range reasonErrors {
+			require.NotErrorIs(t, err, sentinel)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package analysis
+
+import (
+	"errors"
+	"io"
+
+	analysisv1 "github.com/HeavenAQ/nstc-linebot-2025/api/analysis/v1"
+	"google.golang.org/grpc"
+)
+
+// Stage is a step the analysis service reports starting on.
+type Stage string
+
+// The stages in the order the service runs them.
+const (
+	StagePose       Stage = "pose"
+	StageCorrection Stage = "correction"
+	StageScoring    Stage = "scoring"
+	StageCoaching   Stage = "coaching"
+	StageRender     Stage = "render"
+)
+
+var stages = map[analysisv1.AnalysisStage]Stage{
+	analysisv1.AnalysisStage_ANALYSIS_STAGE_POSE:       StagePose,
+	analysisv1.AnalysisStage_ANALYSIS_STAGE_CORRECTION: StageCorrection,
+	analysisv1.AnalysisStage_ANALYSIS_STAGE_SCORING:    StageScoring,
+	analysisv1.AnalysisStage_ANALYSIS_STAGE_COACHING:   StageCoaching,
+	analysisv1.AnalysisStage_ANALYSIS_STAGE_RENDER:     StageRender,
+}
+
+var errNoResult = errors.New("analysis stream ended without a result")
+
+// receiveEvents passes stages on to progress until the result arrives, then
+// reads to the end of the stream so its trailer is set.
+func receiveEvents(
+	stream grpc.BidiStreamingClient[analysisv1.AnalyzeVideoChunk, analysisv1.AnalyzeVideoEvent],
+	progress chan<- Stage,
+) (*analysisv1.AnalyzeVideoResponse, error) {
+	for {
+		event, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil, errNoResult
+		}
+		if err != nil {
+			return nil, err
+		}
+		if result := event.GetResult(); result != nil {
+			if _, err := stream.Recv(); err != nil && !errors.Is(err, io.EOF) {
+				return nil, err
+			}
+			return result, nil
+		}
+		if stage, ok := stages[event.GetStage()]; ok {
+			select {
+			case progress <- stage:
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package analysis
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	analysisv1 "github.com/HeavenAQ/nstc-linebot-2025/api/analysis/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+// eventStream uploads like countingStream, then replays events.
+type eventStream struct {
+	*countingStream
+	events  []*analysisv1.AnalyzeVideoEvent
+	err     error
+	closed  bool
+	trailer metadata.MD
+	addr    *metadata.MD
+}
+
+func (s *eventStream) CloseSend() error {
+	s.closed = true
+	return nil
+}
+
+func (s *eventStream) Recv() (*analysisv1.AnalyzeVideoEvent, error) {
+	if len(s.events) > 0 {
+		event := s.events[0]
+		s.events = s.events[1:]
+		return event, nil
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	*s.addr = s.trailer
+	return nil, io.EOF
+}
+
+type progressService struct {
+	analysisv1.BadmintonAnalysisClient
+	stream *eventStream
+}
+
+func (s *progressService) AnalyzeVideoWithProgress(
+	_ context.Context, opts ...grpc.CallOption,
+) (grpc.BidiStreamingClient[analysisv1.AnalyzeVideoChunk, analysisv1.AnalyzeVideoEvent], error) {
+	for _, opt := range opts {
+		if trailer, ok := opt.(grpc.TrailerCallOption); ok {
+			s.stream.addr = trailer.TrailerAddr
+		}
+	}
+	return s.stream, nil
+}
+
+func stageEvent(stage analysisv1.AnalysisStage) *analysisv1.AnalyzeVideoEvent {
+	return &analysisv1.AnalyzeVideoEvent{Event: &analysisv1.AnalyzeVideoEvent_Stage{Stage: stage}}
+}
+
+func analyzeWithProgress(client *Client, progress chan Stage) error {
+	_, err := client.AnalyzeVideoWithProgress(
+		context.Background(), "r", "u", "v.mp4", "serve", "right", bytes.NewReader([]byte("x")), progress,
+	)
+	return err
+}
+
+func TestAnalyzeVideoWithProgressReportsStages(t *testing.T) {
+	stream := &eventStream{
+		countingStream: &countingStream{},
+		events: []*analysisv1.AnalyzeVideoEvent{
+			stageEvent(analysisv1.AnalysisStage_ANALYSIS_STAGE_POSE),
+			stageEvent(analysisv1.AnalysisStage_ANALYSIS_STAGE_CORRECTION),
+			stageEvent(analysisv1.AnalysisStage_ANALYSIS_STAGE_SCORING),
+			{Event: &analysisv1.AnalyzeVideoEvent_Result{Result: &analysisv1.AnalyzeVideoResponse{
+				AnalysisId: "analysis-id", Grade: &analysisv1.GradingOutcome{}, Expert: &analysisv1.ExpertMatch{},
+			}}},
+		},
+		trailer: metadata.Pairs(analyzerVersionKey, "ckpt7"),
+	}
+	client := &Client{service: &progressService{stream: stream}, retry: quickRetry}
+	// One slot: stages that arrive while it is full are dropped.
+	progress := make(chan Stage, 1)
+
+	result, err := client.AnalyzeVideoWithProgress(
+		context.Background(), "r", "u", "v.mp4", "serve", "right", bytes.NewReader([]byte("x")), progress,
+	)
+
+	require.NoError(t, err)
+	require.Equal(t, "analysis-id", result.AnalysisID)
+	require.Equal(t, "ckpt7", result.AnalyzerVersion)
+	require.True(t, stream.closed)
+	require.Equal(t, 1, stream.headers)
+	require.Equal(t, StagePose, <-progress)
+	require.Empty(t, progress)
+}
+
+func TestAnalyzeVideoWithProgressClassifiesFailures(t *testing.T) {
+	stream := &eventStream{
+		countingStream: &countingStream{},
+		events:         []*analysisv1.AnalyzeVideoEvent{stageEvent(analysisv1.AnalysisStage_ANALYSIS_STAGE_POSE)},
+		err:            rejection(t, codes.FailedPrecondition, errorDomain, "NO_PERSON_DETECTED"),
+	}
+	client := &Client{service: &progressService{stream: stream}, retry: quickRetry}
+
+	err := analyzeWithProgress(client, make(chan Stage, 5))
+
+	require.ErrorIs(t, err, ErrNoPersonDetected)
+}
+
+func TestAnalyzeVideoWithProgressNeedsAResult(t *testing.T) {
+	stream := &eventStream{countingStream: &countingStream{}}
+	client := &Client{service: &progressService{stream: stream}, retry: quickRetry}
+
+	err := analyzeWithProgress(client, make(chan Stage, 5))
+
+	require.ErrorIs(t, err, errNoResult)
+}
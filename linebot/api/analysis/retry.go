@@ -0,0 +1,180 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrServiceBusy means the analysis service is starting up or overloaded, so
+// the caller should try again in a while rather than change anything.
+var ErrServiceBusy = errors.New("analysis service is temporarily busy")
+
+// RetryPolicy repeats calls that fail while the analysis service is starting
+// or overloaded. The n-th retry waits a random time between half and all of
+// BaseDelay doubled n-1 times, at most MaxDelay.
+type RetryPolicy struct {
+	Attempts  int
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{Attempts: 3, BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+}
+
+func (p RetryPolicy) delay(retry int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < retry && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, p.MaxDelay)
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// retryable reports whether err is the service's trouble rather than the
+// request's, so the same request may succeed later. A deadline of the
+// caller's own context is not.
+func retryable(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted:
+		return true
+	default:
+		return false
+	}
+}
+
+// Breaker stops calling the analysis service for a cooldown once enough
+// attempts have failed in a row. Then one call is let through to probe it:
+// success closes the breaker again, failure reopens it.
+type Breaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// BreakerState is a snapshot of a breaker for health checks.
+type BreakerState struct {
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	OpenUntil           *time.Time `json:"open_until,omitempty"`
+}
+
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// A nil Breaker never refuses calls.
+func (b *Breaker) allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.openUntil.IsZero() {
+		return nil
+	}
+	if b.probing || b.now().Before(b.openUntil) {
+		return ErrServiceBusy
+	}
+	b.probing = true
+	return nil
+}
+
+// record counts an attempt. Only failures of the service itself count.
+func (b *Breaker) record(failed bool) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !failed {
+		b.failures = 0
+		b.openUntil = time.Time{}
+		b.probing = false
+		return
+	}
+	b.failures++
+	if b.probing || b.failures >= b.threshold {
+		b.openUntil = b.now().Add(b.cooldown)
+	}
+	b.probing = false
+}
+
+// abandon ends an attempt that says nothing about the service, such as one
+// its caller cancelled. If it was the probe, the next call probes instead.
+func (b *Breaker) abandon() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+func (b *Breaker) State() BreakerState {
+	if b == nil {
+		return BreakerState{State: "closed"}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state := BreakerState{State: "closed", ConsecutiveFailures: b.failures}
+	switch {
+	case b.openUntil.IsZero():
+	case b.probing || !b.now().Before(b.openUntil):
+		state.State = "half-open"
+	default:
+		state.State = "open"
+		openUntil := b.openUntil
+		state.OpenUntil = &openUntil
+	}
+	return state
+}
+
+// call runs attempt through the breaker, retrying service failures while
+// canRetry allows. Once retries run out the error wraps ErrServiceBusy.
+func (c *Client) call(ctx context.Context, attempt func() error, canRetry func() bool) error {
+	for try := 1; ; try++ {
+		if err := c.breaker.allow(); err != nil {
+			return err
+		}
+		err := attempt()
+		if ctx.Err() != nil {
+			// Neither a failure nor a success of the service
+			c.breaker.abandon()
+			return err
+		}
+		failed := retryable(ctx, err)
+		c.breaker.record(failed)
+		if !failed {
+			return err
+		}
+		if try >= c.retry.Attempts || !canRetry() {
+			return fmt.Errorf("%w: %w", ErrServiceBusy, err)
+		}
+		timer := time.NewTimer(c.retry.delay(try))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w: %w", ErrServiceBusy, err)
+		case <-timer.C:
+		}
+	}
+}
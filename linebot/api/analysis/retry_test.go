@@ -0,0 +1,169 @@
+package analysis
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	analysisv1 "github.com/HeavenAQ/nstc-linebot-2025/api/analysis/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// flakyService fails the first streams with the given errors, then counts
+// what a healthy stream receives.
+type flakyService struct {
+	analysisv1.BadmintonAnalysisClient
+	failures []error
+	streams  []*countingStream
+	refresh  int
+}
+
+type failingStream struct {
+	*countingStream
+	err error
+}
+
+func (s *failingStream) CloseAndRecv() (*analysisv1.AnalyzeVideoResponse, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.countingStream.CloseAndRecv()
+}
+
+func (s *flakyService) next() error {
+	if len(s.failures) == 0 {
+		return nil
+	}
+	err := s.failures[0]
+	s.failures = s.failures[1:]
+	return err
+}
+
+func (s *flakyService) AnalyzeVideo(
+	context.Context, ...grpc.CallOption,
+) (grpc.ClientStreamingClient[analysisv1.AnalyzeVideoChunk, analysisv1.AnalyzeVideoResponse], error) {
+	stream := &countingStream{}
+	s.streams = append(s.streams, stream)
+	return &failingStream{countingStream: stream, err: s.next()}, nil
+}
+
+func (s *flakyService) RefreshPlaybackUrls(
+	context.Context, *analysisv1.RefreshPlaybackUrlsRequest, ...grpc.CallOption,
+) (*analysisv1.RefreshPlaybackUrlsResponse, error) {
+	s.refresh++
+	if err := s.next(); err != nil {
+		return nil, err
+	}
+	return &analysisv1.RefreshPlaybackUrlsResponse{}, nil
+}
+
+var (
+	unavailable = status.Error(codes.Unavailable, "instance starting")
+	quickRetry  = RetryPolicy{Attempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+)
+
+func analyze(client *Client, video *bytes.Reader) error {
+	_, err := client.AnalyzeVideo(context.Background(), "r", "u", "v.mp4", "serve", "right", video)
+	return err
+}
+
+func TestAnalyzeVideoReplaysSeekableVideo(t *testing.T) {
+	service := &flakyService{failures: []error{unavailable}}
+	client := &Client{service: service, retry: quickRetry}
+	video := bytes.NewReader(make([]byte, 2*chunkSize+10))
+
+	require.NoError(t, analyze(client, video))
+	require.Len(t, service.streams, 2)
+	require.EqualValues(t, 2*chunkSize+10, service.streams[1].bytes)
+	require.Equal(t, 3, service.streams[1].chunks)
+}
+
+func TestAnalyzeVideoDoesNotReplayPartlyReadStream(t *testing.T) {
+	service := &flakyService{failures: []error{unavailable}}
+	client := &Client{service: service, retry: quickRetry}
+
+	_, err := client.AnalyzeVideo(
+		context.Background(), "r", "u", "v.mp4", "serve", "right", &patternReader{remaining: chunkSize + 1},
+	)
+
+	require.ErrorIs(t, err, ErrServiceBusy)
+	require.Len(t, service.streams, 1)
+}
+
+func TestAnalyzeVideoDoesNotRetryRequestErrors(t *testing.T) {
+	service := &flakyService{failures: []error{status.Error(codes.InvalidArgument, "not a video")}}
+	client := &Client{service: service, retry: quickRetry}
+
+	err := analyze(client, bytes.NewReader([]byte("x")))
+
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	require.NotErrorIs(t, err, ErrServiceBusy)
+	require.Len(t, service.streams, 1)
+}
+
+func TestBreakerOpensAndProbes(t *testing.T) {
+	service := &flakyService{failures: []error{unavailable, unavailable, unavailable, unavailable}}
+	breaker := NewBreaker(2, time.Minute)
+	clock := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	breaker.now = func() time.Time { return clock }
+	client := &Client{service: service, retry: quickRetry, breaker: breaker}
+
+	_, err := client.RefreshPlaybackURLs(context.Background(), "analyses/a.mp4")
+	require.ErrorIs(t, err, ErrServiceBusy)
+	require.Equal(t, 2, service.refresh, "the breaker opens before the third attempt")
+	require.Equal(t, "open", client.BreakerState().State)
+	require.Equal(t, clock.Add(time.Minute), *client.BreakerState().OpenUntil)
+
+	_, err = client.RefreshPlaybackURLs(context.Background(), "analyses/a.mp4")
+	require.True(t, errors.Is(err, ErrServiceBusy))
+	require.Equal(t, 2, service.refresh, "an open breaker refuses without calling")
+
+	clock = clock.Add(time.Minute)
+	require.Equal(t, "half-open", client.BreakerState().State)
+	_, err = client.RefreshPlaybackURLs(context.Background(), "analyses/a.mp4")
+	require.ErrorIs(t, err, ErrServiceBusy)
+	require.Equal(t, 3, service.refresh, "a failed probe reopens at once")
+	require.Equal(t, "open", client.BreakerState().State)
+
+	clock = clock.Add(time.Minute)
+	service.failures = nil
+	_, err = client.RefreshPlaybackURLs(context.Background(), "analyses/a.mp4")
+	require.NoError(t, err)
+	require.Equal(t, BreakerState{State: "closed"}, client.BreakerState())
+}
+
+func TestCancelledCallsLeaveTheBreakerAlone(t *testing.T) {
+	service := &flakyService{failures: []error{unavailable, unavailable}}
+	breaker := NewBreaker(2, time.Minute)
+	clock := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	breaker.now = func() time.Time { return clock }
+	client := &Client{service: service, retry: quickRetry, breaker: breaker}
+	_, err := client.RefreshPlaybackURLs(context.Background(), "analyses/a.mp4")
+	require.ErrorIs(t, err, ErrServiceBusy)
+
+	clock = clock.Add(time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = client.RefreshPlaybackURLs(ctx, "analyses/a.mp4")
+	require.NotErrorIs(t, err, ErrServiceBusy)
+	require.Equal(t, "half-open", client.BreakerState().State, "a cancelled probe does not close the breaker")
+	require.Equal(t, 2, client.BreakerState().ConsecutiveFailures)
+
+	_, err = client.RefreshPlaybackURLs(context.Background(), "analyses/a.mp4")
+	require.NoError(t, err, "the next call probes instead")
+	require.Equal(t, BreakerState{State: "closed"}, client.BreakerState())
+}
+
+func TestRetryDelayIsJitteredAndCapped(t *testing.T) {
+	policy := RetryPolicy{Attempts: 5, BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	for range 100 {
+		require.InDelta(t, 0.75, policy.delay(1).Seconds(), 0.25)
+		require.InDelta(t, 3, policy.delay(3).Seconds(), 1)
+		require.InDelta(t, 3.75, policy.delay(10).Seconds(), 1.25)
+	}
+}
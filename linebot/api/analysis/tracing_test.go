@@ -0,0 +1,63 @@
+package analysis
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	analysisv1 "github.com/HeavenAQ/nstc-linebot-2025/api/analysis/v1"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+type metadataServer struct {
+	analysisv1.UnimplementedBadmintonAnalysisServer
+	received metadata.MD
+}
+
+func (s *metadataServer) RefreshPlaybackUrls(
+	ctx context.Context, _ *analysisv1.RefreshPlaybackUrlsRequest,
+) (*analysisv1.RefreshPlaybackUrlsResponse, error) {
+	s.received, _ = metadata.FromIncomingContext(ctx)
+	return &analysisv1.RefreshPlaybackUrlsResponse{}, nil
+}
+
+func TestCallsCarryTraceContextNextToAPIKey(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	service := &metadataServer{}
+	analysisv1.RegisterBadmintonAnalysisServer(server, service)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	client, err := NewClient(listener.Addr().String(), "secret", true)
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	ctx, span := provider.Tracer("test").Start(context.Background(), "upload")
+	_, err = client.RefreshPlaybackURLs(ctx)
+	span.End()
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"secret"}, service.received.Get("x-api-key"))
+	traceparent := service.received.Get("traceparent")
+	require.Len(t, traceparent, 1)
+	require.Contains(t, traceparent[0], span.SpanContext().TraceID().String())
+	require.Len(t, recorder.Ended(), 2)
+}
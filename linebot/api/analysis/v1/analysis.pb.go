@@ -128,6 +128,64 @@ func (Handedness) EnumDescriptor() ([]byte, []int) {
 	return file_badminton_analysis_v1_analysis_proto_rawDescGZIP(), []int{1}
 }
 
+type AnalysisStage int32
+
+const (
+	AnalysisStage_ANALYSIS_STAGE_UNSPECIFIED AnalysisStage = 0
+	AnalysisStage_ANALYSIS_STAGE_POSE        AnalysisStage = 1
+	AnalysisStage_ANALYSIS_STAGE_CORRECTION  AnalysisStage = 2
+	AnalysisStage_ANALYSIS_STAGE_SCORING     AnalysisStage = 3
+	AnalysisStage_ANALYSIS_STAGE_COACHING    AnalysisStage = 4
+	AnalysisStage_ANALYSIS_STAGE_RENDER      AnalysisStage = 5
+)
+
+// Enum value maps for AnalysisStage.
+var (
+	AnalysisStage_name = map[int32]string{
+		0: "ANALYSIS_STAGE_UNSPECIFIED",
+		1: "ANALYSIS_STAGE_POSE",
+		2: "ANALYSIS_STAGE_CORRECTION",
+		3: "ANALYSIS_STAGE_SCORING",
+		4: "ANALYSIS_STAGE_COACHING",
+		5: "ANALYSIS_STAGE_RENDER",
+	}
+	AnalysisStage_value = map[string]int32{
+		"ANALYSIS_STAGE_UNSPECIFIED": 0,
+		"ANALYSIS_STAGE_POSE":        1,
+		"ANALYSIS_STAGE_CORRECTION":  2,
+		"ANALYSIS_STAGE_SCORING":     3,
+		"ANALYSIS_STAGE_COACHING":    4,
+		"ANALYSIS_STAGE_RENDER":      5,
+	}
+)
+
+func (x AnalysisStage) Enum() *AnalysisStage {
+	p := new(AnalysisStage)
+	*p = x
+	return p
+}
+
+func (x AnalysisStage) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (AnalysisStage) Descriptor() protoreflect.EnumDescriptor {
+	return file_badminton_analysis_v1_analysis_proto_enumTypes[2].Descriptor()
+}
+
+func (AnalysisStage) Type() protoreflect.EnumType {
+	return &file_badminton_analysis_v1_analysis_proto_enumTypes[2]
+}
+
+func (x AnalysisStage) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use AnalysisStage.Descriptor instead.
+func (AnalysisStage) EnumDescriptor() ([]byte, []int) {
+	return file_badminton_analysis_v1_analysis_proto_rawDescGZIP(), []int{2}
+}
+
 type AnalyzeVideoHeader struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	RequestId     string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
@@ -934,6 +992,88 @@ func (x *AnalyzeVideoResponse) GetOverallFeedback() string {
 	return ""
 }
 
+type AnalyzeVideoEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Event:
+	//
+	//	*AnalyzeVideoEvent_Stage
+	//	*AnalyzeVideoEvent_Result
+	Event         isAnalyzeVideoEvent_Event `protobuf_oneof:"event"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AnalyzeVideoEvent) Reset() {
+	*x = AnalyzeVideoEvent{}
+	mi := &file_badminton_analysis_v1_analysis_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AnalyzeVideoEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AnalyzeVideoEvent) ProtoMessage() {}
+
+func (x *AnalyzeVideoEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_badminton_analysis_v1_analysis_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AnalyzeVideoEvent.ProtoReflect.Descriptor instead.
+func (*AnalyzeVideoEvent) Descriptor() ([]byte, []int) {
+	return file_badminton_analysis_v1_analysis_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *AnalyzeVideoEvent) GetEvent() isAnalyzeVideoEvent_Event {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+func (x *AnalyzeVideoEvent) GetStage() AnalysisStage {
+	if x != nil {
+		if x, ok := x.Event.(*AnalyzeVideoEvent_Stage); ok {
+			return x.Stage
+		}
+	}
+	return AnalysisStage_ANALYSIS_STAGE_UNSPECIFIED
+}
+
+func (x *AnalyzeVideoEvent) GetResult() *AnalyzeVideoResponse {
+	if x != nil {
+		if x, ok := x.Event.(*AnalyzeVideoEvent_Result); ok {
+			return x.Result
+		}
+	}
+	return nil
+}
+
+type isAnalyzeVideoEvent_Event interface {
+	isAnalyzeVideoEvent_Event()
+}
+
+type AnalyzeVideoEvent_Stage struct {
+	Stage AnalysisStage `protobuf:"varint,1,opt,name=stage,proto3,enum=badminton.analysis.v1.AnalysisStage,oneof"`
+}
+
+type AnalyzeVideoEvent_Result struct {
+	Result *AnalyzeVideoResponse `protobuf:"bytes,2,opt,name=result,proto3,oneof"`
+}
+
+func (*AnalyzeVideoEvent_Stage) isAnalyzeVideoEvent_Event() {}
+
+func (*AnalyzeVideoEvent_Result) isAnalyzeVideoEvent_Event() {}
+
 type RefreshPlaybackUrlsRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	ObjectPaths   []string               `protobuf:"bytes,1,rep,name=object_paths,json=objectPaths,proto3" json:"object_paths,omitempty"`
@@ -943,7 +1083,7 @@ type RefreshPlaybackUrlsRequest struct {
 
 func (x *RefreshPlaybackUrlsRequest) Reset() {
 	*x = RefreshPlaybackUrlsRequest{}
-	mi := &file_badminton_analysis_v1_analysis_proto_msgTypes[10]
+	mi := &file_badminton_analysis_v1_analysis_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -955,7 +1095,7 @@ func (x *RefreshPlaybackUrlsRequest) String() string {
 func (*RefreshPlaybackUrlsRequest) ProtoMessage() {}
 
 func (x *RefreshPlaybackUrlsRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_badminton_analysis_v1_analysis_proto_msgTypes[10]
+	mi := &file_badminton_analysis_v1_analysis_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -968,7 +1108,7 @@ func (x *RefreshPlaybackUrlsRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RefreshPlaybackUrlsRequest.ProtoReflect.Descriptor instead.
 func (*RefreshPlaybackUrlsRequest) Descriptor() ([]byte, []int) {
-	return file_badminton_analysis_v1_analysis_proto_rawDescGZIP(), []int{10}
+	return file_badminton_analysis_v1_analysis_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *RefreshPlaybackUrlsRequest) GetObjectPaths() []string {
@@ -987,7 +1127,7 @@ type RefreshPlaybackUrlsResponse struct {
 
 func (x *RefreshPlaybackUrlsResponse) Reset() {
 	*x = RefreshPlaybackUrlsResponse{}
-	mi := &file_badminton_analysis_v1_analysis_proto_msgTypes[11]
+	mi := &file_badminton_analysis_v1_analysis_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -999,7 +1139,7 @@ func (x *RefreshPlaybackUrlsResponse) String() string {
 func (*RefreshPlaybackUrlsResponse) ProtoMessage() {}
 
 func (x *RefreshPlaybackUrlsResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_badminton_analysis_v1_analysis_proto_msgTypes[11]
+	mi := &file_badminton_analysis_v1_analysis_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1012,7 +1152,7 @@ func (x *RefreshPlaybackUrlsResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RefreshPlaybackUrlsResponse.ProtoReflect.Descriptor instead.
 func (*RefreshPlaybackUrlsResponse) Descriptor() ([]byte, []int) {
-	return file_badminton_analysis_v1_analysis_proto_rawDescGZIP(), []int{11}
+	return file_badminton_analysis_v1_analysis_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *RefreshPlaybackUrlsResponse) GetVideos() []*StoredVideo {
@@ -1030,7 +1170,7 @@ type HealthRequest struct {
 
 func (x *HealthRequest) Reset() {
 	*x = HealthRequest{}
-	mi := &file_badminton_analysis_v1_analysis_proto_msgTypes[12]
+	mi := &file_badminton_analysis_v1_analysis_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1042,7 +1182,7 @@ func (x *HealthRequest) String() string {
 func (*HealthRequest) ProtoMessage() {}
 
 func (x *HealthRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_badminton_analysis_v1_analysis_proto_msgTypes[12]
+	mi := &file_badminton_analysis_v1_analysis_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1055,7 +1195,7 @@ func (x *HealthRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use HealthRequest.ProtoReflect.Descriptor instead.
 func (*HealthRequest) Descriptor() ([]byte, []int) {
-	return file_badminton_analysis_v1_analysis_proto_rawDescGZIP(), []int{12}
+	return file_badminton_analysis_v1_analysis_proto_rawDescGZIP(), []int{13}
 }
 
 type HealthResponse struct {
@@ -1068,7 +1208,7 @@ type HealthResponse struct {
 
 func (x *HealthResponse) Reset() {
 	*x = HealthResponse{}
-	mi := &file_badminton_analysis_v1_analysis_proto_msgTypes[13]
+	mi := &file_badminton_analysis_v1_analysis_proto_msgTypes[14]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1080,7 +1220,7 @@ func (x *HealthResponse) String() string {
 func (*HealthResponse) ProtoMessage() {}
 
 func (x *HealthResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_badminton_analysis_v1_analysis_proto_msgTypes[13]
+	mi := &file_badminton_analysis_v1_analysis_proto_msgTypes[14]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1093,7 +1233,7 @@ func (x *HealthResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use HealthResponse.ProtoReflect.Descriptor instead.
 func (*HealthResponse) Descriptor() ([]byte, []int) {
-	return file_badminton_analysis_v1_analysis_proto_rawDescGZIP(), []int{13}
+	return file_badminton_analysis_v1_analysis_proto_rawDescGZIP(), []int{14}
 }
 
 func (x *HealthResponse) GetStatus() string {
@@ -1187,7 +1327,11 @@ const file_badminton_analysis_v1_analysis_proto_rawDesc = "" +
 	"\vdiagnostics\x18\b \x03(\v2&.badminton.analysis.v1.DiagnosticValueR\vdiagnostics\x12G\n" +
 	"\rcoaching_cues\x18\t \x03(\v2\".badminton.analysis.v1.CoachingCueR\fcoachingCues\x12)\n" +
 	"\x10overall_feedback\x18\n" +
-	" \x01(\tR\x0foverallFeedback\"?\n" +
+	" \x01(\tR\x0foverallFeedback\"\xa1\x01\n" +
+	"\x11AnalyzeVideoEvent\x12<\n" +
+	"\x05stage\x18\x01 \x01(\x0e2$.badminton.analysis.v1.AnalysisStageH\x00R\x05stage\x12E\n" +
+	"\x06result\x18\x02 \x01(\v2+.badminton.analysis.v1.AnalyzeVideoResponseH\x00R\x06resultB\a\n" +
+	"\x05event\"?\n" +
 	"\x1aRefreshPlaybackUrlsRequest\x12!\n" +
 	"\fobject_paths\x18\x01 \x03(\tR\vobjectPaths\"Y\n" +
 	"\x1bRefreshPlaybackUrlsResponse\x12:\n" +
@@ -1208,9 +1352,17 @@ const file_badminton_analysis_v1_analysis_proto_rawDesc = "" +
 	"\x16HANDEDNESS_UNSPECIFIED\x10\x00\x12\x13\n" +
 	"\x0fHANDEDNESS_AUTO\x10\x01\x12\x14\n" +
 	"\x10HANDEDNESS_RIGHT\x10\x02\x12\x13\n" +
-	"\x0fHANDEDNESS_LEFT\x10\x032\xd1\x02\n" +
+	"\x0fHANDEDNESS_LEFT\x10\x03*\xbb\x01\n" +
+	"\rAnalysisStage\x12\x1e\n" +
+	"\x1aANALYSIS_STAGE_UNSPECIFIED\x10\x00\x12\x17\n" +
+	"\x13ANALYSIS_STAGE_POSE\x10\x01\x12\x1d\n" +
+	"\x19ANALYSIS_STAGE_CORRECTION\x10\x02\x12\x1a\n" +
+	"\x16ANALYSIS_STAGE_SCORING\x10\x03\x12\x1b\n" +
+	"\x17ANALYSIS_STAGE_COACHING\x10\x04\x12\x19\n" +
+	"\x15ANALYSIS_STAGE_RENDER\x10\x052\xc5\x03\n" +
 	"\x11BadmintonAnalysis\x12g\n" +
-	"\fAnalyzeVideo\x12(.badminton.analysis.v1.AnalyzeVideoChunk\x1a+.badminton.analysis.v1.AnalyzeVideoResponse(\x01\x12|\n" +
+	"\fAnalyzeVideo\x12(.badminton.analysis.v1.AnalyzeVideoChunk\x1a+.badminton.analysis.v1.AnalyzeVideoResponse(\x01\x12r\n" +
+	"\x18AnalyzeVideoWithProgress\x12(.badminton.analysis.v1.AnalyzeVideoChunk\x1a(.badminton.analysis.v1.AnalyzeVideoEvent(\x010\x01\x12|\n" +
 	"\x13RefreshPlaybackUrls\x121.badminton.analysis.v1.RefreshPlaybackUrlsRequest\x1a2.badminton.analysis.v1.RefreshPlaybackUrlsResponse\x12U\n" +
 	"\x06Health\x12$.badminton.analysis.v1.HealthRequest\x1a%.badminton.analysis.v1.HealthResponseBBZ@github.com/HeavenAQ/nstc-linebot-2025/api/analysis/v1;analysisv1b\x06proto3"
 
@@ -1226,53 +1378,59 @@ func file_badminton_analysis_v1_analysis_proto_rawDescGZIP() []byte {
 	return file_badminton_analysis_v1_analysis_proto_rawDescData
 }
 
-var file_badminton_analysis_v1_analysis_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
-var file_badminton_analysis_v1_analysis_proto_msgTypes = make([]protoimpl.MessageInfo, 14)
+var file_badminton_analysis_v1_analysis_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_badminton_analysis_v1_analysis_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
 var file_badminton_analysis_v1_analysis_proto_goTypes = []any{
 	(Skill)(0),                          // 0: badminton.analysis.v1.Skill
 	(Handedness)(0),                     // 1: badminton.analysis.v1.Handedness
-	(*AnalyzeVideoHeader)(nil),          // 2: badminton.analysis.v1.AnalyzeVideoHeader
-	(*AnalyzeVideoChunk)(nil),           // 3: badminton.analysis.v1.AnalyzeVideoChunk
-	(*GradingDetail)(nil),               // 4: badminton.analysis.v1.GradingDetail
-	(*GradingOutcome)(nil),              // 5: badminton.analysis.v1.GradingOutcome
-	(*PhaseMarker)(nil),                 // 6: badminton.analysis.v1.PhaseMarker
-	(*CoachingCue)(nil),                 // 7: badminton.analysis.v1.CoachingCue
-	(*StoredVideo)(nil),                 // 8: badminton.analysis.v1.StoredVideo
-	(*ExpertMatch)(nil),                 // 9: badminton.analysis.v1.ExpertMatch
-	(*DiagnosticValue)(nil),             // 10: badminton.analysis.v1.DiagnosticValue
-	(*AnalyzeVideoResponse)(nil),        // 11: badminton.analysis.v1.AnalyzeVideoResponse
-	(*RefreshPlaybackUrlsRequest)(nil),  // 12: badminton.analysis.v1.RefreshPlaybackUrlsRequest
-	(*RefreshPlaybackUrlsResponse)(nil), // 13: badminton.analysis.v1.RefreshPlaybackUrlsResponse
-	(*HealthRequest)(nil),               // 14: badminton.analysis.v1.HealthRequest
-	(*HealthResponse)(nil),              // 15: badminton.analysis.v1.HealthResponse
+	(AnalysisStage)(0),                  // 2: badminton.analysis.v1.AnalysisStage
+	(*AnalyzeVideoHeader)(nil),          // 3: badminton.analysis.v1.AnalyzeVideoHeader
+	(*AnalyzeVideoChunk)(nil),           // 4: badminton.analysis.v1.AnalyzeVideoChunk
+	(*GradingDetail)(nil),               // 5: badminton.analysis.v1.GradingDetail
+	(*GradingOutcome)(nil),              // 6: badminton.analysis.v1.GradingOutcome
+	(*PhaseMarker)(nil),                 // 7: badminton.analysis.v1.PhaseMarker
+	(*CoachingCue)(nil),                 // 8: badminton.analysis.v1.CoachingCue
+	(*StoredVideo)(nil),                 // 9: badminton.analysis.v1.StoredVideo
+	(*ExpertMatch)(nil),                 // 10: badminton.analysis.v1.ExpertMatch
+	(*DiagnosticValue)(nil),             // 11: badminton.analysis.v1.DiagnosticValue
+	(*AnalyzeVideoResponse)(nil),        // 12: badminton.analysis.v1.AnalyzeVideoResponse
+	(*AnalyzeVideoEvent)(nil),           // 13: badminton.analysis.v1.AnalyzeVideoEvent
+	(*RefreshPlaybackUrlsRequest)(nil),  // 14: badminton.analysis.v1.RefreshPlaybackUrlsRequest
+	(*RefreshPlaybackUrlsResponse)(nil), // 15: badminton.analysis.v1.RefreshPlaybackUrlsResponse
+	(*HealthRequest)(nil),               // 16: badminton.analysis.v1.HealthRequest
+	(*HealthResponse)(nil),              // 17: badminton.analysis.v1.HealthResponse
 }
 var file_badminton_analysis_v1_analysis_proto_depIdxs = []int32{
 	0,  // 0: badminton.analysis.v1.AnalyzeVideoHeader.skill:type_name -> badminton.analysis.v1.Skill
 	1,  // 1: badminton.analysis.v1.AnalyzeVideoHeader.handedness:type_name -> badminton.analysis.v1.Handedness
-	2,  // 2: badminton.analysis.v1.AnalyzeVideoChunk.header:type_name -> badminton.analysis.v1.AnalyzeVideoHeader
-	4,  // 3: badminton.analysis.v1.GradingOutcome.grading_details:type_name -> badminton.analysis.v1.GradingDetail
-	8,  // 4: badminton.analysis.v1.ExpertMatch.video:type_name -> badminton.analysis.v1.StoredVideo
+	3,  // 2: badminton.analysis.v1.AnalyzeVideoChunk.header:type_name -> badminton.analysis.v1.AnalyzeVideoHeader
+	5,  // 3: badminton.analysis.v1.GradingOutcome.grading_details:type_name -> badminton.analysis.v1.GradingDetail
+	9,  // 4: badminton.analysis.v1.ExpertMatch.video:type_name -> badminton.analysis.v1.StoredVideo
 	0,  // 5: badminton.analysis.v1.AnalyzeVideoResponse.skill:type_name -> badminton.analysis.v1.Skill
 	1,  // 6: badminton.analysis.v1.AnalyzeVideoResponse.handedness:type_name -> badminton.analysis.v1.Handedness
-	5,  // 7: badminton.analysis.v1.AnalyzeVideoResponse.grade:type_name -> badminton.analysis.v1.GradingOutcome
-	8,  // 8: badminton.analysis.v1.AnalyzeVideoResponse.student_video:type_name -> badminton.analysis.v1.StoredVideo
-	9,  // 9: badminton.analysis.v1.AnalyzeVideoResponse.expert:type_name -> badminton.analysis.v1.ExpertMatch
-	6,  // 10: badminton.analysis.v1.AnalyzeVideoResponse.timeline:type_name -> badminton.analysis.v1.PhaseMarker
-	10, // 11: badminton.analysis.v1.AnalyzeVideoResponse.diagnostics:type_name -> badminton.analysis.v1.DiagnosticValue
-	7,  // 12: badminton.analysis.v1.AnalyzeVideoResponse.coaching_cues:type_name -> badminton.analysis.v1.CoachingCue
-	8,  // 13: badminton.analysis.v1.RefreshPlaybackUrlsResponse.videos:type_name -> badminton.analysis.v1.StoredVideo
-	0,  // 14: badminton.analysis.v1.HealthResponse.loaded_skills:type_name -> badminton.analysis.v1.Skill
-	3,  // 15: badminton.analysis.v1.BadmintonAnalysis.AnalyzeVideo:input_type -> badminton.analysis.v1.AnalyzeVideoChunk
-	12, // 16: badminton.analysis.v1.BadmintonAnalysis.RefreshPlaybackUrls:input_type -> badminton.analysis.v1.RefreshPlaybackUrlsRequest
-	14, // 17: badminton.analysis.v1.BadmintonAnalysis.Health:input_type -> badminton.analysis.v1.HealthRequest
-	11, // 18: badminton.analysis.v1.BadmintonAnalysis.AnalyzeVideo:output_type -> badminton.analysis.v1.AnalyzeVideoResponse
-	13, // 19: badminton.analysis.v1.BadmintonAnalysis.RefreshPlaybackUrls:output_type -> badminton.analysis.v1.RefreshPlaybackUrlsResponse
-	15, // 20: badminton.analysis.v1.BadmintonAnalysis.Health:output_type -> badminton.analysis.v1.HealthResponse
-	18, // [18:21] is the sub-list for method output_type
-	15, // [15:18] is the sub-list for method input_type
-	15, // [15:15] is the sub-list for extension type_name
-	15, // [15:15] is the sub-list for extension extendee
-	0,  // [0:15] is the sub-list for field type_name
+	6,  // 7: badminton.analysis.v1.AnalyzeVideoResponse.grade:type_name -> badminton.analysis.v1.GradingOutcome
+	9,  // 8: badminton.analysis.v1.AnalyzeVideoResponse.student_video:type_name -> badminton.analysis.v1.StoredVideo
+	10, // 9: badminton.analysis.v1.AnalyzeVideoResponse.expert:type_name -> badminton.analysis.v1.ExpertMatch
+	7,  // 10: badminton.analysis.v1.AnalyzeVideoResponse.timeline:type_name -> badminton.analysis.v1.PhaseMarker
+	11, // 11: badminton.analysis.v1.AnalyzeVideoResponse.diagnostics:type_name -> badminton.analysis.v1.DiagnosticValue
+	8,  // 12: badminton.analysis.v1.AnalyzeVideoResponse.coaching_cues:type_name -> badminton.analysis.v1.CoachingCue
+	2,  // 13: badminton.analysis.v1.AnalyzeVideoEvent.stage:type_name -> badminton.analysis.v1.AnalysisStage
+	12, // 14: badminton.analysis.v1.AnalyzeVideoEvent.result:type_name -> badminton.analysis.v1.AnalyzeVideoResponse
+	9,  // 15: badminton.analysis.v1.RefreshPlaybackUrlsResponse.videos:type_name -> badminton.analysis.v1.StoredVideo
+	0,  // 16: badminton.analysis.v1.HealthResponse.loaded_skills:type_name -> badminton.analysis.v1.Skill
+	4,  // 17: badminton.analysis.v1.BadmintonAnalysis.AnalyzeVideo:input_type -> badminton.analysis.v1.AnalyzeVideoChunk
+	4,  // 18: badminton.analysis.v1.BadmintonAnalysis.AnalyzeVideoWithProgress:input_type -> badminton.analysis.v1.AnalyzeVideoChunk
+	14, // 19: badminton.analysis.v1.BadmintonAnalysis.RefreshPlaybackUrls:input_type -> badminton.analysis.v1.RefreshPlaybackUrlsRequest
+	16, // 20: badminton.analysis.v1.BadmintonAnalysis.Health:input_type -> badminton.analysis.v1.HealthRequest
+	12, // 21: badminton.analysis.v1.BadmintonAnalysis.AnalyzeVideo:output_type -> badminton.analysis.v1.AnalyzeVideoResponse
+	13, // 22: badminton.analysis.v1.BadmintonAnalysis.AnalyzeVideoWithProgress:output_type -> badminton.analysis.v1.AnalyzeVideoEvent
+	15, // 23: badminton.analysis.v1.BadmintonAnalysis.RefreshPlaybackUrls:output_type -> badminton.analysis.v1.RefreshPlaybackUrlsResponse
+	17, // 24: badminton.analysis.v1.BadmintonAnalysis.Health:output_type -> badminton.analysis.v1.HealthResponse
+	21, // [21:25] is the sub-list for method output_type
+	17, // [17:21] is the sub-list for method input_type
+	17, // [17:17] is the sub-list for extension type_name
+	17, // [17:17] is the sub-list for extension extendee
+	0,  // [0:17] is the sub-list for field type_name
 }
 
 func init() { file_badminton_analysis_v1_analysis_proto_init() }
@@ -1284,13 +1442,17 @@ func file_badminton_analysis_v1_analysis_proto_init() {
 		(*AnalyzeVideoChunk_Header)(nil),
 		(*AnalyzeVideoChunk_Data)(nil),
 	}
+	file_badminton_analysis_v1_analysis_proto_msgTypes[10].OneofWrappers = []any{
+		(*AnalyzeVideoEvent_Stage)(nil),
+		(*AnalyzeVideoEvent_Result)(nil),
+	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_badminton_analysis_v1_analysis_proto_rawDesc), len(file_badminton_analysis_v1_analysis_proto_rawDesc)),
-			NumEnums:      2,
-			NumMessages:   14,
+			NumEnums:      3,
+			NumMessages:   15,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -19,9 +19,10 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	BadmintonAnalysis_AnalyzeVideo_FullMethodName        = "/badminton.analysis.v1.BadmintonAnalysis/AnalyzeVideo"
-	BadmintonAnalysis_RefreshPlaybackUrls_FullMethodName = "/badminton.analysis.v1.BadmintonAnalysis/RefreshPlaybackUrls"
-	BadmintonAnalysis_Health_FullMethodName              = "/badminton.analysis.v1.BadmintonAnalysis/Health"
+	BadmintonAnalysis_AnalyzeVideo_FullMethodName             = "/badminton.analysis.v1.BadmintonAnalysis/AnalyzeVideo"
+	BadmintonAnalysis_AnalyzeVideoWithProgress_FullMethodName = "/badminton.analysis.v1.BadmintonAnalysis/AnalyzeVideoWithProgress"
+	BadmintonAnalysis_RefreshPlaybackUrls_FullMethodName      = "/badminton.analysis.v1.BadmintonAnalysis/RefreshPlaybackUrls"
+	BadmintonAnalysis_Health_FullMethodName                   = "/badminton.analysis.v1.BadmintonAnalysis/Health"
 )
 
 // BadmintonAnalysisClient is the client API for BadmintonAnalysis service.
@@ -29,6 +30,7 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type BadmintonAnalysisClient interface {
 	AnalyzeVideo(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[AnalyzeVideoChunk, AnalyzeVideoResponse], error)
+	AnalyzeVideoWithProgress(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[AnalyzeVideoChunk, AnalyzeVideoEvent], error)
 	RefreshPlaybackUrls(ctx context.Context, in *RefreshPlaybackUrlsRequest, opts ...grpc.CallOption) (*RefreshPlaybackUrlsResponse, error)
 	Health(ctx context.Context, in *HealthRequest, opts ...grpc.CallOption) (*HealthResponse, error)
 }
@@ -54,6 +56,19 @@ func (c *badmintonAnalysisClient) AnalyzeVideo(ctx context.Context, opts ...grpc
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type BadmintonAnalysis_AnalyzeVideoClient = grpc.ClientStreamingClient[AnalyzeVideoChunk, AnalyzeVideoResponse]
 
+func (c *badmintonAnalysisClient) AnalyzeVideoWithProgress(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[AnalyzeVideoChunk, AnalyzeVideoEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &BadmintonAnalysis_ServiceDesc.Streams[1], BadmintonAnalysis_AnalyzeVideoWithProgress_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[AnalyzeVideoChunk, AnalyzeVideoEvent]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type BadmintonAnalysis_AnalyzeVideoWithProgressClient = grpc.BidiStreamingClient[AnalyzeVideoChunk, AnalyzeVideoEvent]
+
 func (c *badmintonAnalysisClient) RefreshPlaybackUrls(ctx context.Context, in *RefreshPlaybackUrlsRequest, opts ...grpc.CallOption) (*RefreshPlaybackUrlsResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(RefreshPlaybackUrlsResponse)
@@ -79,6 +94,7 @@ func (c *badmintonAnalysisClient) Health(ctx context.Context, in *HealthRequest,
 // for forward compatibility.
 type BadmintonAnalysisServer interface {
 	AnalyzeVideo(grpc.ClientStreamingServer[AnalyzeVideoChunk, AnalyzeVideoResponse]) error
+	AnalyzeVideoWithProgress(grpc.BidiStreamingServer[AnalyzeVideoChunk, AnalyzeVideoEvent]) error
 	RefreshPlaybackUrls(context.Context, *RefreshPlaybackUrlsRequest) (*RefreshPlaybackUrlsResponse, error)
 	Health(context.Context, *HealthRequest) (*HealthResponse, error)
 	mustEmbedUnimplementedBadmintonAnalysisServer()
@@ -94,6 +110,9 @@ type UnimplementedBadmintonAnalysisServer struct{}
 func (UnimplementedBadmintonAnalysisServer) AnalyzeVideo(grpc.ClientStreamingServer[AnalyzeVideoChunk, AnalyzeVideoResponse]) error {
 	return status.Errorf(codes.Unimplemented, "method AnalyzeVideo not implemented")
 }
+func (UnimplementedBadmintonAnalysisServer) AnalyzeVideoWithProgress(grpc.BidiStreamingServer[AnalyzeVideoChunk, AnalyzeVideoEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method AnalyzeVideoWithProgress not implemented")
+}
 func (UnimplementedBadmintonAnalysisServer) RefreshPlaybackUrls(context.Context, *RefreshPlaybackUrlsRequest) (*RefreshPlaybackUrlsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RefreshPlaybackUrls not implemented")
 }
@@ -128,6 +147,13 @@ func _BadmintonAnalysis_AnalyzeVideo_Handler(srv interface{}, stream grpc.Server
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type BadmintonAnalysis_AnalyzeVideoServer = grpc.ClientStreamingServer[AnalyzeVideoChunk, AnalyzeVideoResponse]
 
+func _BadmintonAnalysis_AnalyzeVideoWithProgress_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(BadmintonAnalysisServer).AnalyzeVideoWithProgress(&grpc.GenericServerStream[AnalyzeVideoChunk, AnalyzeVideoEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type BadmintonAnalysis_AnalyzeVideoWithProgressServer = grpc.BidiStreamingServer[AnalyzeVideoChunk, AnalyzeVideoEvent]
+
 func _BadmintonAnalysis_RefreshPlaybackUrls_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(RefreshPlaybackUrlsRequest)
 	if err := dec(in); err != nil {
@@ -186,6 +212,12 @@ var BadmintonAnalysis_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _BadmintonAnalysis_AnalyzeVideo_Handler,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "AnalyzeVideoWithProgress",
+			Handler:       _BadmintonAnalysis_AnalyzeVideoWithProgress_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
 	Metadata: "badminton/analysis/v1/analysis.proto",
 }
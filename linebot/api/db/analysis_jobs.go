@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// States of an analysis job.
+const (
+	AnalysisRunning = "running"
+	AnalysisDone    = "done"
+	AnalysisFailed  = "failed"
+	// AnalysisInterrupted is a job the bot stopped while shutting down. The
+	// student's session is left at the upload step, so sending the video
+	// again resumes it, and OriginalVideo keeps the upload to resume from.
+	AnalysisInterrupted = "interrupted"
+)
+
+// AnalysisJob is where a student's latest upload is in its analysis, so the
+// LIFF can poll it. Stage is "upload" until the analysis service reports one
+// of its stages. Message is what the student was told when it failed or
+// was interrupted. OriginalVideo is the archived upload, set once it is
+// stored, and WorkDate the work it is saved as.
+type AnalysisJob struct {
+	RequestID     string    `json:"request_id" firestore:"request_id"`
+	Skill         string    `json:"skill" firestore:"skill"`
+	State         string    `json:"state" firestore:"state"`
+	Stage         string    `json:"stage" firestore:"stage"`
+	Message       string    `json:"message,omitempty" firestore:"message"`
+	WorkDate      string    `json:"work_date,omitempty" firestore:"work_date"`
+	OriginalVideo string    `json:"-" firestore:"original_video"`
+	StartedAt     time.Time `json:"started_at" firestore:"started_at"`
+	UpdatedAt     time.Time `json:"updated_at" firestore:"updated_at"`
+}
+
+// SaveAnalysisJob stores job as the user's latest analysis.
+func (client *FirestoreClient) SaveAnalysisJob(ctx context.Context, userID string, job AnalysisJob) error {
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
+	if _, err := client.AnalysisJobs.Doc(userID).Set(ctx, job); err != nil {
+		return fmt.Errorf("error saving analysis job: %w", err)
+	}
+	return nil
+}
+
+// GetAnalysisJob returns the user's latest analysis.
+func (client *FirestoreClient) GetAnalysisJob(ctx context.Context, userID string) (*AnalysisJob, error) {
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
+	doc, err := client.AnalysisJobs.Doc(userID).Get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting analysis job: %w", err)
+	}
+	var job AnalysisJob
+	if err := doc.DataTo(&job); err != nil {
+		return nil, fmt.Errorf("error decoding analysis job: %w", err)
+	}
+	return &job, nil
+}
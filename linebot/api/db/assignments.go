@@ -0,0 +1,225 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/api/iterator"
+)
+
+// Assignment is a piece of course work: one skill a class has to upload a
+// video of between OpensAt and DueAt.
+type Assignment struct {
+	ID        string    `json:"id" firestore:"id"`
+	Title     string    `json:"title" firestore:"title"`
+	ClassID   string    `json:"class_id" firestore:"class_id"`
+	Skill     string    `json:"skill" firestore:"skill"`
+	OpensAt   time.Time `json:"opens_at" firestore:"opens_at"`
+	DueAt     time.Time `json:"due_at" firestore:"due_at"`
+	CreatedAt time.Time `json:"created_at" firestore:"created_at"`
+}
+
+// Validate rejects assignments no upload could be tagged to.
+func (a Assignment) Validate() error {
+	if strings.TrimSpace(a.Title) == "" {
+		return errors.New("missing title")
+	}
+	if SkillStrToEnum(a.Skill) < 0 {
+		return fmt.Errorf("invalid skill %q", a.Skill)
+	}
+	if a.OpensAt.IsZero() || a.DueAt.IsZero() {
+		return errors.New("missing open or due date")
+	}
+	if !a.DueAt.After(a.OpensAt) {
+		return errors.New("due date must be after open date")
+	}
+	return nil
+}
+
+// SubmissionTag records which assignment an upload was submitted for.
+type SubmissionTag struct {
+	AssignmentID string
+	Late         bool
+}
+
+// Submission reports whether the student has uploaded for the assignment and
+// whether every such upload came in after the due date.
+func (a Assignment) Submission(user UserData) (submitted, late bool) {
+	late = true
+	for _, work := range user.Portfolio.GetSkillPortfolio(a.Skill) {
+		if work.AssignmentID != a.ID {
+			continue
+		}
+		submitted = true
+		late = late && work.Late
+	}
+	return submitted, submitted && late
+}
+
+// MatchAssignment picks the assignment an upload made at now belongs to. An
+// assignment that is open and not yet due wins, earliest due first. Past that,
+// the upload counts as a late submission for the earliest overdue assignment
+// the student has not submitted yet, so practice uploads after an on-time
+// submission are left untagged.
+func MatchAssignment(assignments []Assignment, user UserData, skill string, now time.Time) (SubmissionTag, *Assignment) {
+	var candidates []Assignment
+	for _, assignment := range assignments {
+		if assignment.ClassID == user.ClassID && assignment.Skill == skill && !now.Before(assignment.OpensAt) {
+			candidates = append(candidates, assignment)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].DueAt.Before(candidates[j].DueAt) })
+
+	for i, assignment := range candidates {
+		if !now.After(assignment.DueAt) {
+			return SubmissionTag{AssignmentID: assignment.ID}, &candidates[i]
+		}
+	}
+	for i, assignment := range candidates {
+		if submitted, _ := assignment.Submission(user); !submitted {
+			return SubmissionTag{AssignmentID: assignment.ID, Late: true}, &candidates[i]
+		}
+	}
+	return SubmissionTag{}, nil
+}
+
+// AssignmentStatus is an assignment as seen by one student.
+type AssignmentStatus struct {
+	Assignment
+	Submitted bool `json:"submitted"`
+	Late      bool `json:"late"`
+	Overdue   bool `json:"overdue"`
+}
+
+// OpenAssignments lists what the student still has to act on at now: every
+// opened assignment that is not yet due, plus overdue ones never submitted.
+func OpenAssignments(assignments []Assignment, user UserData, now time.Time) []AssignmentStatus {
+	statuses := []AssignmentStatus{}
+	for _, assignment := range assignments {
+		if assignment.ClassID != user.ClassID || now.Before(assignment.OpensAt) {
+			continue
+		}
+		submitted, late := assignment.Submission(user)
+		overdue := now.After(assignment.DueAt)
+		if overdue && submitted {
+			continue
+		}
+		statuses = append(statuses, AssignmentStatus{
+			Assignment: assignment,
+			Submitted:  submitted,
+			Late:       late,
+			Overdue:    overdue,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].DueAt.Before(statuses[j].DueAt) })
+	return statuses
+}
+
+// AssignmentReport lists the class's submissions for an assignment.
+type AssignmentReport struct {
+	Assignment Assignment          `json:"assignment"`
+	Submitted  []AssignmentStudent `json:"submitted"`
+	Missing    []AssignmentStudent `json:"missing"`
+}
+
+type AssignmentStudent struct {
+	UserID string `json:"user_id"`
+	Name   string `json:"name"`
+	Late   bool   `json:"late"`
+}
+
+// Report splits the class into students who have and have not submitted.
+func (a Assignment) Report(classmates []UserData) AssignmentReport {
+	report := AssignmentReport{Assignment: a, Submitted: []AssignmentStudent{}, Missing: []AssignmentStudent{}}
+	for _, user := range classmates {
+		submitted, late := a.Submission(user)
+		student := AssignmentStudent{UserID: user.ID, Name: user.Name, Late: late}
+		if submitted {
+			report.Submitted = append(report.Submitted, student)
+		} else {
+			report.Missing = append(report.Missing, student)
+		}
+	}
+	byName := func(students []AssignmentStudent) func(i, j int) bool {
+		return func(i, j int) bool { return students[i].Name < students[j].Name }
+	}
+	sort.Slice(report.Submitted, byName(report.Submitted))
+	sort.Slice(report.Missing, byName(report.Missing))
+	return report
+}
+
+// CreateAssignment validates and stores a new assignment, filling in its ID.
+func (client *FirestoreClient) CreateAssignment(ctx context.Context, assignment Assignment) (*Assignment, error) {
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
+	if err := assignment.Validate(); err != nil {
+		return nil, err
+	}
+	doc := client.Assignments.NewDoc()
+	assignment.ID = doc.ID
+	assignment.CreatedAt = time.Now().UTC()
+	if _, err := doc.Set(ctx, assignment); err != nil {
+		return nil, fmt.Errorf("error creating assignment: %w", err)
+	}
+	return &assignment, nil
+}
+
+// GetAssignment returns a single assignment by ID.
+func (client *FirestoreClient) GetAssignment(ctx context.Context, id string) (*Assignment, error) {
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
+	snap, err := client.Assignments.Doc(id).Get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting assignment: %w", err)
+	}
+	var assignment Assignment
+	if err := snap.DataTo(&assignment); err != nil {
+		return nil, fmt.Errorf("error converting assignment data: %w", err)
+	}
+	return &assignment, nil
+}
+
+// ListAssignments returns the class's assignments, earliest due first.
+func (client *FirestoreClient) ListAssignments(ctx context.Context, classID string) ([]Assignment, error) {
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
+	iter := client.Assignments.Where("class_id", "==", classID).Documents(ctx)
+	defer iter.Stop()
+
+	assignments := []Assignment{}
+	for {
+		doc, err := iter.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error listing assignments: %w", err)
+		}
+		var assignment Assignment
+		if err := doc.DataTo(&assignment); err != nil {
+			continue
+		}
+		assignments = append(assignments, assignment)
+	}
+	sort.Slice(assignments, func(i, j int) bool { return assignments[i].DueAt.Before(assignments[j].DueAt) })
+	return assignments, nil
+}
+
+// GetAssignmentReport lists who in the assignment's class has and has not
+// submitted it.
+func (client *FirestoreClient) GetAssignmentReport(ctx context.Context, id string) (*AssignmentReport, error) {
+	assignment, err := client.GetAssignment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	classmates, err := client.ListClassUsers(ctx, assignment.ClassID)
+	if err != nil {
+		return nil, err
+	}
+	report := assignment.Report(classmates)
+	return &report, nil
+}
@@ -0,0 +1,101 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func day(d int) time.Time {
+	return time.Date(2026, 9, d, 12, 0, 0, 0, time.UTC)
+}
+
+func weekly(id string, opens, due int) Assignment {
+	return Assignment{ID: id, Title: id, ClassID: "A", Skill: "serve", OpensAt: day(opens), DueAt: day(due)}
+}
+
+func submittedWork(assignmentID string, late bool) Work {
+	work := graded(80, 0)
+	work.AssignmentID = assignmentID
+	work.Late = late
+	return work
+}
+
+func TestAssignmentValidate(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, weekly("w1", 1, 8).Validate())
+	require.ErrorContains(t, weekly("w1", 8, 1).Validate(), "due date")
+
+	missingTitle := weekly("w1", 1, 8)
+	missingTitle.Title = " "
+	require.ErrorContains(t, missingTitle.Validate(), "title")
+
+	badSkill := weekly("w1", 1, 8)
+	badSkill.Skill = "drive"
+	require.ErrorContains(t, badSkill.Validate(), "invalid skill")
+}
+
+func TestMatchAssignmentPrefersOpenThenUnsubmittedOverdue(t *testing.T) {
+	t.Parallel()
+
+	assignments := []Assignment{weekly("w2", 8, 15), weekly("w1", 1, 8), weekly("w3", 15, 22)}
+	user := UserData{ID: "u1", ClassID: "A"}
+
+	tag, assignment := MatchAssignment(assignments, user, "serve", day(10))
+	require.Equal(t, SubmissionTag{AssignmentID: "w2"}, tag)
+	require.Equal(t, "w2", assignment.ID)
+
+	tag, _ = MatchAssignment(assignments, user, "serve", day(30))
+	require.Equal(t, SubmissionTag{AssignmentID: "w1", Late: true}, tag)
+
+	user.Portfolio.Serve = map[string]Work{"2026-09-05-10-00": submittedWork("w1", false)}
+	tag, _ = MatchAssignment(assignments, user, "serve", day(30))
+	require.Equal(t, SubmissionTag{AssignmentID: "w2", Late: true}, tag)
+
+	_, assignment = MatchAssignment(assignments, user, "smash", day(10))
+	require.Nil(t, assignment)
+
+	user.ClassID = "B"
+	_, assignment = MatchAssignment(assignments, user, "serve", day(10))
+	require.Nil(t, assignment)
+}
+
+func TestOpenAssignmentsHidesFinishedWork(t *testing.T) {
+	t.Parallel()
+
+	assignments := []Assignment{weekly("w1", 1, 8), weekly("w2", 8, 15), weekly("w3", 15, 22), weekly("w4", 20, 27)}
+	user := UserData{ID: "u1", ClassID: "A", Portfolio: Portfolios{Serve: map[string]Work{
+		"2026-09-05-10-00": submittedWork("w1", false),
+		"2026-09-16-10-00": submittedWork("w3", false),
+	}}}
+
+	statuses := OpenAssignments(assignments, user, day(17))
+
+	require.Len(t, statuses, 2)
+	require.Equal(t, "w2", statuses[0].ID)
+	require.True(t, statuses[0].Overdue)
+	require.False(t, statuses[0].Submitted)
+	require.Equal(t, "w3", statuses[1].ID)
+	require.True(t, statuses[1].Submitted)
+}
+
+func TestAssignmentReportSplitsClass(t *testing.T) {
+	t.Parallel()
+
+	assignment := weekly("w1", 1, 8)
+	classmates := []UserData{
+		{ID: "u2", Name: "Bob", Portfolio: Portfolios{Serve: map[string]Work{"2026-09-09-10-00": submittedWork("w1", true)}}},
+		{ID: "u1", Name: "Amy", Portfolio: Portfolios{Serve: map[string]Work{
+			"2026-09-07-10-00": submittedWork("w1", false),
+			"2026-09-09-10-00": submittedWork("w1", true),
+		}}},
+		{ID: "u3", Name: "Cat"},
+	}
+
+	report := assignment.Report(classmates)
+
+	require.Equal(t, []AssignmentStudent{{UserID: "u1", Name: "Amy"}, {UserID: "u2", Name: "Bob", Late: true}}, report.Submitted)
+	require.Equal(t, []AssignmentStudent{{UserID: "u3", Name: "Cat"}}, report.Missing)
+}
@@ -24,8 +24,9 @@ type ChatHistory struct {
 }
 
 // AppendChatExchange appends a user/assistant message pair to the user's chat history
-func (client *FirestoreClient) AppendChatExchange(userID, skill, conversationID, userText, assistantText string) error {
-	ctx := *client.Ctx
+func (client *FirestoreClient) AppendChatExchange(ctx context.Context, userID, skill, conversationID, userText, assistantText string) error {
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
 	docRef := client.ChatHistory.Doc(userID)
 
 	return client.Client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
@@ -57,8 +58,9 @@ func (client *FirestoreClient) AppendChatExchange(userID, skill, conversationID,
 }
 
 // GetChatHistory returns the full chat history for a user.
-func (client *FirestoreClient) GetChatHistory(userID string) (*ChatHistory, error) {
-	ctx := *client.Ctx
+func (client *FirestoreClient) GetChatHistory(ctx context.Context, userID string) (*ChatHistory, error) {
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
 	docRef := client.ChatHistory.Doc(userID)
 	snap, err := docRef.Get(ctx)
 	if err != nil {
@@ -1,6 +1,7 @@
 package db
 
 import (
+    "context"
     "fmt"
     "time"
 
@@ -25,8 +26,9 @@ func (client *FirestoreClient) dailySummaryDocID(userID, date, skill string) str
 }
 
 // GetDailySummary returns the cached summary for a user on a given date, or nil if none exists.
-func (client *FirestoreClient) GetDailySummary(userID, date, skill string) (*DailySummary, error) {
-    ctx := *client.Ctx
+func (client *FirestoreClient) GetDailySummary(ctx context.Context, userID, date, skill string) (*DailySummary, error) {
+    ctx, cancel := client.withTimeout(ctx)
+    defer cancel()
     docID := client.dailySummaryDocID(userID, date, skill)
     docRef := client.DailySummaries.Doc(docID)
     snap, err := docRef.Get(ctx)
@@ -45,8 +47,9 @@ func (client *FirestoreClient) GetDailySummary(userID, date, skill string) (*Dai
 
 // SetDailySummary upserts the cached summary along with the message count and
 // score fingerprint it was computed from.
-func (client *FirestoreClient) SetDailySummary(userID, date, skill, summary string, lastCount int, scoreKey string) error {
-    ctx := *client.Ctx
+func (client *FirestoreClient) SetDailySummary(ctx context.Context, userID, date, skill, summary string, lastCount int, scoreKey string) error {
+    ctx, cancel := client.withTimeout(ctx)
+    defer cancel()
     docID := client.dailySummaryDocID(userID, date, skill)
     docRef := client.DailySummaries.Doc(docID)
 
@@ -2,25 +2,50 @@ package db
 
 import (
 	"context"
+	"time"
 
 	"cloud.google.com/go/firestore"
 	firebase "firebase.google.com/go"
+	"github.com/HeavenAQ/nstc-linebot-2025/metrics"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+// FirestoreClient reads and writes the bot's collections. Each operation
+// stops when its context is done or, if Timeout is set, after Timeout.
 type FirestoreClient struct {
-	Ctx            *context.Context
+	Timeout time.Duration
+	// RankCacheTTL is how long a class's grades are reused for ranking.
+	RankCacheTTL   time.Duration
 	Client         *firestore.Client
 	Data           *firestore.CollectionRef
 	Sessions       *firestore.CollectionRef
 	ChatHistory    *firestore.CollectionRef
 	DailySummaries *firestore.CollectionRef
+	Rubrics        *firestore.CollectionRef
+	Assignments    *firestore.CollectionRef
+	Notifications  *firestore.CollectionRef
+	Leases         *firestore.CollectionRef
+	Jobs           *firestore.CollectionRef
+	JobRuns        *firestore.CollectionRef
+	AnalysisJobs   *firestore.CollectionRef
+
+	cohorts cohortCache
 }
 
 func NewFirestoreClient(projectID string, dataCollection string, sessionCollection string) (*FirestoreClient, error) {
 	// initialize firebase app
 	ctx := context.Background()
 	conf := &firebase.Config{ProjectID: projectID}
-	app, err := firebase.NewApp(ctx, conf)
+	var opts []option.ClientOption
+	for _, dialOption := range metrics.FirestoreDialOptions() {
+		opts = append(opts, option.WithGRPCDialOption(dialOption))
+	}
+	opts = append(opts, option.WithGRPCDialOption(grpc.WithStatsHandler(otelgrpc.NewClientHandler())))
+	app, err := firebase.NewApp(ctx, conf, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -33,11 +58,40 @@ func NewFirestoreClient(projectID string, dataCollection string, sessionCollecti
 
 	// return firestore client
 	return &FirestoreClient{
-		Ctx:            &ctx,
 		Client:         client,
 		Data:           client.Collection(dataCollection),
 		Sessions:       client.Collection(sessionCollection),
 		ChatHistory:    client.Collection("chat_history"),
 		DailySummaries: client.Collection("daily_summaries"),
+		Rubrics:        client.Collection("rubrics"),
+		Assignments:    client.Collection("assignments"),
+		Notifications:  client.Collection("notifications"),
+		Leases:         client.Collection("scheduler_leases"),
+		Jobs:           client.Collection("scheduler_jobs"),
+		JobRuns:        client.Collection("scheduler_job_runs"),
+		AnalysisJobs:   client.Collection("analysis_jobs"),
 	}, nil
 }
+
+// Close closes the connection to Firestore.
+func (client *FirestoreClient) Close() error {
+	return client.Client.Close()
+}
+
+// withTimeout bounds one operation by the client's Timeout.
+func (client *FirestoreClient) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if client.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, client.Timeout)
+}
+
+// Ping reads a document that need not exist, which proves the database is
+// reachable and the credentials can read it.
+func (client *FirestoreClient) Ping(ctx context.Context) error {
+	_, err := client.Leases.Doc("ping").Get(ctx)
+	if err != nil && status.Code(err) != codes.NotFound {
+		return err
+	}
+	return nil
+}
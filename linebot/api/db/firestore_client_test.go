@@ -1,6 +1,7 @@
 package db_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -16,11 +17,11 @@ func TestFirestoreRealAccess(t *testing.T) {
 	}
 
 	// Test writing a document to Firestore
-	docRef, _, err := firestoreClient.Data.Add(*firestoreClient.Ctx, testData)
+	docRef, _, err := firestoreClient.Data.Add(context.Background(), testData)
 	require.NoError(t, err)
 
 	// Ensure the data was written correctly by fetching it
-	docSnapshot, err := docRef.Get(*firestoreClient.Ctx)
+	docSnapshot, err := docRef.Get(context.Background())
 	require.NoError(t, err)
 	require.NotNil(t, docSnapshot)
 
@@ -33,6 +34,6 @@ func TestFirestoreRealAccess(t *testing.T) {
 	t.Logf("User Document: %v\n", fetchedData)
 
 	// Clean up by deleting the document
-	_, err = docRef.Delete(*firestoreClient.Ctx)
+	_, err = docRef.Delete(context.Background())
 	require.NoError(t, err)
 }
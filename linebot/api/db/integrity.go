@@ -0,0 +1,34 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+// UpdateWorkReferences sets the given fields of a work, named by their
+// dotted keys under the work, such as "student_video.object_path". It is for
+// repairs made from an earlier read, so it fails if the user's document was
+// updated after updateTime rather than undo a newer write.
+func (client *FirestoreClient) UpdateWorkReferences(
+	ctx context.Context, userID, skill, date string, changes map[string]any, updateTime time.Time,
+) error {
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
+	updates := make([]firestore.Update, 0, len(changes))
+	for key, value := range changes {
+		fieldPath := append(firestore.FieldPath{"portfolio", skill, date}, strings.Split(key, ".")...)
+		updates = append(updates, firestore.Update{FieldPath: fieldPath, Value: value})
+	}
+	var preconditions []firestore.Precondition
+	if !updateTime.IsZero() {
+		preconditions = append(preconditions, firestore.LastUpdateTime(updateTime))
+	}
+	if _, err := client.Data.Doc(userID).Update(ctx, updates, preconditions...); err != nil {
+		return fmt.Errorf("error updating work references: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Lease is the document instances compete for to become the job leader.
+type Lease struct {
+	Holder    string    `json:"holder" firestore:"holder"`
+	ExpiresAt time.Time `json:"expires_at" firestore:"expires_at"`
+}
+
+// JobRun is the outcome of one run of a scheduled job.
+type JobRun struct {
+	ID         string    `json:"id" firestore:"id"`
+	Job        string    `json:"job" firestore:"job"`
+	Trigger    string    `json:"trigger" firestore:"trigger"`
+	Holder     string    `json:"holder" firestore:"holder"`
+	StartedAt  time.Time `json:"started_at" firestore:"started_at"`
+	DurationMS int64     `json:"duration_ms" firestore:"duration_ms"`
+	Success    bool      `json:"success" firestore:"success"`
+	Error      string    `json:"error,omitempty" firestore:"error"`
+}
+
+// AcquireLease takes or renews the named lease for holder until ttl from now.
+// It succeeds when the lease is free, expired or already held by holder; the
+// read and write share a transaction, so two instances cannot both win.
+func (client *FirestoreClient) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
+	doc := client.Leases.Doc(name)
+	acquired := false
+	err := client.Client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		acquired = false
+		now := time.Now().UTC()
+		snap, err := tx.Get(doc)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		if err == nil {
+			var lease Lease
+			if err := snap.DataTo(&lease); err != nil {
+				return err
+			}
+			if lease.Holder != holder && lease.ExpiresAt.After(now) {
+				return nil
+			}
+		}
+		acquired = true
+		return tx.Set(doc, Lease{Holder: holder, ExpiresAt: now.Add(ttl)})
+	})
+	if err != nil {
+		return false, fmt.Errorf("error acquiring lease %s: %w", name, err)
+	}
+	return acquired, nil
+}
+
+// ReleaseLease gives up the named lease if holder still holds it, so another
+// instance can take it without waiting for it to expire.
+func (client *FirestoreClient) ReleaseLease(ctx context.Context, name, holder string) error {
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
+	doc := client.Leases.Doc(name)
+	err := client.Client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		snap, err := tx.Get(doc)
+		if status.Code(err) == codes.NotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var lease Lease
+		if err := snap.DataTo(&lease); err != nil {
+			return err
+		}
+		if lease.Holder != holder {
+			return nil
+		}
+		return tx.Delete(doc)
+	})
+	if err != nil {
+		return fmt.Errorf("error releasing lease %s: %w", name, err)
+	}
+	return nil
+}
+
+// RecordJobRun stores the run in the job's history under its ID and as the
+// job's latest run.
+func (client *FirestoreClient) RecordJobRun(ctx context.Context, run JobRun) error {
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
+	if _, err := client.JobRuns.Doc(run.ID).Set(ctx, run); err != nil {
+		return fmt.Errorf("error recording job run: %w", err)
+	}
+	if _, err := client.Jobs.Doc(run.Job).Set(ctx, run); err != nil {
+		return fmt.Errorf("error recording latest job run: %w", err)
+	}
+	return nil
+}
+
+// GetLatestJobRuns returns each job's latest run, keyed by job name.
+func (client *FirestoreClient) GetLatestJobRuns(ctx context.Context) (map[string]JobRun, error) {
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
+	iter := client.Jobs.Documents(ctx)
+	defer iter.Stop()
+
+	runs := map[string]JobRun{}
+	for {
+		doc, err := iter.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error listing job runs: %w", err)
+		}
+		var run JobRun
+		if err := doc.DataTo(&run); err != nil {
+			continue
+		}
+		runs[run.Job] = run
+	}
+	return runs, nil
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Notification records a push message that has been sent, keyed by a
+// deterministic ID so the same message is never claimed twice.
+type Notification struct {
+	Kind   string    `json:"kind" firestore:"kind"`
+	UserID string    `json:"user_id" firestore:"user_id"`
+	SentAt time.Time `json:"sent_at" firestore:"sent_at"`
+}
+
+// ClaimNotification reserves the notification key before the message is sent.
+// Firestore creates are atomic, so when several instances race for the same
+// key exactly one of them gets true.
+func (client *FirestoreClient) ClaimNotification(ctx context.Context, key, kind, userID string) (bool, error) {
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
+	_, err := client.Notifications.Doc(key).Create(ctx, Notification{
+		Kind:   kind,
+		UserID: userID,
+		SentAt: time.Now().UTC(),
+	})
+	if status.Code(err) == codes.AlreadyExists {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error claiming notification: %w", err)
+	}
+	return true, nil
+}
+
+// ReleaseNotification gives a claim back after the message failed to send, so
+// a later run can retry it.
+func (client *FirestoreClient) ReleaseNotification(ctx context.Context, key string) error {
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
+	if _, err := client.Notifications.Doc(key).Delete(ctx); err != nil {
+		return fmt.Errorf("error releasing notification: %w", err)
+	}
+	return nil
+}
+
+// UpdateUserNotifications turns push messages on or off for the user.
+func (client *FirestoreClient) UpdateUserNotifications(ctx context.Context, userID string, off bool) error {
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
+	_, err := client.Data.Doc(userID).Update(ctx, []firestore.Update{
+		{Path: "notifications_off", Value: off},
+	})
+	if err != nil {
+		return fmt.Errorf("error updating user notifications: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,303 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/HeavenAQ/nstc-linebot-2025/commons"
+	"google.golang.org/api/iterator"
+)
+
+// UserRank reports where a student's grades fall within their class. Only the
+// student's own grades are included; classmates feed the percentiles but are
+// never identified.
+type UserRank struct {
+	UserID    string      `json:"user_id"`
+	ClassID   string      `json:"class_id"`
+	MinCohort int         `json:"min_cohort"`
+	Skills    []SkillRank `json:"skills"`
+}
+
+// SkillRank holds one skill's percentiles. Percentiles are only filled in once
+// Available is set, i.e. the student has submitted and at least MinCohort
+// classmates (the student included) have too.
+type SkillRank struct {
+	Skill            string          `json:"skill"`
+	CohortSize       int             `json:"cohort_size"`
+	Submitted        bool            `json:"submitted"`
+	Available        bool            `json:"available"`
+	LatestGrade      float64         `json:"latest_grade"`
+	LatestPercentile float64         `json:"latest_percentile"`
+	BestGrade        float64         `json:"best_grade"`
+	BestPercentile   float64         `json:"best_percentile"`
+	Criteria         []CriterionRank `json:"criteria"`
+}
+
+// CriterionRank is the percentile of one grading criterion, comparing latest
+// attempts with latest attempts and best with best.
+type CriterionRank struct {
+	CriterionID      string  `json:"criterion_id"`
+	Description      string  `json:"description"`
+	LatestGrade      float64 `json:"latest_grade"`
+	LatestPercentile float64 `json:"latest_percentile"`
+	BestGrade        float64 `json:"best_grade"`
+	BestPercentile   float64 `json:"best_percentile"`
+}
+
+// GetUserRank ranks the student against everyone sharing their class ID.
+// Students who have not been assigned a class rank against each other. The
+// classmates' grades may be up to RankCacheTTL old; the student's own are
+// read fresh, so a new attempt shows at once.
+func (client *FirestoreClient) GetUserRank(ctx context.Context, userID string, skills []string, minCohort int) (*UserRank, error) {
+	user, err := client.GetUserData(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	cohort, err := client.classCohort(ctx, user.ClassID, skills)
+	if err != nil {
+		return nil, err
+	}
+
+	rank := &UserRank{
+		UserID:    user.ID,
+		ClassID:   user.ClassID,
+		MinCohort: minCohort,
+		Skills:    make([]SkillRank, 0, len(skills)),
+	}
+	for _, skill := range skills {
+		var own *skillSummary
+		if summary, ok := summarizeSkill(user.Portfolio.GetSkillPortfolio(skill)); ok {
+			own = &summary
+		}
+		rank.Skills = append(rank.Skills, rankSummaries(skill, user.ID, own, cohort[skill], minCohort))
+	}
+	return rank, nil
+}
+
+// ListClassUsers returns every user assigned to the class. Older documents
+// have no class_id field at all, which a query would skip, so the users
+// without a class are found by reading every user.
+func (client *FirestoreClient) ListClassUsers(ctx context.Context, classID string) ([]UserData, error) {
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
+	query := client.Data.Query
+	if classID != "" {
+		query = client.Data.Where("class_id", "==", classID)
+	}
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+
+	var users []UserData
+	for {
+		doc, err := iter.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error listing class users: %w", err)
+		}
+		var user UserData
+		if err := doc.DataTo(&user); err != nil {
+			continue
+		}
+		if user.ClassID == classID {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+// cohortCache keeps each class's skill summaries, so rank lookups do not
+// read the whole class every time.
+type cohortCache struct {
+	mu      sync.Mutex
+	classes map[string]cachedCohort
+}
+
+// cachedCohort maps a skill to the summaries of the classmates who have
+// attempted it, by user ID. It is shared, so it is never modified.
+type cachedCohort struct {
+	at     time.Time
+	skills map[string]map[string]skillSummary
+}
+
+// classCohort returns the class's summaries for skills, reading the class
+// again once the cached ones are RankCacheTTL old.
+func (client *FirestoreClient) classCohort(ctx context.Context, classID string, skills []string) (map[string]map[string]skillSummary, error) {
+	cache := &client.cohorts
+	cache.mu.Lock()
+	cached, ok := cache.classes[classID]
+	cache.mu.Unlock()
+	if ok && time.Since(cached.at) < client.RankCacheTTL && covers(cached.skills, skills) {
+		return cached.skills, nil
+	}
+
+	classmates, err := client.ListClassUsers(ctx, classID)
+	if err != nil {
+		return nil, err
+	}
+	cohort := make(map[string]map[string]skillSummary, len(skills))
+	for _, skill := range skills {
+		cohort[skill] = summarizeCohort(classmates, skill)
+	}
+	cache.mu.Lock()
+	if cache.classes == nil {
+		cache.classes = map[string]cachedCohort{}
+	}
+	cache.classes[classID] = cachedCohort{at: time.Now(), skills: cohort}
+	cache.mu.Unlock()
+	return cohort, nil
+}
+
+func covers(cohort map[string]map[string]skillSummary, skills []string) bool {
+	for _, skill := range skills {
+		if _, ok := cohort[skill]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// summarizeCohort summarizes the skill for each user who has attempted it.
+func summarizeCohort(users []UserData, skill string) map[string]skillSummary {
+	summaries := map[string]skillSummary{}
+	for _, user := range users {
+		if summary, ok := summarizeSkill(user.Portfolio.GetSkillPortfolio(skill)); ok {
+			summaries[user.ID] = summary
+		}
+	}
+	return summaries
+}
+
+// skillSummary condenses one student's attempts at a skill into the values
+// that are ranked.
+type skillSummary struct {
+	latest         commons.SkillScore
+	best           float64
+	latestCriteria map[string]float64
+	bestCriteria   map[string]float64
+}
+
+func summarizeSkill(portfolio map[string]Work) (skillSummary, bool) {
+	scores := RecentSkillScores(portfolio, 0)
+	if len(scores) == 0 {
+		return skillSummary{}, false
+	}
+
+	summary := skillSummary{
+		latest:         scores[0],
+		best:           scores[0].TotalGrade,
+		latestCriteria: map[string]float64{},
+		bestCriteria:   map[string]float64{},
+	}
+	for _, detail := range scores[0].Details {
+		summary.latestCriteria[CriterionKey(detail)] = detail.Grade
+	}
+	for _, score := range scores {
+		summary.best = max(summary.best, score.TotalGrade)
+		for _, detail := range score.Details {
+			key := CriterionKey(detail)
+			if current, ok := summary.bestCriteria[key]; !ok || detail.Grade > current {
+				summary.bestCriteria[key] = detail.Grade
+			}
+		}
+	}
+	return summary, true
+}
+
+// CriterionKey identifies a grading criterion across attempts. It falls back
+// to the description for grades recorded before criteria carried IDs.
+func CriterionKey(detail commons.GradingDetail) string {
+	if detail.CriterionID != "" {
+		return detail.CriterionID
+	}
+	return detail.Description
+}
+
+func rankSkill(userID string, cohort []UserData, skill string, minCohort int) SkillRank {
+	summaries := summarizeCohort(cohort, skill)
+	var own *skillSummary
+	if summary, ok := summaries[userID]; ok {
+		own = &summary
+	}
+	return rankSummaries(skill, userID, own, summaries, minCohort)
+}
+
+// rankSummaries ranks own, the student's summary or nil if they have not
+// attempted the skill, against the cohort's. The cohort's entry for the
+// student is replaced by own.
+func rankSummaries(skill, userID string, own *skillSummary, cohort map[string]skillSummary, minCohort int) SkillRank {
+	result := SkillRank{Skill: skill, Criteria: []CriterionRank{}}
+
+	var (
+		latest, best   []float64
+		latestCriteria = map[string][]float64{}
+		bestCriteria   = map[string][]float64{}
+	)
+	add := func(summary skillSummary) {
+		latest = append(latest, summary.latest.TotalGrade)
+		best = append(best, summary.best)
+		for key, grade := range summary.latestCriteria {
+			latestCriteria[key] = append(latestCriteria[key], grade)
+		}
+		for key, grade := range summary.bestCriteria {
+			bestCriteria[key] = append(bestCriteria[key], grade)
+		}
+	}
+	for id, summary := range cohort {
+		if id != userID {
+			add(summary)
+		}
+	}
+	if own != nil {
+		add(*own)
+	}
+
+	result.CohortSize = len(latest)
+	if own == nil {
+		return result
+	}
+	result.Submitted = true
+	result.LatestGrade = own.latest.TotalGrade
+	result.BestGrade = own.best
+	if result.CohortSize < minCohort {
+		return result
+	}
+
+	result.Available = true
+	result.LatestPercentile = percentileRank(own.latest.TotalGrade, latest)
+	result.BestPercentile = percentileRank(own.best, best)
+	for _, detail := range own.latest.Details {
+		key := CriterionKey(detail)
+		result.Criteria = append(result.Criteria, CriterionRank{
+			CriterionID:      detail.CriterionID,
+			Description:      detail.Description,
+			LatestGrade:      detail.Grade,
+			LatestPercentile: percentileRank(detail.Grade, latestCriteria[key]),
+			BestGrade:        own.bestCriteria[key],
+			BestPercentile:   percentileRank(own.bestCriteria[key], bestCriteria[key]),
+		})
+	}
+	return result
+}
+
+// percentileRank returns the share of values below value on a 0-100 scale,
+// counting ties as half so identical grades share a percentile.
+func percentileRank(value float64, values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	below, equal := 0, 0
+	for _, v := range values {
+		switch {
+		case v < value:
+			below++
+		case v == value:
+			equal++
+		}
+	}
+	return 100 * (float64(below) + 0.5*float64(equal)) / float64(len(values))
+}
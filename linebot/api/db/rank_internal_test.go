@@ -0,0 +1,136 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/HeavenAQ/nstc-linebot-2025/commons"
+	"github.com/stretchr/testify/require"
+)
+
+func graded(total, contact float64) Work {
+	return Work{
+		GradingOutcome: commons.GradingOutcome{
+			TotalGrade: total,
+			GradingDetails: []commons.GradingDetail{
+				{CriterionID: "serve.contact", Description: "擊球點", Grade: contact, Maximum: 20},
+			},
+		},
+	}
+}
+
+func student(id string, serve map[string]Work) UserData {
+	return UserData{ID: id, Portfolio: Portfolios{Serve: serve}}
+}
+
+func TestPercentileRankCountsTiesAsHalf(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, 0.0, percentileRank(50, nil))
+	require.Equal(t, 50.0, percentileRank(70, []float64{70}))
+	require.Equal(t, 50.0, percentileRank(80, []float64{60, 80, 80, 90}))
+	require.Equal(t, 87.5, percentileRank(95, []float64{60, 80, 90, 95}))
+}
+
+func TestRankSkillComparesLatestWithLatestAndBestWithBest(t *testing.T) {
+	t.Parallel()
+
+	cohort := []UserData{
+		student("me", map[string]Work{
+			"2026-07-25-09-00": graded(90, 18),
+			"2026-08-01-10-30": graded(60, 10),
+		}),
+		student("a", map[string]Work{"2026-08-01-09-00": graded(70, 12)}),
+		student("b", map[string]Work{"2026-08-01-09-00": graded(50, 8)}),
+		student("c", map[string]Work{"2026-08-01-09-00": graded(95, 19)}),
+		student("nothing-yet", nil),
+	}
+
+	rank := rankSkill("me", cohort, "serve", 3)
+
+	require.True(t, rank.Submitted)
+	require.True(t, rank.Available)
+	require.Equal(t, 4, rank.CohortSize)
+	require.Equal(t, 60.0, rank.LatestGrade)
+	require.Equal(t, 37.5, rank.LatestPercentile)
+	require.Equal(t, 90.0, rank.BestGrade)
+	require.Equal(t, 62.5, rank.BestPercentile)
+
+	require.Len(t, rank.Criteria, 1)
+	require.Equal(t, "serve.contact", rank.Criteria[0].CriterionID)
+	require.Equal(t, 10.0, rank.Criteria[0].LatestGrade)
+	require.Equal(t, 37.5, rank.Criteria[0].LatestPercentile)
+	require.Equal(t, 18.0, rank.Criteria[0].BestGrade)
+	require.Equal(t, 62.5, rank.Criteria[0].BestPercentile)
+}
+
+// Percentiles over a handful of students would all but reveal classmates'
+// grades, so they stay hidden until enough have submitted.
+func TestRankSkillWithholdsPercentilesBelowMinimumCohort(t *testing.T) {
+	t.Parallel()
+
+	cohort := []UserData{
+		student("me", map[string]Work{"2026-08-01-10-30": graded(60, 10)}),
+		student("a", map[string]Work{"2026-08-01-09-00": graded(70, 12)}),
+	}
+
+	rank := rankSkill("me", cohort, "serve", 3)
+
+	require.True(t, rank.Submitted)
+	require.False(t, rank.Available)
+	require.Equal(t, 2, rank.CohortSize)
+	require.Equal(t, 60.0, rank.LatestGrade)
+	require.Zero(t, rank.LatestPercentile)
+	require.Empty(t, rank.Criteria)
+}
+
+func TestRankSkillReportsStudentWithoutSubmission(t *testing.T) {
+	t.Parallel()
+
+	cohort := []UserData{
+		student("me", nil),
+		student("a", map[string]Work{"2026-08-01-09-00": graded(70, 12)}),
+	}
+
+	rank := rankSkill("me", cohort, "serve", 1)
+
+	require.False(t, rank.Submitted)
+	require.False(t, rank.Available)
+	require.Equal(t, 1, rank.CohortSize)
+}
+
+// A cached cohort can predate the student's latest attempt, which is read
+// fresh and takes the place of their cached summary.
+func TestRankSummariesUsesTheStudentsFreshSummary(t *testing.T) {
+	t.Parallel()
+
+	cohort := summarizeCohort([]UserData{
+		student("me", map[string]Work{"2026-08-01-10-30": graded(60, 10)}),
+		student("a", map[string]Work{"2026-08-01-09-00": graded(70, 12)}),
+	}, "serve")
+	own, ok := summarizeSkill(map[string]Work{
+		"2026-08-01-10-30": graded(60, 10),
+		"2026-08-02-10-30": graded(80, 16),
+	})
+	require.True(t, ok)
+
+	rank := rankSummaries("serve", "me", &own, cohort, 1)
+
+	require.Equal(t, 2, rank.CohortSize)
+	require.Equal(t, 80.0, rank.LatestGrade)
+	require.Equal(t, 75.0, rank.LatestPercentile)
+}
+
+func TestClassCohortIsReusedUntilItExpires(t *testing.T) {
+	t.Parallel()
+
+	cached := map[string]map[string]skillSummary{"serve": {}}
+	client := &FirestoreClient{RankCacheTTL: time.Minute}
+	client.cohorts.classes = map[string]cachedCohort{"7A": {at: time.Now(), skills: cached}}
+
+	// A read would panic: the client has no Firestore connection
+	cohort, err := client.classCohort(context.Background(), "7A", []string{"serve"})
+	require.NoError(t, err)
+	require.Equal(t, cached, cohort)
+}
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/HeavenAQ/nstc-linebot-2025/commons"
+)
+
+// GradeVersion is a grading a work had before it was re-analyzed.
+type GradeVersion struct {
+	AnalysisID      string                 `json:"analysis_id" firestore:"analysis_id"`
+	AnalyzerVersion string                 `json:"analyzer_version" firestore:"analyzer_version"`
+	GradingOutcome  commons.GradingOutcome `json:"grading_outcome" firestore:"grading_outcome"`
+	ReplacedAt      time.Time              `json:"replaced_at" firestore:"replaced_at"`
+}
+
+// OriginalVideoPath is where an upload is archived, under the user's root
+// folder, so it can be re-analyzed when the analyzer improves.
+func OriginalVideoPath(user *UserData, skill, date string) string {
+	return fmt.Sprintf("%soriginals/%s/%s.mp4", user.FolderPaths.Root, skill, date)
+}
+
+// Reanalyzed returns the work graded by analysis. The current grading moves
+// to GradeHistory; the student's notes, thumbnail and submission are kept.
+// Cue clips belong to the old cues, so they are dropped.
+func (w Work) Reanalyzed(analysis commons.AnalysisOutcome, now time.Time) Work {
+	w.GradeHistory = append(append([]GradeVersion(nil), w.GradeHistory...), GradeVersion{
+		AnalysisID:      w.AnalysisID,
+		AnalyzerVersion: w.AnalyzerVersion,
+		GradingOutcome:  w.GradingOutcome,
+		ReplacedAt:      now.UTC(),
+	})
+	w.Handedness = analysis.Handedness
+	w.GradingOutcome = analysis.Grade
+	w.AINote = analysis.OverallFeedback
+	w.SkeletonVideo = analysis.StudentVideo.SignedURL
+	w.AnalysisID = analysis.AnalysisID
+	w.StudentVideo = analysis.StudentVideo
+	w.Expert = analysis.Expert
+	w.Timeline = analysis.Timeline
+	w.CoachingCues = analysis.CoachingCues
+	w.Diagnostics = analysis.Diagnostics
+	w.AnalyzerVersion = analysis.AnalyzerVersion
+	w.CueClips = nil
+	return w
+}
+
+// UpdateWorkAnalysis writes only the analysis fields of a work. Re-analysis
+// takes minutes, so rewriting the whole user document would drop notes the
+// student saved in the meantime.
+func (client *FirestoreClient) UpdateWorkAnalysis(ctx context.Context, userID, skill, date string, work Work) error {
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
+	path := func(field string) firestore.FieldPath {
+		return firestore.FieldPath{"portfolio", skill, date, field}
+	}
+	_, err := client.Data.Doc(userID).Update(ctx, []firestore.Update{
+		{FieldPath: path("handedness"), Value: work.Handedness},
+		{FieldPath: path("grading_outcome"), Value: work.GradingOutcome},
+		{FieldPath: path("ai_note"), Value: work.AINote},
+		{FieldPath: path("skeleton_video"), Value: work.SkeletonVideo},
+		{FieldPath: path("analysis_id"), Value: work.AnalysisID},
+		{FieldPath: path("student_video"), Value: work.StudentVideo},
+		{FieldPath: path("expert"), Value: work.Expert},
+		{FieldPath: path("timeline"), Value: work.Timeline},
+		{FieldPath: path("coaching_cues"), Value: work.CoachingCues},
+		{FieldPath: path("diagnostics"), Value: work.Diagnostics},
+		{FieldPath: path("analyzer_version"), Value: work.AnalyzerVersion},
+		{FieldPath: path("grade_history"), Value: work.GradeHistory},
+		{FieldPath: path("cue_clips"), Value: work.CueClips},
+	})
+	if err != nil {
+		return fmt.Errorf("error updating work analysis: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/HeavenAQ/nstc-linebot-2025/commons"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOriginalVideoPath(t *testing.T) {
+	t.Parallel()
+
+	user := &UserData{FolderPaths: FolderPaths{Root: "U123/"}}
+	require.Equal(t, "U123/originals/serve/2026-09-01-10-00.mp4", OriginalVideoPath(user, "serve", "2026-09-01-10-00"))
+}
+
+func TestWorkReanalyzedKeepsHistoryAndNotes(t *testing.T) {
+	t.Parallel()
+
+	first := graded(60, 0)
+	first.AnalysisID = "a1"
+	first.AnalyzerVersion = "v1"
+	first.Reflection = "擊球點太後面"
+	first.Thumbnail = "thumb.jpeg"
+	first.AssignmentID = "w1"
+	first.OriginalVideo = "U123/originals/serve/2026-09-01-10-00.mp4"
+	first.CueClips = []CueClip{{Title: "擊球點", Video: "analyses/v1/U123/a1/cues/0.mp4"}}
+	now := time.Date(2026, 10, 1, 9, 0, 0, 0, time.FixedZone("CST", 8*60*60))
+
+	second := first.Reanalyzed(commons.AnalysisOutcome{
+		AnalysisID:      "a2",
+		AnalyzerVersion: "v2",
+		Handedness:      "right",
+		Grade:           commons.GradingOutcome{TotalGrade: 72},
+		OverallFeedback: "進步了",
+	}, now)
+	third := second.Reanalyzed(commons.AnalysisOutcome{
+		AnalysisID:      "a3",
+		AnalyzerVersion: "v3",
+		Grade:           commons.GradingOutcome{TotalGrade: 75},
+	}, now.Add(time.Hour))
+
+	require.Equal(t, "a3", third.AnalysisID)
+	require.Equal(t, "v3", third.AnalyzerVersion)
+	require.InDelta(t, 75, third.GradingOutcome.TotalGrade, 0.001)
+	require.Equal(t, []GradeVersion{
+		{AnalysisID: "a1", AnalyzerVersion: "v1", GradingOutcome: first.GradingOutcome, ReplacedAt: now.UTC()},
+		{AnalysisID: "a2", AnalyzerVersion: "v2", GradingOutcome: second.GradingOutcome, ReplacedAt: now.Add(time.Hour).UTC()},
+	}, third.GradeHistory)
+	require.Len(t, second.GradeHistory, 1)
+	require.Equal(t, first.Reflection, third.Reflection)
+	require.Equal(t, first.Thumbnail, third.Thumbnail)
+	require.Equal(t, first.AssignmentID, third.AssignmentID)
+	require.Equal(t, first.OriginalVideo, third.OriginalVideo)
+	require.Empty(t, third.CueClips)
+}
@@ -0,0 +1,263 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// AttemptPolicy decides which of a student's attempts at a skill counts
+// towards the course grade.
+type AttemptPolicy string
+
+const (
+	AttemptBest    AttemptPolicy = "best"
+	AttemptLatest  AttemptPolicy = "latest"
+	AttemptAverage AttemptPolicy = "average"
+)
+
+func (p AttemptPolicy) ChnString() string {
+	switch p {
+	case AttemptLatest:
+		return "採計最新一次"
+	case AttemptAverage:
+		return "採計平均"
+	default:
+		return "採計最佳一次"
+	}
+}
+
+// defaultRubricID stores the rubric for students without a class, since a
+// Firestore document ID cannot be empty.
+const defaultRubricID = "_default"
+
+// Rubric is a class's course-grading scheme. Each skill is worth its weight in
+// points, scaled by the counted attempt's 0-100 analyzer grade. Reflection and
+// preview-note points are awarded in proportion to the share of weighted
+// attempts whose note was filled in.
+type Rubric struct {
+	ClassID           string             `json:"class_id" firestore:"class_id"`
+	AttemptPolicy     AttemptPolicy      `json:"attempt_policy" firestore:"attempt_policy"`
+	SkillWeights      map[string]float64 `json:"skill_weights" firestore:"skill_weights"`
+	ReflectionPoints  float64            `json:"reflection_points" firestore:"reflection_points"`
+	PreviewNotePoints float64            `json:"preview_note_points" firestore:"preview_note_points"`
+	UpdatedAt         time.Time          `json:"updated_at" firestore:"updated_at"`
+}
+
+// DefaultRubric is used until a teacher sets one: every skill weighs the
+// same, the best attempt counts and notes earn nothing.
+func DefaultRubric(classID string) Rubric {
+	weights := map[string]float64{}
+	for _, skill := range BadmintonSkillNames() {
+		weights[skill] = 100 / float64(len(BadmintonSkills))
+	}
+	return Rubric{ClassID: classID, AttemptPolicy: AttemptBest, SkillWeights: weights}
+}
+
+// Validate rejects rubrics that cannot produce a grade or be stored under
+// their class ID.
+func (r Rubric) Validate() error {
+	if err := ValidateClassID(r.ClassID); err != nil {
+		return err
+	}
+	switch r.AttemptPolicy {
+	case AttemptBest, AttemptLatest, AttemptAverage:
+	default:
+		return fmt.Errorf("invalid attempt policy %q", r.AttemptPolicy)
+	}
+	if r.ReflectionPoints < 0 || r.PreviewNotePoints < 0 {
+		return errors.New("note points must not be negative")
+	}
+	total := 0.0
+	for skill, weight := range r.SkillWeights {
+		if SkillStrToEnum(skill) < 0 {
+			return fmt.Errorf("invalid skill %q", skill)
+		}
+		if weight < 0 {
+			return fmt.Errorf("weight of %s must not be negative", skill)
+		}
+		total += weight
+	}
+	if total == 0 {
+		return errors.New("at least one skill must carry weight")
+	}
+	return nil
+}
+
+// ValidateClassID rejects class IDs Firestore cannot use as a document ID,
+// and the ID the rubric for students without a class is stored under.
+func ValidateClassID(classID string) error {
+	switch {
+	case strings.Contains(classID, "/"):
+		return errors.New("class id must not contain '/'")
+	case classID == "." || classID == "..":
+		return fmt.Errorf("class id %q is reserved", classID)
+	case len(classID) >= 4 && strings.HasPrefix(classID, "__") && strings.HasSuffix(classID, "__"):
+		return fmt.Errorf("class id %q is reserved", classID)
+	case classID == defaultRubricID:
+		return fmt.Errorf("class id %q is reserved for students without a class", classID)
+	}
+	return nil
+}
+
+// Maximum is the grade a student earns with a perfect attempt at every
+// weighted skill and every note filled in.
+func (r Rubric) Maximum() float64 {
+	total := r.ReflectionPoints + r.PreviewNotePoints
+	for _, weight := range r.SkillWeights {
+		total += weight
+	}
+	return total
+}
+
+// WorkPoints is what a single attempt would earn towards the course grade if
+// it were the one counted.
+func (r Rubric) WorkPoints(skill string, work Work) float64 {
+	return r.SkillWeights[skill] * work.GradingOutcome.TotalGrade / 100
+}
+
+// CourseGrade is a student's grade under their class rubric.
+type CourseGrade struct {
+	UserID            string       `json:"user_id"`
+	ClassID           string       `json:"class_id"`
+	Rubric            Rubric       `json:"rubric"`
+	Skills            []SkillGrade `json:"skills"`
+	ReflectionPoints  float64      `json:"reflection_points"`
+	PreviewNotePoints float64      `json:"preview_note_points"`
+	Total             float64      `json:"total"`
+	Maximum           float64      `json:"maximum"`
+}
+
+// SkillGrade is one skill's share of the course grade. Score is the counted
+// 0-100 analyzer grade; Points is that score scaled by the skill's weight.
+type SkillGrade struct {
+	Skill    string  `json:"skill"`
+	Weight   float64 `json:"weight"`
+	Attempts int     `json:"attempts"`
+	Score    float64 `json:"score"`
+	Points   float64 `json:"points"`
+}
+
+// Grade computes the student's course grade. Skills the student has not
+// attempted score zero.
+func (r Rubric) Grade(user UserData) CourseGrade {
+	grade := CourseGrade{
+		UserID:  user.ID,
+		ClassID: user.ClassID,
+		Rubric:  r,
+		Skills:  []SkillGrade{},
+		Maximum: r.Maximum(),
+	}
+
+	attempts, reflections, previewNotes := 0, 0, 0
+	for _, skill := range BadmintonSkillNames() {
+		weight, ok := r.SkillWeights[skill]
+		if !ok || weight == 0 {
+			continue
+		}
+		works := user.Portfolio.GetSkillPortfolio(skill)
+		skillGrade := SkillGrade{Skill: skill, Weight: weight, Attempts: len(works)}
+		skillGrade.Score = r.countedScore(works)
+		skillGrade.Points = weight * skillGrade.Score / 100
+		grade.Skills = append(grade.Skills, skillGrade)
+		grade.Total += skillGrade.Points
+
+		for _, work := range works {
+			attempts++
+			if work.HasReflection() {
+				reflections++
+			}
+			if work.HasPreviewNote() {
+				previewNotes++
+			}
+		}
+	}
+
+	if attempts > 0 {
+		grade.ReflectionPoints = r.ReflectionPoints * float64(reflections) / float64(attempts)
+		grade.PreviewNotePoints = r.PreviewNotePoints * float64(previewNotes) / float64(attempts)
+	}
+	grade.Total += grade.ReflectionPoints + grade.PreviewNotePoints
+	return grade
+}
+
+// countedScore applies the attempt policy to a skill's attempts.
+func (r Rubric) countedScore(works map[string]Work) float64 {
+	scores := RecentSkillScores(works, 0)
+	if len(scores) == 0 {
+		return 0
+	}
+	switch r.AttemptPolicy {
+	case AttemptLatest:
+		return scores[0].TotalGrade
+	case AttemptAverage:
+		sum := 0.0
+		for _, score := range scores {
+			sum += score.TotalGrade
+		}
+		return sum / float64(len(scores))
+	default:
+		best := scores[0].TotalGrade
+		for _, score := range scores {
+			best = max(best, score.TotalGrade)
+		}
+		return best
+	}
+}
+
+func rubricDocID(classID string) string {
+	if classID == "" {
+		return defaultRubricID
+	}
+	return classID
+}
+
+// GetRubric returns the class's rubric, or the default one if the teacher has
+// not set one.
+func (client *FirestoreClient) GetRubric(ctx context.Context, classID string) (Rubric, error) {
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
+	snap, err := client.Rubrics.Doc(rubricDocID(classID)).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return DefaultRubric(classID), nil
+		}
+		return Rubric{}, err
+	}
+	var rubric Rubric
+	if err := snap.DataTo(&rubric); err != nil {
+		return Rubric{}, err
+	}
+	return rubric, nil
+}
+
+// SetRubric validates and stores the rubric for its class.
+func (client *FirestoreClient) SetRubric(ctx context.Context, rubric Rubric) error {
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
+	if err := rubric.Validate(); err != nil {
+		return err
+	}
+	rubric.UpdatedAt = time.Now().UTC()
+	_, err := client.Rubrics.Doc(rubricDocID(rubric.ClassID)).Set(ctx, rubric)
+	return err
+}
+
+// GetUserCourseGrade grades the student under their class rubric.
+func (client *FirestoreClient) GetUserCourseGrade(ctx context.Context, userID string) (*CourseGrade, error) {
+	user, err := client.GetUserData(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	rubric, err := client.GetRubric(ctx, user.ClassID)
+	if err != nil {
+		return nil, err
+	}
+	grade := rubric.Grade(*user)
+	return &grade, nil
+}
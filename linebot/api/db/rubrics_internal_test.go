@@ -0,0 +1,91 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func noted(total float64, reflection, previewNote string) Work {
+	work := graded(total, 0)
+	work.Reflection = reflection
+	work.PreviewNote = previewNote
+	return work
+}
+
+func TestRubricValidate(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, DefaultRubric("A").Validate())
+
+	rubric := DefaultRubric("A")
+	rubric.AttemptPolicy = "median"
+	require.ErrorContains(t, rubric.Validate(), "invalid attempt policy")
+
+	rubric = DefaultRubric("A")
+	rubric.SkillWeights = map[string]float64{"drive": 10}
+	require.ErrorContains(t, rubric.Validate(), "invalid skill")
+
+	rubric = DefaultRubric("A")
+	rubric.SkillWeights = map[string]float64{"serve": 0}
+	require.ErrorContains(t, rubric.Validate(), "at least one skill")
+
+	rubric = DefaultRubric("A")
+	rubric.ReflectionPoints = -1
+	require.ErrorContains(t, rubric.Validate(), "negative")
+
+	require.ErrorContains(t, DefaultRubric("A/B").Validate(), "class id")
+	for _, classID := range []string{".", "..", "__name__", "____", "_default"} {
+		require.ErrorContains(t, DefaultRubric(classID).Validate(), "reserved", classID)
+	}
+	for _, classID := range []string{"", "7A", "__", "___", "_a_", "a.b"} {
+		require.NoError(t, DefaultRubric(classID).Validate(), classID)
+	}
+}
+
+func TestRubricGradeAppliesAttemptPolicy(t *testing.T) {
+	t.Parallel()
+
+	user := student("u1", map[string]Work{
+		"2026-09-01-10-00": graded(90, 0),
+		"2026-09-08-10-00": graded(60, 0),
+	})
+	rubric := Rubric{AttemptPolicy: AttemptBest, SkillWeights: map[string]float64{"serve": 40, "smash": 60}}
+
+	cases := map[AttemptPolicy]float64{AttemptBest: 90, AttemptLatest: 60, AttemptAverage: 75}
+	for policy, score := range cases {
+		rubric.AttemptPolicy = policy
+		grade := rubric.Grade(user)
+
+		require.Len(t, grade.Skills, 2, policy)
+		require.Equal(t, score, grade.Skills[0].Score, policy)
+		require.InDelta(t, score*0.4, grade.Skills[0].Points, 1e-9, policy)
+		require.Zero(t, grade.Skills[1].Points, policy)
+		require.InDelta(t, score*0.4, grade.Total, 1e-9, policy)
+		require.Equal(t, 100.0, grade.Maximum)
+	}
+}
+
+func TestRubricGradeAwardsNotePointsByShare(t *testing.T) {
+	t.Parallel()
+
+	user := student("u1", map[string]Work{
+		"2026-09-01-10-00": noted(100, "擊球點要更前面", DefaultPreviewNote),
+		"2026-09-08-10-00": noted(100, DefaultReflection, "注意轉肩"),
+		"2026-09-15-10-00": noted(100, "", "注意轉肩"),
+		"2026-09-22-10-00": noted(100, "手腕放鬆", "注意轉肩"),
+	})
+	rubric := Rubric{
+		AttemptPolicy:     AttemptLatest,
+		SkillWeights:      map[string]float64{"serve": 80},
+		ReflectionPoints:  10,
+		PreviewNotePoints: 10,
+	}
+
+	grade := rubric.Grade(user)
+
+	require.Equal(t, 5.0, grade.ReflectionPoints)
+	require.Equal(t, 7.5, grade.PreviewNotePoints)
+	require.Equal(t, 92.5, grade.Total)
+	require.Equal(t, 100.0, grade.Maximum)
+}
@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"fmt"
 	"sort"
 	"strings"
@@ -9,21 +10,23 @@ import (
 	"github.com/HeavenAQ/nstc-linebot-2025/commons"
 )
 
-// workKeyLayout is the format portfolio entries are keyed by.
-const workKeyLayout = "2006-01-02-15-04"
+// WorkKeyLayout is the format portfolio entries are keyed by.
+const WorkKeyLayout = "2006-01-02-15-04"
 
 // GetRecentSkillScores returns the learner's graded attempts for a skill,
 // newest first. A limit of zero or less returns every attempt. An empty or
 // missing portfolio is not an error: the learner simply has no scores yet.
-func (client *FirestoreClient) GetRecentSkillScores(userID, skill string, limit int) ([]commons.SkillScore, error) {
-	user, err := client.GetUserData(userID)
+func (client *FirestoreClient) GetRecentSkillScores(ctx context.Context, userID, skill string, limit int) ([]commons.SkillScore, error) {
+	user, err := client.GetUserData(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
-	return recentSkillScores(user.Portfolio.GetSkillPortfolio(strings.ToLower(strings.TrimSpace(skill))), limit), nil
+	return RecentSkillScores(user.Portfolio.GetSkillPortfolio(strings.ToLower(strings.TrimSpace(skill))), limit), nil
 }
 
-func recentSkillScores(portfolio map[string]Work, limit int) []commons.SkillScore {
+// RecentSkillScores flattens a portfolio into scores, newest first, keeping at
+// most limit of them when limit is positive.
+func RecentSkillScores(portfolio map[string]Work, limit int) []commons.SkillScore {
 	scores := make([]commons.SkillScore, 0, len(portfolio))
 	for key, work := range portfolio {
 		scores = append(scores, commons.SkillScore{
@@ -37,8 +40,8 @@ func recentSkillScores(portfolio map[string]Work, limit int) []commons.SkillScor
 	// Newest first. Keys that predate the current layout sort last rather than
 	// failing the whole lookup, since a summary is still worth producing.
 	sort.Slice(scores, func(i, j int) bool {
-		left, leftErr := time.Parse(workKeyLayout, scores[i].Date)
-		right, rightErr := time.Parse(workKeyLayout, scores[j].Date)
+		left, leftErr := time.Parse(WorkKeyLayout, scores[i].Date)
+		right, rightErr := time.Parse(WorkKeyLayout, scores[j].Date)
 		switch {
 		case leftErr == nil && rightErr == nil:
 			return left.After(right)
@@ -22,7 +22,7 @@ func work(total float64, status string) Work {
 func TestRecentSkillScoresOrdersNewestFirstAndCaps(t *testing.T) {
 	t.Parallel()
 
-	scores := recentSkillScores(map[string]Work{
+	scores := RecentSkillScores(map[string]Work{
 		"2026-07-25-09-00": work(70, "待加強"),
 		"2026-08-01-10-30": work(82.5, "進步"),
 		"2026-07-30-18-05": work(78, ""),
@@ -39,9 +39,9 @@ func TestRecentSkillScoresOrdersNewestFirstAndCaps(t *testing.T) {
 func TestRecentSkillScoresHandlesEmptyAndUnlimited(t *testing.T) {
 	t.Parallel()
 
-	require.Empty(t, recentSkillScores(nil, 5))
+	require.Empty(t, RecentSkillScores(nil, 5))
 
-	all := recentSkillScores(map[string]Work{
+	all := RecentSkillScores(map[string]Work{
 		"2026-07-25-09-00": work(70, ""),
 		"2026-08-01-10-30": work(82.5, ""),
 	}, 0)
@@ -52,7 +52,7 @@ func TestRecentSkillScoresHandlesEmptyAndUnlimited(t *testing.T) {
 func TestRecentSkillScoresKeepsUnparsableKeysLast(t *testing.T) {
 	t.Parallel()
 
-	scores := recentSkillScores(map[string]Work{
+	scores := RecentSkillScores(map[string]Work{
 		"legacy-key":       work(60, ""),
 		"2026-08-01-10-30": work(82.5, ""),
 	}, 0)
@@ -1,7 +1,10 @@
 package db
 
 import (
+	"context"
 	"fmt"
+
+	"github.com/HeavenAQ/nstc-linebot-2025/metrics"
 )
 
 type UserSession struct {
@@ -12,8 +15,10 @@ type UserSession struct {
 	ActionStep  ActionStep `json:"action_step" firestore:"action_step"`
 }
 
-func (client *FirestoreClient) GetUserSession(userID string) (*UserSession, error) {
-	session, err := client.Sessions.Doc(userID).Get(*client.Ctx)
+func (client *FirestoreClient) GetUserSession(ctx context.Context, userID string) (*UserSession, error) {
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
+	session, err := client.Sessions.Doc(userID).Get(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("error getting user session: %w", err)
 	}
@@ -26,15 +31,17 @@ func (client *FirestoreClient) GetUserSession(userID string) (*UserSession, erro
 	return &userSessioon, nil
 }
 
-func (client *FirestoreClient) UpdateUserSession(userID string, newSessionContent UserSession) error {
-	_, err := client.Sessions.Doc(userID).Set(*client.Ctx, newSessionContent)
+func (client *FirestoreClient) UpdateUserSession(ctx context.Context, userID string, newSessionContent UserSession) error {
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
+	_, err := client.Sessions.Doc(userID).Set(ctx, newSessionContent)
 	if err != nil {
 		return fmt.Errorf("error updating user session: %w", err)
 	}
 	return nil
 }
 
-func (client *FirestoreClient) CreateUserSession(userID string) (*UserSession, error) {
+func (client *FirestoreClient) CreateUserSession(ctx context.Context, userID string) (*UserSession, error) {
 	newSession := UserSession{
 		UserState:   None,
 		Handedness:  "",
@@ -42,35 +49,53 @@ func (client *FirestoreClient) CreateUserSession(userID string) (*UserSession, e
 		ActionStep:  Empty,
 		UpdatedDate: "",
 	}
-	err := client.UpdateUserSession(userID, newSession)
+	err := client.UpdateUserSession(ctx, userID, newSession)
 	if err != nil {
 		return nil, err
 	}
 	return &newSession, nil
 }
 
-func (client *FirestoreClient) UpdateSessionUserState(userID string, state UserState, step ActionStep) error {
-	userSession, err := client.GetUserSession(userID)
+func (client *FirestoreClient) UpdateSessionUserState(ctx context.Context, userID string, state UserState, step ActionStep) error {
+	userSession, err := client.GetUserSession(ctx, userID)
 	if err != nil {
 		return err
 	}
 
+	from := userSession.StateLabel()
 	userSession.UserState = state
 	userSession.ActionStep = step
-	return client.UpdateUserSession(userID, *userSession)
+	return client.updateSessionState(ctx, userID, userSession, from)
+}
+
+// StateLabel names the session's state and step, as in metrics and logs.
+func (session *UserSession) StateLabel() string {
+	return session.UserState.String() + "/" + session.ActionStep.String()
+}
+
+// updateSessionState saves the session and counts the change from the
+// state it had.
+func (client *FirestoreClient) updateSessionState(ctx context.Context, userID string, session *UserSession, from string) error {
+	if err := client.UpdateUserSession(ctx, userID, *session); err != nil {
+		return err
+	}
+	if to := session.StateLabel(); to != from {
+		metrics.StateTransitions.WithLabelValues(from, to).Inc()
+	}
+	return nil
 }
 
-func (client *FirestoreClient) UpdateSessionUserSkill(userID string, skill string) error {
-	userSession, err := client.GetUserSession(userID)
+func (client *FirestoreClient) UpdateSessionUserSkill(ctx context.Context, userID string, skill string) error {
+	userSession, err := client.GetUserSession(ctx, userID)
 	if err != nil {
 		return err
 	}
 
 	userSession.Skill = skill
-	return client.UpdateUserSession(userID, *userSession)
+	return client.UpdateUserSession(ctx, userID, *userSession)
 }
 
-func (client *FirestoreClient) ResetSession(userID string) error {
+func (client *FirestoreClient) ResetSession(ctx context.Context, userID string) error {
 	userSession := UserSession{
 		Skill:       "",
 		Handedness:  "",
@@ -78,39 +103,40 @@ func (client *FirestoreClient) ResetSession(userID string) error {
 		ActionStep:  Empty,
 		UpdatedDate: "",
 	}
-	err := client.UpdateUserSession(userID, userSession)
+	err := client.UpdateUserSession(ctx, userID, userSession)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (client *FirestoreClient) UpdateSessionActionStep(userID string, step ActionStep) error {
-	userSession, err := client.GetUserSession(userID)
+func (client *FirestoreClient) UpdateSessionActionStep(ctx context.Context, userID string, step ActionStep) error {
+	userSession, err := client.GetUserSession(ctx, userID)
 	if err != nil {
 		return err
 	}
 
+	from := userSession.StateLabel()
 	userSession.ActionStep = step
-	return client.UpdateUserSession(userID, *userSession)
+	return client.updateSessionState(ctx, userID, userSession, from)
 }
 
-func (client *FirestoreClient) UpdateSessionUpdatingDate(userID string, date string) error {
-	userSession, err := client.GetUserSession(userID)
+func (client *FirestoreClient) UpdateSessionUpdatingDate(ctx context.Context, userID string, date string) error {
+	userSession, err := client.GetUserSession(ctx, userID)
 	if err != nil {
 		return err
 	}
 
 	userSession.UpdatedDate = date
-	return client.UpdateUserSession(userID, *userSession)
+	return client.UpdateUserSession(ctx, userID, *userSession)
 }
 
-func (client *FirestoreClient) UpdateSessionHandedness(userID string, handedness string) error {
-	userSession, err := client.GetUserSession(userID)
+func (client *FirestoreClient) UpdateSessionHandedness(ctx context.Context, userID string, handedness string) error {
+	userSession, err := client.GetUserSession(ctx, userID)
 	if err != nil {
 		return err
 	}
 
 	userSession.Handedness = handedness
-	return client.UpdateUserSession(userID, *userSession)
+	return client.UpdateUserSession(ctx, userID, *userSession)
 }
@@ -1,6 +1,7 @@
 package db_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/HeavenAQ/nstc-linebot-2025/api/db"
@@ -13,17 +14,17 @@ func TestGetUserSession(t *testing.T) {
 	// Create a test user session
 	testUserID := "test-user-id"
 	expectedSession := db.UserSession{UserState: db.WritingNotes, Skill: "Writing", Handedness: "right"}
-	_, err := firestoreClient.Sessions.Doc(testUserID).Set(*firestoreClient.Ctx, expectedSession)
+	_, err := firestoreClient.Sessions.Doc(testUserID).Set(context.Background(), expectedSession)
 	require.NoError(t, err)
 
 	// Retrieve the user session
-	session, err := firestoreClient.GetUserSession(testUserID)
+	session, err := firestoreClient.GetUserSession(context.Background(), testUserID)
 	require.NoError(t, err)
 	require.Equal(t, expectedSession.UserState, session.UserState)
 	require.Equal(t, expectedSession.Skill, session.Skill)
 
 	// Clean up
-	firestoreClient.Sessions.Doc(testUserID).Delete(*firestoreClient.Ctx)
+	firestoreClient.Sessions.Doc(testUserID).Delete(context.Background())
 }
 
 // Test CreateUserSession function
@@ -31,20 +32,20 @@ func TestCreateUserSession(t *testing.T) {
 	requireLive(t)
 	// Create a new session
 	testUserID := "new-user-id"
-	newSession, err := firestoreClient.CreateUserSession(testUserID)
+	newSession, err := firestoreClient.CreateUserSession(context.Background(), testUserID)
 	require.NoError(t, err)
 	require.NotNil(t, newSession)
 	require.Equal(t, db.None, newSession.UserState)
 	require.Equal(t, "", newSession.Skill)
 
 	// Verify it was written to Firestore
-	savedSession, err := firestoreClient.GetUserSession(testUserID)
+	savedSession, err := firestoreClient.GetUserSession(context.Background(), testUserID)
 	require.NoError(t, err)
 	require.Equal(t, db.None, savedSession.UserState)
 	require.Equal(t, "", savedSession.Skill)
 
 	// Clean up
-	firestoreClient.Sessions.Doc(testUserID).Delete(*firestoreClient.Ctx)
+	firestoreClient.Sessions.Doc(testUserID).Delete(context.Background())
 }
 
 // Test UpdateSessionUserState function
@@ -52,19 +53,19 @@ func TestUpdateSessionUserState(t *testing.T) {
 	requireLive(t)
 	// Create a test user session
 	testUserID := "state-user-id"
-	firestoreClient.CreateUserSession(testUserID)
+	firestoreClient.CreateUserSession(context.Background(), testUserID)
 
 	// Update the user state
-	err := firestoreClient.UpdateSessionUserState(testUserID, db.ChattingWithGPT, db.SelectingSkill)
+	err := firestoreClient.UpdateSessionUserState(context.Background(), testUserID, db.ChattingWithGPT, db.SelectingSkill)
 	require.NoError(t, err)
 
 	// Verify the state was updated in Firestore
-	savedSession, err := firestoreClient.GetUserSession(testUserID)
+	savedSession, err := firestoreClient.GetUserSession(context.Background(), testUserID)
 	require.NoError(t, err)
 	require.Equal(t, db.ChattingWithGPT, savedSession.UserState)
 
 	// Clean up
-	firestoreClient.Sessions.Doc(testUserID).Delete(*firestoreClient.Ctx)
+	firestoreClient.Sessions.Doc(testUserID).Delete(context.Background())
 }
 
 // Test UpdateSessionUserSkill function
@@ -72,18 +73,18 @@ func TestUpdateSessionUserSkill(t *testing.T) {
 	requireLive(t)
 	// Create a test user session
 	testUserID := "skill-user-id"
-	firestoreClient.CreateUserSession(testUserID)
+	firestoreClient.CreateUserSession(context.Background(), testUserID)
 
 	// Update the user's skill
 	newSkill := "Public Speaking"
-	err := firestoreClient.UpdateSessionUserSkill(testUserID, newSkill)
+	err := firestoreClient.UpdateSessionUserSkill(context.Background(), testUserID, newSkill)
 	require.NoError(t, err)
 
 	// Verify the skill was updated in Firestore
-	savedSession, err := firestoreClient.GetUserSession(testUserID)
+	savedSession, err := firestoreClient.GetUserSession(context.Background(), testUserID)
 	require.NoError(t, err)
 	require.Equal(t, newSkill, savedSession.Skill)
 
 	// Clean up
-	firestoreClient.Sessions.Doc(testUserID).Delete(*firestoreClient.Ctx)
+	firestoreClient.Sessions.Doc(testUserID).Delete(context.Background())
 }
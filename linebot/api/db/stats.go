@@ -1,10 +1,13 @@
 package db
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math"
 	"time"
+
+	"google.golang.org/api/iterator"
 )
 
 // Stats represents aggregate statistics for grades
@@ -58,8 +61,8 @@ func computeStats(values []float64) (Stats, error) {
 }
 
 // GetUserSkillStats returns stats for a single user's grades for a given skill
-func (client *FirestoreClient) GetUserSkillStats(userID string, skill string) (DateStats, error) {
-	user, err := client.GetUserData(userID)
+func (client *FirestoreClient) GetUserSkillStats(ctx context.Context, userID string, skill string) (DateStats, error) {
+	user, err := client.GetUserData(ctx, userID)
 	if err != nil {
 		return DateStats{}, err
 	}
@@ -88,15 +91,21 @@ func (client *FirestoreClient) GetUserSkillStats(userID string, skill string) (D
 }
 
 // GetClassSkillStats aggregates across all users for a given skill
-func (client *FirestoreClient) GetClassSkillStats(skill string) (DateStats, error) {
-	iter := client.Data.Documents(*client.Ctx)
+func (client *FirestoreClient) GetClassSkillStats(ctx context.Context, skill string) (DateStats, error) {
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
+	iter := client.Data.Documents(ctx)
+	defer iter.Stop()
 	gradesOnDate := make(map[string][]float64)
 
 	for {
 		doc, err := iter.Next()
-		if err != nil {
+		if errors.Is(err, iterator.Done) {
 			break
 		}
+		if err != nil {
+			return DateStats{}, fmt.Errorf("error listing users: %w", err)
+		}
 		var user UserData
 		if err := doc.DataTo(&user); err != nil {
 			continue
@@ -87,7 +87,7 @@ func ActionStepStrToEnum(str string) (ActionStep, error) {
 }
 
 func (s ActionStep) String() string {
-	return [...]string{"selecting_skill", "selecting_handedness", "writing_preview_note", "writing_reflection", "chatting", "selecting_portfolio", "empty"}[s]
+	return [...]string{"selecting_skill", "selecting_handedness", "writing_preview_note", "writing_reflection", "uploading_video", "chatting", "selecting_portfolio", "empty"}[s]
 }
 
 // Handedness represents the handedness of a player
@@ -127,6 +127,18 @@ const (
 	Lift
 )
 
+// BadmintonSkills lists every skill in the order menus present them.
+var BadmintonSkills = []BadmintonSkill{Serve, Smash, Clear, Lift}
+
+// BadmintonSkillNames returns the String form of every skill, in menu order.
+func BadmintonSkillNames() []string {
+	names := make([]string, 0, len(BadmintonSkills))
+	for _, skill := range BadmintonSkills {
+		names = append(names, skill.String())
+	}
+	return names
+}
+
 func (s BadmintonSkill) String() string {
 	return [...]string{"serve", "smash", "clear", "lift"}[s]
 }
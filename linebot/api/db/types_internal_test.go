@@ -0,0 +1,20 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestActionStepNamesRoundTrip(t *testing.T) {
+	for step := SelectingSkill; step <= Empty; step++ {
+		parsed, err := ActionStepStrToEnum(step.String())
+		require.NoError(t, err)
+		require.Equal(t, step, parsed)
+	}
+}
+
+func TestStateLabel(t *testing.T) {
+	session := &UserSession{UserState: AnalyzingVideo, ActionStep: UploadingVideo}
+	require.Equal(t, "analyzing_video/uploading_video", session.StateLabel())
+}
@@ -1,10 +1,16 @@
 package db
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
+	"cloud.google.com/go/firestore"
 	"github.com/HeavenAQ/nstc-linebot-2025/api/storage"
 	"github.com/HeavenAQ/nstc-linebot-2025/commons"
+	"google.golang.org/api/iterator"
 )
 
 type UserData struct {
@@ -14,6 +20,11 @@ type UserData struct {
 	Name               string             `json:"name" firestore:"name"`
 	ID                 string             `json:"id" firestore:"id"`
 	Handedness         Handedness         `json:"handedness" firestore:"handedness"`
+	ClassID            string             `json:"class_id" firestore:"class_id"`
+	NotificationsOff   bool               `json:"notifications_off" firestore:"notifications_off"`
+	// UpdateTime is when the document was last written as of the read, for
+	// writes that must not overwrite a newer one.
+	UpdateTime time.Time `json:"-" firestore:"-"`
 }
 
 type FolderPaths struct {
@@ -70,9 +81,50 @@ type Work struct {
 	Timeline                []commons.PhaseMarker  `json:"timeline" firestore:"timeline"`
 	CoachingCues            []commons.CoachingCue  `json:"coaching_cues" firestore:"coaching_cues"`
 	Diagnostics             map[string]float64     `json:"diagnostics" firestore:"diagnostics"`
+	AssignmentID            string                 `json:"assignment_id" firestore:"assignment_id"`
+	Late                    bool                   `json:"late" firestore:"late"`
+	OriginalVideo           string                 `json:"original_video" firestore:"original_video"`
+	AnalyzerVersion         string                 `json:"analyzer_version" firestore:"analyzer_version"`
+	GradeHistory            []GradeVersion         `json:"grade_history" firestore:"grade_history"`
+	CueClips                []CueClip              `json:"cue_clips" firestore:"cue_clips"`
 }
 
-func (client *FirestoreClient) CreateUserData(userFolders *storage.UserFolders, gptConvs *GPTConversationIDs) (*UserData, error) {
+// CueClip is a short clip of the annotated student video around one coaching
+// cue, for students who never open the LIFF player. Video and Preview are
+// object paths next to the analysis' student video.
+type CueClip struct {
+	Title   string `json:"title" firestore:"title"`
+	Video   string `json:"video" firestore:"video"`
+	Preview string `json:"preview" firestore:"preview"`
+}
+
+// WorkUploads are the files stored alongside an analyzed upload.
+type WorkUploads struct {
+	OriginalVideo string
+	CueClips      []CueClip
+}
+
+// Placeholder notes a new work starts with, until the student writes their own.
+const (
+	DefaultReflection  = "尚未填寫心得"
+	DefaultPreviewNote = "尚未填寫課前檢視要點"
+)
+
+// HasReflection reports whether the student replaced the placeholder reflection.
+func (w Work) HasReflection() bool {
+	note := strings.TrimSpace(w.Reflection)
+	return note != "" && note != DefaultReflection
+}
+
+// HasPreviewNote reports whether the student replaced the placeholder preview note.
+func (w Work) HasPreviewNote() bool {
+	note := strings.TrimSpace(w.PreviewNote)
+	return note != "" && note != DefaultPreviewNote
+}
+
+func (client *FirestoreClient) CreateUserData(ctx context.Context, userFolders *storage.UserFolders, gptConvs *GPTConversationIDs) (*UserData, error) {
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
 	ref := client.Data.Doc(userFolders.UserID)
 	newUserTemplate := &UserData{
 		Name:       userFolders.UserName,
@@ -100,15 +152,17 @@ func (client *FirestoreClient) CreateUserData(userFolders *storage.UserFolders,
 		},
 	}
 
-	_, err := ref.Set(*client.Ctx, newUserTemplate)
+	_, err := ref.Set(ctx, newUserTemplate)
 	if err != nil {
 		return nil, fmt.Errorf("error creating user data: %w", err)
 	}
 	return newUserTemplate, nil
 }
 
-func (client *FirestoreClient) GetUserData(userID string) (*UserData, error) {
-	docsnap, err := client.Data.Doc(userID).Get(*client.Ctx)
+func (client *FirestoreClient) GetUserData(ctx context.Context, userID string) (*UserData, error) {
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
+	docsnap, err := client.Data.Doc(userID).Get(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("error getting user data: %w", err)
 	}
@@ -117,36 +171,42 @@ func (client *FirestoreClient) GetUserData(userID string) (*UserData, error) {
 	if err != nil {
 		return nil, fmt.Errorf("error converting user data: %w", err)
 	}
+	user.UpdateTime = docsnap.UpdateTime
 	return user, nil
 }
 
-func (client *FirestoreClient) updateUserData(user *UserData) error {
-	_, err := client.Data.Doc(user.ID).Set(*client.Ctx, *user)
+func (client *FirestoreClient) updateUserData(ctx context.Context, user *UserData) error {
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
+	_, err := client.Data.Doc(user.ID).Set(ctx, *user)
 	if err != nil {
 		return fmt.Errorf("error updating user data: %w", err)
 	}
 	return nil
 }
 
-func (client *FirestoreClient) UpdateUserHandedness(user *UserData, handedness Handedness) error {
+func (client *FirestoreClient) UpdateUserHandedness(ctx context.Context, user *UserData, handedness Handedness) error {
 	user.Handedness = handedness
-	return client.updateUserData(user)
+	return client.updateUserData(ctx, user)
 }
 
 func (client *FirestoreClient) CreateUserPortfolioVideo(
+	ctx context.Context,
 	user *UserData,
 	userPortfolio *map[string]Work,
 	date string,
 	session *UserSession,
 	thumbnailFile *storage.UploadedFile,
 	analysis commons.AnalysisOutcome,
+	submission SubmissionTag,
+	uploads WorkUploads,
 ) error {
 	work := Work{
 		DateTime:                date,
 		Handedness:              analysis.Handedness,
 		GradingOutcome:          analysis.Grade,
-		Reflection:              "尚未填寫心得",
-		PreviewNote:             "尚未填寫課前檢視要點",
+		Reflection:              DefaultReflection,
+		PreviewNote:             DefaultPreviewNote,
 		AINote:                  analysis.OverallFeedback,
 		SkeletonVideo:           analysis.StudentVideo.SignedURL,
 		SkeletonComparisonVideo: "",
@@ -157,17 +217,23 @@ func (client *FirestoreClient) CreateUserPortfolioVideo(
 		Timeline:                analysis.Timeline,
 		CoachingCues:            analysis.CoachingCues,
 		Diagnostics:             analysis.Diagnostics,
+		AssignmentID:            submission.AssignmentID,
+		Late:                    submission.Late,
+		OriginalVideo:           uploads.OriginalVideo,
+		AnalyzerVersion:         analysis.AnalyzerVersion,
+		CueClips:                uploads.CueClips,
 	}
 	(*userPortfolio)[date] = work
-	err := client.UpdateUserSession(user.ID, *session)
+	err := client.UpdateUserSession(ctx, user.ID, *session)
 	if err != nil {
 		return fmt.Errorf("error updating user session: %w", err)
 	}
 
-	return client.updateUserData(user)
+	return client.updateUserData(ctx, user)
 }
 
 func (client *FirestoreClient) UpdateUserPortfolioReflection(
+	ctx context.Context,
 	user *UserData,
 	userPortfolio *map[string]Work,
 	date string,
@@ -177,10 +243,11 @@ func (client *FirestoreClient) UpdateUserPortfolioReflection(
 	targetWork.Reflection = reflection
 	(*userPortfolio)[date] = targetWork
 
-	return client.updateUserData(user)
+	return client.updateUserData(ctx, user)
 }
 
 func (client *FirestoreClient) UpdateUserPortfolioPreviewNote(
+	ctx context.Context,
 	user *UserData,
 	userPortfolio *map[string]Work,
 	date string,
@@ -189,10 +256,24 @@ func (client *FirestoreClient) UpdateUserPortfolioPreviewNote(
 	targetWork := (*userPortfolio)[date]
 	targetWork.PreviewNote = previewNote
 	(*userPortfolio)[date] = targetWork
-	return client.updateUserData(user)
+	return client.updateUserData(ctx, user)
 }
 
-func (client *FirestoreClient) UpdateUserGPTConversationID(user *UserData, skill string, id string) error {
+// UpdateUserClass assigns the user to a class. Only the class field is
+// written so a concurrent portfolio update is not overwritten.
+func (client *FirestoreClient) UpdateUserClass(ctx context.Context, userID string, classID string) error {
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
+	_, err := client.Data.Doc(userID).Update(ctx, []firestore.Update{
+		{Path: "class_id", Value: classID},
+	})
+	if err != nil {
+		return fmt.Errorf("error updating user class: %w", err)
+	}
+	return nil
+}
+
+func (client *FirestoreClient) UpdateUserGPTConversationID(ctx context.Context, user *UserData, skill string, id string) error {
 	switch skill {
 	case "serve":
 		user.GPTConversationIDs.Serve = id
@@ -203,15 +284,16 @@ func (client *FirestoreClient) UpdateUserGPTConversationID(user *UserData, skill
 	case "lift":
 		user.GPTConversationIDs.Lift = id
 	}
-	return client.updateUserData(user)
+	return client.updateUserData(ctx, user)
 }
 
-func (client *FirestoreClient) UpdateUserGPTConversationIDs(user *UserData, ids *GPTConversationIDs) error {
+func (client *FirestoreClient) UpdateUserGPTConversationIDs(ctx context.Context, user *UserData, ids *GPTConversationIDs) error {
 	user.GPTConversationIDs = *ids
-	return client.updateUserData(user)
+	return client.updateUserData(ctx, user)
 }
 
 func (client *FirestoreClient) UpdateUserPortfolioAINote(
+	ctx context.Context,
 	user *UserData,
 	userPortfolio *map[string]Work,
 	date string,
@@ -220,22 +302,30 @@ func (client *FirestoreClient) UpdateUserPortfolioAINote(
 	targetWork := (*userPortfolio)[date]
 	targetWork.AINote = aiNote
 	(*userPortfolio)[date] = targetWork
-	return client.updateUserData(user)
+	return client.updateUserData(ctx, user)
 }
 
-func (client *FirestoreClient) ListUsers() (*[]UserData, error) {
-	iter := client.Data.Documents(*client.Ctx)
+func (client *FirestoreClient) ListUsers(ctx context.Context) (*[]UserData, error) {
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
+	iter := client.Data.Documents(ctx)
+	defer iter.Stop()
+
 	var all []UserData
 	for {
 		doc, err := iter.Next()
-		if err != nil {
+		if errors.Is(err, iterator.Done) {
 			break
 		}
+		if err != nil {
+			return nil, fmt.Errorf("error listing users: %w", err)
+		}
 
 		var u UserData
 		if err := doc.DataTo(&u); err != nil {
 			return nil, fmt.Errorf("[db.users] decode failed id=%s err=%v", doc.Ref.ID, err)
 		}
+		u.UpdateTime = doc.UpdateTime
 		all = append(all, u)
 	}
 	return &all, nil
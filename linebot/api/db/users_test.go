@@ -1,6 +1,7 @@
 package db_test
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -31,7 +32,7 @@ func TestCreateUserData(t *testing.T) {
 	}
 
 	// Call the method to create user data
-	userData, err := firestoreClient.CreateUserData(testUserFolders, testGPTConvs)
+	userData, err := firestoreClient.CreateUserData(context.Background(), testUserFolders, testGPTConvs)
 	require.NoError(t, err)
 	require.NotNil(t, userData)
 
@@ -43,7 +44,7 @@ func TestCreateUserData(t *testing.T) {
 	require.Equal(t, testUserFolders.RootPath, userData.FolderPaths.Root)
 
 	// Clean up the created data after the test
-	_, err = firestoreClient.Data.Doc(userData.ID).Delete(*firestoreClient.Ctx)
+	_, err = firestoreClient.Data.Doc(userData.ID).Delete(context.Background())
 	require.NoError(t, err)
 }
 
@@ -73,17 +74,17 @@ func TestGetUserData(t *testing.T) {
 			Lift:  map[string]db.Work{},
 		},
 	}
-	_, err := firestoreClient.Data.Doc(testUserID).Set(*firestoreClient.Ctx, testUser)
+	_, err := firestoreClient.Data.Doc(testUserID).Set(context.Background(), testUser)
 	require.NoError(t, err)
 
 	// Test retrieving user data
-	userData, err := firestoreClient.GetUserData(testUserID)
+	userData, err := firestoreClient.GetUserData(context.Background(), testUserID)
 	require.NoError(t, err)
 	require.Equal(t, testUser.Name, userData.Name)
 	require.Equal(t, db.Right, userData.Handedness)
 
 	// Clean up the created data after the test
-	_, err = firestoreClient.Data.Doc(testUserID).Delete(*firestoreClient.Ctx)
+	_, err = firestoreClient.Data.Doc(testUserID).Delete(context.Background())
 	require.NoError(t, err)
 }
 
@@ -99,20 +100,20 @@ func TestUpdateUserHandedness(t *testing.T) {
 		ID:         testUserID,
 		Handedness: db.Right,
 	}
-	_, err := firestoreClient.Data.Doc(testUserID).Set(*firestoreClient.Ctx, testUser)
+	_, err := firestoreClient.Data.Doc(testUserID).Set(context.Background(), testUser)
 	require.NoError(t, err)
 
 	// Update handedness to left-handed
-	err = firestoreClient.UpdateUserHandedness(testUser, db.Left)
+	err = firestoreClient.UpdateUserHandedness(context.Background(), testUser, db.Left)
 	require.NoError(t, err)
 
 	// Verify that handedness was updated
-	updatedUser, err := firestoreClient.GetUserData(testUserID)
+	updatedUser, err := firestoreClient.GetUserData(context.Background(), testUserID)
 	require.NoError(t, err)
 	require.Equal(t, db.Left, updatedUser.Handedness)
 
 	// Clean up the created data after the test
-	_, err = firestoreClient.Data.Doc(testUserID).Delete(*firestoreClient.Ctx)
+	_, err = firestoreClient.Data.Doc(testUserID).Delete(context.Background())
 	require.NoError(t, err)
 }
 
@@ -133,7 +134,7 @@ func TestCreateUserPortfolioVideo(t *testing.T) {
 			Lift:  map[string]db.Work{},
 		},
 	}
-	_, err := firestoreClient.Data.Doc(testUserID).Set(*firestoreClient.Ctx, testUser)
+	_, err := firestoreClient.Data.Doc(testUserID).Set(context.Background(), testUser)
 	require.NoError(t, err)
 
 	// Define test data for video creation.
@@ -162,17 +163,20 @@ func TestCreateUserPortfolioVideo(t *testing.T) {
 	// Call the method to add video to portfolio
 	today := time.Now().Format("2006-01-02-15-04")
 	err = firestoreClient.CreateUserPortfolioVideo(
+		context.Background(),
 		testUser,
 		&testUser.Portfolio.Serve,
 		today,
 		session,
 		thumbnailFile,
 		analysis,
+		db.SubmissionTag{},
+		db.WorkUploads{OriginalVideo: db.OriginalVideoPath(testUser, "serve", today)},
 	)
 	require.NoError(t, err)
 
 	// Verify that the video was added to the portfolio
-	updatedUser, err := firestoreClient.GetUserData(testUserID)
+	updatedUser, err := firestoreClient.GetUserData(context.Background(), testUserID)
 	require.NoError(t, err)
 	require.NotNil(t, updatedUser.Portfolio.Serve[today])
 	require.Equal(t, analysis.Grade, updatedUser.Portfolio.Serve[today].GradingOutcome)
@@ -180,6 +184,6 @@ func TestCreateUserPortfolioVideo(t *testing.T) {
 	require.Equal(t, analysis.StudentVideo.ObjectPath, updatedUser.Portfolio.Serve[today].StudentVideo.ObjectPath)
 
 	// Clean up the created data after the test
-	_, err = firestoreClient.Data.Doc(testUserID).Delete(*firestoreClient.Ctx)
+	_, err = firestoreClient.Data.Doc(testUserID).Delete(context.Background())
 	require.NoError(t, err)
 }
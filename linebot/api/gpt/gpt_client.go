@@ -4,15 +4,19 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"strings"
+	"time"
 
 	"github.com/HeavenAQ/nstc-linebot-2025/commons"
+	"github.com/HeavenAQ/nstc-linebot-2025/metrics"
 	"github.com/openai/openai-go/v3"
 	"github.com/openai/openai-go/v3/conversations"
 	"github.com/openai/openai-go/v3/option"
 	"github.com/openai/openai-go/v3/packages/param"
 	"github.com/openai/openai-go/v3/responses"
 	"github.com/openai/openai-go/v3/shared"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 // Flow for sending requests using the Responses API:
@@ -20,36 +24,65 @@ import (
 // 2. For each user message, call Responses.New with the conversation ID and prompt.
 // 3. Read the generated text directly from the returned Response.
 
+// Client talks to the OpenAI API. Each request stops when its context is
+// done or, if Timeout is set, after Timeout.
 type Client struct {
-	Ctx          *context.Context
+	Timeout      time.Duration
 	Client       *openai.Client
 	PromptID     string
 	RewriteModel string
 }
 
 func NewGPTClient(apiKey, promptID, rewriteModel string) *Client {
-	ctx := context.Background()
 	if rewriteModel == "" {
 		rewriteModel = "gpt-5.6-terra"
 	}
 	client := openai.NewClient(
 		option.WithAPIKey(apiKey),
+		option.WithHTTPClient(&http.Client{Transport: otelhttp.NewTransport(http.DefaultTransport)}),
 	)
 
 	return &Client{
-		Ctx:          &ctx,
 		Client:       &client,
 		PromptID:     promptID,
 		RewriteModel: rewriteModel,
 	}
 }
 
+// withTimeout bounds one request by the client's Timeout.
+func (client *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if client.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, client.Timeout)
+}
+
+// respond creates a response, recording the call and the tokens it used
+// under operation.
+func (client *Client) respond(ctx context.Context, operation string, req responses.ResponseNewParams) (*responses.Response, error) {
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
+	start := time.Now()
+	resp, err := client.Client.Responses.New(ctx, req)
+	observe(operation, start, err)
+	if err == nil {
+		metrics.GPTTokens.WithLabelValues(operation, "input").Add(float64(resp.Usage.InputTokens))
+		metrics.GPTTokens.WithLabelValues(operation, "output").Add(float64(resp.Usage.OutputTokens))
+	}
+	return resp, err
+}
+
+func observe(operation string, start time.Time, err error) {
+	metrics.GPTDuration.WithLabelValues(operation).Observe(metrics.Since(start))
+	metrics.GPTCalls.WithLabelValues(operation, metrics.Outcome(err)).Inc()
+}
+
 type HistoryMessage struct {
 	Role string `json:"role"`
 	Text string `json:"text"`
 }
 
-func (client *Client) RewriteQuery(history []HistoryMessage, query string) (string, error) {
+func (client *Client) RewriteQuery(ctx context.Context, history []HistoryMessage, query string) (string, error) {
 	if len(history) == 0 {
 		return query, nil
 	}
@@ -72,7 +105,7 @@ func (client *Client) RewriteQuery(history []HistoryMessage, query string) (stri
 		MaxOutputTokens: param.Opt[int64]{Value: 300},
 		Store:           param.Opt[bool]{Value: false},
 	}
-	resp, err := client.Client.Responses.New(*client.Ctx, req)
+	resp, err := client.respond(ctx, "rewrite_query", req)
 	if err != nil {
 		return "", fmt.Errorf("rewrite query: %w", err)
 	}
@@ -83,21 +116,29 @@ func (client *Client) RewriteQuery(history []HistoryMessage, query string) (stri
 	return rewritten, nil
 }
 
-func (client *Client) CreateConversation() (*conversations.Conversation, error) {
+func (client *Client) CreateConversation(ctx context.Context) (*conversations.Conversation, error) {
 	conversationReq := conversations.ConversationNewParams{
 		Items:    []responses.ResponseInputItemUnionParam{},
 		Metadata: shared.Metadata{},
 	}
 
-	conversation, err := client.Client.Conversations.New(*client.Ctx, conversationReq)
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
+	start := time.Now()
+	conversation, err := client.Client.Conversations.New(ctx, conversationReq)
+	observe("create_conversation", start, err)
 	if err != nil {
 		return nil, fmt.Errorf("error creating conversation: %w", err)
 	}
 	return conversation, nil
 }
 
-func (client *Client) RetrieveConversation(conversationID string) (*conversations.Conversation, error) {
-	conversation, err := client.Client.Conversations.Get(*client.Ctx, conversationID)
+func (client *Client) RetrieveConversation(ctx context.Context, conversationID string) (*conversations.Conversation, error) {
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
+	start := time.Now()
+	conversation, err := client.Client.Conversations.Get(ctx, conversationID)
+	observe("retrieve_conversation", start, err)
 	if err != nil {
 		return nil, fmt.Errorf("error retrieving conversation: %w", err)
 	}
@@ -106,7 +147,7 @@ func (client *Client) RetrieveConversation(conversationID string) (*conversation
 
 // AddMessageToConversation sends a user message via Responses API
 // and returns the assistant's generated text output.
-func (client *Client) AddMessageToConversation(conversationID, message string) (string, error) {
+func (client *Client) AddMessageToConversation(ctx context.Context, conversationID, message string) (string, error) {
 	req := responses.ResponseNewParams{
 		Prompt: responses.ResponsePromptParam{
 			ID: client.PromptID,
@@ -123,7 +164,7 @@ func (client *Client) AddMessageToConversation(conversationID, message string) (
 		},
 	}
 
-	resp, err := client.Client.Responses.New(*client.Ctx, req)
+	resp, err := client.respond(ctx, "chat", req)
 	if err != nil {
 		return "", fmt.Errorf("error creating response: %w", err)
 	}
@@ -169,7 +210,7 @@ func buildSummaryPrompt(content string, scores []commons.SkillScore) string {
 
 // Summarize turns the learner's conversation and their recent grades into a
 // short summary using the configured prompt.
-func (client *Client) Summarize(content string, scores []commons.SkillScore) (string, error) {
+func (client *Client) Summarize(ctx context.Context, content string, scores []commons.SkillScore) (string, error) {
 	req := responses.ResponseNewParams{
 		Prompt: responses.ResponsePromptParam{
 			ID: client.PromptID,
@@ -181,7 +222,7 @@ func (client *Client) Summarize(content string, scores []commons.SkillScore) (st
 		},
 	}
 
-	resp, err := client.Client.Responses.New(*client.Ctx, req)
+	resp, err := client.respond(ctx, "summarize", req)
 	if err != nil {
 		return "", fmt.Errorf("error creating summary response: %w", err)
 	}
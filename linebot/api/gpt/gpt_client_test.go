@@ -1,6 +1,7 @@
 package gpt_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/HeavenAQ/nstc-linebot-2025/api/gpt"
@@ -14,7 +15,7 @@ func TestCreateConversation(t *testing.T) {
 	}
 	t.Parallel()
 	// Create a new conversation
-	conv, err := gptClient.CreateConversation()
+	conv, err := gptClient.CreateConversation(context.Background())
 	require.NoError(t, err, "Expected no error when creating conversation")
 	require.NotNil(t, conv, "Expected conversation to be created")
 }
@@ -26,11 +27,11 @@ func TestAddMessageToConversation(t *testing.T) {
 	}
 	t.Parallel()
 	// Create a new conversation
-	conv, err := gptClient.CreateConversation()
+	conv, err := gptClient.CreateConversation(context.Background())
 	require.NoError(t, err, "Expected no error when creating conversation")
 
 	// Add a message to the conversation and get assistant's reply
-	reply, err := gptClient.AddMessageToConversation(conv.ID, "Hello, this is a test message.")
+	reply, err := gptClient.AddMessageToConversation(context.Background(), conv.ID, "Hello, this is a test message.")
 	require.NoError(t, err, "Expected no error when adding message to conversation")
 	require.NotEmpty(t, reply, "Expected assistant to reply with text")
 }
@@ -42,11 +43,11 @@ func TestGetAssistantResponse(t *testing.T) {
 	}
 	t.Parallel()
 	// Create a new conversation
-	conv, err := gptClient.CreateConversation()
+	conv, err := gptClient.CreateConversation(context.Background())
 	require.NoError(t, err, "Expected no error when creating conversation")
 
 	// Add a message to the conversation and verify assistant's response directly from Responses API
-	response, err := gptClient.AddMessageToConversation(conv.ID, "What can you do?")
+	response, err := gptClient.AddMessageToConversation(context.Background(), conv.ID, "What can you do?")
 	require.NoError(t, err, "Expected no error when retrieving assistant response")
 	require.NotEmpty(t, response, "Expected a non-empty response from the assistant")
 	t.Log("Assistant's response:", response)
@@ -57,6 +58,7 @@ func TestRewriteQueryUsesConversationHistory(t *testing.T) {
 		t.Skip("Skipping GPT integration test")
 	}
 	rewritten, err := gptClient.RewriteQuery(
+		context.Background(),
 		[]gpt.HistoryMessage{
 			{Role: "user", Text: "我的高遠球揮拍時手肘太低。"},
 			{Role: "assistant", Text: "可以在引拍時讓手肘稍高於肩膀。"},
@@ -1,26 +1,44 @@
 package line
 
 import (
+    "context"
+    "errors"
     "fmt"
     "net/http"
     "strings"
+    "time"
 
+    "github.com/HeavenAQ/nstc-linebot-2025/api/storage"
+    "github.com/HeavenAQ/nstc-linebot-2025/metrics"
     "github.com/line/line-bot-sdk-go/v7/linebot"
+    "go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 type Client struct {
-	bot        *linebot.Client
-	bucketName string
+	bot          *linebot.Client
+	bucketName   string
+	signURLs     URLSigner
+	channelToken string
+	apiBase      string
+	httpClient   *http.Client
+	timeout      time.Duration
 }
 
 // NewBotClient creates a new BotClient instance
 func NewBotClient(channelSecret, channelToken, bucketName string) (*Client, error) {
-	bot, err := linebot.New(channelSecret, channelToken)
+	transport := metrics.LineTransport(otelhttp.NewTransport(http.DefaultTransport))
+	bot, err := linebot.New(channelSecret, channelToken, linebot.WithHTTPClient(&http.Client{Transport: transport}))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create linebot client: %w", err)
 	}
 
-	return &Client{bot: bot, bucketName: bucketName}, nil
+	return &Client{
+		bot:          bot,
+		bucketName:   bucketName,
+		channelToken: channelToken,
+		apiBase:      linebot.APIEndpointBase,
+		httpClient:   &http.Client{Timeout: 10 * time.Second, Transport: transport},
+	}, nil
 }
 
 // ParseRequest wraps the linebot.Client's ParseRequest method
@@ -32,12 +50,56 @@ func (client *Client) ParseRequest(r *http.Request) ([]*linebot.Event, error) {
 	return res, nil
 }
 
-// assetURL returns a fully-qualified HTTPS URL for a GCS object path.
-// If the input already looks like an http(s) URL, it is returned as-is.
-func (client *Client) assetURL(pathOrURL string) string {
-    if strings.HasPrefix(pathOrURL, "http://") || strings.HasPrefix(pathOrURL, "https://") {
-        return pathOrURL
+// URLSigner signs object paths, returning one URL per path in order.
+type URLSigner func(ctx context.Context, objectPaths ...string) ([]string, error)
+
+// SetURLSigner sets how stored thumbnails and videos are turned into URLs
+// LINE can fetch; the bucket is private.
+func (client *Client) SetURLSigner(signer URLSigner) {
+    client.signURLs = signer
+}
+
+// SetTimeout bounds each call to the Messaging API. Video content downloads
+// are bounded only by their context.
+func (client *Client) SetTimeout(timeout time.Duration) {
+    client.timeout = timeout
+}
+
+// withTimeout bounds one call by the client's timeout.
+func (client *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+    if client.timeout <= 0 {
+        return context.WithCancel(ctx)
+    }
+    return context.WithTimeout(ctx, client.timeout)
+}
+
+// assetURLs signs the given object paths in one batch. Other URLs are kept
+// as they are.
+func (client *Client) assetURLs(ctx context.Context, pathsOrURLs ...string) ([]string, error) {
+    urls := make([]string, len(pathsOrURLs))
+    var objectPaths []string
+    var indexes []int
+    for i, pathOrURL := range pathsOrURLs {
+        objectPath := storage.ObjectPath(client.bucketName, pathOrURL)
+        if strings.HasPrefix(objectPath, "http://") || strings.HasPrefix(objectPath, "https://") {
+            urls[i] = objectPath
+            continue
+        }
+        objectPaths = append(objectPaths, objectPath)
+        indexes = append(indexes, i)
+    }
+    if len(objectPaths) == 0 {
+        return urls, nil
+    }
+    if client.signURLs == nil {
+        return nil, errors.New("no URL signer configured")
+    }
+    signed, err := client.signURLs(ctx, objectPaths...)
+    if err != nil {
+        return nil, fmt.Errorf("failed to sign asset URLs: %w", err)
+    }
+    for i, index := range indexes {
+        urls[index] = signed[i]
     }
-    p := strings.TrimLeft(pathOrURL, "/")
-    return "https://storage.googleapis.com/" + client.bucketName + "/" + p
+    return urls, nil
 }
@@ -0,0 +1,43 @@
+package line
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// loadingPath starts the loading animation in a one-on-one chat. The SDK
+// version in use predates it.
+const loadingPath = "/v2/bot/chat/loading/start"
+
+// ShowLoadingAnimation shows the typing indicator in the user's chat for
+// about seconds, or until the bot's next message arrives. LINE accepts 5 to
+// 60 seconds in steps of 5, so seconds is rounded into that range.
+func (client *Client) ShowLoadingAnimation(ctx context.Context, userID string, seconds int) error {
+	seconds = min(max((seconds+4)/5*5, 5), 60)
+	body, err := json.Marshal(map[string]any{"chatId": userID, "loadingSeconds": seconds})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, client.apiBase+loadingPath, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+client.channelToken)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to show loading animation: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("failed to show loading animation: %s: %s", resp.Status, message)
+	}
+	return nil
+}
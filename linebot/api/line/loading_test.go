@@ -0,0 +1,41 @@
+package line
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestShowLoadingAnimation(t *testing.T) {
+	var got map[string]any
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, loadingPath, r.URL.Path)
+		auth = r.Header.Get("Authorization")
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+	client := &Client{channelToken: "token", apiBase: server.URL, httpClient: server.Client()}
+
+	require.NoError(t, client.ShowLoadingAnimation(context.Background(), "U1", 90))
+	require.Equal(t, "Bearer token", auth)
+	require.Equal(t, map[string]any{"chatId": "U1", "loadingSeconds": float64(60)}, got)
+
+	require.NoError(t, client.ShowLoadingAnimation(context.Background(), "U1", 12))
+	require.Equal(t, float64(15), got["loadingSeconds"])
+}
+
+func TestShowLoadingAnimationReportsRejection(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"Authentication failed"}`, http.StatusUnauthorized)
+	}))
+	defer server.Close()
+	client := &Client{apiBase: server.URL, httpClient: server.Client()}
+
+	require.ErrorContains(t, client.ShowLoadingAnimation(context.Background(), "U1", 60), "Authentication failed")
+}
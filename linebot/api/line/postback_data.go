@@ -10,6 +10,13 @@ type VideoPostback struct {
 	Skill    string `json:"skill" validate:"required"`
 }
 
+// CueClipsPostback asks for a work's coaching-cue clips.
+type CueClipsPostback struct {
+	WorkDate string `json:"work_date" validate:"required"`
+	Skill    string `json:"skill" validate:"required"`
+	CueClips bool   `json:"cue_clips" validate:"required"`
+}
+
 type WritingNotePostback struct {
 	State      string `json:"state" validate:"required"`
 	WorkDate   string `json:"work_date" validate:"required"`
@@ -38,6 +45,7 @@ type StopGPTPostback struct {
 
 // Implement the marker interface for each struct
 func (VideoPostback) isPostbackData()               {}
+func (CueClipsPostback) isPostbackData()            {}
 func (WritingNotePostback) isPostbackData()         {}
 func (SelectingSkillPostback) isPostbackData()      {}
 func (SelectingHandednessPostback) isPostbackData() {}
@@ -76,6 +76,10 @@ func (client *Client) HandleVideoPostbackData(rawData string) (*VideoPostback, e
 	return handlePostbackData[VideoPostback](rawData)
 }
 
+func (client *Client) HandleCueClipsPostbackData(rawData string) (*CueClipsPostback, error) {
+	return handlePostbackData[CueClipsPostback](rawData)
+}
+
 func (client *Client) HandleAskingAIForHelpPostbackData(rawData string) (*AnalyzingWithGPTPostback, error) {
 	return handlePostbackData[AnalyzingWithGPTPostback](rawData)
 }
@@ -1,46 +1,52 @@
 package line
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/HeavenAQ/nstc-linebot-2025/api/db"
 	"github.com/line/line-bot-sdk-go/v7/linebot"
 )
 
-func (client *Client) SendReply(replyToken string, msg string) (*linebot.BasicResponse, error) {
-	res, err := client.bot.ReplyMessage(replyToken, linebot.NewTextMessage(msg)).Do()
+func (client *Client) SendReply(ctx context.Context, replyToken string, msg string) (*linebot.BasicResponse, error) {
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
+	res, err := client.bot.ReplyMessage(replyToken, linebot.NewTextMessage(msg)).WithContext(ctx).Do()
 	if err != nil {
 		return nil, fmt.Errorf("failed to reply message: %w", err)
 	}
 	return res, nil
 }
 
-func (client *Client) SendDefaultReply(replyToken string) (*linebot.BasicResponse, error) {
-	return client.SendReply(replyToken, "請點選選單的項目")
+func (client *Client) SendDefaultReply(ctx context.Context, replyToken string) (*linebot.BasicResponse, error) {
+	return client.SendReply(ctx, replyToken, "請點選選單的項目")
 }
 
-func (client *Client) SendDefaultErrorReply(replyToken string) (*linebot.BasicResponse, error) {
-	return client.SendReply(replyToken, "發生錯誤，請重新操作")
+func (client *Client) SendDefaultErrorReply(ctx context.Context, replyToken string) (*linebot.BasicResponse, error) {
+	return client.SendReply(ctx, replyToken, "發生錯誤，請重新操作")
 }
 
-func (client *Client) SendWelcomeReply(event *linebot.Event) (*linebot.BasicResponse, error) {
-	username, err := client.GetUserName(event.Source.UserID)
+func (client *Client) SendWelcomeReply(ctx context.Context, event *linebot.Event) (*linebot.BasicResponse, error) {
+	username, err := client.GetUserName(ctx, event.Source.UserID)
 	if err != nil {
 		return nil, err
 	}
 	welcomMsg := "Hi " + username + "! 歡迎加入羽球教室🏸\n" + "已建立您的使用者資料🎉🎊 請點選選單的項目開始使用"
-	return client.SendReply(event.ReplyToken, welcomMsg)
+	return client.SendReply(ctx, event.ReplyToken, welcomMsg)
 }
 
-func (client *Client) SendNoPortfolioReply(replyToken string, skill db.BadmintonSkill) error {
+func (client *Client) SendNoPortfolioReply(ctx context.Context, replyToken string, skill db.BadmintonSkill) error {
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
 	_, err := client.bot.ReplyMessage(
 		replyToken,
 		linebot.NewTextMessage(
 			fmt.Sprintf("尚未上傳【%v】的學習反思及影片", skill.ChnString()),
 		),
-	).Do()
+	).WithContext(ctx).Do()
 	if err != nil {
 		return fmt.Errorf("failed to reply message: %w", err)
 	}
@@ -49,25 +55,30 @@ func (client *Client) SendNoPortfolioReply(replyToken string, skill db.Badminton
 
 // ReplyMessage wraps the linebot.Client's ReplyMessage method
 func (client *Client) ReplyMessage(
+	ctx context.Context,
 	replyToken string,
 	messages ...linebot.SendingMessage,
 ) (*linebot.BasicResponse, error) {
-	res, err := client.bot.ReplyMessage(replyToken, messages...).Do()
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
+	res, err := client.bot.ReplyMessage(replyToken, messages...).WithContext(ctx).Do()
 	if err != nil {
 		return nil, fmt.Errorf("failed to reply message: %w", err)
 	}
 	return res, nil
 }
 
-func (client *Client) SendTypeErrorReply(replyToken string) (*linebot.BasicResponse, error) {
-	res, err := client.bot.ReplyMessage(replyToken, linebot.NewTextMessage("抱歉，您所輸入的訊息格式目前並未支援，請重試一次！")).Do()
+func (client *Client) SendTypeErrorReply(ctx context.Context, replyToken string) (*linebot.BasicResponse, error) {
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
+	res, err := client.bot.ReplyMessage(replyToken, linebot.NewTextMessage("抱歉，您所輸入的訊息格式目前並未支援，請重試一次！")).WithContext(ctx).Do()
 	if err != nil {
 		return nil, fmt.Errorf("failed to reply message: %w", err)
 	}
 	return res, nil
 }
 
-func (client *Client) SendInstruction(replyToken string) (*linebot.BasicResponse, error) {
+func (client *Client) SendInstruction(ctx context.Context, replyToken string) (*linebot.BasicResponse, error) {
 	const welcome = "歡迎加入羽球教室🏸，以下為選單的使用說明:\n\n"
 	const instruction = "➡️ 使用說明：呼叫選單各個項目的解說\n\n"
 	const portfolio = "➡️ 學習歷程：查看個人每周的學習歷程記錄\n\n"
@@ -77,28 +88,116 @@ func (client *Client) SendInstruction(replyToken string) (*linebot.BasicResponse
 	const expertVideo = "➡️ 專家影片：觀看專家示範影片\n\n"
 	const learningDashboard = "➡️ 學習儀表板：查看學習進度及成就\n\n"
 	const note1 = "✅ 如需查看課程大綱，請輸入「課程大綱」\n\n"
-	const note2 = "⚠️ 每周的學習歷程都需有【影片】才能建檔"
-	const msg = welcome + instruction + portfolio + addReflection + analyzeRecording + chatWithGPT + expertVideo + learningDashboard + note1 + note2
-	return client.bot.ReplyMessage(replyToken, linebot.NewTextMessage(msg)).Do()
+	const note2 = "✅ 如需查看班級排名，請輸入「我的排名」\n\n"
+	const note3 = "✅ 如需查看尚未完成的作業，請輸入「我的作業」\n\n"
+	const note4 = "✅ 如需關閉或開啟提醒通知，請輸入「關閉通知」或「開啟通知」\n\n"
+	const note5 = "⚠️ 每周的學習歷程都需有【影片】才能建檔"
+	const msg = welcome + instruction + portfolio + addReflection + analyzeRecording + chatWithGPT + expertVideo + learningDashboard + note1 + note2 + note3 + note4 + note5
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
+	return client.bot.ReplyMessage(replyToken, linebot.NewTextMessage(msg)).WithContext(ctx).Do()
 }
 
-func (client *Client) SendSyllabus(replyToken string) (*linebot.BasicResponse, error) {
+func (client *Client) SendSyllabus(ctx context.Context, replyToken string) (*linebot.BasicResponse, error) {
 	const syllabus = "課程大綱：\n"
 
 	const msg = syllabus + "https://drive.google.com/open?id=1PeWkePHtq30ArcGqZwzWP64olL9F7Tqw&usp=drive_fs"
 
-	res, err := client.bot.ReplyMessage(replyToken, linebot.NewTextMessage(msg)).Do()
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
+	res, err := client.bot.ReplyMessage(replyToken, linebot.NewTextMessage(msg)).WithContext(ctx).Do()
 	if err != nil {
 		return nil, fmt.Errorf("failed to reply message: %w", err)
 	}
 	return res, nil
 }
 
+// PushText sends an unprompted text message to the user.
+func (client *Client) PushText(ctx context.Context, userID, text string) error {
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
+	if _, err := client.bot.PushMessage(userID, linebot.NewTextMessage(text)).WithContext(ctx).Do(); err != nil {
+		return fmt.Errorf("failed to push message: %w", err)
+	}
+	return nil
+}
+
+// SendRankReply tells the student where their grades fall within the class.
+func (client *Client) SendRankReply(ctx context.Context, replyToken string, rank *db.UserRank) (*linebot.BasicResponse, error) {
+	return client.SendReply(ctx, replyToken, formatRankMessage(rank))
+}
+
+func formatRankMessage(rank *db.UserRank) string {
+	var b strings.Builder
+	b.WriteString("以下為您在班上的排名（PR 值越高代表表現越好）：")
+	for _, skill := range rank.Skills {
+		b.WriteString(fmt.Sprintf("\n\n【%s】", db.SkillStrToEnum(skill.Skill).ChnString()))
+		switch {
+		case !skill.Submitted:
+			b.WriteString("尚未上傳影片")
+		case !skill.Available:
+			b.WriteString(fmt.Sprintf("最新成績 %.2f，上傳人數未達 %d 位，暫不顯示排名", skill.LatestGrade, rank.MinCohort))
+		default:
+			b.WriteString(fmt.Sprintf("共 %d 位同學上傳", skill.CohortSize))
+			b.WriteString(fmt.Sprintf("\n最新成績 %.2f（PR %.0f）", skill.LatestGrade, skill.LatestPercentile))
+			b.WriteString(fmt.Sprintf("\n最佳成績 %.2f（PR %.0f）", skill.BestGrade, skill.BestPercentile))
+			if len(skill.Criteria) > 0 {
+				b.WriteString("\n各項目（最新／最佳）：")
+			}
+			for _, criterion := range skill.Criteria {
+				b.WriteString(fmt.Sprintf("\n・%s：PR %.0f／PR %.0f", criterion.Description, criterion.LatestPercentile, criterion.BestPercentile))
+			}
+		}
+	}
+	return b.String()
+}
+
+// assignmentTimeLayout is how due dates are shown to students.
+const assignmentTimeLayout = "01/02 15:04"
+
+// SendAssignmentsReply lists the assignments the student still has to act on.
+func (client *Client) SendAssignmentsReply(ctx context.Context, replyToken string, assignments []db.AssignmentStatus) (*linebot.BasicResponse, error) {
+	return client.SendReply(ctx, replyToken, formatAssignmentsMessage(assignments))
+}
+
+func formatAssignmentsMessage(assignments []db.AssignmentStatus) string {
+	if len(assignments) == 0 {
+		return "目前沒有需要繳交的作業 🎉"
+	}
+	var b strings.Builder
+	b.WriteString("以下為您目前的作業：")
+	for _, assignment := range assignments {
+		b.WriteString(fmt.Sprintf(
+			"\n\n【%s】%s\n截止：%s",
+			db.SkillStrToEnum(assignment.Skill).ChnString(),
+			assignment.Title,
+			assignment.DueAt.Local().Format(assignmentTimeLayout),
+		))
+		switch {
+		case assignment.Submitted:
+			b.WriteString("\n✅ 已繳交")
+		case assignment.Overdue:
+			b.WriteString("\n⚠️ 已逾期，仍可上傳影片補交")
+		default:
+			b.WriteString("\n⏳ 尚未繳交，請透過「動作分析」上傳影片")
+		}
+	}
+	return b.String()
+}
+
+// SubmissionNote tells the student which assignment an upload was submitted for.
+func SubmissionNote(assignment *db.Assignment, submission db.SubmissionTag) string {
+	if submission.Late {
+		return fmt.Sprintf("已補交作業「%s」（截止 %s，標記為遲交）", assignment.Title, assignment.DueAt.Local().Format(assignmentTimeLayout))
+	}
+	return fmt.Sprintf("已繳交作業「%s」", assignment.Title)
+}
+
 func (client *Client) getSkillQuickReplyItems(userState db.UserState) *linebot.QuickReplyItems {
 	items := []*linebot.QuickReplyButton{}
 	quickReplyAction := client.getQuickReplyAction()
 
-	for _, skill := range []db.BadmintonSkill{db.Serve, db.Smash, db.Clear, db.Lift} {
+	for _, skill := range db.BadmintonSkills {
 		items = append(items, linebot.NewQuickReplyButton(
 			"",
 			quickReplyAction(userState, skill),
@@ -108,6 +207,7 @@ func (client *Client) getSkillQuickReplyItems(userState db.UserState) *linebot.Q
 }
 
 func (client *Client) PromptSkillSelection(
+	ctx context.Context,
 	replyToken string,
 	userState db.UserState,
 	prompt string,
@@ -115,24 +215,32 @@ func (client *Client) PromptSkillSelection(
 	msg := linebot.NewTextMessage(prompt).WithQuickReplies(
 		client.getSkillQuickReplyItems(userState),
 	)
-	return client.bot.ReplyMessage(replyToken, msg).Do()
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
+	return client.bot.ReplyMessage(replyToken, msg).WithContext(ctx).Do()
 }
 
-func (client *Client) PromptHandednessSelection(event *linebot.Event) error {
+func (client *Client) PromptHandednessSelection(ctx context.Context, event *linebot.Event) error {
 	msg := linebot.NewTextMessage("請選擇左手或右手").WithQuickReplies(
 		client.getHandednessQuickReplyItems(),
 	)
-	_, err := client.bot.ReplyMessage(event.ReplyToken, msg).Do()
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
+	_, err := client.bot.ReplyMessage(event.ReplyToken, msg).WithContext(ctx).Do()
 	return err
 }
 
-func (client *Client) SendVideoMessage(replyToken, videoURL, thumbnailURL string) (*linebot.BasicResponse, error) {
-	videoLink := client.assetURL(videoURL)
-	thumbnailLink := client.assetURL(thumbnailURL)
+func (client *Client) SendVideoMessage(ctx context.Context, replyToken, videoURL, thumbnailURL string) (*linebot.BasicResponse, error) {
+	links, err := client.assetURLs(ctx, videoURL, thumbnailURL)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
 	return client.bot.ReplyMessage(
 		replyToken,
-		linebot.NewVideoMessage(videoLink, thumbnailLink),
-	).Do()
+		linebot.NewVideoMessage(links[0], links[1]),
+	).WithContext(ctx).Do()
 }
 
 type NoPortfolioError struct {
@@ -145,8 +253,10 @@ func (e *NoPortfolioError) Error() string {
 }
 
 func (client *Client) SendPortfolio(
+	ctx context.Context,
 	event *linebot.Event,
 	user *db.UserData,
+	rubric db.Rubric,
 	skill db.BadmintonSkill,
 	handedness string,
 	userState db.UserState,
@@ -159,10 +269,16 @@ func (client *Client) SendPortfolio(
 		return &NoPortfolioError{Skill: skill, Err: errors.New("No portfolio found")}
 	}
 
+	works, err := client.withThumbnailURLs(ctx, works)
+	if err != nil {
+		client.SendDefaultErrorReply(ctx, event.ReplyToken)
+		return err
+	}
+
 	// generate carousels from works
-	carousels, err := client.getCarousels(works, skill.String(), handedness, showBtns)
+	carousels, err := client.getCarousels(works, skill.String(), handedness, rubric, showBtns)
 	if err != nil {
-		client.SendDefaultErrorReply(event.ReplyToken)
+		client.SendDefaultErrorReply(ctx, event.ReplyToken)
 		return errors.New("Error getting carousels: " + err.Error())
 	}
 
@@ -173,17 +289,40 @@ func (client *Client) SendPortfolio(
 		sendMsgs = append(sendMsgs, msg)
 	}
 
+	replyCtx, cancel := client.withTimeout(ctx)
+	defer cancel()
 	_, err = client.bot.ReplyMessage(
 		event.ReplyToken,
 		sendMsgs...,
-	).Do()
+	).WithContext(replyCtx).Do()
 	if err != nil {
-		client.SendDefaultErrorReply(event.ReplyToken)
+		client.SendDefaultErrorReply(ctx, event.ReplyToken)
 		return err
 	}
 	return nil
 }
 
+// withThumbnailURLs returns a copy of works whose thumbnails are signed URLs.
+func (client *Client) withThumbnailURLs(ctx context.Context, works map[string]db.Work) (map[string]db.Work, error) {
+	dates := make([]string, 0, len(works))
+	thumbnails := make([]string, 0, len(works))
+	for date, work := range works {
+		dates = append(dates, date)
+		thumbnails = append(thumbnails, work.Thumbnail)
+	}
+	urls, err := client.assetURLs(ctx, thumbnails...)
+	if err != nil {
+		return nil, err
+	}
+	signed := make(map[string]db.Work, len(works))
+	for i, date := range dates {
+		work := works[date]
+		work.Thumbnail = urls[i]
+		signed[date] = work
+	}
+	return signed, nil
+}
+
 func (client *Client) getSkillUrls(hand db.Handedness, skill db.BadmintonSkill) []string {
 	actionUrls := map[db.Handedness]map[db.BadmintonSkill][]string{
 		db.Right: {
@@ -215,7 +354,7 @@ func (client *Client) getSkillUrls(hand db.Handedness, skill db.BadmintonSkill)
 	return actionUrls[hand][skill]
 }
 
-func (client *Client) SendExpertVideos(handedness db.Handedness, skill db.BadmintonSkill, replyToken string) error {
+func (client *Client) SendExpertVideos(ctx context.Context, handedness db.Handedness, skill db.BadmintonSkill, replyToken string) error {
 	urls := client.getSkillUrls(handedness, skill)
 
 	// create messages
@@ -234,19 +373,23 @@ func (client *Client) SendExpertVideos(handedness db.Handedness, skill db.Badmin
 	}
 
 	// Send messages
-	_, err := client.bot.ReplyMessage(replyToken, msgs...).Do()
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
+	_, err := client.bot.ReplyMessage(replyToken, msgs...).WithContext(ctx).Do()
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (client *Client) SendGPTChattingModeReply(replyToken string, msg string) (*linebot.BasicResponse, error) {
+func (client *Client) SendGPTChattingModeReply(ctx context.Context, replyToken string, msg string) (*linebot.BasicResponse, error) {
 	data, err := json.Marshal(StopGPTPostback{Stop: true})
 	if err != nil {
 		return nil, err
 	}
 
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
 	return client.bot.ReplyMessage(replyToken, linebot.NewTextMessage(
 		msg,
 	).WithQuickReplies(&linebot.QuickReplyItems{
@@ -263,5 +406,5 @@ func (client *Client) SendGPTChattingModeReply(replyToken string, msg string) (*
 				),
 			),
 		},
-	})).Do()
+	})).WithContext(ctx).Do()
 }
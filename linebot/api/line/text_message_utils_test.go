@@ -0,0 +1,46 @@
+package line
+
+import (
+	"testing"
+	"time"
+
+	"github.com/HeavenAQ/nstc-linebot-2025/api/db"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatRankMessageCoversEverySkillState(t *testing.T) {
+	msg := formatRankMessage(&db.UserRank{
+		MinCohort: 5,
+		Skills: []db.SkillRank{
+			{
+				Skill: "serve", Submitted: true, Available: true, CohortSize: 12,
+				LatestGrade: 82.5, LatestPercentile: 75, BestGrade: 90, BestPercentile: 83.3,
+				Criteria: []db.CriterionRank{{Description: "擊球點", LatestPercentile: 60, BestPercentile: 70}},
+			},
+			{Skill: "smash", Submitted: true, LatestGrade: 70, CohortSize: 3},
+			{Skill: "clear"},
+		},
+	})
+
+	require.Contains(t, msg, "【發球】共 12 位同學上傳")
+	require.Contains(t, msg, "最新成績 82.50（PR 75）")
+	require.Contains(t, msg, "最佳成績 90.00（PR 83）")
+	require.Contains(t, msg, "・擊球點：PR 60／PR 70")
+	require.Contains(t, msg, "【殺球】最新成績 70.00，上傳人數未達 5 位，暫不顯示排名")
+	require.Contains(t, msg, "【高遠球】尚未上傳影片")
+}
+
+func TestFormatAssignmentsMessage(t *testing.T) {
+	require.Equal(t, "目前沒有需要繳交的作業 🎉", formatAssignmentsMessage(nil))
+
+	due := time.Date(2026, 9, 8, 23, 59, 0, 0, time.Local)
+	msg := formatAssignmentsMessage([]db.AssignmentStatus{
+		{Assignment: db.Assignment{Title: "第一週", Skill: "serve", DueAt: due}, Overdue: true},
+		{Assignment: db.Assignment{Title: "第二週", Skill: "smash", DueAt: due}, Submitted: true},
+		{Assignment: db.Assignment{Title: "第三週", Skill: "clear", DueAt: due}},
+	})
+
+	require.Contains(t, msg, "【發球】第一週\n截止：09/08 23:59\n⚠️ 已逾期")
+	require.Contains(t, msg, "【殺球】第二週\n截止：09/08 23:59\n✅ 已繳交")
+	require.Contains(t, msg, "【高遠球】第三週\n截止：09/08 23:59\n⏳ 尚未繳交")
+}
@@ -0,0 +1,30 @@
+package line
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// botInfoPath answers only for a valid channel access token.
+const botInfoPath = "/v2/bot/info"
+
+// CheckToken checks that LINE accepts the channel access token.
+func (client *Client) CheckToken(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, client.apiBase+botInfoPath, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+client.channelToken)
+	resp, err := client.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to check channel token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("failed to check channel token: %s: %s", resp.Status, message)
+	}
+	return nil
+}
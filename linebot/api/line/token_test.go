@@ -0,0 +1,28 @@
+package line
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, botInfoPath, r.URL.Path)
+		if r.Header.Get("Authorization") != "Bearer valid" {
+			http.Error(w, `{"message":"Authentication failed. Confirm that the access token in the authorization header is valid."}`, http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"userId":"Ubot","displayName":"bot"}`))
+	}))
+	defer server.Close()
+	client := &Client{channelToken: "valid", apiBase: server.URL, httpClient: server.Client()}
+
+	require.NoError(t, client.CheckToken(context.Background()))
+
+	client.channelToken = "expired"
+	require.ErrorContains(t, client.CheckToken(context.Background()), "401 Unauthorized")
+}
@@ -12,36 +12,41 @@ import (
 	"golang.org/x/exp/maps"
 )
 
-// getPortfolioRating creates the star rating component
-func (client *Client) getPortfolioRating(work db.Work) *linebot.BoxComponent {
-	rating := work.GradingOutcome.TotalGrade
-	contents := []linebot.FlexComponent{}
-	for i := 0; i < 5; i++ {
-		url := "https://scdn.line-apps.com/n/channel_devcenter/img/fx/review_gray_star_28.png"
-		if rating >= 20 {
-			url = "https://scdn.line-apps.com/n/channel_devcenter/img/fx/review_gold_star_28.png"
-		}
-		contents = append(contents, &linebot.IconComponent{
-			Type: "icon",
-			Size: "sm",
-			URL:  url,
-		})
-		rating -= 20
-	}
-	contents = append(contents, &linebot.TextComponent{
-		Type:   "text",
-		Text:   fmt.Sprintf("%.2f", work.GradingOutcome.TotalGrade),
-		Size:   "sm",
-		Color:  "#8c8c8c",
-		Margin: "md",
-		Flex:   linebot.IntPtr(0),
-	})
+// getPortfolioRating shows the attempt's analyzer grade together with what it
+// is worth under the class rubric.
+func (client *Client) getPortfolioRating(work db.Work, skill string, rubric db.Rubric) *linebot.BoxComponent {
 	return &linebot.BoxComponent{
-		Type:     "box",
-		Layout:   "baseline",
-		Margin:   "md",
-		Contents: contents,
+		Type:   "box",
+		Layout: "vertical",
+		Margin: "md",
+		Contents: []linebot.FlexComponent{
+			&linebot.TextComponent{
+				Type:   "text",
+				Text:   fmt.Sprintf("動作評分 %.2f／100", work.GradingOutcome.TotalGrade),
+				Size:   "md",
+				Weight: "bold",
+			},
+			&linebot.TextComponent{
+				Type:  "text",
+				Text:  rubricNote(work, skill, rubric),
+				Wrap:  true,
+				Size:  "sm",
+				Color: "#8c8c8c",
+			},
+		},
+	}
+}
+
+// rubricNote explains how the attempt counts towards the course grade.
+func rubricNote(work db.Work, skill string, rubric db.Rubric) string {
+	weight := rubric.SkillWeights[skill]
+	if weight == 0 {
+		return "此動作不列入課程成績"
 	}
+	return fmt.Sprintf(
+		"課程成績：本次可得 %.2f／%g 分（%s）",
+		rubric.WorkPoints(skill, work), weight, rubric.AttemptPolicy.ChnString(),
+	)
 }
 
 // createButtonActions generates the buttons for preview and reflection actions
@@ -80,7 +85,7 @@ func (client *Client) createButtonActions(work db.Work, skill string, handedness
 		Skill:      skill,
 	})
 
-	return []linebot.FlexComponent{
+	buttons := []linebot.FlexComponent{
 		&linebot.ButtonComponent{
 			Type:   "button",
 			Style:  "primary",
@@ -133,7 +138,32 @@ func (client *Client) createButtonActions(work db.Work, skill string, handedness
 				"",
 			),
 		},
-	}, nil
+	}
+	if len(work.CueClips) == 0 {
+		return buttons, nil
+	}
+
+	cueClipsData, err := json.Marshal(CueClipsPostback{
+		WorkDate: work.DateTime,
+		Skill:    skill,
+		CueClips: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(buttons, &linebot.ButtonComponent{
+		Type:   "button",
+		Style:  "link",
+		Height: "sm",
+		Action: linebot.NewPostbackAction(
+			"看重點片段",
+			string(cueClipsData),
+			"",
+			"",
+			"",
+			"",
+		),
+	}), nil
 }
 
 // createNotesSection generates the notes sections for AI Note, Preview Note, and Reflection
@@ -168,10 +198,10 @@ func createNotesSection(label string, content string) *linebot.BoxComponent {
 }
 
 // getCarouselItem constructs the carousel item using helper functions
-func (client *Client) getCarouselItem(work db.Work, skill string, handedness string, showBtns bool) *linebot.BubbleContainer {
+func (client *Client) getCarouselItem(work db.Work, skill string, handedness string, rubric db.Rubric, showBtns bool) *linebot.BubbleContainer {
 	dateTime, _ := time.Parse("2006-01-02-15-04", work.DateTime)
 	formattedDate := dateTime.Format("2006-01-02")
-	rating := client.getPortfolioRating(work)
+	rating := client.getPortfolioRating(work, skill, rubric)
 	buttons, err := client.createButtonActions(work, skill, handedness)
 	if err != nil {
 		return nil
@@ -181,7 +211,7 @@ func (client *Client) getCarouselItem(work db.Work, skill string, handedness str
 		Type: "bubble",
 		Hero: &linebot.ImageComponent{
 			Type:        "image",
-			URL:         client.assetURL(work.Thumbnail),
+			URL:         work.Thumbnail,
 			Size:        "full",
 			AspectRatio: "20:13",
 			AspectMode:  "cover",
@@ -247,12 +277,12 @@ func (client *Client) sortWorks(works map[string]db.Work) []db.Work {
 	return sortedWorks
 }
 
-func (client *Client) getCarousels(works map[string]db.Work, skill string, handedness string, showBtns bool) ([]*linebot.FlexMessage, error) {
+func (client *Client) getCarousels(works map[string]db.Work, skill string, handedness string, rubric db.Rubric, showBtns bool) ([]*linebot.FlexMessage, error) {
 	items := []*linebot.BubbleContainer{}
 	carouselItems := []*linebot.FlexMessage{}
 	sortedWorks := client.sortWorks(works)
 	for _, work := range sortedWorks {
-		items = append(items, client.getCarouselItem(work, skill, handedness, showBtns))
+		items = append(items, client.getCarouselItem(work, skill, handedness, rubric, showBtns))
 
 		// since the carousel can only contain 10 items, we need to split the works into multiple carousels in order to display all of them
 		if len(items) == 10 {
@@ -1,6 +1,7 @@
 package line
 
 import (
+	"context"
 	"testing"
 
 	"github.com/HeavenAQ/nstc-linebot-2025/api/db"
@@ -31,3 +32,64 @@ func TestPortfolioVideoPostbackStaysWithinLineLimit(t *testing.T) {
 	require.Equal(t, work.DateTime, postback.WorkDate)
 	require.Equal(t, "serve", postback.Skill)
 }
+
+func TestCueClipsButtonOnlyWithClips(t *testing.T) {
+	client := &Client{}
+	work := db.Work{DateTime: "2026-08-01-20-30"}
+
+	buttons, err := client.createButtonActions(work, "serve", "right")
+	require.NoError(t, err)
+	require.Len(t, buttons, 4)
+
+	work.CueClips = []db.CueClip{{Title: "擊球點太低", Video: "analyses/v1/U1/r1/cues/0.mp4"}}
+	buttons, err = client.createButtonActions(work, "serve", "right")
+	require.NoError(t, err)
+	require.Len(t, buttons, 5)
+	action := buttons[4].(*linebotsdk.ButtonComponent).Action.(*linebotsdk.PostbackAction)
+	require.Equal(t, "看重點片段", action.Label)
+
+	postback, err := client.HandleCueClipsPostbackData(action.Data)
+	require.NoError(t, err)
+	require.Equal(t, work.DateTime, postback.WorkDate)
+	_, err = client.HandleVideoPostbackData(action.Data)
+	require.Error(t, err, "must not be mistaken for the watch-video button")
+}
+
+func TestPortfolioRatingFollowsRubric(t *testing.T) {
+	work := db.Work{}
+	work.GradingOutcome.TotalGrade = 82.5
+	rubric := db.Rubric{AttemptPolicy: db.AttemptAverage, SkillWeights: map[string]float64{"serve": 40}}
+
+	require.Equal(t, "課程成績：本次可得 33.00／40 分（採計平均）", rubricNote(work, "serve", rubric))
+	require.Equal(t, "此動作不列入課程成績", rubricNote(work, "smash", rubric))
+	require.Equal(t, "課程成績：本次可得 20.62／25 分（採計最佳一次）", rubricNote(work, "serve", db.DefaultRubric("")))
+}
+
+func TestThumbnailsAreSignedInOneBatch(t *testing.T) {
+	var batches [][]string
+	client := &Client{bucketName: "bucket", signURLs: func(_ context.Context, objectPaths ...string) ([]string, error) {
+		batches = append(batches, objectPaths)
+		urls := make([]string, len(objectPaths))
+		for i, objectPath := range objectPaths {
+			urls[i] = "https://signed/" + objectPath
+		}
+		return urls, nil
+	}}
+	works := map[string]db.Work{
+		"2026-08-01-20-30": {Thumbnail: "U1/thumbnail/a.jpeg"},
+		"2026-08-02-20-30": {Thumbnail: "https://storage.googleapis.com/bucket/U1/thumbnail/b.jpeg"},
+		"2026-08-03-20-30": {Thumbnail: "https://example.com/c.jpeg"},
+	}
+
+	signed, err := client.withThumbnailURLs(context.Background(), works)
+
+	require.NoError(t, err)
+	require.Len(t, batches, 1)
+	require.Equal(t, "https://signed/U1/thumbnail/a.jpeg", signed["2026-08-01-20-30"].Thumbnail)
+	require.Equal(t, "https://signed/U1/thumbnail/b.jpeg", signed["2026-08-02-20-30"].Thumbnail)
+	require.Equal(t, "https://example.com/c.jpeg", signed["2026-08-03-20-30"].Thumbnail)
+	require.Equal(t, "U1/thumbnail/a.jpeg", works["2026-08-01-20-30"].Thumbnail, "the user's works must not change")
+
+	_, err = (&Client{}).withThumbnailURLs(context.Background(), works)
+	require.EqualError(t, err, "no URL signer configured")
+}
@@ -1,9 +1,14 @@
 package line
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
-func (client *Client) GetUserName(userID string) (string, error) {
-	profile, err := client.bot.GetProfile(userID).Do()
+func (client *Client) GetUserName(ctx context.Context, userID string) (string, error) {
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
+	profile, err := client.bot.GetProfile(userID).WithContext(ctx).Do()
 	if err != nil {
 		return "", fmt.Errorf("failed to get user profile: %w", err)
 	}
@@ -1,6 +1,9 @@
 package line
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -14,7 +17,9 @@ const (
 	videoContentRetryDelay = 2 * time.Second
 )
 
-func (client *Client) PromptUploadVideo(event *linebot.Event) error {
+func (client *Client) PromptUploadVideo(ctx context.Context, event *linebot.Event) error {
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
 	_, err := client.bot.ReplyMessage(
 		event.ReplyToken,
 		linebot.NewTextMessage("請上傳影片").WithQuickReplies(
@@ -29,28 +34,86 @@ func (client *Client) PromptUploadVideo(event *linebot.Event) error {
 				),
 			),
 		),
-	).Do()
+	).WithContext(ctx).Do()
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (client *Client) GetVideoContent(msgID string) ([]byte, error) {
-	return readVideoContent(
+// SendVideoRejectedReply tells the student why their video cannot be analyzed.
+func (client *Client) SendVideoRejectedReply(ctx context.Context, replyToken string, problems []string) (*linebot.BasicResponse, error) {
+	return client.SendReply(ctx, replyToken, formatVideoRejectedMessage(problems))
+}
+
+func formatVideoRejectedMessage(problems []string) string {
+	var b strings.Builder
+	b.WriteString("這部影片無法進行分析：")
+	for _, problem := range problems {
+		b.WriteString("\n・" + problem)
+	}
+	b.WriteString("\n\n請調整後重新上傳影片。")
+	return b.String()
+}
+
+// GetVideoContent opens the video's content stream once LINE has finished
+// transcoding it. The caller reads it and must close it.
+// CueClipMessage is a coaching-cue clip with playable URLs.
+type CueClipMessage struct {
+	Title      string
+	VideoURL   string
+	PreviewURL string
+}
+
+// SendCueClips replies with the clips' titles followed by one video message
+// per clip. LINE allows five messages per reply, so at most four clips fit.
+func (client *Client) SendCueClips(ctx context.Context, replyToken string, clips []CueClipMessage) (*linebot.BasicResponse, error) {
+	messages := []linebot.SendingMessage{linebot.NewTextMessage(formatCueClipTitles(clips))}
+	for _, clip := range clips {
+		messages = append(messages, linebot.NewVideoMessage(clip.VideoURL, clip.PreviewURL))
+	}
+	ctx, cancel := client.withTimeout(ctx)
+	defer cancel()
+	return client.bot.ReplyMessage(replyToken, messages...).WithContext(ctx).Do()
+}
+
+func formatCueClipTitles(clips []CueClipMessage) string {
+	var b strings.Builder
+	b.WriteString("重點片段：")
+	for i, clip := range clips {
+		fmt.Fprintf(&b, "\n%d. %s", i+1, clip.Title)
+	}
+	return b.String()
+}
+
+// GetVideoContent streams the video the user sent. The download is bounded
+// only by ctx, which also cuts short the wait between attempts.
+func (client *Client) GetVideoContent(ctx context.Context, msgID string) (io.ReadCloser, error) {
+	return openVideoContent(
 		func() (*linebot.MessageContentResponse, error) {
-			return client.bot.GetMessageContent(msgID).Do()
+			return client.bot.GetMessageContent(msgID).WithContext(ctx).Do()
 		},
 		videoContentAttempts,
-		func() { time.Sleep(videoContentRetryDelay) },
+		func() {
+			select {
+			case <-ctx.Done():
+			case <-time.After(videoContentRetryDelay):
+			}
+		},
 	)
 }
 
-func readVideoContent(
+// videoContent is a content stream whose first bytes have been peeked.
+type videoContent struct {
+	*bufio.Reader
+	io.Closer
+}
+
+func openVideoContent(
 	fetch func() (*linebot.MessageContentResponse, error),
 	attempts int,
 	wait func(),
-) ([]byte, error) {
+) (io.ReadCloser, error) {
 	if attempts < 1 {
 		return nil, fmt.Errorf("video content attempts must be positive")
 	}
@@ -61,16 +124,24 @@ func readVideoContent(
 			return nil, err
 		}
 		lastContentType = contentResp.ContentType
-		blob, readErr := io.ReadAll(contentResp.Content)
-		closeErr := contentResp.Content.Close()
-		if readErr != nil {
-			return nil, readErr
-		}
-		if closeErr != nil {
-			return nil, closeErr
+		if strings.HasPrefix(lastContentType, "video/") {
+			// LINE may answer with an empty body while it is still processing,
+			// so peek before handing the stream over.
+			reader := bufio.NewReader(contentResp.Content)
+			_, peekErr := reader.Peek(1)
+			if peekErr == nil {
+				return videoContent{Reader: reader, Closer: contentResp.Content}, nil
+			}
+			if !errors.Is(peekErr, io.EOF) {
+				contentResp.Content.Close()
+				return nil, peekErr
+			}
+		} else if _, err := io.Copy(io.Discard, contentResp.Content); err != nil {
+			contentResp.Content.Close()
+			return nil, err
 		}
-		if len(blob) > 0 && strings.HasPrefix(lastContentType, "video/") {
-			return blob, nil
+		if err := contentResp.Content.Close(); err != nil {
+			return nil, err
 		}
 		if attempt < attempts {
 			wait()
@@ -26,11 +26,14 @@ func contentResponse(contentType string, body []byte) *linebotsdk.MessageContent
 func TestReadVideoContentRetriesWhileLINEIsTranscoding(t *testing.T) {
 	attempt := 0
 	waits := 0
-	blob, err := readVideoContent(
+	content, err := openVideoContent(
 		func() (*linebotsdk.MessageContentResponse, error) {
 			attempt++
-			if attempt < 3 {
-				return contentResponse("application/json", nil), nil
+			switch attempt {
+			case 1:
+				return contentResponse("application/json", []byte("{}")), nil
+			case 2:
+				return contentResponse("video/mp4", nil), nil
 			}
 			return contentResponse("video/mp4", []byte("video-data")), nil
 		},
@@ -38,6 +41,9 @@ func TestReadVideoContentRetriesWhileLINEIsTranscoding(t *testing.T) {
 		func() { waits++ },
 	)
 
+	require.NoError(t, err)
+	t.Cleanup(func() { content.Close() })
+	blob, err := io.ReadAll(content)
 	require.NoError(t, err)
 	require.Equal(t, []byte("video-data"), blob)
 	require.Equal(t, 3, attempt)
@@ -46,7 +52,7 @@ func TestReadVideoContentRetriesWhileLINEIsTranscoding(t *testing.T) {
 
 func TestReadVideoContentRejectsPersistentEmptyResponse(t *testing.T) {
 	waits := 0
-	_, err := readVideoContent(
+	_, err := openVideoContent(
 		func() (*linebotsdk.MessageContentResponse, error) {
 			return contentResponse("application/json", nil), nil
 		},
@@ -58,6 +64,18 @@ func TestReadVideoContentRejectsPersistentEmptyResponse(t *testing.T) {
 	require.Equal(t, 2, waits)
 }
 
+func TestFormatVideoRejectedMessage(t *testing.T) {
+	msg := formatVideoRejectedMessage([]string{"影片需介於 2 到 15 秒（目前 1.0 秒）", "影片檔案需小於 100 MB（目前 120.0 MB）"})
+
+	require.Equal(t, "這部影片無法進行分析：\n・影片需介於 2 到 15 秒（目前 1.0 秒）\n・影片檔案需小於 100 MB（目前 120.0 MB）\n\n請調整後重新上傳影片。", msg)
+}
+
+func TestFormatCueClipTitles(t *testing.T) {
+	msg := formatCueClipTitles([]CueClipMessage{{Title: "擊球點太低"}, {Title: "重心沒有轉移"}})
+
+	require.Equal(t, "重點片段：\n1. 擊球點太低\n2. 重心沒有轉移", msg)
+}
+
 func TestLiveGetVideoContent(t *testing.T) {
 	if os.Getenv("RUN_LIVE_LINE_CONTENT") != "1" {
 		t.Skip("set RUN_LIVE_LINE_CONTENT=1 to download a real LINE video")
@@ -72,7 +90,10 @@ func TestLiveGetVideoContent(t *testing.T) {
 	)
 	require.NoError(t, err)
 
-	blob, err := client.GetVideoContent(messageID)
+	content, err := client.GetVideoContent(context.Background(), messageID)
+	require.NoError(t, err)
+	t.Cleanup(func() { content.Close() })
+	blob, err := io.ReadAll(content)
 	require.NoError(t, err)
 	require.Greater(t, len(blob), 1024)
 	require.Equal(t, "ftyp", string(blob[4:8]))
@@ -100,9 +121,9 @@ func TestLiveLINEVideoAnalysis(t *testing.T) {
 		os.Getenv("GCS_BUCKET_NAME"),
 	)
 	require.NoError(t, err)
-	video, err := lineClient.GetVideoContent(messageID)
+	video, err := lineClient.GetVideoContent(context.Background(), messageID)
 	require.NoError(t, err)
-	require.Greater(t, len(video), 1024)
+	t.Cleanup(func() { video.Close() })
 
 	analysisClient, err := analysis.NewClient(target, apiKey, false)
 	require.NoError(t, err)
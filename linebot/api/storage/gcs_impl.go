@@ -3,8 +3,10 @@ package storage
 import (
     "context"
     "io"
+    "time"
 
     gcs "cloud.google.com/go/storage"
+    "google.golang.org/api/iterator"
 )
 
 // StorageClient abstracts the subset of Cloud Storage client used by BucketClient.
@@ -16,10 +18,19 @@ type StorageClient interface {
 
 type BucketHandle interface {
     Object(name string) ObjectHandle
+    SignedURL(object string, opts *gcs.SignedURLOptions) (string, error)
+    ListObjects(ctx context.Context, prefix string) ([]ObjectAttrs, error)
+}
+
+// ObjectAttrs describes a stored object.
+type ObjectAttrs struct {
+    Name    string
+    Updated time.Time
 }
 
 type ObjectHandle interface {
     NewWriter(ctx context.Context) ObjectWriter
+    NewReader(ctx context.Context) (io.ReadCloser, error)
     Delete(ctx context.Context) error
     ObjectName() string
 }
@@ -39,9 +50,27 @@ type gcsBucket struct{ *gcs.BucketHandle }
 
 func (b *gcsBucket) Object(name string) ObjectHandle { return &gcsObject{b.BucketHandle.Object(name)} }
 
+func (b *gcsBucket) ListObjects(ctx context.Context, prefix string) ([]ObjectAttrs, error) {
+    var objects []ObjectAttrs
+    it := b.BucketHandle.Objects(ctx, &gcs.Query{Prefix: prefix})
+    for {
+        attrs, err := it.Next()
+        if err == iterator.Done {
+            return objects, nil
+        }
+        if err != nil {
+            return nil, err
+        }
+        objects = append(objects, ObjectAttrs{Name: attrs.Name, Updated: attrs.Updated})
+    }
+}
+
 type gcsObject struct{ *gcs.ObjectHandle }
 
 func (o *gcsObject) NewWriter(ctx context.Context) ObjectWriter { return &gcsWriter{o.ObjectHandle.NewWriter(ctx)} }
+func (o *gcsObject) NewReader(ctx context.Context) (io.ReadCloser, error) {
+    return o.ObjectHandle.NewReader(ctx)
+}
 func (o *gcsObject) Delete(ctx context.Context) error           { return o.ObjectHandle.Delete(ctx) }
 func (o *gcsObject) ObjectName() string                         { return o.ObjectHandle.ObjectName() }
 
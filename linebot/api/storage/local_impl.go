@@ -0,0 +1,271 @@
+package storage
+
+import (
+    "context"
+    "crypto/hmac"
+    "crypto/rand"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "io/fs"
+    "net/http"
+    "net/url"
+    "os"
+    "path"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "time"
+
+    gcs "cloud.google.com/go/storage"
+)
+
+// localClient keeps objects as files under root/<bucket>/, with each object's
+// content type in a sidecar under root/.metadata/<bucket>/. It stands in for
+// Cloud Storage in local development and offline tests, and serves its
+// signed URLs itself from baseURL.
+type localClient struct {
+    root    string
+    baseURL string
+    key     []byte
+}
+
+type localMetadata struct {
+    ContentType string `json:"content_type"`
+}
+
+var errInvalidObjectName = errors.New("invalid object name")
+
+// NewLocalBucketClient stores objects under root. Signed URLs point at
+// baseURL, where FileServer must be mounted; they stop working when the
+// process restarts.
+func NewLocalBucketClient(root, baseURL, bucketName string) (*BucketClient, error) {
+    key := make([]byte, 32)
+    if _, err := rand.Read(key); err != nil {
+        return nil, fmt.Errorf("failed to create signing key: %w", err)
+    }
+    if err := os.MkdirAll(root, 0o755); err != nil {
+        return nil, fmt.Errorf("failed to create storage root: %w", err)
+    }
+    client := &localClient{root: root, baseURL: strings.TrimRight(baseURL, "/"), key: key}
+    return &BucketClient{client: client, bucketName: bucketName}, nil
+}
+
+// FileServer serves the local backend's signed URLs. It is nil for Cloud
+// Storage, which serves its own.
+func (c *BucketClient) FileServer() http.Handler {
+    if local, ok := c.client.(*localClient); ok {
+        return local
+    }
+    return nil
+}
+
+func (c *localClient) Bucket(name string) BucketHandle { return &localBucket{client: c, name: name} }
+func (c *localClient) Close() error                     { return nil }
+
+// paths returns where an object and its sidecar live, refusing names that
+// would escape the bucket.
+func (c *localClient) paths(bucket, name string) (string, string, error) {
+    cleaned := path.Clean("/" + name)[1:]
+    if name == "" || cleaned != name || bucket == "" || strings.ContainsAny(bucket, `/\`) || strings.HasPrefix(bucket, ".") {
+        return "", "", fmt.Errorf("%w: %q", errInvalidObjectName, name)
+    }
+    object := filepath.Join(c.root, bucket, filepath.FromSlash(name))
+    sidecar := filepath.Join(c.root, ".metadata", bucket, filepath.FromSlash(name)+".json")
+    return object, sidecar, nil
+}
+
+func (c *localClient) signature(bucket, name string, expires int64) string {
+    mac := hmac.New(sha256.New, c.key)
+    fmt.Fprintf(mac, "%s\n%s\n%d", bucket, name, expires)
+    return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ServeHTTP serves GET /<bucket>/<object>?expires=<unix>&signature=<hex>.
+func (c *localClient) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    if r.Method != http.MethodGet && r.Method != http.MethodHead {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+    bucket, name, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+    expires, err := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+    signature := r.URL.Query().Get("signature")
+    if !ok || err != nil || !hmac.Equal([]byte(signature), []byte(c.signature(bucket, name, expires))) {
+        http.Error(w, "invalid signature", http.StatusForbidden)
+        return
+    }
+    if time.Now().Unix() > expires {
+        http.Error(w, "URL expired", http.StatusForbidden)
+        return
+    }
+    objectPath, sidecarPath, err := c.paths(bucket, name)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+    file, err := os.Open(objectPath)
+    if err != nil {
+        http.NotFound(w, r)
+        return
+    }
+    defer file.Close()
+    info, err := file.Stat()
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    if data, err := os.ReadFile(sidecarPath); err == nil {
+        var metadata localMetadata
+        if json.Unmarshal(data, &metadata) == nil && metadata.ContentType != "" {
+            w.Header().Set("Content-Type", metadata.ContentType)
+        }
+    }
+    http.ServeContent(w, r, name, info.ModTime(), file)
+}
+
+type localBucket struct {
+    client *localClient
+    name   string
+}
+
+func (b *localBucket) Object(name string) ObjectHandle {
+    return &localObject{client: b.client, bucket: b.name, name: name}
+}
+
+func (b *localBucket) SignedURL(object string, opts *gcs.SignedURLOptions) (string, error) {
+    if _, _, err := b.client.paths(b.name, object); err != nil {
+        return "", err
+    }
+    if opts.Method != http.MethodGet {
+        return "", fmt.Errorf("local storage only signs GET URLs, not %s", opts.Method)
+    }
+    expires := opts.Expires.Unix()
+    query := url.Values{
+        "expires":   {strconv.FormatInt(expires, 10)},
+        "signature": {b.client.signature(b.name, object, expires)},
+    }
+    escaped := (&url.URL{Path: b.name + "/" + object}).EscapedPath()
+    return b.client.baseURL + "/" + escaped + "?" + query.Encode(), nil
+}
+
+func (b *localBucket) ListObjects(ctx context.Context, prefix string) ([]ObjectAttrs, error) {
+    root := filepath.Join(b.client.root, b.name)
+    var objects []ObjectAttrs
+    err := filepath.WalkDir(root, func(filePath string, entry fs.DirEntry, err error) error {
+        if errors.Is(err, os.ErrNotExist) {
+            return nil
+        }
+        if err != nil || entry.IsDir() || strings.HasPrefix(entry.Name(), ".upload-") {
+            return err
+        }
+        relative, err := filepath.Rel(root, filePath)
+        if err != nil {
+            return err
+        }
+        name := filepath.ToSlash(relative)
+        if !strings.HasPrefix(name, prefix) {
+            return nil
+        }
+        info, err := entry.Info()
+        if err != nil {
+            return err
+        }
+        objects = append(objects, ObjectAttrs{Name: name, Updated: info.ModTime()})
+        return nil
+    })
+    return objects, err
+}
+
+type localObject struct {
+    client *localClient
+    bucket string
+    name   string
+}
+
+func (o *localObject) NewWriter(ctx context.Context) ObjectWriter {
+    return &localWriter{object: o}
+}
+
+func (o *localObject) NewReader(ctx context.Context) (io.ReadCloser, error) {
+    objectPath, _, err := o.client.paths(o.bucket, o.name)
+    if err != nil {
+        return nil, err
+    }
+    return os.Open(objectPath)
+}
+
+func (o *localObject) Delete(ctx context.Context) error {
+    objectPath, sidecarPath, err := o.client.paths(o.bucket, o.name)
+    if err != nil {
+        return err
+    }
+    if err := os.Remove(objectPath); err != nil {
+        return err
+    }
+    if err := os.Remove(sidecarPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+        return err
+    }
+    return nil
+}
+
+func (o *localObject) ObjectName() string { return o.name }
+
+// localWriter writes to a temporary file and moves it into place on Close,
+// so readers never see a partial object, as with Cloud Storage.
+type localWriter struct {
+    object      *localObject
+    contentType string
+    file        *os.File
+    err         error
+}
+
+func (w *localWriter) SetContentType(ct string) { w.contentType = ct }
+
+func (w *localWriter) Write(p []byte) (int, error) {
+    if w.file == nil && w.err == nil {
+        w.file, w.err = w.create()
+    }
+    if w.err != nil {
+        return 0, w.err
+    }
+    return w.file.Write(p)
+}
+
+func (w *localWriter) create() (*os.File, error) {
+    objectPath, _, err := w.object.client.paths(w.object.bucket, w.object.name)
+    if err != nil {
+        return nil, err
+    }
+    if err := os.MkdirAll(filepath.Dir(objectPath), 0o755); err != nil {
+        return nil, err
+    }
+    return os.CreateTemp(filepath.Dir(objectPath), ".upload-*")
+}
+
+func (w *localWriter) Close() error {
+    if w.file == nil && w.err == nil {
+        w.file, w.err = w.create()
+    }
+    if w.err != nil {
+        return w.err
+    }
+    defer os.Remove(w.file.Name())
+    if err := w.file.Close(); err != nil {
+        return err
+    }
+    objectPath, sidecarPath, _ := w.object.client.paths(w.object.bucket, w.object.name)
+    metadata, err := json.Marshal(localMetadata{ContentType: w.contentType})
+    if err != nil {
+        return err
+    }
+    if err := os.MkdirAll(filepath.Dir(sidecarPath), 0o755); err != nil {
+        return err
+    }
+    if err := os.WriteFile(sidecarPath, metadata, 0o644); err != nil {
+        return err
+    }
+    return os.Rename(w.file.Name(), objectPath)
+}
@@ -0,0 +1,116 @@
+package storage
+
+import (
+    "context"
+    "encoding/json"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/require"
+)
+
+func newLocalTestClient(t *testing.T) (*BucketClient, *httptest.Server, string) {
+    root := t.TempDir()
+    mux := http.NewServeMux()
+    server := httptest.NewServer(mux)
+    t.Cleanup(server.Close)
+    bc, err := NewLocalBucketClient(root, server.URL+"/storage", "test-bucket")
+    require.NoError(t, err)
+    mux.Handle("/storage/", http.StripPrefix("/storage", bc.FileServer()))
+    return bc, server, root
+}
+
+func TestLocalBackendRoundTrip(t *testing.T) {
+    bc, _, root := newLocalTestClient(t)
+
+    fi := &FileInfo{}
+    fi.Bucket.VideoPath = "U1/videos/serve/v1.mp4"
+    fi.Local.VideoBlob = []byte("local video")
+    _, err := bc.UploadVideo(context.Background(), fi)
+    require.NoError(t, err)
+
+    reader, err := bc.OpenVideo(context.Background(), fi.Bucket.VideoPath)
+    require.NoError(t, err)
+    data, err := io.ReadAll(reader)
+    require.NoError(t, err)
+    require.NoError(t, reader.Close())
+    require.Equal(t, "local video", string(data))
+
+    sidecar, err := os.ReadFile(filepath.Join(root, ".metadata", "test-bucket", "U1", "videos", "serve", "v1.mp4.json"))
+    require.NoError(t, err)
+    var metadata localMetadata
+    require.NoError(t, json.Unmarshal(sidecar, &metadata))
+    require.Equal(t, "video/mp4", metadata.ContentType)
+
+    objects, err := bc.ListObjects(context.Background(), "U1/")
+    require.NoError(t, err)
+    require.Len(t, objects, 1)
+    require.Equal(t, "U1/videos/serve/v1.mp4", objects[0].Name)
+    require.WithinDuration(t, time.Now(), objects[0].Updated, time.Minute)
+    objects, err = bc.ListObjects(context.Background(), "U2/")
+    require.NoError(t, err)
+    require.Empty(t, objects)
+    require.NoError(t, bc.Ping(context.Background()))
+
+    require.NoError(t, bc.DeleteFile(context.Background(), fi.Bucket.VideoPath))
+    _, err = bc.OpenVideo(context.Background(), fi.Bucket.VideoPath)
+    require.Error(t, err)
+    require.NoFileExists(t, filepath.Join(root, ".metadata", "test-bucket", "U1", "videos", "serve", "v1.mp4.json"))
+}
+
+func TestLocalBackendServesSignedURLs(t *testing.T) {
+    bc, _, _ := newLocalTestClient(t)
+    fi := &FileInfo{}
+    fi.Bucket.ThumbnailPath = "U1/thumbnail/t1.jpg"
+    fi.Local.ThumbnailPath = filepath.Join("test_files", "test_thumbnail.jpg")
+    _, err := bc.UploadThumbnail(context.Background(), fi)
+    require.NoError(t, err)
+
+    url, _, err := bc.SignURL(context.Background(), fi.Bucket.ThumbnailPath, time.Minute)
+    require.NoError(t, err)
+    response, err := http.Get(url)
+    require.NoError(t, err)
+    body, _ := io.ReadAll(response.Body)
+    response.Body.Close()
+    disk, _ := os.ReadFile(fi.Local.ThumbnailPath)
+    require.Equal(t, http.StatusOK, response.StatusCode)
+    require.Equal(t, "image/jpeg", response.Header.Get("Content-Type"))
+    require.Equal(t, disk, body)
+
+    tampered := strings.Replace(url, "t1.jpg", "t2.jpg", 1)
+    response, err = http.Get(tampered)
+    require.NoError(t, err)
+    response.Body.Close()
+    require.Equal(t, http.StatusForbidden, response.StatusCode)
+
+    expired, _, err := bc.SignURL(context.Background(), fi.Bucket.ThumbnailPath, -time.Minute)
+    require.NoError(t, err)
+    response, err = http.Get(expired)
+    require.NoError(t, err)
+    response.Body.Close()
+    require.Equal(t, http.StatusForbidden, response.StatusCode)
+}
+
+func TestLocalBackendRejectsEscapingNames(t *testing.T) {
+    bc, _, root := newLocalTestClient(t)
+
+    fi := &FileInfo{}
+    fi.Bucket.VideoPath = "../outside.mp4"
+    fi.Local.VideoBlob = []byte("x")
+    _, err := bc.UploadVideo(context.Background(), fi)
+    require.ErrorIs(t, err, errInvalidObjectName)
+    require.NoFileExists(t, filepath.Join(filepath.Dir(root), "outside.mp4"))
+
+    _, _, err = bc.SignURL(context.Background(), "a/../../b", time.Minute)
+    require.ErrorIs(t, err, errInvalidObjectName)
+}
+
+func TestFileServerIsOnlyForLocalBackend(t *testing.T) {
+    require.Nil(t, NewBucketClientWithClient(newFakeClient(), "test-bucket").FileServer())
+}
@@ -4,10 +4,16 @@ import (
     "bytes"
     "context"
     "errors"
+    "fmt"
+    "io"
     "os"
     "path/filepath"
+    "sort"
+    "strings"
     "testing"
+    "time"
 
+    gcs "cloud.google.com/go/storage"
     "github.com/stretchr/testify/require"
 )
 
@@ -36,6 +42,24 @@ func (b *fakeBucket) Object(name string) ObjectHandle {
     return &fakeObjectRef{bucket: b, name: name}
 }
 
+func (b *fakeBucket) ListObjects(ctx context.Context, prefix string) ([]ObjectAttrs, error) {
+    var objects []ObjectAttrs
+    for name := range b.objects {
+        if strings.HasPrefix(name, prefix) {
+            objects = append(objects, ObjectAttrs{Name: name})
+        }
+    }
+    sort.Slice(objects, func(i, j int) bool { return objects[i].Name < objects[j].Name })
+    return objects, nil
+}
+
+func (b *fakeBucket) SignedURL(object string, opts *gcs.SignedURLOptions) (string, error) {
+    if opts.Scheme != gcs.SigningSchemeV4 || opts.Method != "GET" {
+        return "", errors.New("unexpected signing options")
+    }
+    return fmt.Sprintf("https://storage.example/%s/%s?expires=%d", b.name, object, opts.Expires.Unix()), nil
+}
+
 type fakeObject struct {
     data        []byte
     contentType string
@@ -58,6 +82,13 @@ func (r *fakeObjectRef) ensure() *fakeObject {
 func (r *fakeObjectRef) NewWriter(ctx context.Context) ObjectWriter {
     return &fakeWriter{ref: r}
 }
+func (r *fakeObjectRef) NewReader(ctx context.Context) (io.ReadCloser, error) {
+    obj, ok := r.bucket.objects[r.name]
+    if !ok {
+        return nil, errors.New("object not found")
+    }
+    return io.NopCloser(bytes.NewReader(obj.data)), nil
+}
 func (r *fakeObjectRef) Delete(ctx context.Context) error {
     if _, ok := r.bucket.objects[r.name]; !ok {
         return errors.New("object not found")
@@ -86,9 +117,9 @@ func (w *fakeWriter) SetContentType(ct string) { w.ct = ct }
 
 func TestCreateUserFolders(t *testing.T) {
     fake := newFakeClient()
-    bc := NewBucketClientWithClient(context.Background(), fake, "test-bucket")
+    bc := NewBucketClientWithClient(fake, "test-bucket")
 
-    uf, err := bc.CreateUserFolders("user123", "alice")
+    uf, err := bc.CreateUserFolders(context.Background(), "user123", "alice")
     require.NoError(t, err)
     require.Equal(t, "user123/", uf.RootPath)
 
@@ -101,13 +132,13 @@ func TestCreateUserFolders(t *testing.T) {
 
 func TestUploadVideo(t *testing.T) {
     fake := newFakeClient()
-    bc := NewBucketClientWithClient(context.Background(), fake, "test-bucket")
+    bc := NewBucketClientWithClient(fake, "test-bucket")
 
     fi := &FileInfo{}
     fi.Bucket.VideoPath = "user123/videos/v1.mp4"
     fi.Local.VideoBlob = []byte{1, 2, 3, 4, 5}
 
-    uploaded, err := bc.UploadVideo(fi)
+    uploaded, err := bc.UploadVideo(context.Background(), fi)
     require.NoError(t, err)
     require.Equal(t, fi.Bucket.VideoPath, uploaded.Path)
     require.Equal(t, "user123/videos/v1.mp4", uploaded.Name)
@@ -117,16 +148,57 @@ func TestUploadVideo(t *testing.T) {
     require.Equal(t, "video/mp4", obj.contentType)
 }
 
+func TestUploadVideoFromFileAndOpen(t *testing.T) {
+    fake := newFakeClient()
+    bc := NewBucketClientWithClient(fake, "test-bucket")
+
+    localPath := filepath.Join(t.TempDir(), "upload.mp4")
+    require.NoError(t, os.WriteFile(localPath, []byte("original video"), 0600))
+    fi := &FileInfo{}
+    fi.Bucket.VideoPath = "user123/originals/serve/2025-03-01-10-00.mp4"
+    fi.Local.VideoPath = localPath
+
+    _, err := bc.UploadVideo(context.Background(), fi)
+    require.NoError(t, err)
+
+    reader, err := bc.OpenVideo(context.Background(), fi.Bucket.VideoPath)
+    require.NoError(t, err)
+    defer reader.Close()
+    data, err := io.ReadAll(reader)
+    require.NoError(t, err)
+    require.Equal(t, "original video", string(data))
+
+    _, err = bc.OpenVideo(context.Background(), "user123/originals/serve/missing.mp4")
+    require.Error(t, err)
+}
+
+func TestSignURL(t *testing.T) {
+    bc := NewBucketClientWithClient(newFakeClient(), "test-bucket")
+
+    before := time.Now()
+    url, expires, err := bc.SignURL(context.Background(), "analyses/a1/student.mp4", time.Hour)
+
+    require.NoError(t, err)
+    require.WithinDuration(t, before.Add(time.Hour), expires, time.Second)
+    require.Equal(t, fmt.Sprintf("https://storage.example/test-bucket/analyses/a1/student.mp4?expires=%d", expires.Unix()), url)
+}
+
+func TestObjectPath(t *testing.T) {
+    require.Equal(t, "U1/thumbnail/a.jpeg", ObjectPath("test-bucket", "U1/thumbnail/a.jpeg"))
+    require.Equal(t, "U1/thumbnail/a.jpeg", ObjectPath("test-bucket", "https://storage.googleapis.com/test-bucket/U1/thumbnail/a.jpeg"))
+    require.Equal(t, "https://example.com/a.jpeg", ObjectPath("test-bucket", "https://example.com/a.jpeg"))
+}
+
 func TestUploadThumbnail(t *testing.T) {
     fake := newFakeClient()
-    bc := NewBucketClientWithClient(context.Background(), fake, "test-bucket")
+    bc := NewBucketClientWithClient(fake, "test-bucket")
 
     thumbPath := filepath.Join("test_files", "test_thumbnail.jpg")
     fi := &FileInfo{}
     fi.Bucket.ThumbnailPath = "user123/thumbnails/t1.jpg"
     fi.Local.ThumbnailPath = thumbPath
 
-    uploaded, err := bc.UploadThumbnail(fi)
+    uploaded, err := bc.UploadThumbnail(context.Background(), fi)
     require.NoError(t, err)
     require.Equal(t, fi.Bucket.ThumbnailPath, uploaded.Path)
 
@@ -139,17 +211,17 @@ func TestUploadThumbnail(t *testing.T) {
 
 func TestDeleteFile(t *testing.T) {
     fake := newFakeClient()
-    bc := NewBucketClientWithClient(context.Background(), fake, "test-bucket")
+    bc := NewBucketClientWithClient(fake, "test-bucket")
 
     // Seed an object via upload
     fi := &FileInfo{}
     fi.Bucket.VideoPath = "user123/videos/v2.mp4"
     fi.Local.VideoBlob = []byte{9, 9, 9}
-    _, err := bc.UploadVideo(fi)
+    _, err := bc.UploadVideo(context.Background(), fi)
     require.NoError(t, err)
 
     // Delete it
-    err = bc.DeleteFile(fi.Bucket.VideoPath)
+    err = bc.DeleteFile(context.Background(), fi.Bucket.VideoPath)
     require.NoError(t, err)
 
     // Verify it no longer exists
@@ -157,6 +229,6 @@ func TestDeleteFile(t *testing.T) {
     require.False(t, ok)
 
     // Delete again should error
-    err = bc.DeleteFile(fi.Bucket.VideoPath)
+    err = bc.DeleteFile(context.Background(), fi.Bucket.VideoPath)
     require.Error(t, err)
 }
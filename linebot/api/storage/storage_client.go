@@ -6,15 +6,24 @@ import (
     "context"
     "fmt"
     "io"
+    "net/http"
     "os"
+    "strings"
+    "time"
 
     gcs "cloud.google.com/go/storage"
+    "github.com/HeavenAQ/nstc-linebot-2025/config"
+    "go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+    "google.golang.org/api/option"
+    htransport "google.golang.org/api/transport/http"
 )
 
+// BucketClient stores the bot's uploads. Each operation stops when its
+// context is done or, if a timeout is set, after the timeout.
 type BucketClient struct {
     client     StorageClient
     bucketName string
-    ctx        context.Context
+    timeout    time.Duration
 }
 
 type UserFolders struct {
@@ -26,17 +35,40 @@ type UserFolders struct {
 func NewBucketClient(bucketName string) (*BucketClient, error) {
     ctx := context.Background()
 
-    // init Google Cloud Storage client
-    client, err := gcs.NewClient(ctx)
+    // init Google Cloud Storage client, tracing its requests under the
+    // credentials it would have used
+    transport, err := htransport.NewTransport(
+        ctx, otelhttp.NewTransport(http.DefaultTransport), option.WithScopes(gcs.ScopeFullControl),
+    )
+    if err != nil {
+        return nil, err
+    }
+    client, err := gcs.NewClient(ctx, option.WithHTTPClient(&http.Client{Transport: transport}))
     if err != nil {
         return nil, err
     }
 
-    return &BucketClient{
-        &gcsClient{client},
-        bucketName,
-        ctx,
-    }, nil
+    return &BucketClient{client: &gcsClient{client}, bucketName: bucketName}, nil
+}
+
+// Open returns the bucket client for the configured backend, with the
+// configured timeout.
+func Open(cfg config.StorageConfig) (*BucketClient, error) {
+    var client *BucketClient
+    var err error
+    switch cfg.Backend {
+    case "", "gcs":
+        client, err = NewBucketClient(cfg.BucketName)
+    case "local":
+        client, err = NewLocalBucketClient(cfg.LocalRoot, cfg.LocalBaseURL, cfg.BucketName)
+    default:
+        return nil, fmt.Errorf("unsupported storage backend: %s", cfg.Backend)
+    }
+    if err != nil {
+        return nil, err
+    }
+    client.timeout = cfg.Timeout
+    return client, nil
 }
 
 // NewBucketClientWithClient was used for tests; moved to a _test.go helper.
@@ -45,14 +77,24 @@ func (c *BucketClient) Close() error {
 	return c.client.Close()
 }
 
-func (c *BucketClient) CreateUserFolders(userID string, userName string) (*UserFolders, error) {
+// withTimeout bounds one operation by the client's timeout.
+func (c *BucketClient) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+    if c.timeout <= 0 {
+        return context.WithCancel(ctx)
+    }
+    return context.WithTimeout(ctx, c.timeout)
+}
+
+func (c *BucketClient) CreateUserFolders(ctx context.Context, userID string, userName string) (*UserFolders, error) {
+    ctx, cancel := c.withTimeout(ctx)
+    defer cancel()
     rootPath := fmt.Sprintf("%s/", userID)
     // a placeholder for UI display
     placeholderPath := fmt.Sprintf("%s.folder_placeholder", rootPath)
     bucket := c.client.Bucket(c.bucketName)
     obj := bucket.Object(placeholderPath)
 
-    writer := obj.NewWriter(c.ctx)
+    writer := obj.NewWriter(ctx)
     if _, err := writer.Write([]byte("")); err != nil {
         _ = writer.Close()
         return nil, fmt.Errorf("failed to create user folder placeholder: %w", err)
@@ -75,6 +117,7 @@ type FileInfo struct {
 	}
 	Local struct {
 		ThumbnailPath string
+		VideoPath     string
 		VideoBlob     []byte
 	}
 }
@@ -84,15 +127,28 @@ type UploadedFile struct {
 	Path string
 }
 
-func (c *BucketClient) UploadVideo(fileInfo *FileInfo) (*UploadedFile, error) {
+// UploadVideo uploads the video at Local.VideoPath, streaming it from disk,
+// or else Local.VideoBlob.
+func (c *BucketClient) UploadVideo(ctx context.Context, fileInfo *FileInfo) (*UploadedFile, error) {
+    ctx, cancel := c.withTimeout(ctx)
+    defer cancel()
+    var video io.Reader = bytes.NewReader(fileInfo.Local.VideoBlob)
+    if fileInfo.Local.VideoPath != "" {
+        file, err := os.Open(fileInfo.Local.VideoPath)
+        if err != nil {
+            return nil, fmt.Errorf("failed to open video file: %w", err)
+        }
+        defer file.Close()
+        video = file
+    }
+
     bucket := c.client.Bucket(c.bucketName)
     obj := bucket.Object(fileInfo.Bucket.VideoPath)
 
-    writer := obj.NewWriter(c.ctx)
+    writer := obj.NewWriter(ctx)
     writer.SetContentType("video/mp4")
 
-    blobReader := bytes.NewReader(fileInfo.Local.VideoBlob)
-    if _, err := io.Copy(writer, blobReader); err != nil {
+    if _, err := io.Copy(writer, video); err != nil {
         _ = writer.Close()
         return nil, fmt.Errorf("failed to upload video: %w", err)
     }
@@ -107,7 +163,9 @@ func (c *BucketClient) UploadVideo(fileInfo *FileInfo) (*UploadedFile, error) {
     }, nil
 }
 
-func (c *BucketClient) UploadThumbnail(fileInfo *FileInfo) (*UploadedFile, error) {
+func (c *BucketClient) UploadThumbnail(ctx context.Context, fileInfo *FileInfo) (*UploadedFile, error) {
+    ctx, cancel := c.withTimeout(ctx)
+    defer cancel()
 	// upload video thumbnail to google drive
 	thumbnailData, err := os.ReadFile(fileInfo.Local.ThumbnailPath)
 	if err != nil {
@@ -119,7 +177,7 @@ func (c *BucketClient) UploadThumbnail(fileInfo *FileInfo) (*UploadedFile, error
     obj := bucket.Object(fileInfo.Bucket.ThumbnailPath)
 
 	// Initiate a writer of the object
-    writer := obj.NewWriter(c.ctx)
+    writer := obj.NewWriter(ctx)
     writer.SetContentType("image/jpeg")
 
 	// write in-memory media blob to the object
@@ -139,11 +197,70 @@ func (c *BucketClient) UploadThumbnail(fileInfo *FileInfo) (*UploadedFile, error
 	}, nil
 }
 
-func (c *BucketClient) DeleteFile(filePath string) error {
+// OpenVideo streams a stored video. The caller must close it. The stream is
+// bounded only by ctx, since reading it may outlast the operation timeout.
+func (c *BucketClient) OpenVideo(ctx context.Context, filePath string) (io.ReadCloser, error) {
+    bucket := c.client.Bucket(c.bucketName)
+    reader, err := bucket.Object(filePath).NewReader(ctx)
+    if err != nil {
+        return nil, fmt.Errorf("failed to open video %s: %w", filePath, err)
+    }
+    return reader, nil
+}
+
+// ObjectPath returns the object path of a stored reference. Works saved while
+// the bucket was public hold a public URL instead of a path.
+func ObjectPath(bucketName, pathOrURL string) string {
+    publicPrefix := "https://storage.googleapis.com/" + bucketName + "/"
+    return strings.TrimLeft(strings.TrimPrefix(pathOrURL, publicPrefix), "/")
+}
+
+// SignURL signs a V4 GET URL for the object lasting lifetime. On Cloud Run
+// the service account signs through the IAM API, so it needs the Service
+// Account Token Creator role on itself.
+func (c *BucketClient) SignURL(_ context.Context, objectPath string, lifetime time.Duration) (string, time.Time, error) {
+    expires := time.Now().Add(lifetime)
+    url, err := c.client.Bucket(c.bucketName).SignedURL(objectPath, &gcs.SignedURLOptions{
+        Scheme:  gcs.SigningSchemeV4,
+        Method:  http.MethodGet,
+        Expires: expires,
+    })
+    if err != nil {
+        return "", time.Time{}, fmt.Errorf("failed to sign URL for %s: %w", objectPath, err)
+    }
+    return url, expires, nil
+}
+
+// ListObjects lists the objects whose names start with prefix.
+func (c *BucketClient) ListObjects(ctx context.Context, prefix string) ([]ObjectAttrs, error) {
+    ctx, cancel := c.withTimeout(ctx)
+    defer cancel()
+    objects, err := c.client.Bucket(c.bucketName).ListObjects(ctx, prefix)
+    if err != nil {
+        return nil, fmt.Errorf("failed to list objects under %s: %w", prefix, err)
+    }
+    return objects, nil
+}
+
+// pingPrefix holds no objects; listing it proves the bucket is reachable
+// and readable without walking real data.
+const pingPrefix = ".ping/"
+
+// Ping checks that the bucket can be listed.
+func (c *BucketClient) Ping(ctx context.Context) error {
+    if _, err := c.client.Bucket(c.bucketName).ListObjects(ctx, pingPrefix); err != nil {
+        return fmt.Errorf("failed to list bucket %s: %w", c.bucketName, err)
+    }
+    return nil
+}
+
+func (c *BucketClient) DeleteFile(ctx context.Context, filePath string) error {
+    ctx, cancel := c.withTimeout(ctx)
+    defer cancel()
     bucket := c.client.Bucket(c.bucketName)
     obj := bucket.Object(filePath)
 
-    if err := obj.Delete(c.ctx); err != nil {
+    if err := obj.Delete(ctx); err != nil {
         return fmt.Errorf("failed to delete file %s: %w", filePath, err)
     }
     return nil
@@ -0,0 +1,28 @@
+package video
+
+import (
+	"time"
+
+	"github.com/HeavenAQ/nstc-linebot-2025/commons"
+)
+
+// A cue clip shows the lead-in to the problem, the coaching pause on it and
+// a moment after.
+const (
+	clipLeadIn = time.Second
+	clipTail   = 1500 * time.Millisecond
+)
+
+// CueClipWindow returns where a cue's clip starts in the annotated student
+// video and how long it runs. A zero duration means the video's length is
+// unknown.
+func CueClipWindow(cue commons.CoachingCue, duration time.Duration) (start, length time.Duration) {
+	at := time.Duration(cue.StudentTimestampSeconds * float64(time.Second))
+	pause := time.Duration(cue.PauseDurationSeconds * float64(time.Second))
+	start = max(at-clipLeadIn, 0)
+	end := at + pause + clipTail
+	if duration > 0 {
+		end = min(end, duration)
+	}
+	return start, max(end-start, 0)
+}
@@ -0,0 +1,100 @@
+package video
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Limits bound what the analyzer can grade for a skill.
+type Limits struct {
+	MinDuration  time.Duration
+	MaxDuration  time.Duration
+	MinShortSide int
+	MinFrameRate float64
+	MaxSize      int64
+	Codecs       []string
+}
+
+// defaultLimits fit a single stroke filmed on a phone. LINE re-encodes videos
+// to H.264, so other codecs only arrive from unusual clients.
+var defaultLimits = Limits{
+	MinDuration:  2 * time.Second,
+	MaxDuration:  15 * time.Second,
+	MinShortSide: 360,
+	MinFrameRate: 24,
+	MaxSize:      100 << 20,
+	Codecs:       []string{"h264", "hevc", "mpeg4"},
+}
+
+// skillLimits overrides the defaults where a stroke needs less footage.
+var skillLimits = map[string]Limits{
+	"serve": withMaxDuration(defaultLimits, 10*time.Second),
+}
+
+func withMaxDuration(limits Limits, maxDuration time.Duration) Limits {
+	limits.MaxDuration = maxDuration
+	return limits
+}
+
+// LimitsFor returns the limits for a skill.
+func LimitsFor(skill string) Limits {
+	if limits, ok := skillLimits[skill]; ok {
+		return limits
+	}
+	return defaultLimits
+}
+
+// RejectedError lists what the student has to fix before re-uploading.
+type RejectedError struct {
+	Problems []string
+}
+
+func (e *RejectedError) Error() string {
+	return "video rejected: " + strings.Join(e.Problems, "; ")
+}
+
+// Check compares the video against the limits and returns a *RejectedError
+// naming every problem, or nil if the video can be analyzed.
+func (l Limits) Check(info Info) error {
+	var problems []string
+	if info.Duration < l.MinDuration || info.Duration > l.MaxDuration {
+		problems = append(problems, fmt.Sprintf(
+			"影片需介於 %s 到 %s 秒（目前 %.1f 秒）",
+			seconds(l.MinDuration), seconds(l.MaxDuration), info.Duration.Seconds(),
+		))
+	}
+	if shortSide := min(info.Width, info.Height); shortSide < l.MinShortSide {
+		problems = append(problems, fmt.Sprintf(
+			"影片解析度過低，短邊需至少 %d 像素（目前 %dx%d）", l.MinShortSide, info.Width, info.Height,
+		))
+	}
+	if info.FrameRate < l.MinFrameRate {
+		problems = append(problems, fmt.Sprintf(
+			"影片每秒需至少 %.0f 格（目前 %.1f 格），請關閉省電或縮時模式後重新拍攝", l.MinFrameRate, info.FrameRate,
+		))
+	}
+	if !slices.Contains(l.Codecs, info.Codec) {
+		problems = append(problems, fmt.Sprintf("不支援的影片格式（%s），請使用手機相機直接拍攝", info.Codec))
+	}
+	if info.Size > l.MaxSize {
+		problems = append(problems, fmt.Sprintf(
+			"影片檔案需小於 %d MB（目前 %.1f MB）", l.MaxSize>>20, float64(info.Size)/(1<<20),
+		))
+	}
+	if len(problems) > 0 {
+		return &RejectedError{Problems: problems}
+	}
+	return nil
+}
+
+// Oversized returns the *RejectedError for a video found to be larger than
+// MaxSize before all of it was read, so its size is not known.
+func (l Limits) Oversized() error {
+	return &RejectedError{Problems: []string{fmt.Sprintf("影片檔案需小於 %d MB", l.MaxSize>>20)}}
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%g", d.Seconds())
+}
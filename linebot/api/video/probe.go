@@ -0,0 +1,118 @@
+// Package video inspects uploaded videos with ffprobe, so clips the analyzer
+// cannot grade are turned away before they reach the GPU.
+package video
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// probeTimeout bounds ffprobe, which only reads the container headers.
+const probeTimeout = 15 * time.Second
+
+// ErrProbeUnavailable means ffprobe is not installed, e.g. on a development
+// machine. Callers skip the pre-flight check rather than refuse every upload.
+var ErrProbeUnavailable = errors.New("ffprobe is not installed")
+
+// Info is what the pre-flight check needs to know about a video.
+type Info struct {
+	Duration  time.Duration
+	Width     int
+	Height    int
+	FrameRate float64
+	Codec     string
+	Size      int64
+}
+
+// Probe runs ffprobe on the file at path.
+func Probe(ctx context.Context, path string) (Info, error) {
+	binary, err := exec.LookPath("ffprobe")
+	if err != nil {
+		return Info{}, ErrProbeUnavailable
+	}
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+	output, err := exec.CommandContext(
+		ctx, binary, "-v", "error", "-print_format", "json", "-show_format", "-show_streams", path,
+	).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return Info{}, fmt.Errorf("ffprobe: %w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return Info{}, fmt.Errorf("ffprobe: %w", err)
+	}
+	return ParseProbe(output)
+}
+
+type probeOutput struct {
+	Streams []struct {
+		CodecType    string `json:"codec_type"`
+		CodecName    string `json:"codec_name"`
+		Width        int    `json:"width"`
+		Height       int    `json:"height"`
+		AvgFrameRate string `json:"avg_frame_rate"`
+		RFrameRate   string `json:"r_frame_rate"`
+		Duration     string `json:"duration"`
+	} `json:"streams"`
+	Format struct {
+		Duration string `json:"duration"`
+		Size     string `json:"size"`
+	} `json:"format"`
+}
+
+// ParseProbe reads ffprobe's JSON output. The first video stream describes the
+// clip; the container's duration is used when the stream has none.
+func ParseProbe(data []byte) (Info, error) {
+	var output probeOutput
+	if err := json.Unmarshal(data, &output); err != nil {
+		return Info{}, fmt.Errorf("parse ffprobe output: %w", err)
+	}
+
+	for _, stream := range output.Streams {
+		if stream.CodecType != "video" {
+			continue
+		}
+		info := Info{
+			Width:     stream.Width,
+			Height:    stream.Height,
+			Codec:     stream.CodecName,
+			FrameRate: parseRate(stream.AvgFrameRate),
+		}
+		if info.FrameRate == 0 {
+			info.FrameRate = parseRate(stream.RFrameRate)
+		}
+		seconds, err := strconv.ParseFloat(stream.Duration, 64)
+		if err != nil {
+			seconds, _ = strconv.ParseFloat(output.Format.Duration, 64)
+		}
+		info.Duration = time.Duration(seconds * float64(time.Second))
+		info.Size, _ = strconv.ParseInt(output.Format.Size, 10, 64)
+		return info, nil
+	}
+	return Info{}, errors.New("no video stream found")
+}
+
+// parseRate reads ffprobe's "30000/1001" style rates.
+func parseRate(value string) float64 {
+	numerator, denominator, ok := strings.Cut(value, "/")
+	if !ok {
+		rate, _ := strconv.ParseFloat(value, 64)
+		return rate
+	}
+	n, err := strconv.ParseFloat(numerator, 64)
+	if err != nil {
+		return 0
+	}
+	d, err := strconv.ParseFloat(denominator, 64)
+	if err != nil || d == 0 {
+		return 0
+	}
+	return n / d
+}
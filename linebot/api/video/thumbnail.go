@@ -0,0 +1,45 @@
+package video
+
+import (
+	"time"
+
+	"github.com/HeavenAQ/nstc-linebot-2025/commons"
+)
+
+// fallbackThumbnailAt is used when the clip's length is not known.
+const fallbackThumbnailAt = time.Second
+
+// PhaseAt returns when the named phase is shown in the annotated student
+// video. The timeline counts the frames of the action alone, while the video
+// holds each cued frame for the cue's pause, so the pauses on earlier frames
+// are added. ok is false when the phase is missing or falls past duration; a
+// zero duration means the video's length is unknown.
+func PhaseAt(
+	timeline []commons.PhaseMarker, cues []commons.CoachingCue, phaseID string, duration time.Duration,
+) (at time.Duration, ok bool) {
+	for _, phase := range timeline {
+		if phase.ID != phaseID {
+			continue
+		}
+		seconds := phase.TimestampSeconds
+		paused := map[int32]bool{}
+		for _, cue := range cues {
+			if cue.NormalizedFrame < phase.NormalizedFrame && !paused[cue.NormalizedFrame] {
+				paused[cue.NormalizedFrame] = true
+				seconds += cue.PauseDurationSeconds
+			}
+		}
+		at = time.Duration(seconds * float64(time.Second))
+		return at, at >= 0 && (duration == 0 || at < duration)
+	}
+	return 0, false
+}
+
+// MidClipAt returns halfway through a clip of the given duration. A zero
+// duration means the clip's length is unknown.
+func MidClipAt(duration time.Duration) time.Duration {
+	if duration > 0 {
+		return duration / 2
+	}
+	return fallbackThumbnailAt
+}
@@ -0,0 +1,145 @@
+package video_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/HeavenAQ/nstc-linebot-2025/api/video"
+	"github.com/HeavenAQ/nstc-linebot-2025/commons"
+	"github.com/stretchr/testify/require"
+)
+
+// phoneProbe is trimmed ffprobe output for a portrait phone clip as LINE
+// delivers it.
+const phoneProbe = `{
+  "streams": [
+    {"codec_type": "audio", "codec_name": "aac", "duration": "6.040000"},
+    {
+      "codec_type": "video", "codec_name": "h264", "width": 720, "height": 1280,
+      "avg_frame_rate": "30000/1001", "r_frame_rate": "30/1", "duration": "6.006000"
+    }
+  ],
+  "format": {"duration": "6.040000", "size": "4194304"}
+}`
+
+func TestParseProbe(t *testing.T) {
+	info, err := video.ParseProbe([]byte(phoneProbe))
+
+	require.NoError(t, err)
+	require.Equal(t, "h264", info.Codec)
+	require.Equal(t, 720, info.Width)
+	require.Equal(t, 1280, info.Height)
+	require.InDelta(t, 29.97, info.FrameRate, 0.01)
+	require.Equal(t, 6006*time.Millisecond, info.Duration)
+	require.Equal(t, int64(4<<20), info.Size)
+}
+
+func TestParseProbeFallsBackToContainerDuration(t *testing.T) {
+	info, err := video.ParseProbe([]byte(`{
+		"streams": [{"codec_type": "video", "codec_name": "hevc", "width": 1920, "height": 1080, "avg_frame_rate": "0/0", "r_frame_rate": "60/1"}],
+		"format": {"duration": "3.5", "size": "100"}
+	}`))
+
+	require.NoError(t, err)
+	require.Equal(t, 3500*time.Millisecond, info.Duration)
+	require.Equal(t, 60.0, info.FrameRate)
+}
+
+func TestParseProbeRequiresVideoStream(t *testing.T) {
+	_, err := video.ParseProbe([]byte(`{"streams": [{"codec_type": "audio"}], "format": {}}`))
+	require.ErrorContains(t, err, "no video stream")
+}
+
+func TestCheckAcceptsTypicalClip(t *testing.T) {
+	info, err := video.ParseProbe([]byte(phoneProbe))
+	require.NoError(t, err)
+
+	require.NoError(t, video.LimitsFor("smash").Check(info))
+	require.NoError(t, video.LimitsFor("serve").Check(info))
+}
+
+func TestCheckNamesEveryProblem(t *testing.T) {
+	info := video.Info{
+		Duration:  12 * time.Second,
+		Width:     320,
+		Height:    240,
+		FrameRate: 15,
+		Codec:     "vp9",
+		Size:      120 << 20,
+	}
+
+	err := video.LimitsFor("serve").Check(info)
+
+	var rejected *video.RejectedError
+	require.True(t, errors.As(err, &rejected))
+	require.Equal(t, []string{
+		"影片需介於 2 到 10 秒（目前 12.0 秒）",
+		"影片解析度過低，短邊需至少 360 像素（目前 320x240）",
+		"影片每秒需至少 24 格（目前 15.0 格），請關閉省電或縮時模式後重新拍攝",
+		"不支援的影片格式（vp9），請使用手機相機直接拍攝",
+		"影片檔案需小於 100 MB（目前 120.0 MB）",
+	}, rejected.Problems)
+
+	info.Duration = time.Second
+	err = video.LimitsFor("clear").Check(info)
+	require.True(t, errors.As(err, &rejected))
+	require.Equal(t, "影片需介於 2 到 15 秒（目前 1.0 秒）", rejected.Problems[0])
+}
+
+var serveTimeline = []commons.PhaseMarker{
+	{ID: "start", NormalizedFrame: 0, TimestampSeconds: 0},
+	{ID: "transition", NormalizedFrame: 18, TimestampSeconds: 1.2},
+	{ID: "contact", NormalizedFrame: 38, TimestampSeconds: 2.5},
+	{ID: "end", NormalizedFrame: 63, TimestampSeconds: 3.9},
+}
+
+func TestPhaseAtConfiguredPhase(t *testing.T) {
+	at, ok := video.PhaseAt(serveTimeline, nil, "contact", 4*time.Second)
+	require.True(t, ok)
+	require.Equal(t, 2500*time.Millisecond, at)
+	at, ok = video.PhaseAt(serveTimeline, nil, "transition", 0)
+	require.True(t, ok)
+	require.Equal(t, 1200*time.Millisecond, at)
+}
+
+func TestPhaseAtAddsEarlierCoachingPauses(t *testing.T) {
+	cues := []commons.CoachingCue{
+		{NormalizedFrame: 10, PauseDurationSeconds: 2},
+		{NormalizedFrame: 10, PauseDurationSeconds: 2},
+		{NormalizedFrame: 38, PauseDurationSeconds: 2},
+		{NormalizedFrame: 50, PauseDurationSeconds: 2},
+	}
+	at, ok := video.PhaseAt(serveTimeline, cues, "contact", 10*time.Second)
+	require.True(t, ok)
+	require.Equal(t, 4500*time.Millisecond, at, "one pause for frame 10, none for the contact frame itself")
+}
+
+func TestPhaseAtMissesAbsentOrLatePhases(t *testing.T) {
+	_, ok := video.PhaseAt(serveTimeline, nil, "follow_through", 4*time.Second)
+	require.False(t, ok)
+	_, ok = video.PhaseAt(serveTimeline, nil, "contact", 2*time.Second)
+	require.False(t, ok, "phase past the end")
+	_, ok = video.PhaseAt(nil, nil, "contact", 6*time.Second)
+	require.False(t, ok)
+}
+
+func TestMidClipAt(t *testing.T) {
+	require.Equal(t, 3*time.Second, video.MidClipAt(6*time.Second))
+	require.Equal(t, time.Second, video.MidClipAt(0))
+}
+
+func TestCueClipWindow(t *testing.T) {
+	cue := commons.CoachingCue{StudentTimestampSeconds: 2.4, PauseDurationSeconds: 2}
+
+	start, length := video.CueClipWindow(cue, 10*time.Second)
+	require.Equal(t, 1400*time.Millisecond, start)
+	require.Equal(t, 4500*time.Millisecond, length)
+
+	start, length = video.CueClipWindow(commons.CoachingCue{StudentTimestampSeconds: 0.5, PauseDurationSeconds: 2}, 3*time.Second)
+	require.Equal(t, time.Duration(0), start, "clamped to the start")
+	require.Equal(t, 3*time.Second, length, "clamped to the end")
+
+	_, length = video.CueClipWindow(cue, 0)
+	require.Equal(t, 4500*time.Millisecond, length)
+}
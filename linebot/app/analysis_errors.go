@@ -0,0 +1,47 @@
+package app
+
+import (
+	"errors"
+
+	"github.com/HeavenAQ/nstc-linebot-2025/api/analysis"
+)
+
+// analysisErrorReplies tell the student what to change when the analysis
+// service rejects a video for a reason they can fix.
+var analysisErrorReplies = []struct {
+	err   error
+	reply string
+}{
+	{analysis.ErrNoPersonDetected, "影片中找不到球員。請讓全身都在畫面中，站在光線充足的地方，再重新拍攝上傳。"},
+	{analysis.ErrWrongSkill, "影片中沒有找到所選技術的完整動作。請確認選擇的技術與影片內容相同，或重新拍攝一次完整動作。"},
+	{analysis.ErrClipTooShort, "影片太短或球員入鏡時間太短。請從準備動作拍到收拍，讓整個動作都在畫面中。"},
+	{analysis.ErrVideoUnreadable, "無法讀取這部影片。請直接用手機相機重新錄製後上傳，不要傳送螢幕錄影或經過編輯的檔案。"},
+	{analysis.ErrNoMatchingExpert, "目前沒有同慣用手的專家影片可供比較，本次不會跨左右手評分。請聯絡教練新增同手別的專家資料。"},
+	{analysis.ErrServiceBusy, "分析服務暫時忙碌，請過幾分鐘再上傳一次影片。"},
+}
+
+// analysisErrorReply returns the guidance for err, if the student can act
+// on it.
+func analysisErrorReply(err error) (string, bool) {
+	for _, candidate := range analysisErrorReplies {
+		if errors.Is(err, candidate.err) {
+			return candidate.reply, true
+		}
+	}
+	return "", false
+}
+
+// analysisOutcome labels an analysis for metrics: ok, busy, rejected for a
+// video the student can fix, or error.
+func analysisOutcome(err error) string {
+	if err == nil {
+		return "ok"
+	}
+	if errors.Is(err, analysis.ErrServiceBusy) {
+		return "busy"
+	}
+	if _, ok := analysisErrorReply(err); ok {
+		return "rejected"
+	}
+	return "error"
+}
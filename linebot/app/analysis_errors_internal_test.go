@@ -0,0 +1,30 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/HeavenAQ/nstc-linebot-2025/api/analysis"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalysisErrorReply(t *testing.T) {
+	reply, ok := analysisErrorReply(fmt.Errorf("receive analysis: %w", analysis.ErrClipTooShort))
+	require.True(t, ok)
+	require.Contains(t, reply, "影片太短")
+
+	_, ok = analysisErrorReply(errors.New("analysis failed"))
+	require.False(t, ok)
+
+	for _, candidate := range analysisErrorReplies {
+		require.NotEmpty(t, candidate.reply)
+	}
+}
+
+func TestAnalysisOutcome(t *testing.T) {
+	require.Equal(t, "ok", analysisOutcome(nil))
+	require.Equal(t, "busy", analysisOutcome(fmt.Errorf("%w: breaker open", analysis.ErrServiceBusy)))
+	require.Equal(t, "rejected", analysisOutcome(analysis.ErrWrongSkill))
+	require.Equal(t, "error", analysisOutcome(errors.New("analysis failed")))
+}
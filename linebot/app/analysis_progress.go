@@ -0,0 +1,126 @@
+package app
+
+import (
+	"context"
+	"time"
+
+	"github.com/HeavenAQ/nstc-linebot-2025/api/analysis"
+	"github.com/HeavenAQ/nstc-linebot-2025/api/db"
+)
+
+// progressUpdates are the stages a student is told about while their video
+// is analyzed. Two messages keep push usage low.
+var progressUpdates = map[analysis.Stage]string{
+	analysis.StageCorrection: "已辨識出你的動作，正在和專家動作比對並評分⋯",
+	analysis.StageCoaching:   "評分完成，正在撰寫教練建議並製作影片，請再稍等一下。",
+}
+
+// loadingSeconds is how long each loading animation lasts. Analyses take
+// longer, so it is shown again at every stage.
+const loadingSeconds = 60
+
+// analysisJob tells a student how far their upload's analysis is and
+// records it for the LIFF to poll.
+type analysisJob struct {
+	app    *App
+	ctx    context.Context // tags the job's logs and stops its calls
+	userID string
+	job    db.AnalysisJob
+}
+
+func (app *App) startAnalysisJob(ctx context.Context, userID, requestID, skill string) *analysisJob {
+	now := time.Now().UTC()
+	job := &analysisJob{app: app, ctx: ctx, userID: userID, job: db.AnalysisJob{
+		RequestID: requestID, Skill: skill, State: db.AnalysisRunning, Stage: "upload", StartedAt: now,
+	}}
+	job.save()
+	job.showLoading()
+	return job
+}
+
+// save records the job. A job cut short is still recorded as failed rather
+// than left running, so the save is not cancelled with it.
+func (j *analysisJob) save() {
+	j.saveWithin(context.WithoutCancel(j.ctx))
+}
+
+// saveWithin records the job, giving up when ctx is done.
+func (j *analysisJob) saveWithin(ctx context.Context) {
+	j.job.UpdatedAt = time.Now().UTC()
+	if err := j.app.FirestoreClient.SaveAnalysisJob(ctx, j.userID, j.job); err != nil {
+		j.app.Logger.WarnContext(j.ctx, "failed to save analysis job", "error", err)
+	}
+}
+
+func (j *analysisJob) showLoading() {
+	if err := j.app.LineBot.ShowLoadingAnimation(j.ctx, j.userID, loadingSeconds); err != nil {
+		j.app.Logger.WarnContext(j.ctx, "failed to show loading animation", "error", err)
+	}
+}
+
+// follow reports each stage from progress until it is closed.
+func (j *analysisJob) follow(progress <-chan analysis.Stage) {
+	for stage := range progress {
+		j.job.Stage = string(stage)
+		j.save()
+		if text, ok := progressUpdates[stage]; ok {
+			if err := j.app.LineBot.PushText(j.ctx, j.userID, text); err != nil {
+				j.app.Logger.WarnContext(j.ctx, "failed to push analysis progress", "error", err)
+			}
+		}
+		j.showLoading()
+	}
+}
+
+// archived records where the original upload was stored, which an
+// interrupted job can be resumed from.
+func (j *analysisJob) archived(originalVideo string) {
+	j.job.OriginalVideo = originalVideo
+	j.save()
+}
+
+// done records the work the analysis was saved as.
+func (j *analysisJob) done(workDate string) {
+	j.job.State = db.AnalysisDone
+	j.job.WorkDate = workDate
+	j.save()
+}
+
+// fail records what the student was told went wrong.
+func (j *analysisJob) fail(message string) {
+	j.job.State = db.AnalysisFailed
+	j.job.Message = message
+	j.save()
+}
+
+// interruptedMessage tells a student their analysis was stopped by a
+// shutdown and how to resume it.
+const interruptedMessage = "系統正在更新，這次分析被中斷了，請稍後再上傳一次影片。"
+
+// end fails a job that is still running, for the error paths that reply
+// without saying why, or marks it interrupted if the app stopped it. Defer it
+// once the job starts.
+func (j *analysisJob) end() {
+	if j.job.State != db.AnalysisRunning {
+		return
+	}
+	if j.app.stopped(j.ctx) {
+		j.interrupt()
+		return
+	}
+	j.fail("分析沒有完成，請再上傳一次影片。")
+}
+
+// interrupt records that the app stopped the job and pushes the student the
+// reason, since nothing replies to them during a shutdown. Both calls share
+// stopTimeout, so they finish before the instance is killed.
+func (j *analysisJob) interrupt() {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(j.ctx), stopTimeout)
+	defer cancel()
+	j.job.State = db.AnalysisInterrupted
+	j.job.Message = interruptedMessage
+	j.saveWithin(ctx)
+	if err := j.app.LineBot.PushText(ctx, j.userID, interruptedMessage); err != nil {
+		j.app.Logger.WarnContext(j.ctx, "failed to push analysis interruption", "error", err)
+	}
+}
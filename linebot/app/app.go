@@ -1,7 +1,13 @@
 package app
 
 import (
+	"context"
+	"errors"
+	"log/slog"
 	"os"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/HeavenAQ/nstc-linebot-2025/api/analysis"
 	"github.com/HeavenAQ/nstc-linebot-2025/api/db"
@@ -10,21 +16,39 @@ import (
 	"github.com/HeavenAQ/nstc-linebot-2025/api/secret"
 	"github.com/HeavenAQ/nstc-linebot-2025/api/storage"
 	"github.com/HeavenAQ/nstc-linebot-2025/config"
+	"github.com/HeavenAQ/nstc-linebot-2025/health"
+	"github.com/HeavenAQ/nstc-linebot-2025/logging"
+	"github.com/HeavenAQ/nstc-linebot-2025/notify"
+	"github.com/HeavenAQ/nstc-linebot-2025/playback"
+	"github.com/HeavenAQ/nstc-linebot-2025/reanalysis"
+	"github.com/HeavenAQ/nstc-linebot-2025/scheduler"
 )
 
 type App struct {
 	Config          *config.Config
-	Logger          *Logger
+	Logger          *slog.Logger
 	LineBot         *line.Client
 	FirestoreClient *db.FirestoreClient
 	StorageClient   *storage.BucketClient
 	GPTClient       *gpt.Client
 	AnalysisClient  *analysis.Client
+	Notifier        *notify.Runner
+	Scheduler       *scheduler.Scheduler
+	Reanalysis      *reanalysis.Runner
+	Playback        *playback.Cache
+	Health          *health.Checker
+
+	// ctx lasts as long as the app; work that outlives its request, such as
+	// an analysis, is cancelled with it rather than with the request.
+	ctx    context.Context
+	cancel context.CancelFunc
+	// webhooks counts the webhooks being handled, for Shutdown to wait on.
+	webhooks sync.WaitGroup
+	// draining is set once Shutdown starts, so optional work is skipped.
+	draining atomic.Bool
 }
 
 func NewApp(configPath string) *App {
-	// Set up logger
-	logger := NewLogger()
 	testMode := os.Getenv("SKIP_EXTERNAL_CLIENTS") == "1"
 
 	// Download env file only when not in test mode and when the config file does not exist locally
@@ -42,18 +66,32 @@ func NewApp(configPath string) *App {
 		panic(err)
 	}
 
+	// Set up logger
+	level, err := logging.ParseLevel(cfg.Logging.Level)
+	if err != nil {
+		panic(err)
+	}
+	logger := logging.New(os.Stdout, level)
+
 	// Set up the LineBot client
 	lineBot, err := line.NewBotClient(cfg.Line.ChannelSecret, cfg.Line.ChannelToken, cfg.GCP.Storage.BucketName)
 	if err != nil {
 		panic(err)
 	}
 
-	// When in test mode, skip external clients (Firestore, Storage, GPT)
+	lineBot.SetTimeout(cfg.Line.Timeout)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// When in test mode, skip external clients (Firestore, Storage, GPT) and
+	// the checks that would reach them
 	if testMode {
 		return &App{
 			Config:  cfg,
 			Logger:  logger,
 			LineBot: lineBot,
+			Health:  health.NewChecker(cfg.Health.CheckTimeout),
+			ctx:     ctx,
+			cancel:  cancel,
 		}
 	}
 
@@ -66,17 +104,18 @@ func NewApp(configPath string) *App {
 	if err != nil {
 		panic(err)
 	}
+	firestoreClient.Timeout = cfg.GCP.Database.Timeout
+	firestoreClient.RankCacheTTL = cfg.Ranking.CacheTTL
 
 	// Set up Cloud Storage client
-	storageClient, err := storage.NewBucketClient(
-		cfg.GCP.Storage.BucketName,
-	)
+	storageClient, err := storage.Open(cfg.GCP.Storage)
 	if err != nil {
 		panic(err)
 	}
 
 	// Set up GPT Client
 	gptClient := gpt.NewGPTClient(cfg.GPT.APIKey, cfg.GPT.PromptID, cfg.GPT.RewriteModel)
+	gptClient.Timeout = cfg.GPT.Timeout
 
 	analysisClient, err := analysis.NewClient(
 		cfg.AnalysisServer.Target,
@@ -86,8 +125,23 @@ func NewApp(configPath string) *App {
 	if err != nil {
 		panic(err)
 	}
+	analysisClient.SetTimeout(cfg.AnalysisServer.Timeout)
+	analysisClient.SetRetryPolicy(
+		analysis.RetryPolicy{
+			Attempts:  cfg.AnalysisServer.RetryAttempts,
+			BaseDelay: cfg.AnalysisServer.RetryBaseDelay,
+			MaxDelay:  cfg.AnalysisServer.RetryMaxDelay,
+		},
+		analysis.NewBreaker(cfg.AnalysisServer.BreakerFailures, cfg.AnalysisServer.BreakerCooldown),
+	)
+
+	var signers []playback.SignFunc
+	if cfg.Playback.LocalSigning {
+		signers = append(signers, measuredSigner("local", playback.LocalSigner(storageClient, cfg.Playback.URLLifetime)))
+	}
+	signers = append(signers, measuredSigner("analysis", analysisClient.RefreshPlaybackURLs))
 
-	return &App{
+	app := &App{
 		Config:          cfg,
 		Logger:          logger,
 		LineBot:         lineBot,
@@ -95,5 +149,119 @@ func NewApp(configPath string) *App {
 		StorageClient:   storageClient,
 		GPTClient:       gptClient,
 		AnalysisClient:  analysisClient,
+		Notifier:        notify.NewRunner(firestoreClient, lineBot, cfg.Notification),
+		Scheduler:       scheduler.New(firestoreClient, cfg.Scheduler.LeaseTTL, logger),
+		Reanalysis:      reanalysis.NewRunner(firestoreClient, storageClient, analysisClient, logger),
+		Playback:        playback.NewCache(cfg.Playback.MinRemaining, signers...),
+		Health:          health.NewChecker(cfg.Health.CheckTimeout),
+		ctx:             ctx,
+		cancel:          cancel,
 	}
+	lineBot.SetURLSigner(app.signURLs)
+	app.registerHealthChecks()
+	if err := app.registerJobs(); err != nil {
+		panic(err)
+	}
+	return app
+}
+
+// Context returns the app's lifetime context, which is cancelled by Stop.
+func (app *App) Context() context.Context {
+	if app.ctx == nil {
+		return context.Background()
+	}
+	return app.ctx
+}
+
+// Stop cancels the work running under the app's lifetime context.
+func (app *App) Stop() {
+	if app.cancel != nil {
+		app.cancel()
+	}
+}
+
+// stopTimeout is how long Shutdown gives cancelled work to record where it
+// stopped.
+const stopTimeout = 2 * time.Second
+
+// Shutdown drains the app. It waits for the webhooks, re-analysis and
+// scheduled jobs in progress until ctx is done, then cancels what is left,
+// gives it stopTimeout to record where it stopped and closes the clients. The
+// HTTP server should stop taking requests first.
+func (app *App) Shutdown(ctx context.Context) error {
+	app.draining.Store(true)
+	if app.Scheduler != nil {
+		app.Scheduler.Stop()
+	}
+	drained := app.wait(ctx)
+	if !drained {
+		app.Logger.WarnContext(ctx, "grace period over, cancelling work in progress")
+	}
+	app.Stop()
+	if !drained {
+		stopCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), stopTimeout)
+		defer cancel()
+		if !app.wait(stopCtx) {
+			app.Logger.WarnContext(ctx, "cancelled work did not stop in time")
+		}
+	}
+	return app.close()
+}
+
+// wait reports whether the work in progress finished before ctx was done.
+func (app *App) wait(ctx context.Context) bool {
+	done := make(chan struct{})
+	go func() {
+		app.webhooks.Wait()
+		if app.Reanalysis != nil {
+			app.Reanalysis.Wait()
+		}
+		if app.Scheduler != nil {
+			app.Scheduler.Wait()
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// close closes the clients that hold connections.
+func (app *App) close() error {
+	var errs []error
+	if app.AnalysisClient != nil {
+		errs = append(errs, app.AnalysisClient.Close())
+	}
+	if app.FirestoreClient != nil {
+		errs = append(errs, app.FirestoreClient.Close())
+	}
+	if app.StorageClient != nil {
+		errs = append(errs, app.StorageClient.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// stopped reports whether ctx was cancelled because the app stopped.
+func (app *App) stopped(ctx context.Context) bool {
+	return ctx.Err() != nil && app.Context().Err() != nil
+}
+
+// Detach returns a context carrying ctx's values, such as its logging fields
+// and trace, that is cancelled when the app stops rather than with ctx.
+func (app *App) Detach(ctx context.Context) context.Context {
+	return detached{Context: app.Context(), values: ctx}
+}
+
+// detached takes its cancellation from the embedded context and its values
+// from another.
+type detached struct {
+	context.Context
+	values context.Context
+}
+
+func (d detached) Value(key any) any {
+	return d.values.Value(key)
 }
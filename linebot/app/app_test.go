@@ -1,6 +1,7 @@
 package app_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/HeavenAQ/nstc-linebot-2025/app"
@@ -27,4 +28,32 @@ func TestNewApp(t *testing.T) {
 
 	// Check that the LineBot client is initialized
 	require.NotNil(t, app.LineBot, "LineBot should not be nil")
+
+	// Check that readiness has nothing external to check
+	require.NotNil(t, app.Health, "Health should not be nil")
+	statuses, ready := app.Health.Run(context.Background())
+	require.True(t, ready)
+	require.Empty(t, statuses)
+}
+
+type ctxKey struct{}
+
+func TestDetach(t *testing.T) {
+	t.Setenv("SKIP_EXTERNAL_CLIENTS", "1")
+	t.Setenv("LINE_CHANNEL_SECRET", "test-channel-secret")
+	t.Setenv("LINE_CHANNEL_TOKEN", "test-channel-token")
+	t.Setenv("GCS_BUCKET_NAME", "test-bucket")
+	app := app.NewApp("../.env")
+
+	reqCtx, cancelReq := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "request"))
+	ctx := app.Detach(reqCtx)
+
+	// The request ending leaves the detached work running, with its values
+	cancelReq()
+	require.NoError(t, ctx.Err())
+	require.Equal(t, "request", ctx.Value(ctxKey{}))
+
+	// Stopping the app cancels it
+	app.Stop()
+	require.ErrorIs(t, ctx.Err(), context.Canceled)
 }
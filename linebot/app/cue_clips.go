@@ -0,0 +1,85 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/HeavenAQ/nstc-linebot-2025/api/db"
+	"github.com/HeavenAQ/nstc-linebot-2025/api/storage"
+	"github.com/HeavenAQ/nstc-linebot-2025/api/video"
+	"github.com/HeavenAQ/nstc-linebot-2025/commons"
+)
+
+// maxCueClips keeps the clips within one LINE reply, after the message
+// listing their titles, and each clip with its preview within one
+// RefreshPlaybackURLs batch.
+const maxCueClips = 4
+
+// createCueClips cuts a short clip around each coaching cue from the
+// annotated student video and stores it next to that video. Clips are a
+// bonus: a cue that cannot be cut is logged and left out, and none are cut
+// once the app starts shutting down.
+func (app *App) createCueClips(ctx context.Context, analysis *commons.AnalysisOutcome) []db.CueClip {
+	source := analysis.StudentVideo
+	if source.SignedURL == "" || source.ObjectPath == "" || len(analysis.CoachingCues) == 0 {
+		return nil
+	}
+	directory, err := os.MkdirTemp(tmpFolder, "linebot-cues-")
+	if err != nil {
+		app.Logger.WarnContext(ctx, "cue clips skipped", "error", err)
+		return nil
+	}
+	defer os.RemoveAll(directory)
+
+	duration := time.Duration(source.DurationSeconds * float64(time.Second))
+	prefix := path.Join(path.Dir(source.ObjectPath), "cues")
+	var clips []db.CueClip
+	for i, cue := range analysis.CoachingCues[:min(len(analysis.CoachingCues), maxCueClips)] {
+		if ctx.Err() != nil || app.draining.Load() {
+			app.Logger.WarnContext(ctx, "remaining cue clips skipped", "from", i)
+			break
+		}
+		clip, err := app.createCueClip(ctx, source.SignedURL, directory, prefix, i, cue, duration)
+		if err != nil {
+			app.Logger.WarnContext(ctx, "cue clip skipped", "cue", i, "analysis_id", analysis.AnalysisID, "error", err)
+			continue
+		}
+		clips = append(clips, clip)
+	}
+	return clips
+}
+
+func (app *App) createCueClip(
+	ctx context.Context,
+	sourceURL, directory, prefix string, index int, cue commons.CoachingCue, duration time.Duration,
+) (db.CueClip, error) {
+	clipPath := filepath.Join(directory, fmt.Sprintf("%d.mp4", index))
+	previewPath := filepath.Join(directory, fmt.Sprintf("%d.jpeg", index))
+	start, length := video.CueClipWindow(cue, duration)
+	if err := cutClip(ctx, sourceURL, start, length, clipPath); err != nil {
+		return db.CueClip{}, fmt.Errorf("cut clip: %w", err)
+	}
+	at := time.Duration(cue.StudentTimestampSeconds * float64(time.Second))
+	if err := grabFrame(ctx, sourceURL, at, previewPath); err != nil {
+		return db.CueClip{}, fmt.Errorf("grab preview: %w", err)
+	}
+
+	fileInfo := storage.FileInfo{}
+	fileInfo.Bucket.VideoPath = fmt.Sprintf("%s/%d.mp4", prefix, index)
+	fileInfo.Bucket.ThumbnailPath = fmt.Sprintf("%s/%d.jpeg", prefix, index)
+	fileInfo.Local.VideoPath = clipPath
+	fileInfo.Local.ThumbnailPath = previewPath
+	clip, err := app.StorageClient.UploadVideo(ctx, &fileInfo)
+	if err != nil {
+		return db.CueClip{}, err
+	}
+	preview, err := app.StorageClient.UploadThumbnail(ctx, &fileInfo)
+	if err != nil {
+		return db.CueClip{}, err
+	}
+	return db.CueClip{Title: cue.Title, Video: clip.Path, Preview: preview.Path}, nil
+}
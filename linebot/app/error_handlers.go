@@ -1,140 +1,162 @@
 package app
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/HeavenAQ/nstc-linebot-2025/api/line"
 	"github.com/line/line-bot-sdk-go/v7/linebot"
 )
 
-func handleLineMessageResponseError(err error) {
+func (app *App) handleLineMessageResponseError(ctx context.Context, err error) {
 	if err != nil {
-		fmt.Println("Error sending type error message: ", err)
+		app.Logger.ErrorContext(ctx, "Error sending LINE message", "error", err)
 		return
 	}
 }
 
-func (app *App) handleLineError(errMsg string, successMsg string) func(err error, replyToken string) {
-	return func(err error, replyToken string) {
+func (app *App) handleLineError(errMsg string, successMsg string) func(ctx context.Context, err error, replyToken string) {
+	return func(ctx context.Context, err error, replyToken string) {
 		if err != nil {
-			app.Logger.Error.Println(errMsg, err)
-			_, err = app.LineBot.SendDefaultErrorReply(replyToken)
-			handleLineMessageResponseError(err)
+			app.Logger.ErrorContext(ctx, errMsg, "error", err)
+			_, err = app.LineBot.SendDefaultErrorReply(ctx, replyToken)
+			app.handleLineMessageResponseError(ctx, err)
 			return
 		}
-		app.Logger.Info.Println(successMsg)
+		app.Logger.InfoContext(ctx, successMsg)
 	}
 }
 
-func (app *App) handleUpdateSessionError(err error, replyToken string) {
+func (app *App) handleUpdateSessionError(ctx context.Context, err error, replyToken string) {
 	app.handleLineError(
 		"Error updating session user state",
 		"Session user state has been updated",
-	)(err, replyToken)
+	)(ctx, err, replyToken)
 }
 
-func (app *App) handleVideoUploadPromptError(err error, replyToken string) {
+func (app *App) handleVideoUploadPromptError(ctx context.Context, err error, replyToken string) {
 	app.handleLineError(
 		"Error prompting video upload",
 		"Video upload has been prompted",
-	)(err, replyToken)
+	)(ctx, err, replyToken)
 }
 
-func (app *App) handleGetVideoError(err error, replyToken string) {
+func (app *App) handleGetVideoError(ctx context.Context, err error, replyToken string) {
 	app.handleLineError(
 		"Error getting the video",
 		"Video content has been received",
-	)(err, replyToken)
+	)(ctx, err, replyToken)
 }
 
-func (app *App) handleThumbnailCreationError(err error, replyToken string) {
+func (app *App) handleThumbnailCreationError(ctx context.Context, err error, replyToken string) {
 	app.handleLineError(
 		"Error creating a thumbnail for the video",
 		"A Thumbnail has been created!",
-	)(err, replyToken)
+	)(ctx, err, replyToken)
 }
 
-func (app *App) handleUploadToDriveError(err error, replyToken string) {
+func (app *App) handleUploadToDriveError(ctx context.Context, err error, replyToken string) {
     // Despite the historical name, this handles Cloud Storage (GCS) uploads.
     app.handleLineError(
         "Error uploading the video to Cloud Storage",
         "Video has been uploaded to Cloud Storage successfully",
-    )(err, replyToken)
+    )(ctx, err, replyToken)
 }
 
-func (app *App) handleSendingReplyMessageError(err error, replyToken string) {
+func (app *App) handleSendingReplyMessageError(ctx context.Context, err error, replyToken string) {
 	app.handleLineError(
 		"Failed to send reply messages through LINE",
 		"Reply messages has been sent",
-	)(err, replyToken)
+	)(ctx, err, replyToken)
 }
 
-func (app *App) handleUpdateUserPortfolioError(err error, replyToken string) {
+func (app *App) handleUpdateUserPortfolioError(ctx context.Context, err error, replyToken string) {
 	app.handleLineError(
 		"Failed to update user portfolio",
 		"The user portfolio has been updated successfully",
-	)(err, replyToken)
+	)(ctx, err, replyToken)
 }
 
-func (app *App) handlePostbackDataTypeError(err error, replyToken string) {
+func (app *App) handlePostbackDataTypeError(ctx context.Context, err error, replyToken string) {
 	app.handleLineError(
 		"Error handling postback data type casting",
 		"Postback data type has been handled",
-	)(err, replyToken)
+	)(ctx, err, replyToken)
 }
 
-func (app *App) handleSendPortfolioError(err error, replyToken string) {
+func (app *App) handleSendPortfolioError(ctx context.Context, err error, replyToken string) {
 	if err, ok := err.(*line.NoPortfolioError); ok {
-		app.Logger.Info.Println(err)
-		err := app.LineBot.SendNoPortfolioReply(replyToken, err.Skill)
-		handleLineMessageResponseError(err)
+		app.Logger.InfoContext(ctx, "No portfolio to send", "error", err)
+		err := app.LineBot.SendNoPortfolioReply(ctx, replyToken, err.Skill)
+		app.handleLineMessageResponseError(ctx, err)
 		return
 	}
 
 	app.handleLineError(
 		"Error sending portfolio",
 		"Portfolio has been sent",
-	)(err, replyToken)
+	)(ctx, err, replyToken)
 }
 
-func (app *App) handleSendExpertVideosError(err error, replyToken string) {
+func (app *App) handleSendExpertVideosError(ctx context.Context, err error, replyToken string) {
 	app.handleLineError(
 		"Error sending expert videos",
 		"Expert videos has been sent",
-	)(err, replyToken)
+	)(ctx, err, replyToken)
 }
 
-func (app *App) handleMessageResponseError(res *linebot.BasicResponse, err error, replyToken string) {
+func (app *App) handleMessageResponseError(ctx context.Context, res *linebot.BasicResponse, err error, replyToken string) {
 	app.handleLineError(
 		"Error sending message",
 		fmt.Sprintf("Message sent successfully. Response from LINE: %v", res),
-	)(err, replyToken)
+	)(ctx, err, replyToken)
 }
 
-func (app *App) handleVideoAnalysisError(err error, replyToken string) {
+func (app *App) handleVideoAnalysisError(ctx context.Context, err error, replyToken string) {
 	app.handleLineError(
 		"Error analyzing the video",
 		"Video has been analyzed",
-	)(err, replyToken)
+	)(ctx, err, replyToken)
 }
 
-func (app *App) handleAddMessageToGPTConversationError(err error, replyToken string) {
+func (app *App) handleGetRankError(ctx context.Context, err error, replyToken string) {
+	app.handleLineError(
+		"Error computing class ranking",
+		"Class ranking has been computed",
+	)(ctx, err, replyToken)
+}
+
+func (app *App) handleGetAssignmentsError(ctx context.Context, err error, replyToken string) {
+	app.handleLineError(
+		"Error listing assignments",
+		"Assignments have been listed",
+	)(ctx, err, replyToken)
+}
+
+func (app *App) handleUpdateNotificationsError(ctx context.Context, err error, replyToken string) {
+	app.handleLineError(
+		"Error updating notification preference",
+		"Notification preference has been updated",
+	)(ctx, err, replyToken)
+}
+
+func (app *App) handleAddMessageToGPTConversationError(ctx context.Context, err error, replyToken string) {
     app.handleLineError(
         "Error adding message to GPT conversation",
         "Message has been added to GPT conversation.",
-    )(err, replyToken)
+    )(ctx, err, replyToken)
 }
 
-func (app *App) handleGPTRunConversationError(err error, replyToken string) {
+func (app *App) handleGPTRunConversationError(ctx context.Context, err error, replyToken string) {
     app.handleLineError(
         "Error running GPT conversation",
         "GPT conversation has been run. Response from LINE: %v",
-    )(err, replyToken)
+    )(ctx, err, replyToken)
 }
 
-func (app *App) handleGetGPTResponseError(err error, replyToken string) {
+func (app *App) handleGetGPTResponseError(ctx context.Context, err error, replyToken string) {
     app.handleLineError(
         "Error getting GPT response",
         "GPT response has been received. Response from LINE: %v",
-    )(err, replyToken)
+    )(ctx, err, replyToken)
 }
@@ -1,35 +1,64 @@
 package app
 
-import "github.com/line/line-bot-sdk-go/v7/linebot"
+import (
+	"context"
+	"time"
 
-func (app *App) handleEvents(events []*linebot.Event) {
+	"github.com/HeavenAQ/nstc-linebot-2025/logging"
+	"github.com/HeavenAQ/nstc-linebot-2025/metrics"
+	"github.com/HeavenAQ/nstc-linebot-2025/tracing"
+	"github.com/line/line-bot-sdk-go/v7/linebot"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func (app *App) handleEvents(ctx context.Context, events []*linebot.Event) {
 	for _, event := range events {
-		user := app.createUserIfNotExist(event.Source.UserID)
-		session := app.createUserSessionIfNotExist(event.Source.UserID)
-
-		switch event.Type {
-		case linebot.EventTypeFollow:
-			app.handleFollowEvent(event)
-		case linebot.EventTypeMessage:
-			app.handleMessageEvent(event, user, session)
-		case linebot.EventTypePostback:
-			app.handlePostbackEvent(event, user, session)
-		default:
-			app.handleUnsupportedEvent(event)
-		}
+		app.handleEvent(ctx, event)
+	}
+}
+
+// handleEvent handles one webhook event, counting and timing it by type and
+// tracing it in a span. Its logs carry the event ID, the hashed user ID and
+// the session's skill and state.
+func (app *App) handleEvent(ctx context.Context, event *linebot.Event) {
+	start := time.Now()
+	eventType := string(event.Type)
+	metrics.WebhookEvents.WithLabelValues(eventType).Inc()
+	ctx, span := tracing.Start(ctx, "line.event", attribute.String("line.event_type", eventType))
+	defer func() {
+		metrics.WebhookEventDuration.WithLabelValues(eventType).Observe(metrics.Since(start))
+		span.End()
+	}()
+
+	ctx = logging.WithUser(logging.WithEvent(ctx, event.WebhookEventID), event.Source.UserID)
+	user := app.createUserIfNotExist(ctx, event.Source.UserID)
+	session := app.createUserSessionIfNotExist(ctx, event.Source.UserID)
+	if session != nil {
+		ctx = logging.WithState(logging.WithSkill(ctx, session.Skill), session.StateLabel())
+	}
+
+	switch event.Type {
+	case linebot.EventTypeFollow:
+		app.handleFollowEvent(ctx, event)
+	case linebot.EventTypeMessage:
+		app.handleMessageEvent(ctx, event, user, session)
+	case linebot.EventTypePostback:
+		app.handlePostbackEvent(ctx, event, user, session)
+	default:
+		app.handleUnsupportedEvent(ctx, event)
 	}
 }
 
-func (app *App) handleFollowEvent(event *linebot.Event) {
-	app.Logger.Info.Printf("Follow event received. New user ID: %s", event.Source.UserID)
-	res, err := app.LineBot.SendWelcomeReply(event)
-	app.handleMessageResponseError(res, err, event.ReplyToken)
+func (app *App) handleFollowEvent(ctx context.Context, event *linebot.Event) {
+	app.Logger.InfoContext(ctx, "Follow event received")
+	res, err := app.LineBot.SendWelcomeReply(ctx, event)
+	app.handleMessageResponseError(ctx, res, err, event.ReplyToken)
 }
 
-func (app *App) handleUnsupportedEvent(event *linebot.Event) {
-	app.Logger.Warn.Printf("Unsupported event type: %s", event.Type)
-	_, err := app.LineBot.SendDefaultErrorReply(event.ReplyToken)
+func (app *App) handleUnsupportedEvent(ctx context.Context, event *linebot.Event) {
+	app.Logger.WarnContext(ctx, "Unsupported event type", "type", event.Type)
+	_, err := app.LineBot.SendDefaultErrorReply(ctx, event.ReplyToken)
 	if err != nil {
-		app.Logger.Warn.Println("Error sending type error message:", err)
+		app.Logger.WarnContext(ctx, "Error sending type error message", "error", err)
 	}
 }
@@ -0,0 +1,23 @@
+package app
+
+// registerHealthChecks adds a check for every external client the app was
+// built with. Checking the analysis service wakes its GPU instance, so a
+// deployment that lets it scale to zero can leave it to the admin status.
+func (app *App) registerHealthChecks() {
+	if app.FirestoreClient != nil {
+		app.Health.Add("firestore", app.FirestoreClient.Ping)
+	}
+	if app.StorageClient != nil {
+		app.Health.Add("storage", app.StorageClient.Ping)
+	}
+	if app.LineBot != nil {
+		app.Health.Add("line", app.LineBot.CheckToken)
+	}
+	if app.AnalysisClient != nil {
+		if app.Config.Health.AnalysisReportOnly {
+			app.Health.AddReportOnly("analysis", app.AnalysisClient.Ready)
+		} else {
+			app.Health.Add("analysis", app.AnalysisClient.Ready)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/HeavenAQ/nstc-linebot-2025/scheduler"
+)
+
+// warmupTimeout bounds a warm-up ping, which may have to wait for a cold start.
+const warmupTimeout = 30 * time.Second
+
+// registerJobs adds the periodic work to the scheduler. Jobs for features that
+// are switched off are not registered.
+func (app *App) registerJobs() error {
+	if app.Config.Notification.Enabled {
+		err := app.Scheduler.Register("notifications", app.Config.Notification.Schedule, func(ctx context.Context) error {
+			result, err := app.Notifier.Run(ctx, time.Now())
+			app.Logger.InfoContext(ctx, "notification run", "result", result)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := app.registerPrewarm(); err != nil {
+		return err
+	}
+
+	if spec := app.Config.AnalysisServer.WarmupSchedule; spec != "" {
+		err := app.Scheduler.Register("analysis-warmup", spec, func(ctx context.Context) error {
+			ctx, cancel := context.WithTimeout(ctx, warmupTimeout)
+			defer cancel()
+			return app.AnalysisClient.Health(ctx)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// registerPrewarm pings the analysis service around each class so students
+// uploading at once do not wait for cold starts. Every ping is recorded as a
+// run of the job.
+func (app *App) registerPrewarm() error {
+	cfg := app.Config.AnalysisServer
+	table, err := scheduler.ParseTimetable(cfg.ClassTimetable)
+	if err != nil || table.Empty() {
+		return err
+	}
+	if cfg.PrewarmInterval <= 0 {
+		return errors.New("ANALYSIS_PREWARM_INTERVAL must be positive")
+	}
+	spec := fmt.Sprintf("%s (lead %s, every %s)", cfg.ClassTimetable, cfg.PrewarmLead, cfg.PrewarmInterval)
+	return app.Scheduler.RegisterTiming("analysis-prewarm", spec, table.Warmup(cfg.PrewarmLead, cfg.PrewarmInterval), func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, warmupTimeout)
+		defer cancel()
+		return app.AnalysisClient.Health(ctx)
+	})
+}
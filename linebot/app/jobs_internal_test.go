@@ -0,0 +1,52 @@
+package app
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/HeavenAQ/nstc-linebot-2025/api/db"
+	"github.com/HeavenAQ/nstc-linebot-2025/config"
+	"github.com/HeavenAQ/nstc-linebot-2025/scheduler"
+	"github.com/stretchr/testify/require"
+)
+
+type noStore struct{}
+
+func (noStore) AcquireLease(context.Context, string, string, time.Duration) (bool, error) {
+	return false, nil
+}
+func (noStore) ReleaseLease(context.Context, string, string) error             { return nil }
+func (noStore) RecordJobRun(context.Context, db.JobRun) error                  { return nil }
+func (noStore) GetLatestJobRuns(context.Context) (map[string]db.JobRun, error) { return nil, nil }
+
+func prewarmApp(timetable string, interval time.Duration) *App {
+	cfg := &config.Config{}
+	cfg.AnalysisServer.ClassTimetable = timetable
+	cfg.AnalysisServer.PrewarmLead = 15 * time.Minute
+	cfg.AnalysisServer.PrewarmInterval = interval
+	return &App{Config: cfg, Scheduler: scheduler.New(noStore{}, time.Minute, slog.New(slog.NewTextHandler(io.Discard, nil)))}
+}
+
+func TestRegisterJobsAddsPrewarmForTimetable(t *testing.T) {
+	app := prewarmApp("mon 08:10-10:00", 5*time.Minute)
+	require.NoError(t, app.registerJobs())
+	jobs, err := app.Scheduler.Jobs(context.Background())
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	require.Equal(t, "analysis-prewarm", jobs[0].Name)
+	require.Equal(t, "mon 08:10-10:00 (lead 15m0s, every 5m0s)", jobs[0].Spec)
+
+	app = prewarmApp("", 5*time.Minute)
+	require.NoError(t, app.registerJobs())
+	jobs, err = app.Scheduler.Jobs(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, jobs)
+}
+
+func TestRegisterJobsRejectsInvalidTimetables(t *testing.T) {
+	require.Error(t, prewarmApp("monday 8-10", 5*time.Minute).registerJobs())
+	require.Error(t, prewarmApp("mon 08:10-10:00", 0).registerJobs())
+}
@@ -1,45 +1,110 @@
 package app
 
 import (
+	"context"
+	"strings"
+	"time"
+
 	"github.com/HeavenAQ/nstc-linebot-2025/api/db"
 	"github.com/line/line-bot-sdk-go/v7/linebot"
 )
 
-func (app *App) handleMessageEvent(event *linebot.Event, user *db.UserData, session *db.UserSession) {
+// rankCommand is the text a student sends to see their class ranking.
+const rankCommand = "我的排名"
+
+// assignmentsCommand is the text the rich menu's assignment button sends.
+const assignmentsCommand = "我的作業"
+
+// Texts a student sends to turn push notifications off and back on.
+const (
+	notificationsOffCommand = "關閉通知"
+	notificationsOnCommand  = "開啟通知"
+)
+
+func (app *App) handleMessageEvent(ctx context.Context, event *linebot.Event, user *db.UserData, session *db.UserSession) {
 	message, ok := event.Message.(*linebot.TextMessage)
 	if !ok {
-		app.handleNonTextMessage(event, session, user)
+		app.handleNonTextMessage(ctx, event, session, user)
 		return
 	}
-	app.handleTextMessage(event, message, user, session)
+	app.handleTextMessage(ctx, event, message, user, session)
 }
 
-func (app *App) handleNonTextMessage(event *linebot.Event, session *db.UserSession, user *db.UserData) {
+func (app *App) handleNonTextMessage(ctx context.Context, event *linebot.Event, session *db.UserSession, user *db.UserData) {
 	if _, ok := event.Message.(*linebot.VideoMessage); ok {
-		app.Logger.Info.Println("Video message received")
-		app.handleUploadingVideo(event, session, user, event.ReplyToken)
+		app.Logger.InfoContext(ctx, "Video message received")
+		app.handleUploadingVideo(ctx, event, session, user, event.ReplyToken)
 	} else {
-		app.Logger.Warn.Println("The message type is not supported")
+		app.Logger.WarnContext(ctx, "The message type is not supported")
 	}
 }
 
-func (app *App) handleTextMessage(event *linebot.Event, message *linebot.TextMessage, user *db.UserData, session *db.UserSession) {
+func (app *App) handleTextMessage(ctx context.Context, event *linebot.Event, message *linebot.TextMessage, user *db.UserData, session *db.UserSession) {
+	switch strings.TrimSpace(message.Text) {
+	case rankCommand:
+		app.handleRankCommand(ctx, user, event.ReplyToken)
+		return
+	case assignmentsCommand:
+		app.handleAssignmentsCommand(ctx, user, event.ReplyToken)
+		return
+	case notificationsOffCommand:
+		app.handleNotificationsCommand(ctx, user, true, event.ReplyToken)
+		return
+	case notificationsOnCommand:
+		app.handleNotificationsCommand(ctx, user, false, event.ReplyToken)
+		return
+	}
+
 	incomingState, err := db.UserStateChnStrToEnum(message.Text)
 	if err != nil {
-		app.Logger.Info.Println("Incoming message is not a rich menu message; handling as a reflection note")
-		app.handleUserState(event, user, session, event.ReplyToken)
+		app.Logger.InfoContext(ctx, "Incoming message is not a rich menu message; handling as a reflection note")
+		app.handleUserState(ctx, event, user, session, event.ReplyToken)
+		return
+	}
+	app.handleRichMenuMessage(ctx, incomingState, user, session.UserState, event.ReplyToken)
+}
+
+func (app *App) handleRankCommand(ctx context.Context, user *db.UserData, replyToken string) {
+	rank, err := app.FirestoreClient.GetUserRank(ctx, user.ID, db.BadmintonSkillNames(), app.Config.Ranking.MinCohort)
+	if err != nil {
+		app.handleGetRankError(ctx, err, replyToken)
 		return
 	}
-	app.handleRichMenuMessage(incomingState, user, session.UserState, event.ReplyToken)
+	res, err := app.LineBot.SendRankReply(ctx, replyToken, rank)
+	app.handleMessageResponseError(ctx, res, err, replyToken)
+}
+
+func (app *App) handleAssignmentsCommand(ctx context.Context, user *db.UserData, replyToken string) {
+	assignments, err := app.FirestoreClient.ListAssignments(ctx, user.ClassID)
+	if err != nil {
+		app.handleGetAssignmentsError(ctx, err, replyToken)
+		return
+	}
+	res, err := app.LineBot.SendAssignmentsReply(ctx, replyToken, db.OpenAssignments(assignments, *user, time.Now()))
+	app.handleMessageResponseError(ctx, res, err, replyToken)
+}
+
+func (app *App) handleNotificationsCommand(ctx context.Context, user *db.UserData, off bool, replyToken string) {
+	if err := app.FirestoreClient.UpdateUserNotifications(ctx, user.ID, off); err != nil {
+		app.handleUpdateNotificationsError(ctx, err, replyToken)
+		return
+	}
+	reply := "已開啟提醒通知，將會提醒您作業截止、填寫反思並寄送每週學習摘要。"
+	if off {
+		reply = "已關閉提醒通知，如需重新開啟，請輸入「開啟通知」。"
+	}
+	res, err := app.LineBot.SendReply(ctx, replyToken, reply)
+	app.handleMessageResponseError(ctx, res, err, replyToken)
 }
 
-func (app *App) handleUnsupportedMessage(replyToken string) {
-	app.Logger.Warn.Println("Unsupported message type")
-	_, err := app.LineBot.SendDefaultReply(replyToken)
-	handleLineMessageResponseError(err)
+func (app *App) handleUnsupportedMessage(ctx context.Context, replyToken string) {
+	app.Logger.WarnContext(ctx, "Unsupported message type")
+	_, err := app.LineBot.SendDefaultReply(ctx, replyToken)
+	app.handleLineMessageResponseError(ctx, err)
 }
 
 func (app *App) handleRichMenuMessage(
+	ctx context.Context,
 	incomingState db.UserState,
 	user *db.UserData,
 	userState db.UserState,
@@ -47,18 +112,18 @@ func (app *App) handleRichMenuMessage(
 ) {
 	switch incomingState {
 	case db.ReadingInstruction:
-		app.processReadingInstruction(user, replyToken)
+		app.processReadingInstruction(ctx, user, replyToken)
 	case db.ViewingPortfoilo:
-		app.processViewingPortfolio(user, userState, replyToken)
+		app.processViewingPortfolio(ctx, user, userState, replyToken)
 	case db.ViewingExpertVideos:
-		app.processViewingExpertVideos(user, userState, replyToken)
+		app.processViewingExpertVideos(ctx, user, userState, replyToken)
 	case db.AnalyzingVideo:
-		app.processAnalyzingVideo(user, userState, replyToken)
+		app.processAnalyzingVideo(ctx, user, userState, replyToken)
 	case db.WritingNotes:
-		app.processWritingNotes(user, userState, replyToken)
+		app.processWritingNotes(ctx, user, userState, replyToken)
 	case db.ChattingWithGPT:
-		app.processChattingWithGPT(user, userState, replyToken)
+		app.processChattingWithGPT(ctx, user, userState, replyToken)
 	default:
-		app.handleUnsupportedMessage(replyToken)
+		app.handleUnsupportedMessage(ctx, replyToken)
 	}
 }
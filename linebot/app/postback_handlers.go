@@ -3,14 +3,17 @@ package app
 import (
 	"context"
 	"errors"
+	"io"
 	"os"
 	"path/filepath"
 	"time"
 
-	"github.com/HeavenAQ/nstc-linebot-2025/api/analysis"
 	"github.com/HeavenAQ/nstc-linebot-2025/api/db"
 	"github.com/HeavenAQ/nstc-linebot-2025/api/gpt"
 	"github.com/HeavenAQ/nstc-linebot-2025/api/line"
+	"github.com/HeavenAQ/nstc-linebot-2025/api/video"
+	"github.com/HeavenAQ/nstc-linebot-2025/logging"
+	"github.com/HeavenAQ/nstc-linebot-2025/tracing"
 	"github.com/line/line-bot-sdk-go/v7/linebot"
 )
 
@@ -21,63 +24,69 @@ import (
 // handlePostbackEvent processes LINE postback events.
 // - If it’s a menu-switch event, it’s ignored.
 // - Otherwise, it delegates to handleUserState.
-func (app *App) handlePostbackEvent(event *linebot.Event, user *db.UserData, session *db.UserSession) {
+func (app *App) handlePostbackEvent(ctx context.Context, event *linebot.Event, user *db.UserData, session *db.UserSession) {
 	if isMenuSwitchEvent(event.Postback.Data) {
-		app.Logger.Info.Printf("Menu switch event ignored. User ID: %v", event.Source.UserID)
+		app.Logger.InfoContext(ctx, "Menu switch event ignored")
 		return
 	}
 
-	app.Logger.Info.Printf("Postback event received. User ID: %v", event.Source.UserID)
-	app.handleUserState(event, user, session, event.ReplyToken)
+	app.Logger.InfoContext(ctx, "Postback event received")
+	app.handleUserState(ctx, event, user, session, event.ReplyToken)
 }
 
 // handleUserState manages the user's session state, routing to the appropriate
 // handler function based on the session’s current state.
-func (app *App) handleUserState(event *linebot.Event, user *db.UserData, session *db.UserSession, replyToken string) {
+func (app *App) handleUserState(ctx context.Context, event *linebot.Event, user *db.UserData, session *db.UserSession, replyToken string) {
 	rawData := getPostbackData(event)
-	app.Logger.Info.Println("rawData: ", rawData)
+	app.Logger.DebugContext(ctx, "Postback data received", "data", rawData)
 
 	// 1. GPT stop-chatting action
 	if data, ok := app.isStopChattingWithGPTAction(rawData); ok {
 		if data.Stop {
-			app.FirestoreClient.ResetSession(user.ID)
-			app.LineBot.SendReply(replyToken, "已結束對話")
+			app.FirestoreClient.ResetSession(ctx, user.ID)
+			app.LineBot.SendReply(ctx, replyToken, "已結束對話")
 			return
 		}
 	}
 
 	// 2. Note updating action
 	if data, ok := app.isUpdateNoteAction(rawData); ok {
-		app.forceStateToWritingNotes(user, session, data, replyToken)
+		app.forceStateToWritingNotes(ctx, user, session, data, replyToken)
 		return
 	}
 
 	// 3. Video watching action
 	if data, ok := app.isWatchVideoAction(rawData); ok {
-		app.handleWatchPortfolioVideo(user, data, replyToken)
+		app.handleWatchPortfolioVideo(ctx, user, data, replyToken)
+		return
+	}
+
+	// 3b. Coaching-cue clips
+	if data, ok := app.isWatchCueClipsAction(rawData); ok {
+		app.handleWatchCueClips(ctx, user, data, replyToken)
 		return
 	}
 
 	// 4. Ask AI for help
 	if data, ok := app.isAnalyzingPortfolioWithGPT(rawData); ok {
-		app.handleAnalyzePortfolioWithGPT(event, user, data, session, replyToken)
+		app.handleAnalyzePortfolioWithGPT(ctx, event, user, data, session, replyToken)
 		return
 	}
 
 	// 5. Route by user state
 	switch session.UserState {
 	case db.WritingNotes:
-		app.handleWritingNotes(event, rawData, user, session, replyToken)
+		app.handleWritingNotes(ctx, event, rawData, user, session, replyToken)
 	case db.ChattingWithGPT:
-		app.handleChattingWithGPT(event, rawData, user, session, replyToken)
+		app.handleChattingWithGPT(ctx, event, rawData, user, session, replyToken)
 	case db.ViewingExpertVideos:
-		app.handleViewingExpertVideos(event, rawData, user, session, replyToken)
+		app.handleViewingExpertVideos(ctx, event, rawData, user, session, replyToken)
 	case db.ViewingPortfoilo:
-		app.handleViewingPortfolio(event, rawData, user, session, replyToken)
+		app.handleViewingPortfolio(ctx, event, rawData, user, session, replyToken)
 	case db.AnalyzingVideo:
-		app.handleAnalyzingVideoActions(event, rawData, user, session, replyToken)
+		app.handleAnalyzingVideoActions(ctx, event, rawData, user, session, replyToken)
 	default:
-		app.handleInvalidActionStep(user.ID, replyToken)
+		app.handleInvalidActionStep(ctx, user.ID, replyToken)
 	}
 }
 
@@ -86,65 +95,67 @@ func (app *App) handleUserState(event *linebot.Event, user *db.UserData, session
 // ============================================================================
 
 // handleWritingNotes handles logic for the “WritingNotes” state.
-func (app *App) handleWritingNotes(event *linebot.Event, rawData string, user *db.UserData, session *db.UserSession, replyToken string) {
+func (app *App) handleWritingNotes(ctx context.Context, event *linebot.Event, rawData string, user *db.UserData, session *db.UserSession, replyToken string) {
 	switch session.ActionStep {
 	case db.SelectingSkill:
 		// Move to “SelectingPortfolio” after skill selection
 		session.ActionStep = db.SelectingPortfolio
 		data, err := app.LineBot.HandleSelectingSkillPostbackData(rawData)
 		if err != nil {
-			app.handlePostbackDataTypeError(err, replyToken)
+			app.handlePostbackDataTypeError(ctx, err, replyToken)
 			return
 		}
 		session.Skill = data.Skill
 
-		if err := app.FirestoreClient.UpdateUserSession(user.ID, *session); err != nil {
-			app.handleUpdateSessionError(err, replyToken)
+		if err := app.FirestoreClient.UpdateUserSession(ctx, user.ID, *session); err != nil {
+			app.handleUpdateSessionError(ctx, err, replyToken)
 			return
 		}
 
 		// Prompt user to select which portfolio entry to update
 		if err := app.LineBot.SendPortfolio(
+			ctx,
 			event,
 			user,
+			app.classRubric(ctx, user),
 			db.SkillStrToEnum(data.Skill),
 			session.Handedness,
 			session.UserState,
 			"請選擇您要更新的學習歷程：",
 			true,
 		); err != nil {
-			app.handleSendPortfolioError(err, replyToken)
+			app.handleSendPortfolioError(ctx, err, replyToken)
 			return
 		}
 
 	case db.SelectingPortfolio:
-		app.handleSelectingPortfolio(rawData, user, session, replyToken)
+		app.handleSelectingPortfolio(ctx, rawData, user, session, replyToken)
 
 	case db.WritingPreviewNote, db.WritingReflection:
-		app.handleUpdatingNote(event, user, session)
-		app.FirestoreClient.ResetSession(user.ID)
+		app.handleUpdatingNote(ctx, event, user, session)
+		app.FirestoreClient.ResetSession(ctx, user.ID)
 
 	default:
-		app.handleInvalidActionStep(user.ID, replyToken)
+		app.handleInvalidActionStep(ctx, user.ID, replyToken)
 	}
 }
 
 // handleChattingWithGPT handles logic for the “ChattingWithGPT” state.
-func (app *App) handleChattingWithGPT(event *linebot.Event, rawData string, user *db.UserData, session *db.UserSession, replyToken string) {
+func (app *App) handleChattingWithGPT(ctx context.Context, event *linebot.Event, rawData string, user *db.UserData, session *db.UserSession, replyToken string) {
 	switch session.ActionStep {
 	case db.SelectingSkill:
 		// Move to “Chatting”
 		session.ActionStep = db.Chatting
 		lineData, err := app.LineBot.HandleSelectingSkillPostbackData(rawData)
 		if err != nil {
-			app.handlePostbackDataTypeError(err, replyToken)
+			app.handlePostbackDataTypeError(ctx, err, replyToken)
 			return
 		}
 		session.Skill = lineData.Skill
-		app.FirestoreClient.UpdateUserSession(user.ID, *session)
+		app.FirestoreClient.UpdateUserSession(ctx, user.ID, *session)
 
 		// Inform user we are entering GPT chatting mode
-		app.LineBot.SendGPTChattingModeReply(replyToken, "已進入和GPT對話模式")
+		app.LineBot.SendGPTChattingModeReply(ctx, replyToken, "已進入和GPT對話模式")
 
 	case db.Chatting:
 		// Get user text message
@@ -155,9 +166,9 @@ func (app *App) handleChattingWithGPT(event *linebot.Event, rawData string, user
 		}
 
 		// Resolve omitted references against persisted, skill-specific history.
-		history, err := app.FirestoreClient.GetChatHistory(user.ID)
+		history, err := app.FirestoreClient.GetChatHistory(ctx, user.ID)
 		if err != nil {
-			app.handleAddMessageToGPTConversationError(err, replyToken)
+			app.handleAddMessageToGPTConversationError(ctx, err, replyToken)
 			return
 		}
 		rewriteHistory := make([]gpt.HistoryMessage, 0, 12)
@@ -166,103 +177,107 @@ func (app *App) handleChattingWithGPT(event *linebot.Event, rawData string, user
 				rewriteHistory = append(rewriteHistory, gpt.HistoryMessage{Role: value.Role, Text: value.Text})
 			}
 		}
-		rewritten, err := app.GPTClient.RewriteQuery(rewriteHistory, msg)
+		rewritten, err := app.GPTClient.RewriteQuery(ctx, rewriteHistory, msg)
 		if err != nil {
-			app.handleAddMessageToGPTConversationError(err, replyToken)
+			app.handleAddMessageToGPTConversationError(ctx, err, replyToken)
 			return
 		}
 
 		// Send the standalone query through the skill conversation.
 		conversationID := app.getUserGPTConversation(user, session.Skill)
-		response, err := app.GPTClient.AddMessageToConversation(conversationID, rewritten)
+		response, err := app.GPTClient.AddMessageToConversation(ctx, conversationID, rewritten)
 		if err != nil {
-			app.handleAddMessageToGPTConversationError(err, replyToken)
+			app.handleAddMessageToGPTConversationError(ctx, err, replyToken)
 			return
 		}
 
 		// Persist the user/assistant exchange to Firestore chat history
-		if err := app.FirestoreClient.AppendChatExchange(
+		err = app.FirestoreClient.AppendChatExchange(
+			ctx,
 			user.ID,
 			session.Skill,
 			conversationID,
 			msg,
 			response,
-		); err != nil {
-			app.Logger.Error.Printf("failed to append chat history: %v\n", err)
+		)
+		if err != nil {
+			app.Logger.ErrorContext(ctx, "failed to append chat history", "error", err)
 		}
 
-		if _, err := app.LineBot.SendGPTChattingModeReply(replyToken, response); err != nil {
-			handleLineMessageResponseError(err)
+		if _, err := app.LineBot.SendGPTChattingModeReply(ctx, replyToken, response); err != nil {
+			app.handleLineMessageResponseError(ctx, err)
 			return
 		}
 
 	default:
-		app.handleInvalidActionStep(user.ID, replyToken)
+		app.handleInvalidActionStep(ctx, user.ID, replyToken)
 	}
 }
 
 // handleViewingExpertVideos handles logic for the “ViewingExpertVideos” state.
-func (app *App) handleViewingExpertVideos(event *linebot.Event, rawData string, user *db.UserData, session *db.UserSession, replyToken string) {
+func (app *App) handleViewingExpertVideos(ctx context.Context, event *linebot.Event, rawData string, user *db.UserData, session *db.UserSession, replyToken string) {
 	switch session.ActionStep {
 	case db.SelectingSkill:
 		session.ActionStep = db.SelectingHandedness
-		app.handleSelectingSkill(event, session, rawData, replyToken, app.LineBot.PromptHandednessSelection)
+		app.handleSelectingSkill(ctx, event, session, rawData, replyToken, app.LineBot.PromptHandednessSelection)
 
 	case db.SelectingHandedness:
-		app.handleSendingExpertVideos(event, session, replyToken)
-		app.resetSessionWithErrorHandling(user.ID, replyToken)
+		app.handleSendingExpertVideos(ctx, event, session, replyToken)
+		app.resetSessionWithErrorHandling(ctx, user.ID, replyToken)
 
 	default:
-		app.handleInvalidActionStep(user.ID, replyToken)
+		app.handleInvalidActionStep(ctx, user.ID, replyToken)
 	}
 }
 
 // handleViewingPortfolio handles logic for the “ViewingPortfoilo” state.
-func (app *App) handleViewingPortfolio(event *linebot.Event, rawData string, user *db.UserData, session *db.UserSession, replyToken string) {
+func (app *App) handleViewingPortfolio(ctx context.Context, event *linebot.Event, rawData string, user *db.UserData, session *db.UserSession, replyToken string) {
 	data, err := app.LineBot.HandleSelectingSkillPostbackData(rawData)
 	if err != nil {
-		app.handlePostbackDataTypeError(err, replyToken)
+		app.handlePostbackDataTypeError(ctx, err, replyToken)
 		return
 	}
 
 	if err := app.LineBot.SendPortfolio(
+		ctx,
 		event,
 		user,
+		app.classRubric(ctx, user),
 		db.SkillStrToEnum(data.Skill),
 		session.Handedness,
 		session.UserState,
 		"以下為您的學習歷程：",
 		false,
 	); err != nil {
-		app.handleSendPortfolioError(err, replyToken)
+		app.handleSendPortfolioError(ctx, err, replyToken)
 		return
 	}
 
-	app.resetSessionWithErrorHandling(user.ID, replyToken)
+	app.resetSessionWithErrorHandling(ctx, user.ID, replyToken)
 }
 
 // handleAnalyzingVideoActions handles logic for the “AnalyzingVideo” state.
-func (app *App) handleAnalyzingVideoActions(event *linebot.Event, rawData string, user *db.UserData, session *db.UserSession, replyToken string) {
+func (app *App) handleAnalyzingVideoActions(ctx context.Context, event *linebot.Event, rawData string, user *db.UserData, session *db.UserSession, replyToken string) {
 	switch session.ActionStep {
 	case db.SelectingSkill:
 		session.ActionStep = db.SelectingHandedness
-		app.handleSelectingSkill(event, session, rawData, replyToken, app.LineBot.PromptHandednessSelection)
+		app.handleSelectingSkill(ctx, event, session, rawData, replyToken, app.LineBot.PromptHandednessSelection)
 
 	case db.SelectingHandedness:
 		session.ActionStep = db.UploadingVideo
 		data, err := app.LineBot.HandleSelectingHandednessPostbackData(rawData)
 		if err != nil {
-			app.handlePostbackDataTypeError(err, replyToken)
+			app.handlePostbackDataTypeError(ctx, err, replyToken)
 			return
 		}
-		app.FirestoreClient.UpdateSessionHandedness(user.ID, data.Handedness)
-		app.LineBot.PromptUploadVideo(event)
+		app.FirestoreClient.UpdateSessionHandedness(ctx, user.ID, data.Handedness)
+		app.LineBot.PromptUploadVideo(ctx, event)
 
 	case db.UploadingVideo:
-		app.handleUploadingVideo(event, session, user, replyToken)
+		app.handleUploadingVideo(ctx, event, session, user, replyToken)
 
 	default:
-		app.handleInvalidActionStep(user.ID, replyToken)
+		app.handleInvalidActionStep(ctx, user.ID, replyToken)
 	}
 }
 
@@ -271,11 +286,11 @@ func (app *App) handleAnalyzingVideoActions(event *linebot.Event, rawData string
 // ============================================================================
 
 // forceStateToWritingNotes forces the session to WritingNotes, prompting the user.
-func (app *App) forceStateToWritingNotes(user *db.UserData, session *db.UserSession, data *line.WritingNotePostback, replyToken string) {
+func (app *App) forceStateToWritingNotes(ctx context.Context, user *db.UserData, session *db.UserSession, data *line.WritingNotePostback, replyToken string) {
 	actionStep, err := db.ActionStepStrToEnum(data.ActionStep)
 	if err != nil {
-		app.Logger.Warn.Println("Invalid action step for updating note")
-		app.FirestoreClient.ResetSession(user.ID)
+		app.Logger.WarnContext(ctx, "Invalid action step for updating note")
+		app.FirestoreClient.ResetSession(ctx, user.ID)
 		return
 	}
 
@@ -284,49 +299,49 @@ func (app *App) forceStateToWritingNotes(user *db.UserData, session *db.UserSess
 	session.UserState = db.WritingNotes
 	session.Skill = data.Skill
 
-	if err := app.FirestoreClient.UpdateUserSession(user.ID, *session); err != nil {
-		app.handleUpdateSessionError(err, replyToken)
+	if err := app.FirestoreClient.UpdateUserSession(ctx, user.ID, *session); err != nil {
+		app.handleUpdateSessionError(ctx, err, replyToken)
 		return
 	}
 
 	msg := generateUpdateNoteMessage(data.WorkDate, data.Skill, actionStep)
-	app.LineBot.SendReply(replyToken, msg)
+	app.LineBot.SendReply(ctx, replyToken, msg)
 }
 
 // handleSelectingPortfolio is invoked when selecting which portfolio entry to update.
-func (app *App) handleSelectingPortfolio(rawData string, user *db.UserData, session *db.UserSession, replyToken string) {
+func (app *App) handleSelectingPortfolio(ctx context.Context, rawData string, user *db.UserData, session *db.UserSession, replyToken string) {
 	data, err := app.LineBot.HandleWritingNotePostbackData(rawData)
 	if err != nil {
-		app.handlePostbackDataTypeError(err, replyToken)
+		app.handlePostbackDataTypeError(ctx, err, replyToken)
 		return
 	}
 
 	actionStep, err := db.ActionStepStrToEnum(data.ActionStep)
 	if err != nil {
-		app.handlePostbackDataTypeError(err, replyToken)
+		app.handlePostbackDataTypeError(ctx, err, replyToken)
 		return
 	}
 
 	session.ActionStep = actionStep
 	session.UpdatedDate = data.WorkDate
 
-	if err := app.FirestoreClient.UpdateUserSession(user.ID, *session); err != nil {
-		app.handleUpdateSessionError(err, replyToken)
+	if err := app.FirestoreClient.UpdateUserSession(ctx, user.ID, *session); err != nil {
+		app.handleUpdateSessionError(ctx, err, replyToken)
 	}
 }
 
 // handleUpdatingNote updates the preview or reflection note in the user’s portfolio.
-func (app *App) handleUpdatingNote(event *linebot.Event, user *db.UserData, session *db.UserSession) {
+func (app *App) handleUpdatingNote(ctx context.Context, event *linebot.Event, user *db.UserData, session *db.UserSession) {
 	if session.ActionStep != db.WritingPreviewNote && session.ActionStep != db.WritingReflection {
-		app.Logger.Warn.Println("Invalid action step for updating note")
-		app.handleInvalidActionStep(user.ID, event.ReplyToken)
+		app.Logger.WarnContext(ctx, "Invalid action step for updating note")
+		app.handleInvalidActionStep(ctx, user.ID, event.ReplyToken)
 		return
 	}
 
 	note, ok := event.Message.(*linebot.TextMessage)
 	if !ok {
-		app.Logger.Warn.Println("Non-text message received when updating note")
-		app.FirestoreClient.ResetSession(user.ID)
+		app.Logger.WarnContext(ctx, "Non-text message received when updating note")
+		app.FirestoreClient.ResetSession(ctx, user.ID)
 		return
 	}
 
@@ -334,6 +349,7 @@ func (app *App) handleUpdatingNote(event *linebot.Event, user *db.UserData, sess
 
 	if session.ActionStep == db.WritingPreviewNote {
 		app.FirestoreClient.UpdateUserPortfolioPreviewNote(
+			ctx,
 			user,
 			&portfolio,
 			session.UpdatedDate,
@@ -341,6 +357,7 @@ func (app *App) handleUpdatingNote(event *linebot.Event, user *db.UserData, sess
 		)
 	} else {
 		app.FirestoreClient.UpdateUserPortfolioReflection(
+			ctx,
 			user,
 			&portfolio,
 			session.UpdatedDate,
@@ -349,8 +366,10 @@ func (app *App) handleUpdatingNote(event *linebot.Event, user *db.UserData, sess
 	}
 
 	app.LineBot.SendPortfolio(
+		ctx,
 		event,
 		user,
+		app.classRubric(ctx, user),
 		db.SkillStrToEnum(session.Skill),
 		session.Handedness,
 		session.UserState,
@@ -361,6 +380,7 @@ func (app *App) handleUpdatingNote(event *linebot.Event, user *db.UserData, sess
 
 // handleAnalyzePortfolioWithGPT processes the user's request to ask GPT for help.
 func (app *App) handleAnalyzePortfolioWithGPT(
+	ctx context.Context,
 	_ *linebot.Event,
 	user *db.UserData,
 	data *line.AnalyzingWithGPTPostback,
@@ -370,14 +390,15 @@ func (app *App) handleAnalyzePortfolioWithGPT(
 	portfolio := app.getUserPortfolio(user, data.Skill)
 	work, ok := (*portfolio)[data.WorkDate]
 	if !ok || work.AINote == "" {
-		app.LineBot.SendReply(replyToken, "這次分析尚未產生教練建議，請重新上傳影片")
+		app.LineBot.SendReply(ctx, replyToken, "這次分析尚未產生教練建議，請重新上傳影片")
 		return
 	}
 
-	app.LineBot.SendReply(replyToken, work.AINote)
+	app.LineBot.SendReply(ctx, replyToken, work.AINote)
 }
 
 func (app *App) handleWatchPortfolioVideo(
+	ctx context.Context,
 	user *db.UserData,
 	data *line.VideoPostback,
 	replyToken string,
@@ -385,96 +406,216 @@ func (app *App) handleWatchPortfolioVideo(
 	portfolio := user.Portfolio.GetSkillPortfolio(data.Skill)
 	work, ok := portfolio[data.WorkDate]
 	if !ok {
-		app.LineBot.SendReply(replyToken, "找不到這次影片紀錄，請重新開啟學習歷程")
+		app.LineBot.SendReply(ctx, replyToken, "找不到這次影片紀錄，請重新開啟學習歷程")
 		return
 	}
 
 	videoURL := work.SkeletonVideo
 	if work.StudentVideo.ObjectPath != "" {
-		videos, err := app.AnalysisClient.RefreshPlaybackURLs(
-			context.Background(), work.StudentVideo.ObjectPath,
-		)
-		if err == nil && len(videos) == 1 {
+		videos, err := app.Playback.Resolve(ctx, work.StudentVideo)
+		if err == nil {
 			videoURL = videos[0].SignedURL
 		} else if work.StudentVideo.SignedURLExpires <= time.Now().Unix() {
-			app.Logger.Error.Printf("failed to refresh portfolio video URL: %v", err)
-			app.LineBot.SendReply(replyToken, "影片連結更新失敗，請稍後再試")
+			app.Logger.ErrorContext(ctx, "failed to refresh portfolio video URL", "error", err)
+			app.LineBot.SendReply(ctx, replyToken, "影片連結更新失敗，請稍後再試")
 			return
 		}
 	}
 
 	_, err := app.LineBot.SendVideoMessage(
+		ctx,
 		replyToken, videoURL, work.Thumbnail,
 	)
 	if err != nil {
-		app.Logger.Error.Printf("failed to send portfolio video through LINE: %v", err)
+		app.Logger.ErrorContext(ctx, "failed to send portfolio video through LINE", "error", err)
+	}
+}
+
+// handleWatchCueClips sends the work's coaching-cue clips.
+func (app *App) handleWatchCueClips(
+	ctx context.Context,
+	user *db.UserData,
+	data *line.CueClipsPostback,
+	replyToken string,
+) {
+	work, ok := user.Portfolio.GetSkillPortfolio(data.Skill)[data.WorkDate]
+	if !ok || len(work.CueClips) == 0 {
+		app.LineBot.SendReply(ctx, replyToken, "這次分析沒有重點片段")
+		return
+	}
+
+	paths := make([]string, 0, 2*len(work.CueClips))
+	for _, clip := range work.CueClips {
+		paths = append(paths, clip.Video, clip.Preview)
+	}
+	media, err := app.Playback.URLs(ctx, paths...)
+	if err != nil {
+		app.Logger.ErrorContext(ctx, "failed to sign cue clip URLs", "error", err)
+		app.LineBot.SendReply(ctx, replyToken, "影片連結更新失敗，請稍後再試")
+		return
+	}
+	clips := make([]line.CueClipMessage, 0, len(work.CueClips))
+	for i, clip := range work.CueClips {
+		clips = append(clips, line.CueClipMessage{
+			Title:      clip.Title,
+			VideoURL:   media[2*i].SignedURL,
+			PreviewURL: media[2*i+1].SignedURL,
+		})
+	}
+	if _, err := app.LineBot.SendCueClips(ctx, replyToken, clips); err != nil {
+		app.Logger.ErrorContext(ctx, "failed to send cue clips through LINE", "error", err)
 	}
 }
 
 // handleUploadingVideo processes video uploads, calls AI analysis, and updates the portfolio.
-func (app *App) handleUploadingVideo(event *linebot.Event, session *db.UserSession, user *db.UserData, replyToken string) {
-	// Get video content
-	videoContent, err := app.getVideoContent(event, user.ID)
+func (app *App) handleUploadingVideo(ctx context.Context, event *linebot.Event, session *db.UserSession, user *db.UserData, replyToken string) {
+	// An assignment is submitted when the video is sent, not once the
+	// analysis, which can take minutes, is done
+	submittedAt := event.Timestamp
+	if submittedAt.IsZero() {
+		submittedAt = time.Now()
+	}
+
+	// Stream the video content to disk
+	videoContent, err := app.getVideoContent(ctx, event, user.ID)
+	if err != nil {
+		app.handleGetVideoError(ctx, err, replyToken)
+		return
+	}
+	videoPath, err := app.saveVideo(videoContent, user.ID, session.Skill)
+	videoContent.Close()
+	var rejected *video.RejectedError
+	if errors.As(err, &rejected) {
+		app.Logger.InfoContext(ctx, "video rejected while downloading", "error", err)
+		_, replyErr := app.LineBot.SendVideoRejectedReply(ctx, replyToken, rejected.Problems)
+		app.handleLineMessageResponseError(ctx, replyErr)
+		return
+	}
 	if err != nil {
-		app.handleGetVideoError(err, replyToken)
+		app.handleGetVideoError(ctx, err, replyToken)
+		return
+	}
+	defer os.RemoveAll(filepath.Dir(videoPath))
+
+	// Check the clip can be graded before spending GPU time on it
+	var upload video.Info
+	err = tracing.Run(ctx, "video.preflight", func(ctx context.Context) error {
+		var err error
+		upload, err = app.preflightVideo(ctx, videoPath, session.Skill)
+		return err
+	})
+	if errors.As(err, &rejected) {
+		app.Logger.InfoContext(ctx, "video rejected before analysis", "error", err)
+		_, replyErr := app.LineBot.SendVideoRejectedReply(ctx, replyToken, rejected.Problems)
+		app.handleLineMessageResponseError(ctx, replyErr)
 		return
 	}
 
 	// Send video to AI server for analysis
 	videoMessage, ok := event.Message.(*linebot.VideoMessage)
 	if !ok {
-		app.handleVideoAnalysisError(errors.New("uploaded message is not a video"), replyToken)
+		app.handleVideoAnalysisError(ctx, errors.New("uploaded message is not a video"), replyToken)
 		return
 	}
+	ctx = logging.WithRequest(ctx, videoMessage.ID)
+	job := app.startAnalysisJob(ctx, user.ID, videoMessage.ID, session.Skill)
+	defer job.end()
+
+	// Archive the original before the analysis, so a job cut short by a
+	// shutdown can be resumed from it
+	timestamp := time.Now().Format("2006-01-02-15-04")
+	originalVideo := app.archiveVideo(ctx, user, videoPath, session.Skill, timestamp)
+	if originalVideo != "" {
+		job.archived(originalVideo)
+	}
 	resp, err := app.analyzeVideo(
-		videoContent,
+		ctx,
+		videoPath,
 		videoMessage.ID,
 		user.ID,
 		session.Skill,
 		session.Handedness,
+		job,
 	)
 	if err != nil {
-		if errors.Is(err, analysis.ErrNoMatchingExpert) {
-			app.Logger.Warn.Printf("same-handed expert unavailable: %v", err)
-			_, replyErr := app.LineBot.SendReply(
-				replyToken,
-				"目前沒有同慣用手的專家影片可供比較，本次不會跨左右手評分。請聯絡教練新增同手別的專家資料。",
-			)
-			handleLineMessageResponseError(replyErr)
+		if app.stopped(ctx) {
+			// The deferred end tells the student
+			app.Logger.WarnContext(ctx, "analysis interrupted by shutdown", "error", err)
+			return
+		}
+		if reply, ok := analysisErrorReply(err); ok {
+			app.Logger.WarnContext(ctx, "analysis rejected the video", "error", err)
+			job.fail(reply)
+			_, replyErr := app.LineBot.SendReply(ctx, replyToken, reply)
+			app.handleLineMessageResponseError(ctx, replyErr)
 			return
 		}
-		app.handleVideoAnalysisError(err, replyToken)
+		app.handleVideoAnalysisError(ctx, err, replyToken)
 		return
 	}
-	app.Logger.Info.Println("AI total grade: ", resp.Grade.TotalGrade)
+	app.Logger.InfoContext(ctx, "Video analyzed", "total_grade", resp.Grade.TotalGrade)
 
-	// Create thumbnail
-	thumbnailPath, err := app.createVideoThumbnail(videoContent, user.ID)
+	// Saving the result gets what is left of a shutdown's grace period. If
+	// it is cut short, the deferred end records the job as interrupted
+	// Create thumbnail; without ffmpeg the work still gets saved
+	thumbnailCtx, span := tracing.Start(ctx, "video.thumbnail")
+	input, at := app.thumbnailFrame(session.Skill, resp, videoPath, upload.Duration)
+	thumbnailPath, err := app.createVideoThumbnail(thumbnailCtx, videoPath, input, at)
+	if err != nil {
+		app.Logger.WarnContext(ctx, "using placeholder thumbnail", "error", err)
+		thumbnailPath, err = app.writePlaceholderThumbnail(videoPath)
+	}
+	tracing.End(span, err)
 	if err != nil {
-		app.handleThumbnailCreationError(err, replyToken)
+		app.handleThumbnailCreationError(ctx, err, replyToken)
 		return
 	}
-	defer os.RemoveAll(filepath.Dir(thumbnailPath))
 
-	timestamp := time.Now().Format("2006-01-02-15-04")
-	thumbnail, err := app.uploadThumbnail(user, thumbnailPath, timestamp)
+	thumbnail, err := app.uploadThumbnail(ctx, user, thumbnailPath, timestamp)
+	if app.savingInterrupted(ctx, err) {
+		return
+	}
 	if err != nil {
-		app.handleUploadToDriveError(err, replyToken)
+		app.handleUploadToDriveError(ctx, err, replyToken)
 		return
 	}
-	if err := app.updateUserPortfolioVideo(user, session, timestamp, *resp, thumbnail); err != nil {
-		app.handleUpdateUserPortfolioError(err, replyToken)
+	clipsCtx, span := tracing.Start(ctx, "video.cue_clips")
+	cueClips := app.createCueClips(clipsCtx, resp)
+	span.End()
+	uploads := db.WorkUploads{
+		OriginalVideo: originalVideo,
+		CueClips:      cueClips,
+	}
+	submission, assignment := app.matchAssignment(ctx, user, session.Skill, submittedAt)
+	err = app.updateUserPortfolioVideo(ctx, user, session, timestamp, *resp, thumbnail, submission, uploads)
+	if app.savingInterrupted(ctx, err) {
 		return
 	}
-	if err := app.FirestoreClient.ResetSession(user.ID); err != nil {
-		app.Logger.Error.Printf("failed to reset session after completed analysis: %v", err)
+	if err != nil {
+		app.handleUpdateUserPortfolioError(ctx, err, replyToken)
+		return
 	}
-	if err := app.sendVideoUploadedReply(event, session, user); err != nil {
-		app.Logger.Error.Printf("failed to send completed analysis through LINE: %v", err)
+	job.done(timestamp)
+	if err := app.FirestoreClient.ResetSession(ctx, user.ID); err != nil {
+		app.Logger.ErrorContext(ctx, "failed to reset session after completed analysis", "error", err)
+	}
+	err = app.sendVideoUploadedReply(ctx, event, session, user, assignment, submission)
+	if err != nil {
+		app.Logger.ErrorContext(ctx, "failed to send completed analysis through LINE", "error", err)
 		return
 	}
 }
 
+// savingInterrupted reports whether err came from a shutdown cutting short
+// the save of an analysis. The deferred end of its job tells the student.
+func (app *App) savingInterrupted(ctx context.Context, err error) bool {
+	if err == nil || !app.stopped(ctx) {
+		return false
+	}
+	app.Logger.WarnContext(ctx, "saving the analysis interrupted by shutdown", "error", err)
+	return true
+}
+
 // ============================================================================
 // 4. Helper Functions
 // ============================================================================
@@ -522,6 +663,14 @@ func (app *App) isWatchVideoAction(rawData string) (*line.VideoPostback, bool) {
 	return data, true
 }
 
+func (app *App) isWatchCueClipsAction(rawData string) (*line.CueClipsPostback, bool) {
+	data, err := app.LineBot.HandleCueClipsPostbackData(rawData)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
 func (app *App) isAnalyzingPortfolioWithGPT(rawData string) (*line.AnalyzingWithGPTPostback, bool) {
 	data, err := app.LineBot.HandleAskingAIForHelpPostbackData(rawData)
 	if err != nil {
@@ -534,15 +683,15 @@ func (app *App) isAnalyzingPortfolioWithGPT(rawData string) (*line.AnalyzingWith
 // 4.3 Video & Portfolio Updates
 // --------------------------------------------------------------------
 
-// getVideoContent retrieves the video bytes from a linebot.VideoMessage.
-func (app *App) getVideoContent(event *linebot.Event, userID string) ([]byte, error) {
+// getVideoContent opens the content stream of a linebot.VideoMessage.
+func (app *App) getVideoContent(ctx context.Context, event *linebot.Event, userID string) (io.ReadCloser, error) {
 	videoMsg, ok := event.Message.(*linebot.VideoMessage)
 	if !ok {
-		app.Logger.Warn.Println("Non-video message received")
-		app.FirestoreClient.ResetSession(userID)
+		app.Logger.WarnContext(ctx, "Non-video message received")
+		app.FirestoreClient.ResetSession(ctx, userID)
 		return nil, errors.New("non-video message")
 	}
-	return app.LineBot.GetVideoContent(videoMsg.ID)
+	return app.LineBot.GetVideoContent(ctx, videoMsg.ID)
 }
 
 // uploadVideoContent handles the final step of uploading the processed video
@@ -566,52 +715,53 @@ func generateUpdateNoteMessage(workDate, skill string, actionStep db.ActionStep)
 // handleSelectingSkill helps transition the user from “SelectingSkill” to the
 // next action, e.g., choosing handedness or uploading a video.
 func (app *App) handleSelectingSkill(
+	ctx context.Context,
 	event *linebot.Event,
 	session *db.UserSession,
 	rawData string,
 	replyToken string,
-	nextStepFunc func(*linebot.Event) error,
+	nextStepFunc func(context.Context, *linebot.Event) error,
 ) {
 	data, err := app.LineBot.HandleSelectingSkillPostbackData(rawData)
 	if err != nil {
-		app.handlePostbackDataTypeError(err, replyToken)
+		app.handlePostbackDataTypeError(ctx, err, replyToken)
 		return
 	}
 
-	if err := nextStepFunc(event); err != nil {
-		app.handleVideoUploadPromptError(err, replyToken)
+	if err := nextStepFunc(ctx, event); err != nil {
+		app.handleVideoUploadPromptError(ctx, err, replyToken)
 		return
 	}
 
 	session.Skill = data.Skill
-	if err := app.FirestoreClient.UpdateUserSession(event.Source.UserID, *session); err != nil {
-		app.handleUpdateSessionError(err, replyToken)
+	if err := app.FirestoreClient.UpdateUserSession(ctx, event.Source.UserID, *session); err != nil {
+		app.handleUpdateSessionError(ctx, err, replyToken)
 	}
 }
 
 // handleSendingExpertVideos is a helper that sets up the correct expert videos
 // after the user selects their handedness.
-func (app *App) handleSendingExpertVideos(event *linebot.Event, session *db.UserSession, replyToken string) {
+func (app *App) handleSendingExpertVideos(ctx context.Context, event *linebot.Event, session *db.UserSession, replyToken string) {
 	data, err := app.LineBot.HandleSelectingHandednessPostbackData(event.Postback.Data)
 	if err != nil {
-		app.handlePostbackDataTypeError(err, replyToken)
+		app.handlePostbackDataTypeError(ctx, err, replyToken)
 		return
 	}
 
 	handedness, err := db.HandednessStrToEnum(data.Handedness)
 	if err != nil {
-		app.Logger.Warn.Println("Invalid handedness received:", data.Handedness)
-		app.handlePostbackDataTypeError(err, replyToken)
+		app.Logger.WarnContext(ctx, "Invalid handedness received", "handedness", data.Handedness)
+		app.handlePostbackDataTypeError(ctx, err, replyToken)
 		return
 	}
 
 	skill := db.SkillStrToEnum(session.Skill)
-	if err := app.LineBot.SendExpertVideos(handedness, skill, replyToken); err != nil {
-		app.handleSendExpertVideosError(err, replyToken)
+	if err := app.LineBot.SendExpertVideos(ctx, handedness, skill, replyToken); err != nil {
+		app.handleSendExpertVideosError(ctx, err, replyToken)
 		return
 	}
 
-	app.Logger.Info.Printf("Expert videos sent for User ID: %v, Skill: %v, Handedness: %v", event.Source.UserID, skill, handedness)
+	app.Logger.InfoContext(ctx, "Expert videos sent", "handedness", handedness.String())
 }
 
 // ============================================================================
@@ -619,16 +769,16 @@ func (app *App) handleSendingExpertVideos(event *linebot.Event, session *db.User
 // ============================================================================
 
 // handleInvalidActionStep resets the session and sends a default error reply.
-func (app *App) handleInvalidActionStep(userID, replyToken string) {
-	app.FirestoreClient.ResetSession(userID)
-	if _, err := app.LineBot.SendDefaultErrorReply(replyToken); err != nil {
-		app.Logger.Warn.Println("Error sending default error reply:", err)
+func (app *App) handleInvalidActionStep(ctx context.Context, userID, replyToken string) {
+	app.FirestoreClient.ResetSession(ctx, userID)
+	if _, err := app.LineBot.SendDefaultErrorReply(ctx, replyToken); err != nil {
+		app.Logger.WarnContext(ctx, "Error sending default error reply", "error", err)
 	}
 }
 
 // resetSessionWithErrorHandling is a small helper to reset the session.
-func (app *App) resetSessionWithErrorHandling(userID, replyToken string) {
-	if err := app.FirestoreClient.ResetSession(userID); err != nil {
-		app.handleUpdateSessionError(err, replyToken)
+func (app *App) resetSessionWithErrorHandling(ctx context.Context, userID, replyToken string) {
+	if err := app.FirestoreClient.ResetSession(ctx, userID); err != nil {
+		app.handleUpdateSessionError(ctx, err, replyToken)
 	}
 }
@@ -1,80 +1,83 @@
 package app
 
 import (
+	"context"
+
 	"github.com/HeavenAQ/nstc-linebot-2025/api/db"
 	"github.com/line/line-bot-sdk-go/v7/linebot"
 )
 
 func processWrapper(
+	ctx context.Context,
 	app *App,
 	user *db.UserData,
 	replyToken string,
 	processFunc func(replyToken string) (res *linebot.BasicResponse, err error),
 ) func() {
 	return func() {
-		app.FirestoreClient.ResetSession(user.ID)
+		app.FirestoreClient.ResetSession(ctx, user.ID)
 		res, err := processFunc(replyToken)
-		app.handleMessageResponseError(res, err, replyToken)
+		app.handleMessageResponseError(ctx, res, err, replyToken)
 	}
 }
 
-func (app *App) processReadingInstruction(user *db.UserData, replyToken string) {
-	processWrapper(app, user, replyToken, func(replyToken string) (*linebot.BasicResponse, error) {
-		return app.LineBot.SendInstruction(replyToken)
+func (app *App) processReadingInstruction(ctx context.Context, user *db.UserData, replyToken string) {
+	processWrapper(ctx, app, user, replyToken, func(replyToken string) (*linebot.BasicResponse, error) {
+		return app.LineBot.SendInstruction(ctx, replyToken)
 	})()
 }
 
-func (app *App) processViewingPortfolio(user *db.UserData, userState db.UserState, replyToken string) {
-	processWrapper(app, user, replyToken, func(replyToken string) (*linebot.BasicResponse, error) {
-		err := app.FirestoreClient.UpdateSessionUserState(user.ID, db.ViewingPortfoilo, db.SelectingSkill)
+func (app *App) processViewingPortfolio(ctx context.Context, user *db.UserData, userState db.UserState, replyToken string) {
+	processWrapper(ctx, app, user, replyToken, func(replyToken string) (*linebot.BasicResponse, error) {
+		err := app.FirestoreClient.UpdateSessionUserState(ctx, user.ID, db.ViewingPortfoilo, db.SelectingSkill)
 		if err != nil {
-			app.handleUpdateSessionError(err, replyToken)
+			app.handleUpdateSessionError(ctx, err, replyToken)
 			return nil, err
 		}
-		return app.LineBot.PromptSkillSelection(replyToken, userState, "請選擇要查看的動作")
+		return app.LineBot.PromptSkillSelection(ctx, replyToken, userState, "請選擇要查看的動作")
 	})()
 }
 
-func (app *App) processViewingExpertVideos(user *db.UserData, userState db.UserState, replyToken string) {
-	processWrapper(app, user, replyToken, func(replyToken string) (*linebot.BasicResponse, error) {
-		err := app.FirestoreClient.UpdateSessionUserState(user.ID, db.ViewingExpertVideos, db.SelectingSkill)
+func (app *App) processViewingExpertVideos(ctx context.Context, user *db.UserData, userState db.UserState, replyToken string) {
+	processWrapper(ctx, app, user, replyToken, func(replyToken string) (*linebot.BasicResponse, error) {
+		err := app.FirestoreClient.UpdateSessionUserState(ctx, user.ID, db.ViewingExpertVideos, db.SelectingSkill)
 		if err != nil {
-			app.handleUpdateSessionError(err, replyToken)
+			app.handleUpdateSessionError(ctx, err, replyToken)
 			return nil, err
 		}
-		return app.LineBot.PromptSkillSelection(replyToken, userState, "請選擇要觀看的動作")
+		return app.LineBot.PromptSkillSelection(ctx, replyToken, userState, "請選擇要觀看的動作")
 	})()
 }
 
-func (app *App) processAnalyzingVideo(user *db.UserData, userState db.UserState, replyToken string) {
-	processWrapper(app, user, replyToken, func(replyToken string) (*linebot.BasicResponse, error) {
-		err := app.FirestoreClient.UpdateSessionUserState(user.ID, db.AnalyzingVideo, db.SelectingSkill)
+func (app *App) processAnalyzingVideo(ctx context.Context, user *db.UserData, userState db.UserState, replyToken string) {
+	processWrapper(ctx, app, user, replyToken, func(replyToken string) (*linebot.BasicResponse, error) {
+		err := app.FirestoreClient.UpdateSessionUserState(ctx, user.ID, db.AnalyzingVideo, db.SelectingSkill)
 		if err != nil {
-			app.handleUpdateSessionError(err, replyToken)
+			app.handleUpdateSessionError(ctx, err, replyToken)
 			return nil, err
 		}
-		return app.LineBot.PromptSkillSelection(replyToken, userState, "請選擇要分析的動作")
+		return app.LineBot.PromptSkillSelection(ctx, replyToken, userState, "請選擇要分析的動作")
 	})()
 }
 
-func (app *App) processWritingNotes(user *db.UserData, userState db.UserState, replyToken string) {
-	processWrapper(app, user, replyToken, func(replyToken string) (*linebot.BasicResponse, error) {
-		err := app.FirestoreClient.UpdateSessionUserState(user.ID, db.WritingNotes, db.SelectingSkill)
+func (app *App) processWritingNotes(ctx context.Context, user *db.UserData, userState db.UserState, replyToken string) {
+	processWrapper(ctx, app, user, replyToken, func(replyToken string) (*linebot.BasicResponse, error) {
+		err := app.FirestoreClient.UpdateSessionUserState(ctx, user.ID, db.WritingNotes, db.SelectingSkill)
 		if err != nil {
-			app.handleUpdateSessionError(err, replyToken)
+			app.handleUpdateSessionError(ctx, err, replyToken)
 			return nil, err
 		}
-		return app.LineBot.PromptSkillSelection(replyToken, userState, "請選擇要紀錄的動作")
+		return app.LineBot.PromptSkillSelection(ctx, replyToken, userState, "請選擇要紀錄的動作")
 	})()
 }
 
-func (app *App) processChattingWithGPT(user *db.UserData, userState db.UserState, replyToken string) {
-	processWrapper(app, user, replyToken, func(replyToken string) (*linebot.BasicResponse, error) {
-		err := app.FirestoreClient.UpdateSessionUserState(user.ID, db.ChattingWithGPT, db.SelectingSkill)
+func (app *App) processChattingWithGPT(ctx context.Context, user *db.UserData, userState db.UserState, replyToken string) {
+	processWrapper(ctx, app, user, replyToken, func(replyToken string) (*linebot.BasicResponse, error) {
+		err := app.FirestoreClient.UpdateSessionUserState(ctx, user.ID, db.ChattingWithGPT, db.SelectingSkill)
 		if err != nil {
-			app.handleUpdateSessionError(err, replyToken)
+			app.handleUpdateSessionError(ctx, err, replyToken)
 			return nil, err
 		}
-		return app.LineBot.PromptSkillSelection(replyToken, userState, "請選擇要與 GPT 討論的動作")
+		return app.LineBot.PromptSkillSelection(ctx, replyToken, userState, "請選擇要與 GPT 討論的動作")
 	})()
 }
@@ -0,0 +1,61 @@
+package app
+
+import (
+	"context"
+	"github.com/HeavenAQ/nstc-linebot-2025/commons"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func stoppableApp() *App {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &App{Logger: slog.New(slog.NewTextHandler(io.Discard, nil)), ctx: ctx, cancel: cancel}
+}
+
+func TestShutdownWaitsForWebhooksInProgress(t *testing.T) {
+	app := stoppableApp()
+	var finished atomic.Bool
+	app.webhooks.Add(1)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		finished.Store(true)
+		app.webhooks.Done()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	require.NoError(t, app.Shutdown(ctx))
+	require.True(t, finished.Load())
+}
+
+func TestShutdownCancelsWorkLeftAfterTheGracePeriod(t *testing.T) {
+	app := stoppableApp()
+	work := app.Detach(context.Background())
+	app.webhooks.Add(1)
+	go func() {
+		<-work.Done()
+		app.webhooks.Done()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.NoError(t, app.Shutdown(ctx))
+	require.ErrorIs(t, work.Err(), context.Canceled)
+	require.True(t, app.stopped(work))
+}
+
+func TestCueClipsAreSkippedOnceShutdownStarts(t *testing.T) {
+	app := stoppableApp()
+	app.draining.Store(true)
+	analysis := &commons.AnalysisOutcome{
+		StudentVideo: commons.MediaRef{SignedURL: "https://storage.example/student.mp4", ObjectPath: "analyses/student.mp4"},
+		CoachingCues: []commons.CoachingCue{{Title: "轉體"}},
+	}
+
+	require.Empty(t, app.createCueClips(context.Background(), analysis))
+}
@@ -0,0 +1,96 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/HeavenAQ/nstc-linebot-2025/api/db"
+	"github.com/HeavenAQ/nstc-linebot-2025/api/storage"
+	"github.com/HeavenAQ/nstc-linebot-2025/commons"
+	"github.com/HeavenAQ/nstc-linebot-2025/metrics"
+	"github.com/HeavenAQ/nstc-linebot-2025/playback"
+)
+
+// measuredSigner counts and times the batches sign signs, under name.
+func measuredSigner(name string, sign playback.SignFunc) playback.SignFunc {
+	return func(ctx context.Context, objectPaths ...string) ([]commons.MediaRef, error) {
+		start := time.Now()
+		refs, err := sign(ctx, objectPaths...)
+		metrics.SignedURLRefreshDuration.WithLabelValues(name).Observe(metrics.Since(start))
+		metrics.SignedURLRefreshes.WithLabelValues(name, metrics.Outcome(err)).Inc()
+		return refs, err
+	}
+}
+
+// signURLs signs object paths for the LINE client, through the same cache as
+// the playback videos.
+func (app *App) signURLs(ctx context.Context, objectPaths ...string) ([]string, error) {
+	refs, err := app.Playback.URLs(ctx, objectPaths...)
+	if err != nil {
+		return nil, err
+	}
+	urls := make([]string, len(refs))
+	for i, ref := range refs {
+		urls[i] = ref.SignedURL
+	}
+	return urls, nil
+}
+
+// SignThumbnails replaces the thumbnail of each of the users' works with a
+// signed URL, for clients that display them directly. A thumbnail that cannot
+// be signed is left empty and logged, so one bad object does not fail the
+// rest.
+func (app *App) SignThumbnails(ctx context.Context, users ...*db.UserData) {
+	type workKey struct {
+		user        *db.UserData
+		skill, date string
+	}
+	var keys []workKey
+	var objectPaths []string
+	for _, user := range users {
+		for _, skill := range db.BadmintonSkillNames() {
+			for date, work := range user.Portfolio.GetSkillPortfolio(skill) {
+				objectPath := storage.ObjectPath(app.Config.GCP.Storage.BucketName, work.Thumbnail)
+				if objectPath == "" || strings.HasPrefix(objectPath, "https://") {
+					continue
+				}
+				keys = append(keys, workKey{user, skill, date})
+				objectPaths = append(objectPaths, objectPath)
+			}
+		}
+	}
+	if len(objectPaths) == 0 {
+		return
+	}
+	urls := app.signEach(ctx, objectPaths)
+	for i, key := range keys {
+		portfolio := key.user.Portfolio.GetSkillPortfolio(key.skill)
+		work := portfolio[key.date]
+		work.Thumbnail = urls[i]
+		portfolio[key.date] = work
+	}
+}
+
+// signEach signs the object paths together, or one at a time when the batch
+// fails, leaving the URL of each path that cannot be signed empty.
+func (app *App) signEach(ctx context.Context, objectPaths []string) []string {
+	urls, err := app.signURLs(ctx, objectPaths...)
+	if err == nil {
+		return urls
+	}
+	app.Logger.WarnContext(ctx, "signing thumbnails one at a time", "count", len(objectPaths), "error", err)
+	urls = make([]string, len(objectPaths))
+	for i, objectPath := range objectPaths {
+		if ctx.Err() != nil {
+			break
+		}
+		signed, err := app.signURLs(ctx, objectPath)
+		if err != nil {
+			app.Logger.WarnContext(ctx, "thumbnail left unsigned", "object", objectPath, "error", err)
+			continue
+		}
+		urls[i] = signed[0]
+	}
+	return urls
+}
@@ -0,0 +1,57 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HeavenAQ/nstc-linebot-2025/api/db"
+	"github.com/HeavenAQ/nstc-linebot-2025/commons"
+	"github.com/HeavenAQ/nstc-linebot-2025/config"
+	"github.com/HeavenAQ/nstc-linebot-2025/playback"
+	"github.com/stretchr/testify/require"
+)
+
+// signingApp signs every path except those naming a missing object, which
+// fail the whole batch they are in.
+func signingApp() *App {
+	sign := func(_ context.Context, objectPaths ...string) ([]commons.MediaRef, error) {
+		refs := make([]commons.MediaRef, len(objectPaths))
+		for i, objectPath := range objectPaths {
+			if strings.Contains(objectPath, "missing") {
+				return nil, errors.New("object not found")
+			}
+			refs[i] = commons.MediaRef{
+				ObjectPath: objectPath, SignedURL: "https://signed/" + objectPath,
+				SignedURLExpires: time.Now().Add(time.Hour).Unix(),
+			}
+		}
+		return refs, nil
+	}
+	return &App{
+		Config:   &config.Config{},
+		Logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Playback: playback.NewCache(time.Minute, sign),
+	}
+}
+
+func TestSignThumbnailsLeavesUnsignableOnesEmpty(t *testing.T) {
+	app := signingApp()
+	first := &db.UserData{ID: "a", Portfolio: db.Portfolios{Serve: map[string]db.Work{
+		"2026-08-01-10-30": {Thumbnail: "a/thumbnails/1.jpeg"},
+		"2026-08-02-10-30": {Thumbnail: "a/thumbnails/missing.jpeg"},
+	}}}
+	second := &db.UserData{ID: "b", Portfolio: db.Portfolios{Clear: map[string]db.Work{
+		"2026-08-01-10-30": {Thumbnail: "b/thumbnails/1.jpeg"},
+	}}}
+
+	app.SignThumbnails(context.Background(), first, second)
+
+	require.Equal(t, "https://signed/a/thumbnails/1.jpeg", first.Portfolio.Serve["2026-08-01-10-30"].Thumbnail)
+	require.Empty(t, first.Portfolio.Serve["2026-08-02-10-30"].Thumbnail)
+	require.Equal(t, "https://signed/b/thumbnails/1.jpeg", second.Portfolio.Clear["2026-08-01-10-30"].Thumbnail)
+}
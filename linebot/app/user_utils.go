@@ -1,48 +1,49 @@
 package app
 
 import (
+	"context"
 	"fmt"
 	"sync"
 
 	"github.com/HeavenAQ/nstc-linebot-2025/api/db"
 )
 
-func (app *App) createUser(userID string) *db.UserData {
+func (app *App) createUser(ctx context.Context, userID string) *db.UserData {
 	// Retrieve user's name from LINE
-	app.Logger.Info.Println("Getting the user's name")
-	username, err := app.LineBot.GetUserName(userID)
+	app.Logger.InfoContext(ctx, "Getting the user's name")
+	username, err := app.LineBot.GetUserName(ctx, userID)
 	if err != nil {
-		app.Logger.Error.Println("Error getting new user's name:", err)
+		app.Logger.ErrorContext(ctx, "Error getting new user's name", "error", err)
 	}
-	app.Logger.Info.Println("User name has been retrieved")
+	app.Logger.InfoContext(ctx, "User name has been retrieved")
 
 	// Create user's folders
-	app.Logger.Info.Println("Creating the user's folders")
-	userFolders, err := app.StorageClient.CreateUserFolders(userID, username)
+	app.Logger.InfoContext(ctx, "Creating the user's folders")
+	userFolders, err := app.StorageClient.CreateUserFolders(ctx, userID, username)
 	if err != nil {
-		app.Logger.Error.Println("Error creating new user's folders:", err)
+		app.Logger.ErrorContext(ctx, "Error creating new user's folders", "error", err)
 	}
-	app.Logger.Info.Println("User's folders has been created")
+	app.Logger.InfoContext(ctx, "User's folders has been created")
 
 	// create GPT conversations for users
-	app.Logger.Info.Println("Creating the user's GPT conversations")
-	gptConversationIDs, err := app.createUserGPTConversations()
+	app.Logger.InfoContext(ctx, "Creating the user's GPT conversations")
+	gptConversationIDs, err := app.createUserGPTConversations(ctx)
 	if err != nil {
-		app.Logger.Error.Println("Error creating user's GPT conversations:", err)
+		app.Logger.ErrorContext(ctx, "Error creating user's GPT conversations", "error", err)
 	}
-	app.Logger.Info.Println("User's GPT conversations have been created")
+	app.Logger.InfoContext(ctx, "User's GPT conversations have been created")
 
 	// Store user's data in database
-	app.Logger.Info.Println("Add the user's data to database")
-	userData, err := app.FirestoreClient.CreateUserData(userFolders, gptConversationIDs)
+	app.Logger.InfoContext(ctx, "Add the user's data to database")
+	userData, err := app.FirestoreClient.CreateUserData(ctx, userFolders, gptConversationIDs)
 	if err != nil {
-		app.Logger.Error.Println("Error creating new user's data:", err)
+		app.Logger.ErrorContext(ctx, "Error creating new user's data", "error", err)
 	}
-	app.Logger.Info.Println("User's data has been added")
+	app.Logger.InfoContext(ctx, "User's data has been added")
 	return userData
 }
 
-func (app *App) createUserGPTConversations() (*db.GPTConversationIDs, error) {
+func (app *App) createUserGPTConversations(ctx context.Context) (*db.GPTConversationIDs, error) {
 	userGPTConversations := db.GPTConversationIDs{}
 	idAddrs := []*string{
 		&userGPTConversations.Serve,
@@ -58,7 +59,7 @@ func (app *App) createUserGPTConversations() (*db.GPTConversationIDs, error) {
 	for _, idAddr := range idAddrs {
 		go func(target *string) {
 			defer wait.Done()
-			conv, err := app.GPTClient.CreateConversation()
+			conv, err := app.GPTClient.CreateConversation(ctx)
 			if err != nil {
 				errMu.Lock()
 				if firstErr == nil {
@@ -77,26 +78,26 @@ func (app *App) createUserGPTConversations() (*db.GPTConversationIDs, error) {
 	return &userGPTConversations, nil
 }
 
-func (app *App) createUserIfNotExist(userID string) *db.UserData {
-	user, err := app.FirestoreClient.GetUserData(userID)
+func (app *App) createUserIfNotExist(ctx context.Context, userID string) *db.UserData {
+	user, err := app.FirestoreClient.GetUserData(ctx, userID)
 	if err != nil {
-		app.Logger.Warn.Println("User not found, creating new user...")
-		userData := app.createUser(userID)
+		app.Logger.WarnContext(ctx, "User not found, creating new user...")
+		userData := app.createUser(ctx, userID)
 		user = userData
 
-		app.Logger.Info.Println("New user created successfully.")
+		app.Logger.InfoContext(ctx, "New user created successfully.")
 	}
 
 	return user
 }
 
-func (app *App) createUserSessionIfNotExist(userID string) *db.UserSession {
-	session, err := app.FirestoreClient.GetUserSession(userID)
+func (app *App) createUserSessionIfNotExist(ctx context.Context, userID string) *db.UserSession {
+	session, err := app.FirestoreClient.GetUserSession(ctx, userID)
 	if err != nil {
-		app.Logger.Warn.Println("User session not found, creating new session")
-		session, err = app.FirestoreClient.CreateUserSession(userID)
+		app.Logger.WarnContext(ctx, "User session not found, creating new session")
+		session, err = app.FirestoreClient.CreateUserSession(ctx, userID)
 		if err != nil {
-			app.Logger.Error.Println("Error creating new user session:", err)
+			app.Logger.ErrorContext(ctx, "Error creating new user session", "error", err)
 		}
 	}
 
@@ -132,3 +133,15 @@ func (app *App) getUserGPTConversation(user *db.UserData, skill string) string {
 		return ""
 	}
 }
+
+// classRubric returns the rubric of the user's class. The portfolio is still
+// worth showing if the rubric cannot be read, so errors fall back to the
+// default rubric.
+func (app *App) classRubric(ctx context.Context, user *db.UserData) db.Rubric {
+	rubric, err := app.FirestoreClient.GetRubric(ctx, user.ClassID)
+	if err != nil {
+		app.Logger.ErrorContext(ctx, "Error getting rubric", "class", user.ClassID, "error", err)
+		return db.DefaultRubric(user.ClassID)
+	}
+	return rubric
+}
@@ -1,93 +1,338 @@
 package app
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/HeavenAQ/nstc-linebot-2025/api/analysis"
 	"github.com/HeavenAQ/nstc-linebot-2025/api/db"
+	"github.com/HeavenAQ/nstc-linebot-2025/api/line"
 	"github.com/HeavenAQ/nstc-linebot-2025/api/storage"
+	"github.com/HeavenAQ/nstc-linebot-2025/api/video"
 	"github.com/HeavenAQ/nstc-linebot-2025/commons"
+	"github.com/HeavenAQ/nstc-linebot-2025/metrics"
+	"github.com/HeavenAQ/nstc-linebot-2025/tracing"
 	linebotsdk "github.com/line/line-bot-sdk-go/v7/linebot"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const tmpFolder = "/tmp/"
 
+// analyzeVideo streams the saved upload to the analysis service, reporting
+// its stages to job when the service is set up to send them.
 func (app *App) analyzeVideo(
-	video []byte,
-	requestID, userID, skill, handedness string,
-) (*commons.AnalysisOutcome, error) {
-	app.Logger.Info.Printf(
-		"streaming video to analysis service request_id=%s skill=%s bytes=%d",
-		requestID,
-		skill,
-		len(video),
+	ctx context.Context,
+	videoPath, requestID, userID, skill, handedness string,
+	job *analysisJob,
+) (outcome *commons.AnalysisOutcome, err error) {
+	ctx, span := tracing.Start(ctx, "analysis.request",
+		attribute.String("analysis.request_id", requestID),
+		attribute.String("analysis.skill", skill),
 	)
-	return app.AnalysisClient.AnalyzeVideo(
-		context.Background(), requestID, userID, "line-upload.mp4", skill, handedness, video,
+	defer func() { tracing.End(span, err) }()
+	file, err := os.Open(videoPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	app.Logger.InfoContext(ctx, "streaming video to analysis service", "bytes", info.Size())
+	metrics.AnalysesInFlight.Inc()
+	start := time.Now()
+	outcome, err = app.requestAnalysis(ctx, file, requestID, userID, skill, handedness, job)
+	metrics.AnalysesInFlight.Dec()
+	metrics.AnalysisDuration.WithLabelValues(skill, analysisOutcome(err)).Observe(metrics.Since(start))
+	if err == nil {
+		metrics.ObserveDiagnostics(outcome.Diagnostics)
+	}
+	return outcome, err
+}
+
+func (app *App) requestAnalysis(
+	ctx context.Context,
+	file *os.File, requestID, userID, skill, handedness string,
+	job *analysisJob,
+) (*commons.AnalysisOutcome, error) {
+	if !app.Config.AnalysisServer.Progress {
+		return app.AnalysisClient.AnalyzeVideo(
+			ctx, requestID, userID, "line-upload.mp4", skill, handedness, file,
+		)
+	}
+	progress := make(chan analysis.Stage, 8)
+	followed := make(chan struct{})
+	go func() {
+		job.follow(progress)
+		close(followed)
+	}()
+	defer func() {
+		close(progress)
+		<-followed
+	}()
+	return app.AnalysisClient.AnalyzeVideoWithProgress(
+		ctx, requestID, userID, "line-upload.mp4", skill, handedness, file, progress,
 	)
 }
 
-func (app *App) createVideoThumbnail(video []byte, userID string) (string, error) {
-	directory, err := os.MkdirTemp(tmpFolder, "linebot-thumbnail-")
+// saveVideo copies the upload to a fresh temp directory, where ffprobe,
+// ffmpeg and the analyzer read it, so it is never held in memory. On Cloud Run
+// the directory is itself in memory, so the copy stops once the upload is
+// larger than the skill allows and a *video.RejectedError is returned.
+// Callers remove the directory when done.
+func (app *App) saveVideo(content io.Reader, userID, skill string) (string, error) {
+	limits := video.LimitsFor(skill)
+	directory, err := os.MkdirTemp(tmpFolder, "linebot-upload-")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(directory, userID+".mp4")
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
 	if err != nil {
+		os.RemoveAll(directory)
 		return "", err
 	}
-	input := filepath.Join(directory, userID+".mp4")
-	output := filepath.Join(directory, userID+".jpeg")
-	if err := os.WriteFile(input, video, 0600); err != nil {
+	written, err := io.Copy(file, io.LimitReader(content, limits.MaxSize+1))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && written > limits.MaxSize {
+		err = limits.Oversized()
+	}
+	if err != nil {
+		os.RemoveAll(directory)
 		return "", err
 	}
-	command := exec.Command(
-		"ffmpeg", "-hide_banner", "-loglevel", "error", "-y",
-		"-ss", "00:00:01", "-i", input, "-frames:v", "1", "-q:v", "3", output,
+	return path, nil
+}
+
+// preflightVideo turns away clips the analyzer cannot grade and returns what
+// the probe found. Only a *video.RejectedError is returned: if the video
+// cannot be probed, the info is zero and the analyzer gets the final say.
+func (app *App) preflightVideo(ctx context.Context, path, skill string) (video.Info, error) {
+	info, err := video.Probe(ctx, path)
+	if errors.Is(err, video.ErrProbeUnavailable) {
+		app.Logger.WarnContext(ctx, "ffprobe is not installed; skipping the video pre-flight check")
+		return video.Info{}, nil
+	}
+	if err != nil {
+		app.Logger.WarnContext(ctx, "video pre-flight probe failed", "error", err)
+		return video.Info{}, nil
+	}
+	app.Logger.InfoContext(ctx, "video pre-flight",
+		"duration", info.Duration.String(), "width", info.Width, "height", info.Height,
+		"fps", info.FrameRate, "codec", info.Codec, "bytes", info.Size,
 	)
+	return info, video.LimitsFor(skill).Check(info)
+}
+
+// thumbnailFrame picks where the thumbnail is grabbed: the skill's configured
+// phase in the annotated student video, whose timeline the analysis reports,
+// or else halfway through the upload, which lasts uploadDuration.
+func (app *App) thumbnailFrame(
+	skill string, analysis *commons.AnalysisOutcome, videoPath string, uploadDuration time.Duration,
+) (input string, at time.Duration) {
+	annotated := analysis.StudentVideo
+	if annotated.SignedURL != "" {
+		duration := time.Duration(annotated.DurationSeconds * float64(time.Second))
+		at, ok := video.PhaseAt(analysis.Timeline, analysis.CoachingCues, app.Config.Thumbnail.Phase(skill), duration)
+		if ok {
+			return annotated.SignedURL, at
+		}
+	}
+	return videoPath, video.MidClipAt(uploadDuration)
+}
+
+// createVideoThumbnail grabs the frame of input at the given time, next to
+// the uploaded video.
+func (app *App) createVideoThumbnail(ctx context.Context, videoPath, input string, at time.Duration) (string, error) {
+	output := strings.TrimSuffix(videoPath, filepath.Ext(videoPath)) + ".jpeg"
+	if err := grabFrame(ctx, input, at, output); err != nil {
+		return "", fmt.Errorf("create thumbnail: %w", err)
+	}
+	return output, nil
+}
+
+// grabFrame writes the frame of input at the given time to output as a JPEG.
+// input may be a local path or a URL. ffmpeg succeeds without writing
+// anything when the time is past the end, so that is an error here.
+func grabFrame(ctx context.Context, input string, at time.Duration, output string) error {
+	args := append([]string{"-ss", ffmpegTime(at)}, ffmpegInput(input)...)
+	err := runFFmpeg(ctx, append(args, "-frames:v", "1", "-q:v", "3", output)...)
+	if err != nil {
+		return err
+	}
+	if info, err := os.Stat(output); err != nil || info.Size() == 0 {
+		return fmt.Errorf("no frame at %s", ffmpegTime(at))
+	}
+	return nil
+}
+
+// cutClip re-encodes length of input from start to an H.264 MP4 that LINE
+// can play.
+func cutClip(ctx context.Context, input string, start, length time.Duration, output string) error {
+	args := append([]string{"-ss", ffmpegTime(start)}, ffmpegInput(input)...)
+	return runFFmpeg(ctx, append(args,
+		"-t", ffmpegTime(length),
+		"-c:v", "libx264", "-preset", "veryfast", "-crf", "26", "-pix_fmt", "yuv420p",
+		"-movflags", "+faststart", "-an", output,
+	)...)
+}
+
+const (
+	// ffmpegTimeout bounds one ffmpeg run, which cuts a few seconds of video
+	// or grabs a single frame.
+	ffmpegTimeout = time.Minute
+	// ffmpegReadTimeout gives up on a URL input that stops sending data.
+	ffmpegReadTimeout = 15 * time.Second
+)
+
+// ffmpegInput names input to ffmpeg. A URL, such as the analyzer's signed
+// URLs, also gets a read timeout so a stalled fetch fails the run.
+func ffmpegInput(input string) []string {
+	if !strings.Contains(input, "://") {
+		return []string{"-i", input}
+	}
+	return []string{"-rw_timeout", strconv.FormatInt(ffmpegReadTimeout.Microseconds(), 10), "-i", input}
+}
+
+func runFFmpeg(ctx context.Context, args ...string) error {
+	ctx, cancel := context.WithTimeout(ctx, ffmpegTimeout)
+	defer cancel()
+	command := exec.CommandContext(ctx, "ffmpeg", append([]string{"-hide_banner", "-loglevel", "error", "-y"}, args...)...)
 	if data, err := command.CombinedOutput(); err != nil {
-		return "", fmt.Errorf("create thumbnail: %w: %s", err, string(data))
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(data)))
+	}
+	return nil
+}
+
+func ffmpegTime(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+// placeholderThumbnail stands in when no frame can be grabbed, so the
+// analysis is still saved.
+var placeholderThumbnail = sync.OnceValues(func() ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, 640, 360))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: color.RGBA{R: 0xe0, G: 0xe0, B: 0xe0, A: 0xff}}, image.Point{}, draw.Src)
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+})
+
+// writePlaceholderThumbnail writes the placeholder where the thumbnail would
+// have gone.
+func (app *App) writePlaceholderThumbnail(videoPath string) (string, error) {
+	data, err := placeholderThumbnail()
+	if err != nil {
+		return "", err
+	}
+	output := strings.TrimSuffix(videoPath, filepath.Ext(videoPath)) + ".jpeg"
+	if err := os.WriteFile(output, data, 0600); err != nil {
+		return "", err
 	}
 	return output, nil
 }
 
 func (app *App) uploadThumbnail(
+	ctx context.Context,
 	user *db.UserData, thumbnailPath, timestamp string,
 ) (*storage.UploadedFile, error) {
 	fileInfo := storage.FileInfo{}
 	fileInfo.Bucket.ThumbnailPath = fmt.Sprintf("%s/%s.jpeg", user.FolderPaths.Thumbnail, timestamp)
 	fileInfo.Local.ThumbnailPath = thumbnailPath
-	return app.StorageClient.UploadThumbnail(&fileInfo)
+	return app.StorageClient.UploadThumbnail(ctx, &fileInfo)
+}
+
+// archiveVideo keeps the original upload so the work can be re-analyzed
+// later. Losing it only rules that out, so a failure leaves the work without
+// an original rather than failing the upload.
+func (app *App) archiveVideo(ctx context.Context, user *db.UserData, videoPath, skill, date string) string {
+	fileInfo := storage.FileInfo{}
+	fileInfo.Bucket.VideoPath = db.OriginalVideoPath(user, skill, date)
+	fileInfo.Local.VideoPath = videoPath
+	uploaded, err := app.StorageClient.UploadVideo(ctx, &fileInfo)
+	if err != nil {
+		app.Logger.ErrorContext(ctx, "failed to archive original video", "error", err)
+		return ""
+	}
+	return uploaded.Path
 }
 
 func (app *App) updateUserPortfolioVideo(
+	ctx context.Context,
 	user *db.UserData,
 	session *db.UserSession,
 	date string,
 	analysis commons.AnalysisOutcome,
 	thumbnail *storage.UploadedFile,
+	submission db.SubmissionTag,
+	uploads db.WorkUploads,
 ) error {
 	portfolio := app.getUserPortfolio(user, session.Skill)
-	thumbnailURL := "https://storage.googleapis.com/" + app.Config.GCP.Storage.BucketName + "/" + thumbnail.Path
 	return app.FirestoreClient.CreateUserPortfolioVideo(
+		ctx,
 		user,
 		portfolio,
 		date,
 		session,
-		&storage.UploadedFile{Name: thumbnail.Name, Path: thumbnailURL},
+		thumbnail,
 		analysis,
+		submission,
+		uploads,
 	)
 }
 
+// matchAssignment finds the assignment an upload sent at submittedAt is
+// submitted for.
+// A failed lookup must not lose the analysis, so it only leaves the upload
+// untagged.
+func (app *App) matchAssignment(
+	ctx context.Context,
+	user *db.UserData, skill string, submittedAt time.Time,
+) (db.SubmissionTag, *db.Assignment) {
+	assignments, err := app.FirestoreClient.ListAssignments(ctx, user.ClassID)
+	if err != nil {
+		app.Logger.ErrorContext(ctx, "failed to list assignments", "class", user.ClassID, "error", err)
+		return db.SubmissionTag{}, nil
+	}
+	return db.MatchAssignment(assignments, *user, skill, submittedAt)
+}
+
 func (app *App) sendVideoUploadedReply(
-	event *linebotsdk.Event, session *db.UserSession, user *db.UserData,
+	ctx context.Context,
+	event *linebotsdk.Event, session *db.UserSession, user *db.UserData, assignment *db.Assignment, submission db.SubmissionTag,
 ) error {
+	text := "影片分析完成，已加入學習歷程。"
+	if assignment != nil {
+		text += "\n" + line.SubmissionNote(assignment, submission)
+	}
 	return app.LineBot.SendPortfolio(
+		ctx,
 		event,
 		user,
+		app.classRubric(ctx, user),
 		db.SkillStrToEnum(session.Skill),
 		session.Handedness,
 		session.UserState,
-		"影片分析完成，已加入學習歷程。",
+		text,
 		true,
 	)
 }
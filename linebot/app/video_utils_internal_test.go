@@ -0,0 +1,73 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"image/jpeg"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/HeavenAQ/nstc-linebot-2025/api/video"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWritePlaceholderThumbnail(t *testing.T) {
+	videoPath := filepath.Join(t.TempDir(), "U123.mp4")
+
+	path, err := (&App{}).writePlaceholderThumbnail(videoPath)
+
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(filepath.Dir(videoPath), "U123.jpeg"), path)
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	img, err := jpeg.Decode(file)
+	require.NoError(t, err)
+	require.Equal(t, 640, img.Bounds().Dx())
+	require.Equal(t, 360, img.Bounds().Dy())
+}
+
+// endless is an upload that never ends, counting what was read of it.
+type endless struct{ read int64 }
+
+func (e *endless) Read(p []byte) (int, error) {
+	e.read += int64(len(p))
+	return len(p), nil
+}
+
+func TestSaveVideoStopsAtTheSizeLimit(t *testing.T) {
+	content := &endless{}
+
+	_, err := (&App{}).saveVideo(content, "U123", "clear")
+
+	var rejected *video.RejectedError
+	require.True(t, errors.As(err, &rejected))
+	require.Equal(t, []string{"影片檔案需小於 100 MB"}, rejected.Problems)
+	require.LessOrEqual(t, content.read, video.LimitsFor("clear").MaxSize+1)
+}
+
+func TestSaveVideoKeepsUploadsWithinTheLimit(t *testing.T) {
+	path, err := (&App{}).saveVideo(io.LimitReader(&endless{}, 1<<10), "U123", "clear")
+
+	require.NoError(t, err)
+	defer os.RemoveAll(filepath.Dir(path))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.EqualValues(t, 1<<10, info.Size())
+}
+
+func TestFFmpegInputTimesOutStalledURLs(t *testing.T) {
+	require.Equal(t, []string{"-i", "/tmp/upload.mp4"}, ffmpegInput("/tmp/upload.mp4"))
+	require.Equal(t,
+		[]string{"-rw_timeout", "15000000", "-i", "https://storage.example/video.mp4"},
+		ffmpegInput("https://storage.example/video.mp4"),
+	)
+}
+
+func TestRunFFmpegStopsWithItsContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.Error(t, runFFmpeg(ctx, "-version"))
+}
@@ -1,29 +1,41 @@
 package app
 
 import (
+	"context"
 	"errors"
 	"net/http"
 
+	"github.com/HeavenAQ/nstc-linebot-2025/tracing"
 	"github.com/line/line-bot-sdk-go/v7/linebot"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 func (app *App) LineWebhookHandler() http.HandlerFunc {
     return func(writer http.ResponseWriter, req *http.Request) {
+        app.webhooks.Add(1)
+        defer app.webhooks.Done()
+        // Events are handled before the response is sent, and an analysis can
+        // outlast LINE's wait for it, so handling is cancelled when the app
+        // stops rather than with the request.
+        ctx, span := tracing.Start(app.Detach(req.Context()), "line.webhook")
         events, err := app.LineBot.ParseRequest(req)
         if err != nil {
-            app.handleParseError(err, writer)
+            tracing.End(span, err)
+            app.handleParseError(ctx, err, writer)
             return
 		}
-		app.handleEvents(events)
+		span.SetAttributes(attribute.Int("line.event_count", len(events)))
+		app.handleEvents(ctx, events)
+		span.End()
 	}
 }
 
-func (app *App) handleParseError(err error, writer http.ResponseWriter) {
+func (app *App) handleParseError(ctx context.Context, err error, writer http.ResponseWriter) {
 	if errors.Is(err, linebot.ErrInvalidSignature) {
-		app.Logger.Warn.Println("Invalid signature")
+		app.Logger.WarnContext(ctx, "Invalid signature")
 		writer.WriteHeader(http.StatusBadRequest)
 		return
 	}
-	app.Logger.Error.Println("Error parsing request:", err)
+	app.Logger.ErrorContext(ctx, "Error parsing request", "error", err)
 	writer.WriteHeader(http.StatusInternalServerError)
 }
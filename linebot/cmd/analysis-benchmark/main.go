@@ -91,17 +91,18 @@ func main() {
 	}
 
 	for _, benchmark := range cases {
-		video, err := os.ReadFile(benchmark.path)
-		if err != nil {
-			fatalf("read %s: %v", benchmark.path, err)
-		}
 		for run := 1; run <= *runs; run++ {
+			video, err := os.Open(benchmark.path)
+			if err != nil {
+				fatalf("open %s: %v", benchmark.path, err)
+			}
 			requestID := fmt.Sprintf("benchmark-%s-%d-%d", benchmark.skill, time.Now().UnixNano(), run)
 			started := time.Now()
 			result, err := client.AnalyzeVideo(
 				context.Background(), requestID, "latency-benchmark", filepath.Base(benchmark.path),
 				benchmark.skill, *handedness, video,
 			)
+			video.Close()
 			if err != nil {
 				fatalf("analyze %s run %d: %v", benchmark.skill, run, err)
 			}
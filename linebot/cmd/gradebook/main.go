@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/HeavenAQ/nstc-linebot-2025/api/db"
+	"github.com/HeavenAQ/nstc-linebot-2025/config"
+	"github.com/HeavenAQ/nstc-linebot-2025/gradebook"
+)
+
+func main() {
+	envPath := flag.String("env", ".env", "path to the .env file")
+	classID := flag.String("class", "", "only export students in this class")
+	from := flag.String("from", "", "first upload date to include (YYYY-MM-DD)")
+	to := flag.String("to", "", "last upload date to include (YYYY-MM-DD)")
+	skills := flag.String("skill", "", "comma-separated skills to export (default all)")
+	formatValue := flag.String("format", "", "csv or xlsx (default from the output extension, else csv)")
+	outputPath := flag.String("output", "-", "output path, or - for stdout")
+	flag.Parse()
+
+	if *formatValue == "" && strings.EqualFold(filepath.Ext(*outputPath), ".xlsx") {
+		*formatValue = string(gradebook.XLSX)
+	}
+	format, err := gradebook.ParseFormat(*formatValue)
+	if err != nil {
+		fatalf("parse format: %v", err)
+	}
+	filter, err := gradebook.NewFilter(*classID, *from, *to, *skills)
+	if err != nil {
+		fatalf("parse filter: %v", err)
+	}
+
+	cfg, err := config.LoadConfig(*envPath)
+	if err != nil || cfg == nil {
+		fatalf("load config: %v", err)
+	}
+	client, err := db.NewFirestoreClient(cfg.GCP.ProjectID, cfg.GCP.Database.DataDB, cfg.GCP.Database.SessionDB)
+	if err != nil {
+		fatalf("create firestore client: %v", err)
+	}
+	client.Timeout = cfg.GCP.Database.Timeout
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	users, err := client.ListUsers(ctx)
+	if err != nil {
+		fatalf("list users: %v", err)
+	}
+
+	rows := gradebook.Build(*users, filter)
+	var out bytes.Buffer
+	if err := gradebook.Write(&out, format, rows); err != nil {
+		fatalf("write gradebook: %v", err)
+	}
+
+	var output io.Writer = os.Stdout
+	if *outputPath != "-" {
+		file, err := os.Create(*outputPath)
+		if err != nil {
+			fatalf("create output: %v", err)
+		}
+		defer file.Close()
+		output = file
+	}
+	if _, err := out.WriteTo(output); err != nil {
+		fatalf("write output: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "exported %d rows\n", len(rows))
+}
+
+func fatalf(format string, values ...any) {
+	fmt.Fprintf(os.Stderr, format+"\n", values...)
+	os.Exit(1)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/HeavenAQ/nstc-linebot-2025/api/db"
+	"github.com/HeavenAQ/nstc-linebot-2025/api/storage"
+	"github.com/HeavenAQ/nstc-linebot-2025/config"
+	"github.com/HeavenAQ/nstc-linebot-2025/integrity"
+)
+
+func main() {
+	envPath := flag.String("env", ".env", "path to the .env file")
+	userID := flag.String("user", "", "only check this student's objects and works")
+	fix := flag.Bool("fix", false, "delete orphans and save repairs (default is a dry run)")
+	grace := flag.Duration("grace", 24*time.Hour, "keep orphans written more recently than this")
+	flag.Parse()
+
+	cfg, err := config.LoadConfig(*envPath)
+	if err != nil || cfg == nil {
+		fatalf("load config: %v", err)
+	}
+	firestoreClient, err := db.NewFirestoreClient(cfg.GCP.ProjectID, cfg.GCP.Database.DataDB, cfg.GCP.Database.SessionDB)
+	if err != nil {
+		fatalf("create firestore client: %v", err)
+	}
+	firestoreClient.Timeout = cfg.GCP.Database.Timeout
+	storageClient, err := storage.Open(cfg.GCP.Storage)
+	if err != nil {
+		fatalf("create storage client: %v", err)
+	}
+	defer storageClient.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var users []db.UserData
+	if *userID != "" {
+		user, err := firestoreClient.GetUserData(ctx, *userID)
+		if err != nil {
+			fatalf("get user: %v", err)
+		}
+		users = []db.UserData{*user}
+	} else {
+		all, err := firestoreClient.ListUsers(ctx)
+		if err != nil {
+			fatalf("list users: %v", err)
+		}
+		users = *all
+	}
+
+	report, err := integrity.NewChecker(storageClient, cfg.GCP.Storage.BucketName).Check(ctx, users)
+	if err != nil {
+		fatalf("check: %v", err)
+	}
+	for _, orphan := range report.Orphans {
+		fmt.Printf("orphan\t%s\tupdated %s\n", orphan.Path, orphan.Updated.Format(time.RFC3339))
+	}
+	for _, finding := range report.Findings {
+		action := finding.Fix
+		if action == "" {
+			action = "report only"
+		}
+		fmt.Printf(
+			"%s\t%s %s %s\t%s\t%s\t%s\n",
+			finding.Problem, finding.UserID, finding.Skill, finding.Date, finding.Field, finding.Ref, action,
+		)
+	}
+	fmt.Fprintf(
+		os.Stderr, "checked %d users: %d orphans, %d broken references, %d repairable works\n",
+		len(users), len(report.Orphans), len(report.Findings), len(report.Repairs),
+	)
+	if !*fix {
+		fmt.Fprintln(os.Stderr, "dry run; pass -fix to delete orphans and save repairs")
+		return
+	}
+
+	result, err := integrity.Fix(ctx, report, storageClient, firestoreClient, time.Now().Add(-*grace))
+	fmt.Fprintf(
+		os.Stderr, "deleted %d orphans, kept %d newer than %s, repaired %d works\n",
+		result.Deleted, result.Kept, *grace, result.Repaired,
+	)
+	if err != nil {
+		fatalf("fix: %v", err)
+	}
+}
+
+func fatalf(format string, values ...any) {
+	fmt.Fprintf(os.Stderr, format+"\n", values...)
+	os.Exit(1)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/HeavenAQ/nstc-linebot-2025/api/analysis"
+	"github.com/HeavenAQ/nstc-linebot-2025/api/db"
+	"github.com/HeavenAQ/nstc-linebot-2025/api/storage"
+	"github.com/HeavenAQ/nstc-linebot-2025/config"
+	"github.com/HeavenAQ/nstc-linebot-2025/reanalysis"
+)
+
+func main() {
+	envPath := flag.String("env", ".env", "path to the .env file")
+	userID := flag.String("user", "", "only re-analyze this student's work")
+	skill := flag.String("skill", "", "only re-analyze this skill")
+	flag.Parse()
+
+	scope := reanalysis.Scope{UserID: *userID, Skill: *skill}
+	if err := scope.Validate(); err != nil {
+		fatalf("parse scope: %v", err)
+	}
+
+	cfg, err := config.LoadConfig(*envPath)
+	if err != nil || cfg == nil {
+		fatalf("load config: %v", err)
+	}
+	firestoreClient, err := db.NewFirestoreClient(cfg.GCP.ProjectID, cfg.GCP.Database.DataDB, cfg.GCP.Database.SessionDB)
+	if err != nil {
+		fatalf("create firestore client: %v", err)
+	}
+	firestoreClient.Timeout = cfg.GCP.Database.Timeout
+	storageClient, err := storage.Open(cfg.GCP.Storage)
+	if err != nil {
+		fatalf("create storage client: %v", err)
+	}
+	defer storageClient.Close()
+	analysisClient, err := analysis.NewClient(cfg.AnalysisServer.Target, cfg.AnalysisServer.APIKey, cfg.AnalysisServer.Insecure)
+	if err != nil {
+		fatalf("create analysis client: %v", err)
+	}
+	defer analysisClient.Close()
+	analysisClient.SetTimeout(cfg.AnalysisServer.Timeout)
+
+	// Ctrl-C stops the re-analysis between and during videos.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	runner := reanalysis.NewRunner(firestoreClient, storageClient, analysisClient, logger)
+	started := time.Now()
+	result, err := runner.Run(ctx, scope)
+	fmt.Fprintf(
+		os.Stderr, "re-analyzed %d works, skipped %d without an original, %d failed in %s\n",
+		result.Reanalyzed, result.Skipped, result.Failed, time.Since(started).Round(time.Second),
+	)
+	if err != nil {
+		fatalf("re-analysis: %v", err)
+	}
+}
+
+func fatalf(format string, values ...any) {
+	fmt.Fprintf(os.Stderr, format+"\n", values...)
+	os.Exit(1)
+}
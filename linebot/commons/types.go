@@ -71,4 +71,5 @@ type AnalysisOutcome struct {
 	CoachingCues    []CoachingCue      `json:"coaching_cues" firestore:"coaching_cues"`
 	OverallFeedback string             `json:"overall_feedback" firestore:"overall_feedback"`
 	Diagnostics     map[string]float64 `json:"diagnostics" firestore:"diagnostics"`
+	AnalyzerVersion string             `json:"analyzer_version" firestore:"analyzer_version"`
 }
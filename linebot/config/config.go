@@ -2,14 +2,18 @@ package config
 
 import (
 	"log"
+	"time"
 
 	env "github.com/Netflix/go-env"
 	"github.com/joho/godotenv"
 )
 
+// LineConfig holds the channel credentials. Each call to the Messaging API
+// gives up after Timeout.
 type LineConfig struct {
-	ChannelSecret string `env:"LINE_CHANNEL_SECRET"`
-	ChannelToken  string `env:"LINE_CHANNEL_TOKEN"`
+	ChannelSecret string        `env:"LINE_CHANNEL_SECRET"`
+	ChannelToken  string        `env:"LINE_CHANNEL_TOKEN"`
+	Timeout       time.Duration `env:"LINE_TIMEOUT,default=10s"`
 }
 
 type GCPConfig struct {
@@ -20,28 +24,182 @@ type GCPConfig struct {
 	Database    FirestoreConfig
 }
 
+// StorageConfig selects where uploads are kept. Backend "gcs" uses the
+// Cloud Storage bucket; "local" keeps objects under LocalRoot and serves their
+// signed URLs from LocalBaseURL, the server's /storage route, so development
+// and offline tests need no cloud credentials. Each upload, listing or delete
+// gives up after Timeout.
 type StorageConfig struct {
-	BucketName string `env:"GCS_BUCKET_NAME"`
+	BucketName   string        `env:"GCS_BUCKET_NAME"`
+	Backend      string        `env:"STORAGE_BACKEND,default=gcs"`
+	LocalRoot    string        `env:"STORAGE_LOCAL_ROOT,default=.storage"`
+	LocalBaseURL string        `env:"STORAGE_LOCAL_BASE_URL,default=http://localhost:8080/storage"`
+	Timeout      time.Duration `env:"STORAGE_TIMEOUT,default=2m"`
 }
 type SecretManagerConfig struct {
 	SecretVersion string `env:"GCP_SECRET_VERSION"`
 }
 
+// FirestoreConfig names the collections. Each read or write gives up after
+// Timeout.
 type FirestoreConfig struct {
-	DataDB    string `env:"FIREBASE_DATA_DB"`
-	SessionDB string `env:"FIREBASE_SESSION_DB"`
+	DataDB    string        `env:"FIREBASE_DATA_DB"`
+	SessionDB string        `env:"FIREBASE_SESSION_DB"`
+	Timeout   time.Duration `env:"FIRESTORE_TIMEOUT,default=10s"`
 }
 
+// GPTConfig configures the OpenAI client. Each request gives up after
+// Timeout.
 type GPTConfig struct {
-	APIKey       string `env:"OPENAI_API_KEY"`
-	PromptID     string `env:"OPENAI_PROMPT_ID"`
-	RewriteModel string `env:"OPENAI_REWRITE_MODEL"`
+	APIKey       string        `env:"OPENAI_API_KEY"`
+	PromptID     string        `env:"OPENAI_PROMPT_ID"`
+	RewriteModel string        `env:"OPENAI_REWRITE_MODEL"`
+	Timeout      time.Duration `env:"OPENAI_TIMEOUT,default=60s"`
 }
 
+// AnalysisServerConfig points at the analysis service. WarmupSchedule is a
+// cron spec for health pings that keep an instance warm; it is empty by
+// default because a warm GPU instance is billed. ClassTimetable lists the
+// classes, such as "mon,wed 08:10-10:00; fri 13:20-15:10"; from PrewarmLead
+// before each class until it ends the service is pinged every
+// PrewarmInterval. Calls failing while the service starts or is overloaded
+// are tried up to RetryAttempts times, with jittered backoff from
+// RetryBaseDelay up to RetryMaxDelay. After BreakerFailures such failures in
+// a row, calls are refused for BreakerCooldown. An analysis, with its
+// retries, gives up after Timeout.
 type AnalysisServerConfig struct {
-	Target   string `env:"ANALYSIS_GRPC_TARGET"`
-	APIKey   string `env:"ANALYSIS_GRPC_API_KEY"`
-	Insecure bool   `env:"ANALYSIS_GRPC_INSECURE"`
+	Target          string        `env:"ANALYSIS_GRPC_TARGET"`
+	APIKey          string        `env:"ANALYSIS_GRPC_API_KEY"`
+	Insecure        bool          `env:"ANALYSIS_GRPC_INSECURE"`
+	WarmupSchedule  string        `env:"ANALYSIS_WARMUP_SCHEDULE"`
+	ClassTimetable  string        `env:"CLASS_TIMETABLE"`
+	PrewarmLead     time.Duration `env:"ANALYSIS_PREWARM_LEAD,default=15m"`
+	PrewarmInterval time.Duration `env:"ANALYSIS_PREWARM_INTERVAL,default=5m"`
+	RetryAttempts   int           `env:"ANALYSIS_RETRY_ATTEMPTS,default=3"`
+	RetryBaseDelay  time.Duration `env:"ANALYSIS_RETRY_BASE_DELAY,default=1s"`
+	RetryMaxDelay   time.Duration `env:"ANALYSIS_RETRY_MAX_DELAY,default=10s"`
+	BreakerFailures int           `env:"ANALYSIS_BREAKER_FAILURES,default=5"`
+	BreakerCooldown time.Duration `env:"ANALYSIS_BREAKER_COOLDOWN,default=30s"`
+	Progress        bool          `env:"ANALYSIS_PROGRESS,default=true"`
+	Timeout         time.Duration `env:"ANALYSIS_TIMEOUT,default=30m"`
+}
+
+// RankingConfig controls the class percentile ranking. Percentiles stay
+// hidden until MinCohort students have submitted, so a small class cannot be
+// used to work out a classmate's grade. A class's grades are read again once
+// they are CacheTTL old.
+type RankingConfig struct {
+	MinCohort int           `env:"RANK_MIN_COHORT,default=5"`
+	CacheTTL  time.Duration `env:"RANK_CACHE_TTL,default=1m"`
+}
+
+// AdminConfig guards the teacher-only /api/admin endpoints. Requests must send
+// APIKey as a bearer token; with no key configured they are all refused.
+// MetricsToken is the bearer token for /metrics, so a scraper need not hold
+// the admin key; it falls back to APIKey.
+type AdminConfig struct {
+	APIKey       string `env:"ADMIN_API_KEY"`
+	MetricsToken string `env:"METRICS_TOKEN"`
+}
+
+// ScrapeToken is the bearer token /metrics accepts.
+func (c AdminConfig) ScrapeToken() string {
+	if c.MetricsToken != "" {
+		return c.MetricsToken
+	}
+	return c.APIKey
+}
+
+// NotificationConfig controls the push messages sent to students, which are
+// checked on the cron Schedule. Nothing is pushed between QuietStart and
+// QuietEnd (hours, server local time); equal hours disable quiet hours. The
+// weekly digest goes out on DigestWeekday (0 is Sunday) from DigestHour
+// onwards.
+type NotificationConfig struct {
+	Enabled       bool          `env:"NOTIFY_ENABLED,default=false"`
+	Schedule      string        `env:"NOTIFY_SCHEDULE,default=*/15 * * * *"`
+	QuietStart    int           `env:"NOTIFY_QUIET_START,default=22"`
+	QuietEnd      int           `env:"NOTIFY_QUIET_END,default=8"`
+	DueWindow     time.Duration `env:"NOTIFY_DUE_WINDOW,default=24h"`
+	NudgeAfter    time.Duration `env:"NOTIFY_NUDGE_AFTER,default=24h"`
+	NudgeWithin   time.Duration `env:"NOTIFY_NUDGE_WITHIN,default=168h"`
+	DigestWeekday int           `env:"NOTIFY_DIGEST_WEEKDAY,default=0"`
+	DigestHour    int           `env:"NOTIFY_DIGEST_HOUR,default=20"`
+}
+
+// SchedulerConfig controls the in-process job scheduler. Instances elect a
+// leader through a Firestore lease lasting LeaseTTL, and only the leader runs
+// scheduled jobs.
+type SchedulerConfig struct {
+	Enabled  bool          `env:"SCHEDULER_ENABLED,default=false"`
+	LeaseTTL time.Duration `env:"SCHEDULER_LEASE_TTL,default=90s"`
+}
+
+// ThumbnailConfig names the analysis phase each skill's thumbnail is taken at:
+// start, transition, contact, follow_through or end. A clip whose timeline
+// lacks the phase gets a frame from halfway through instead.
+type ThumbnailConfig struct {
+	ServePhase string `env:"THUMBNAIL_PHASE_SERVE,default=contact"`
+	SmashPhase string `env:"THUMBNAIL_PHASE_SMASH,default=contact"`
+	ClearPhase string `env:"THUMBNAIL_PHASE_CLEAR,default=contact"`
+	LiftPhase  string `env:"THUMBNAIL_PHASE_LIFT,default=contact"`
+}
+
+// Phase returns the thumbnail phase configured for skill.
+func (c ThumbnailConfig) Phase(skill string) string {
+	switch skill {
+	case "serve":
+		return c.ServePhase
+	case "smash":
+		return c.SmashPhase
+	case "clear":
+		return c.ClearPhase
+	case "lift":
+		return c.LiftPhase
+	default:
+		return ""
+	}
+}
+
+// PlaybackConfig controls the signed URLs videos are played from. With
+// LocalSigning the bot signs V4 URLs lasting URLLifetime for objects in
+// GCS_BUCKET_NAME, falling back to the analyzer when it cannot. A URL is
+// reused while at least MinRemaining of it is left.
+type PlaybackConfig struct {
+	LocalSigning bool          `env:"PLAYBACK_LOCAL_SIGNING,default=true"`
+	URLLifetime  time.Duration `env:"PLAYBACK_URL_LIFETIME,default=1h"`
+	MinRemaining time.Duration `env:"PLAYBACK_URL_MIN_REMAINING,default=10m"`
+}
+
+// HealthConfig bounds how long /readyz waits on each dependency.
+// AnalysisReportOnly leaves the analysis service out of readiness, for a
+// deployment whose analyzer scales to zero; the admin status still checks it.
+type HealthConfig struct {
+	CheckTimeout       time.Duration `env:"HEALTH_CHECK_TIMEOUT,default=5s"`
+	AnalysisReportOnly bool          `env:"HEALTH_ANALYSIS_REPORT_ONLY,default=false"`
+}
+
+// TracingConfig chooses where spans go: "stdout", "otlp" (set up by the
+// standard OTEL_EXPORTER_OTLP_* variables) or "none". SampleRatio is the share
+// of new traces recorded.
+type TracingConfig struct {
+	Exporter    string  `env:"TRACING_EXPORTER,default=none"`
+	SampleRatio float64 `env:"TRACING_SAMPLE_RATIO,default=1"`
+	ServiceName string  `env:"OTEL_SERVICE_NAME,default=linebot"`
+}
+
+// LoggingConfig sets the lowest level logged: "debug", "info", "warn" or
+// "error".
+type LoggingConfig struct {
+	Level string `env:"LOG_LEVEL,default=info"`
+}
+
+// ShutdownConfig bounds how long a stopping server waits for webhooks,
+// analyses and jobs in progress before cancelling them. Cloud Run kills an
+// instance 10s after SIGTERM, so the default leaves time to record what was
+// cancelled and close the clients.
+type ShutdownConfig struct {
+	GracePeriod time.Duration `env:"SHUTDOWN_GRACE_PERIOD,default=5s"`
 }
 
 type Config struct {
@@ -50,6 +208,16 @@ type Config struct {
 	GCP            GCPConfig
 	GPT            GPTConfig
 	AnalysisServer AnalysisServerConfig
+	Ranking        RankingConfig
+	Admin          AdminConfig
+	Notification   NotificationConfig
+	Scheduler      SchedulerConfig
+	Thumbnail      ThumbnailConfig
+	Playback       PlaybackConfig
+	Health         HealthConfig
+	Tracing        TracingConfig
+	Logging        LoggingConfig
+	Shutdown       ShutdownConfig
 }
 
 func (c *Config) isConfigEmpty() bool {
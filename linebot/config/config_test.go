@@ -3,6 +3,7 @@ package config_test
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/HeavenAQ/nstc-linebot-2025/config"
 	"github.com/stretchr/testify/require"
@@ -25,6 +26,7 @@ func TestLoadConfig(t *testing.T) {
 	t.Setenv("ANALYSIS_GRPC_TARGET", "analysis.example.test:443")
 	t.Setenv("ANALYSIS_GRPC_API_KEY", "test_analysis_api_key")
 	t.Setenv("ANALYSIS_GRPC_INSECURE", "false")
+	t.Setenv("ADMIN_API_KEY", "test_admin_api_key")
 	t.Setenv("PORT", "8080")
 
 	// Load config
@@ -49,4 +51,44 @@ func TestLoadConfig(t *testing.T) {
 	require.Equal(t, "test_analysis_api_key", config.AnalysisServer.APIKey)
 	require.False(t, config.AnalysisServer.Insecure)
 	require.Equal(t, "8080", config.Port)
+	require.Equal(t, 5, config.Ranking.MinCohort)
+	require.Equal(t, time.Minute, config.Ranking.CacheTTL)
+	require.Equal(t, "test_admin_api_key", config.Admin.APIKey)
+	require.Equal(t, "test_admin_api_key", config.Admin.ScrapeToken())
+	require.False(t, config.Notification.Enabled)
+	require.Equal(t, "*/15 * * * *", config.Notification.Schedule)
+	require.Equal(t, 22, config.Notification.QuietStart)
+	require.Equal(t, 8, config.Notification.QuietEnd)
+	require.Equal(t, 24*time.Hour, config.Notification.DueWindow)
+	require.Equal(t, 7*24*time.Hour, config.Notification.NudgeWithin)
+	require.Equal(t, 20, config.Notification.DigestHour)
+	require.Empty(t, config.AnalysisServer.WarmupSchedule)
+	require.Empty(t, config.AnalysisServer.ClassTimetable)
+	require.Equal(t, 15*time.Minute, config.AnalysisServer.PrewarmLead)
+	require.Equal(t, 5*time.Minute, config.AnalysisServer.PrewarmInterval)
+	require.False(t, config.Scheduler.Enabled)
+	require.Equal(t, 90*time.Second, config.Scheduler.LeaseTTL)
+	require.Equal(t, "contact", config.Thumbnail.Phase("serve"))
+	require.Equal(t, "contact", config.Thumbnail.Phase("lift"))
+	require.Empty(t, config.Thumbnail.Phase("drop"))
+	require.Equal(t, 3, config.AnalysisServer.RetryAttempts)
+	require.Equal(t, 30*time.Second, config.AnalysisServer.BreakerCooldown)
+	require.True(t, config.AnalysisServer.Progress)
+	require.Equal(t, "gcs", config.GCP.Storage.Backend)
+	require.Equal(t, ".storage", config.GCP.Storage.LocalRoot)
+	require.True(t, config.Playback.LocalSigning)
+	require.Equal(t, time.Hour, config.Playback.URLLifetime)
+	require.Equal(t, 10*time.Minute, config.Playback.MinRemaining)
+	require.Equal(t, 5*time.Second, config.Health.CheckTimeout)
+	require.False(t, config.Health.AnalysisReportOnly)
+	require.Equal(t, "none", config.Tracing.Exporter)
+	require.Equal(t, 1.0, config.Tracing.SampleRatio)
+	require.Equal(t, "linebot", config.Tracing.ServiceName)
+	require.Equal(t, "info", config.Logging.Level)
+	require.Equal(t, 5*time.Second, config.Shutdown.GracePeriod)
+	require.Equal(t, 10*time.Second, config.Line.Timeout)
+	require.Equal(t, 10*time.Second, config.GCP.Database.Timeout)
+	require.Equal(t, 2*time.Minute, config.GCP.Storage.Timeout)
+	require.Equal(t, time.Minute, config.GPT.Timeout)
+	require.Equal(t, 30*time.Minute, config.AnalysisServer.Timeout)
 }
@@ -0,0 +1,176 @@
+package gradebook
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/HeavenAQ/nstc-linebot-2025/api/db"
+	"github.com/xuri/excelize/v2"
+)
+
+// Format is a file type the gradebook can be exported as.
+type Format string
+
+const (
+	CSV  Format = "csv"
+	XLSX Format = "xlsx"
+)
+
+const sheetName = "成績"
+
+// utf8BOM lets Excel detect the encoding of a CSV with Chinese headers.
+const utf8BOM = "\ufeff"
+
+func ParseFormat(value string) (Format, error) {
+	switch Format(strings.ToLower(strings.TrimSpace(value))) {
+	case "", CSV:
+		return CSV, nil
+	case XLSX:
+		return XLSX, nil
+	default:
+		return "", fmt.Errorf("unsupported export format %q", value)
+	}
+}
+
+func (f Format) ContentType() string {
+	if f == XLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Write encodes rows in the given format.
+func Write(w io.Writer, format Format, rows []Row) error {
+	header, records := table(rows)
+	if format == XLSX {
+		return writeXLSX(w, header, records)
+	}
+	return writeCSV(w, header, records)
+}
+
+// criterionColumn is one grading criterion of one skill. Skills are graded on
+// different criteria, so each gets its own columns, left blank for rows of
+// other skills.
+type criterionColumn struct {
+	skill string
+	key   string
+	label string
+}
+
+// table lays rows out as cells. Cells hold numbers where the value is numeric
+// so spreadsheets can sort and sum them; a nil cell is left empty.
+func table(rows []Row) ([]string, [][]any) {
+	var columns []criterionColumn
+	seen := map[string]bool{}
+	for _, row := range rows {
+		for _, detail := range row.Details {
+			key := db.CriterionKey(detail)
+			if seen[row.Skill+"/"+key] {
+				continue
+			}
+			seen[row.Skill+"/"+key] = true
+			columns = append(columns, criterionColumn{
+				skill: row.Skill,
+				key:   key,
+				label: fmt.Sprintf("%s-%s", db.SkillStrToEnum(row.Skill).ChnString(), detail.Description),
+			})
+		}
+	}
+
+	header := []string{"使用者ID", "姓名", "班級", "動作", "上傳次數", "最佳成績", "最新成績", "最新上傳時間"}
+	for _, column := range columns {
+		header = append(header, column.label)
+	}
+	header = append(header, "學習反思填寫數", "課前檢視要點填寫數")
+
+	records := make([][]any, 0, len(rows))
+	for _, row := range rows {
+		record := []any{
+			row.UserID, row.Name, row.ClassID, db.SkillStrToEnum(row.Skill).ChnString(), row.Attempts,
+		}
+		if row.Attempts > 0 {
+			record = append(record, row.BestGrade, row.LatestGrade, row.LatestDate)
+		} else {
+			record = append(record, nil, nil, nil)
+		}
+
+		grades := map[string]float64{}
+		for _, detail := range row.Details {
+			grades[db.CriterionKey(detail)] = detail.Grade
+		}
+		for _, column := range columns {
+			grade, ok := grades[column.key]
+			if column.skill != row.Skill || !ok {
+				record = append(record, nil)
+				continue
+			}
+			record = append(record, grade)
+		}
+		record = append(record, row.Reflections, row.PreviewNotes)
+		records = append(records, record)
+	}
+	return header, records
+}
+
+func writeCSV(w io.Writer, header []string, records [][]any) error {
+	if _, err := io.WriteString(w, utf8BOM); err != nil {
+		return err
+	}
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("write gradebook header: %w", err)
+	}
+	for _, record := range records {
+		cells := make([]string, len(record))
+		for i, value := range record {
+			cells[i] = csvCell(value)
+		}
+		if err := writer.Write(cells); err != nil {
+			return fmt.Errorf("write gradebook row: %w", err)
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func csvCell(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', 2, 64)
+	case int:
+		return strconv.Itoa(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func writeXLSX(w io.Writer, header []string, records [][]any) error {
+	file := excelize.NewFile()
+	defer file.Close()
+	if err := file.SetSheetName(file.GetSheetName(0), sheetName); err != nil {
+		return err
+	}
+
+	headerCells := make([]any, len(header))
+	for i, value := range header {
+		headerCells[i] = value
+	}
+	if err := file.SetSheetRow(sheetName, "A1", &headerCells); err != nil {
+		return fmt.Errorf("write gradebook header: %w", err)
+	}
+	for i, record := range records {
+		cell, err := excelize.CoordinatesToCellName(1, i+2)
+		if err != nil {
+			return err
+		}
+		if err := file.SetSheetRow(sheetName, cell, &record); err != nil {
+			return fmt.Errorf("write gradebook row: %w", err)
+		}
+	}
+	return file.Write(w)
+}
@@ -0,0 +1,150 @@
+// Package gradebook turns student portfolios into the per-skill grade table
+// teachers export at the end of term.
+package gradebook
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/HeavenAQ/nstc-linebot-2025/api/db"
+	"github.com/HeavenAQ/nstc-linebot-2025/commons"
+)
+
+const dateLayout = "2006-01-02"
+
+// Filter narrows the export. Empty fields leave that dimension unfiltered.
+type Filter struct {
+	ClassID string
+	From    time.Time
+	To      time.Time
+	Skills  []string
+}
+
+// NewFilter parses the query values an export request carries. Dates are
+// YYYY-MM-DD in server local time, and both ends of the range are inclusive.
+func NewFilter(classID, from, to, skills string) (Filter, error) {
+	filter := Filter{ClassID: strings.TrimSpace(classID), Skills: db.BadmintonSkillNames()}
+	var err error
+	if from = strings.TrimSpace(from); from != "" {
+		if filter.From, err = time.ParseInLocation(dateLayout, from, time.Local); err != nil {
+			return Filter{}, fmt.Errorf("invalid from date %q", from)
+		}
+	}
+	if to = strings.TrimSpace(to); to != "" {
+		if filter.To, err = time.ParseInLocation(dateLayout, to, time.Local); err != nil {
+			return Filter{}, fmt.Errorf("invalid to date %q", to)
+		}
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.To.Before(filter.From) {
+		return Filter{}, errors.New("to date is before from date")
+	}
+	if skills = strings.TrimSpace(skills); skills != "" {
+		filter.Skills = nil
+		for _, skill := range strings.Split(skills, ",") {
+			skill = strings.ToLower(strings.TrimSpace(skill))
+			if db.SkillStrToEnum(skill) < 0 {
+				return Filter{}, fmt.Errorf("invalid skill %q", skill)
+			}
+			filter.Skills = append(filter.Skills, skill)
+		}
+	}
+	return filter, nil
+}
+
+// includes reports whether a work keyed by key falls within the date range.
+// Keys that predate the current layout cannot be placed in a range, so they
+// are only exported when no range is set.
+func (f Filter) includes(key string) bool {
+	if f.From.IsZero() && f.To.IsZero() {
+		return true
+	}
+	date, err := time.ParseInLocation(db.WorkKeyLayout, key, time.Local)
+	if err != nil {
+		return false
+	}
+	if !f.From.IsZero() && date.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !date.Before(f.To.AddDate(0, 0, 1)) {
+		return false
+	}
+	return true
+}
+
+// Row is one student's results for one skill. Students who have not
+// submitted within the range still get a row, with no attempts, so teachers
+// can see who is missing.
+type Row struct {
+	UserID       string
+	Name         string
+	ClassID      string
+	Skill        string
+	Attempts     int
+	BestGrade    float64
+	LatestGrade  float64
+	LatestDate   string
+	Details      []commons.GradingDetail
+	Reflections  int
+	PreviewNotes int
+}
+
+// Build produces the rows for every matching student, ordered by class, name
+// and then the filter's skill order.
+func Build(users []db.UserData, filter Filter) []Row {
+	users = append([]db.UserData(nil), users...)
+	sort.SliceStable(users, func(i, j int) bool {
+		if users[i].ClassID != users[j].ClassID {
+			return users[i].ClassID < users[j].ClassID
+		}
+		if users[i].Name != users[j].Name {
+			return users[i].Name < users[j].Name
+		}
+		return users[i].ID < users[j].ID
+	})
+
+	var rows []Row
+	for _, user := range users {
+		if filter.ClassID != "" && user.ClassID != filter.ClassID {
+			continue
+		}
+		for _, skill := range filter.Skills {
+			rows = append(rows, buildRow(user, skill, filter))
+		}
+	}
+	return rows
+}
+
+func buildRow(user db.UserData, skill string, filter Filter) Row {
+	row := Row{UserID: user.ID, Name: user.Name, ClassID: user.ClassID, Skill: skill}
+	works := map[string]db.Work{}
+	for key, work := range user.Portfolio.GetSkillPortfolio(skill) {
+		if filter.includes(key) {
+			works[key] = work
+		}
+	}
+
+	scores := db.RecentSkillScores(works, 0)
+	if len(scores) == 0 {
+		return row
+	}
+	row.Attempts = len(scores)
+	row.LatestGrade = scores[0].TotalGrade
+	row.LatestDate = scores[0].Date
+	row.Details = scores[0].Details
+	row.BestGrade = scores[0].TotalGrade
+	for _, score := range scores {
+		row.BestGrade = max(row.BestGrade, score.TotalGrade)
+	}
+	for _, work := range works {
+		if work.HasReflection() {
+			row.Reflections++
+		}
+		if work.HasPreviewNote() {
+			row.PreviewNotes++
+		}
+	}
+	return row
+}
@@ -0,0 +1,129 @@
+package gradebook_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/HeavenAQ/nstc-linebot-2025/api/db"
+	"github.com/HeavenAQ/nstc-linebot-2025/commons"
+	"github.com/HeavenAQ/nstc-linebot-2025/gradebook"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func serveWork(total, contact float64, reflection string) db.Work {
+	return db.Work{
+		Reflection:  reflection,
+		PreviewNote: db.DefaultPreviewNote,
+		GradingOutcome: commons.GradingOutcome{
+			TotalGrade: total,
+			GradingDetails: []commons.GradingDetail{
+				{CriterionID: "serve.contact", Description: "擊球點", Grade: contact, Maximum: 20},
+			},
+		},
+	}
+}
+
+func users() []db.UserData {
+	return []db.UserData{
+		{
+			ID: "u2", Name: "Bob", ClassID: "A",
+			Portfolio: db.Portfolios{Serve: map[string]db.Work{
+				"2026-09-01-10-00": serveWork(90, 18, "擊球點要更前面"),
+				"2026-09-20-10-00": serveWork(70, 12, db.DefaultReflection),
+			}},
+		},
+		{ID: "u1", Name: "Amy", ClassID: "A"},
+		{
+			ID: "u3", Name: "Cat", ClassID: "B",
+			Portfolio: db.Portfolios{Serve: map[string]db.Work{"2026-09-02-10-00": serveWork(50, 8, "")}},
+		},
+	}
+}
+
+func TestNewFilterValidatesInput(t *testing.T) {
+	filter, err := gradebook.NewFilter(" A ", "2026-09-01", "2026-09-30", "serve, smash")
+	require.NoError(t, err)
+	require.Equal(t, "A", filter.ClassID)
+	require.Equal(t, []string{"serve", "smash"}, filter.Skills)
+
+	filter, err = gradebook.NewFilter("", "", "", "")
+	require.NoError(t, err)
+	require.Equal(t, db.BadmintonSkillNames(), filter.Skills)
+
+	_, err = gradebook.NewFilter("", "09/01", "", "")
+	require.ErrorContains(t, err, "invalid from date")
+	_, err = gradebook.NewFilter("", "2026-09-30", "2026-09-01", "")
+	require.ErrorContains(t, err, "before from date")
+	_, err = gradebook.NewFilter("", "", "", "badminton")
+	require.ErrorContains(t, err, "invalid skill")
+}
+
+func TestBuildSummarizesEachStudentAndSkill(t *testing.T) {
+	filter, err := gradebook.NewFilter("A", "", "", "serve")
+	require.NoError(t, err)
+
+	rows := gradebook.Build(users(), filter)
+
+	require.Len(t, rows, 2)
+	require.Equal(t, "Amy", rows[0].Name)
+	require.Zero(t, rows[0].Attempts)
+
+	bob := rows[1]
+	require.Equal(t, 2, bob.Attempts)
+	require.Equal(t, 90.0, bob.BestGrade)
+	require.Equal(t, 70.0, bob.LatestGrade)
+	require.Equal(t, "2026-09-20-10-00", bob.LatestDate)
+	require.Equal(t, 12.0, bob.Details[0].Grade)
+	require.Equal(t, 1, bob.Reflections)
+	require.Zero(t, bob.PreviewNotes)
+}
+
+func TestBuildRestrictsAttemptsToDateRange(t *testing.T) {
+	filter, err := gradebook.NewFilter("A", "2026-09-01", "2026-09-01", "serve")
+	require.NoError(t, err)
+
+	bob := gradebook.Build(users(), filter)[1]
+
+	require.Equal(t, 1, bob.Attempts)
+	require.Equal(t, 90.0, bob.LatestGrade)
+}
+
+func TestWriteCSV(t *testing.T) {
+	filter, err := gradebook.NewFilter("", "", "", "serve")
+	require.NoError(t, err)
+	var out bytes.Buffer
+
+	require.NoError(t, gradebook.Write(&out, gradebook.CSV, gradebook.Build(users(), filter)))
+
+	require.True(t, strings.HasPrefix(out.String(), "\ufeff"))
+	records, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(out.String(), "\ufeff"))).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+	require.Equal(t, []string{
+		"使用者ID", "姓名", "班級", "動作", "上傳次數", "最佳成績", "最新成績", "最新上傳時間",
+		"發球-擊球點", "學習反思填寫數", "課前檢視要點填寫數",
+	}, records[0])
+	require.Equal(t, []string{"u1", "Amy", "A", "發球", "0", "", "", "", "", "0", "0"}, records[1])
+	require.Equal(t, []string{"u2", "Bob", "A", "發球", "2", "90.00", "70.00", "2026-09-20-10-00", "12.00", "1", "0"}, records[2])
+}
+
+func TestWriteXLSX(t *testing.T) {
+	filter, err := gradebook.NewFilter("B", "", "", "serve")
+	require.NoError(t, err)
+	var out bytes.Buffer
+
+	require.NoError(t, gradebook.Write(&out, gradebook.XLSX, gradebook.Build(users(), filter)))
+
+	file, err := excelize.OpenReader(&out)
+	require.NoError(t, err)
+	defer file.Close()
+	rows, err := file.GetRows("成績")
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	require.Equal(t, "Cat", rows[1][1])
+	require.Equal(t, "50", rows[1][5])
+	require.Equal(t, "8", rows[1][8])
+}
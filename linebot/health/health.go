@@ -0,0 +1,148 @@
+// Package health checks the services the bot depends on. Every check runs
+// with its own timeout, and the last failure of each is remembered so the
+// admin status still shows why a dependency flapped after it recovered.
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Check reports whether a dependency can serve requests.
+type Check func(ctx context.Context) error
+
+// Status is the outcome of one check.
+type Status struct {
+	Name        string     `json:"name"`
+	OK          bool       `json:"ok"`
+	LatencyMS   int64      `json:"latency_ms"`
+	Error       string     `json:"error,omitempty"`
+	CheckedAt   time.Time  `json:"checked_at"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+}
+
+type dependency struct {
+	name        string
+	check       Check
+	reportOnly  bool
+	lastError   string
+	lastErrorAt *time.Time
+}
+
+type Checker struct {
+	timeout time.Duration
+	now     func() time.Time
+
+	mu           sync.Mutex
+	dependencies []*dependency
+}
+
+// NewChecker creates a checker that gives each check timeout to answer.
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout, now: time.Now}
+}
+
+// Add registers a dependency the app is not ready without. Checks run in the
+// order they were added.
+func (c *Checker) Add(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dependencies = append(c.dependencies, &dependency{name: name, check: check})
+}
+
+// AddReportOnly registers a dependency that Run reports but that readiness
+// leaves out, for checks too costly to run on every probe.
+func (c *Checker) AddReportOnly(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dependencies = append(c.dependencies, &dependency{name: name, check: check, reportOnly: true})
+}
+
+// Run checks every dependency at once and reports whether all of those
+// readiness needs passed.
+func (c *Checker) Run(ctx context.Context) ([]Status, bool) {
+	return c.check(ctx, true)
+}
+
+// Ready checks only the dependencies readiness needs, and reports whether
+// all of them passed.
+func (c *Checker) Ready(ctx context.Context) ([]Status, bool) {
+	return c.check(ctx, false)
+}
+
+func (c *Checker) check(ctx context.Context, reportOnly bool) ([]Status, bool) {
+	c.mu.Lock()
+	var dependencies []*dependency
+	for _, dep := range c.dependencies {
+		if reportOnly || !dep.reportOnly {
+			dependencies = append(dependencies, dep)
+		}
+	}
+	c.mu.Unlock()
+
+	statuses := make([]Status, len(dependencies))
+	var wg sync.WaitGroup
+	for i, dep := range dependencies {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses[i] = c.run(ctx, dep)
+		}()
+	}
+	wg.Wait()
+
+	ready := true
+	for i, status := range statuses {
+		ready = ready && (status.OK || dependencies[i].reportOnly)
+	}
+	return statuses, ready
+}
+
+func (c *Checker) run(ctx context.Context, dep *dependency) Status {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	started := c.now()
+	err := call(ctx, dep.check)
+	finished := c.now()
+	status := Status{
+		Name:      dep.name,
+		OK:        err == nil,
+		LatencyMS: finished.Sub(started).Milliseconds(),
+		CheckedAt: finished,
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil {
+		status.Error = err.Error()
+		dep.lastError = status.Error
+		dep.lastErrorAt = &finished
+	}
+	status.LastError = dep.lastError
+	status.LastErrorAt = dep.lastErrorAt
+	return status
+}
+
+// call runs check but gives up when ctx ends, so a client that ignores its
+// context cannot hold up the probe.
+func call(ctx context.Context, check Check) error {
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("check panicked: %v", r)
+			}
+		}()
+		done <- check(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
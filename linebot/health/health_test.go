@@ -0,0 +1,112 @@
+package health_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/HeavenAQ/nstc-linebot-2025/health"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunReportsEveryDependency(t *testing.T) {
+	checker := health.NewChecker(time.Second)
+	checker.Add("firestore", func(context.Context) error { return nil })
+	checker.Add("analysis", func(context.Context) error { return errors.New("not serving") })
+
+	statuses, ready := checker.Run(context.Background())
+
+	require.False(t, ready)
+	require.Len(t, statuses, 2)
+	require.Equal(t, "firestore", statuses[0].Name)
+	require.True(t, statuses[0].OK)
+	require.Empty(t, statuses[0].LastError)
+	require.Equal(t, "analysis", statuses[1].Name)
+	require.False(t, statuses[1].OK)
+	require.Equal(t, "not serving", statuses[1].Error)
+	require.Equal(t, "not serving", statuses[1].LastError)
+	require.NotNil(t, statuses[1].LastErrorAt)
+}
+
+func TestRunTimesOutSlowChecks(t *testing.T) {
+	checker := health.NewChecker(20 * time.Millisecond)
+	release := make(chan struct{})
+	defer close(release)
+	checker.Add("line", func(context.Context) error {
+		<-release // ignores its context
+		return nil
+	})
+	checker.Add("storage", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	started := time.Now()
+	statuses, ready := checker.Run(context.Background())
+
+	require.False(t, ready)
+	require.Less(t, time.Since(started), time.Second)
+	for _, status := range statuses {
+		require.ErrorContains(t, errors.New(status.Error), context.DeadlineExceeded.Error())
+	}
+}
+
+func TestRunKeepsLastErrorAfterRecovery(t *testing.T) {
+	checker := health.NewChecker(time.Second)
+	failing := true
+	checker.Add("firestore", func(context.Context) error {
+		if failing {
+			return errors.New("unavailable")
+		}
+		return nil
+	})
+
+	_, ready := checker.Run(context.Background())
+	require.False(t, ready)
+	failing = false
+	statuses, ready := checker.Run(context.Background())
+
+	require.True(t, ready)
+	require.True(t, statuses[0].OK)
+	require.Empty(t, statuses[0].Error)
+	require.Equal(t, "unavailable", statuses[0].LastError)
+}
+
+func TestRunRecoversPanickingChecks(t *testing.T) {
+	checker := health.NewChecker(time.Second)
+	checker.Add("gpt", func(context.Context) error { panic("nil client") })
+
+	statuses, ready := checker.Run(context.Background())
+
+	require.False(t, ready)
+	require.Contains(t, statuses[0].Error, "nil client")
+}
+
+func TestReadyLeavesOutReportOnlyDependencies(t *testing.T) {
+	checker := health.NewChecker(time.Second)
+	checker.Add("firestore", func(context.Context) error { return nil })
+	calls := 0
+	checker.AddReportOnly("analysis", func(context.Context) error {
+		calls++
+		return errors.New("cold")
+	})
+
+	statuses, ready := checker.Ready(context.Background())
+	require.True(t, ready)
+	require.Len(t, statuses, 1)
+	require.Zero(t, calls)
+
+	statuses, ready = checker.Run(context.Background())
+	require.True(t, ready)
+	require.Len(t, statuses, 2)
+	require.False(t, statuses[1].OK)
+	require.Equal(t, 1, calls)
+}
+
+func TestRunWithoutDependenciesIsReady(t *testing.T) {
+	statuses, ready := health.NewChecker(time.Second).Run(context.Background())
+
+	require.True(t, ready)
+	require.Empty(t, statuses)
+}
@@ -0,0 +1,336 @@
+// Package integrity reconciles stored objects with the works that point at
+// them. It reports objects no work references and references to objects that
+// are gone, and can delete or repair them.
+package integrity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/HeavenAQ/nstc-linebot-2025/api/db"
+	"github.com/HeavenAQ/nstc-linebot-2025/api/storage"
+)
+
+// Bucket lists and deletes objects; *storage.BucketClient satisfies it.
+type Bucket interface {
+	ListObjects(ctx context.Context, prefix string) ([]storage.ObjectAttrs, error)
+	DeleteFile(ctx context.Context, filePath string) error
+}
+
+// Store saves repaired works; *db.FirestoreClient satisfies it.
+type Store interface {
+	UpdateWorkReferences(
+		ctx context.Context, userID, skill, date string, changes map[string]any, updateTime time.Time,
+	) error
+}
+
+// Fields of a work that reference stored objects.
+const (
+	FieldThumbnail     = "thumbnail"
+	FieldOriginalVideo = "original_video"
+	FieldStudentVideo  = "student_video"
+	FieldExpertVideo   = "expert_video"
+	FieldCueClip       = "cue_clip"
+	FieldSkeletonVideo = "skeleton_video"
+)
+
+// Problems a reference can have.
+const (
+	ProblemMissing   = "missing object"
+	ProblemLegacyURL = "legacy URL"
+)
+
+// placeholderName is the empty object that makes a new user's folder show up
+// in the console.
+const placeholderName = ".folder_placeholder"
+
+// Orphan is an object in a user's folders that no work references.
+type Orphan struct {
+	Path    string
+	Updated time.Time
+}
+
+// Finding is a broken reference. Fix says how a repair changes the work; it
+// is empty when the reference can only be reported.
+type Finding struct {
+	UserID  string
+	Skill   string
+	Date    string
+	Field   string
+	Ref     string
+	Problem string
+	Fix     string
+}
+
+// Repair is the new value of each repairable reference of a work, keyed as
+// db.UpdateWorkReferences takes them. UpdateTime is the user's as checked, so
+// a repair is refused once the work may have changed since.
+type Repair struct {
+	UserID     string
+	Skill      string
+	Date       string
+	Changes    map[string]any
+	UpdateTime time.Time
+}
+
+type Report struct {
+	Orphans  []Orphan
+	Findings []Finding
+	Repairs  []Repair
+}
+
+type Checker struct {
+	bucket     Bucket
+	bucketName string
+	exists     map[string]bool
+}
+
+// NewChecker checks references into bucketName, which also identifies the
+// public and signed URLs older works stored instead of object paths.
+func NewChecker(bucket Bucket, bucketName string) *Checker {
+	return &Checker{bucket: bucket, bucketName: bucketName, exists: map[string]bool{}}
+}
+
+// UserPrefixes are the folders holding a user's objects: their own folder and
+// the analyzer's output for them.
+func UserPrefixes(user db.UserData) []string {
+	root := user.FolderPaths.Root
+	if root == "" {
+		root = user.ID + "/"
+	}
+	return []string{root, "analyses/v1/" + user.ID + "/"}
+}
+
+// Check cross-checks each user's works against the objects in their folders.
+func (c *Checker) Check(ctx context.Context, users []db.UserData) (Report, error) {
+	var report Report
+	for _, user := range users {
+		if user.ID == "" {
+			continue
+		}
+		if err := c.checkUser(ctx, user, &report); err != nil {
+			return report, fmt.Errorf("check user %s: %w", user.ID, err)
+		}
+	}
+	sort.Slice(report.Orphans, func(i, j int) bool { return report.Orphans[i].Path < report.Orphans[j].Path })
+	return report, nil
+}
+
+func (c *Checker) checkUser(ctx context.Context, user db.UserData, report *Report) error {
+	prefixes := UserPrefixes(user)
+	listed := map[string]storage.ObjectAttrs{}
+	for _, prefix := range prefixes {
+		objects, err := c.bucket.ListObjects(ctx, prefix)
+		if err != nil {
+			return err
+		}
+		for _, object := range objects {
+			listed[object.Name] = object
+			c.exists[object.Name] = true
+		}
+	}
+
+	referenced := map[string]bool{}
+	for _, skill := range db.BadmintonSkillNames() {
+		portfolio := user.Portfolio.GetSkillPortfolio(skill)
+		dates := make([]string, 0, len(portfolio))
+		for date := range portfolio {
+			dates = append(dates, date)
+		}
+		sort.Strings(dates)
+		for _, date := range dates {
+			check := workCheck{checker: c, userID: user.ID, skill: skill, date: date, prefixes: prefixes, referenced: referenced}
+			if err := check.run(ctx, portfolio[date]); err != nil {
+				return err
+			}
+			report.Findings = append(report.Findings, check.findings...)
+			if len(check.changes) > 0 {
+				report.Repairs = append(report.Repairs, Repair{
+					UserID: user.ID, Skill: skill, Date: date, Changes: check.changes, UpdateTime: user.UpdateTime,
+				})
+			}
+		}
+	}
+
+	for name, object := range listed {
+		if !referenced[name] && path.Base(name) != placeholderName {
+			report.Orphans = append(report.Orphans, Orphan{Path: name, Updated: object.Updated})
+		}
+	}
+	return nil
+}
+
+// objectExists looks outside the user's folders, such as at expert videos,
+// only once per object.
+func (c *Checker) objectExists(ctx context.Context, prefixes []string, objectPath string) (bool, error) {
+	if exists, ok := c.exists[objectPath]; ok {
+		return exists, nil
+	}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(objectPath, prefix) {
+			return false, nil
+		}
+	}
+	objects, err := c.bucket.ListObjects(ctx, objectPath)
+	if err != nil {
+		return false, err
+	}
+	exists := false
+	for _, object := range objects {
+		exists = exists || object.Name == objectPath
+	}
+	c.exists[objectPath] = exists
+	return exists, nil
+}
+
+// gcsObjectPath returns the object a public or signed Cloud Storage URL
+// points at in the checker's bucket.
+func (c *Checker) gcsObjectPath(rawURL string) (string, bool) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host != "storage.googleapis.com" {
+		return "", false
+	}
+	bucket, objectPath, ok := strings.Cut(strings.TrimPrefix(parsed.Path, "/"), "/")
+	if !ok || bucket != c.bucketName || objectPath == "" {
+		return "", false
+	}
+	return objectPath, true
+}
+
+type workCheck struct {
+	checker    *Checker
+	userID     string
+	skill      string
+	date       string
+	prefixes   []string
+	referenced map[string]bool
+	findings   []Finding
+	changes    map[string]any
+}
+
+func (w *workCheck) report(field, ref, problem, fix string) {
+	w.findings = append(w.findings, Finding{
+		UserID: w.userID, Skill: w.skill, Date: w.date, Field: field, Ref: ref, Problem: problem, Fix: fix,
+	})
+}
+
+// change records the repaired value of the work's field at key.
+func (w *workCheck) change(key string, value any) {
+	if w.changes == nil {
+		w.changes = map[string]any{}
+	}
+	w.changes[key] = value
+}
+
+// exists marks the object referenced and reports whether it is stored.
+func (w *workCheck) exists(ctx context.Context, objectPath string) (bool, error) {
+	w.referenced[objectPath] = true
+	return w.checker.objectExists(ctx, w.prefixes, objectPath)
+}
+
+func (w *workCheck) run(ctx context.Context, work db.Work) error {
+	var errs []error
+	check := func(objectPath string) bool {
+		exists, err := w.exists(ctx, objectPath)
+		errs = append(errs, err)
+		return exists
+	}
+
+	if work.Thumbnail != "" {
+		objectPath := storage.ObjectPath(w.checker.bucketName, work.Thumbnail)
+		switch {
+		case strings.HasPrefix(objectPath, "https://") || strings.HasPrefix(objectPath, "http://"):
+		case !check(objectPath):
+			w.report(FieldThumbnail, work.Thumbnail, ProblemMissing, "")
+		case objectPath != work.Thumbnail:
+			w.report(FieldThumbnail, work.Thumbnail, ProblemLegacyURL, "store the object path")
+			w.change("thumbnail", objectPath)
+		}
+	}
+
+	if work.OriginalVideo != "" && !check(work.OriginalVideo) {
+		w.report(FieldOriginalVideo, work.OriginalVideo, ProblemMissing, "clear it, so re-analysis skips the work")
+		w.change("original_video", "")
+	}
+
+	if work.StudentVideo.ObjectPath != "" && !check(work.StudentVideo.ObjectPath) {
+		w.report(FieldStudentVideo, work.StudentVideo.ObjectPath, ProblemMissing, "")
+	}
+	if work.Expert.Video.ObjectPath != "" && !check(work.Expert.Video.ObjectPath) {
+		w.report(FieldExpertVideo, work.Expert.Video.ObjectPath, ProblemMissing, "")
+	}
+
+	clips := work.CueClips[:0:0]
+	for _, clip := range work.CueClips {
+		videoExists := check(clip.Video)
+		previewExists := check(clip.Preview)
+		switch {
+		case !videoExists:
+			w.report(FieldCueClip, clip.Video, ProblemMissing, "drop the clip")
+		case !previewExists:
+			w.report(FieldCueClip, clip.Preview, ProblemMissing, "drop the clip")
+		default:
+			clips = append(clips, clip)
+		}
+	}
+	if len(clips) != len(work.CueClips) {
+		w.change("cue_clips", clips)
+	}
+
+	// Works from before object paths were stored only have a signed URL,
+	// which expires. If it names an object that is still there, the work can
+	// be played through fresh URLs again.
+	if work.StudentVideo.ObjectPath == "" && work.SkeletonVideo != "" {
+		if objectPath, ok := w.checker.gcsObjectPath(work.SkeletonVideo); ok {
+			if check(objectPath) {
+				w.report(FieldSkeletonVideo, objectPath, ProblemLegacyURL, "use the object as the student video")
+				w.change("student_video.object_path", objectPath)
+			} else {
+				w.report(FieldSkeletonVideo, objectPath, ProblemMissing, "")
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// FixResult counts what Fix changed. Kept counts orphans too recent to
+// delete.
+type FixResult struct {
+	Deleted  int
+	Kept     int
+	Repaired int
+}
+
+// Fix deletes the orphans last written before cutoff and saves the repairs.
+// Newer orphans may belong to an upload still being saved, so they are kept.
+// A failure is reported and does not stop the others.
+func Fix(ctx context.Context, report Report, bucket Bucket, store Store, cutoff time.Time) (FixResult, error) {
+	var result FixResult
+	var errs []error
+	for _, orphan := range report.Orphans {
+		if !orphan.Updated.Before(cutoff) {
+			result.Kept++
+			continue
+		}
+		if err := bucket.DeleteFile(ctx, orphan.Path); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		result.Deleted++
+	}
+	for _, repair := range report.Repairs {
+		err := store.UpdateWorkReferences(ctx, repair.UserID, repair.Skill, repair.Date, repair.Changes, repair.UpdateTime)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s %s %s: %w", repair.UserID, repair.Skill, repair.Date, err))
+			continue
+		}
+		result.Repaired++
+	}
+	return result, errors.Join(errs...)
+}
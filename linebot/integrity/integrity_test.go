@@ -0,0 +1,175 @@
+package integrity_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/HeavenAQ/nstc-linebot-2025/api/db"
+	"github.com/HeavenAQ/nstc-linebot-2025/api/storage"
+	"github.com/HeavenAQ/nstc-linebot-2025/commons"
+	"github.com/HeavenAQ/nstc-linebot-2025/integrity"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	old    = time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	recent = time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+)
+
+type fakeBucket struct {
+	objects map[string]time.Time
+	deleted []string
+	lists   []string
+}
+
+func (b *fakeBucket) ListObjects(_ context.Context, prefix string) ([]storage.ObjectAttrs, error) {
+	b.lists = append(b.lists, prefix)
+	var objects []storage.ObjectAttrs
+	for name, updated := range b.objects {
+		if strings.HasPrefix(name, prefix) {
+			objects = append(objects, storage.ObjectAttrs{Name: name, Updated: updated})
+		}
+	}
+	return objects, nil
+}
+
+func (b *fakeBucket) DeleteFile(_ context.Context, filePath string) error {
+	if _, ok := b.objects[filePath]; !ok {
+		return errors.New("object not found")
+	}
+	delete(b.objects, filePath)
+	b.deleted = append(b.deleted, filePath)
+	return nil
+}
+
+type fakeStore map[string]integrity.Repair
+
+func (s fakeStore) UpdateWorkReferences(
+	_ context.Context, userID, skill, date string, changes map[string]any, updateTime time.Time,
+) error {
+	s[userID+"/"+skill+"/"+date] = integrity.Repair{Changes: changes, UpdateTime: updateTime}
+	return nil
+}
+
+func newFixture() (*fakeBucket, []db.UserData) {
+	bucket := &fakeBucket{objects: map[string]time.Time{
+		"U1/.folder_placeholder":                        old,
+		"U1/thumbnail/2026-09-01-10-00.jpeg":            old,
+		"U1/thumbnail/2026-09-02-10-00.jpeg":            old,
+		"U1/thumbnail/failed-upload.jpeg":               old,
+		"U1/thumbnail/just-uploaded.jpeg":               recent,
+		"U1/originals/serve/2026-09-01-10-00.mp4":       old,
+		"analyses/v1/U1/r1/student_corrected.mp4":       old,
+		"analyses/v1/U1/r1/cues/0.mp4":                  old,
+		"analyses/v1/U1/r1/cues/0.jpeg":                 old,
+		"analyses/v1/U1/legacy/student_corrected.mp4":   old,
+		"analyses/v1/U1/replaced/student_corrected.mp4": old,
+		"experts/v1/serve/videos/e1.mp4":                old,
+	}}
+	users := []db.UserData{{
+		ID:          "U1",
+		UpdateTime:  old,
+		FolderPaths: db.FolderPaths{Root: "U1/"},
+		Portfolio: db.Portfolios{Serve: map[string]db.Work{
+			"2026-09-01-10-00": {
+				Thumbnail:     "U1/thumbnail/2026-09-01-10-00.jpeg",
+				OriginalVideo: "U1/originals/serve/2026-09-01-10-00.mp4",
+				StudentVideo:  commons.MediaRef{ObjectPath: "analyses/v1/U1/r1/student_corrected.mp4"},
+				Expert:        commons.ExpertMatch{Video: commons.MediaRef{ObjectPath: "experts/v1/serve/videos/e1.mp4"}},
+				CueClips: []db.CueClip{
+					{Title: "ok", Video: "analyses/v1/U1/r1/cues/0.mp4", Preview: "analyses/v1/U1/r1/cues/0.jpeg"},
+					{Title: "gone", Video: "analyses/v1/U1/r1/cues/1.mp4", Preview: "analyses/v1/U1/r1/cues/1.jpeg"},
+				},
+			},
+			"2026-09-02-10-00": {
+				Thumbnail:     "https://storage.googleapis.com/bucket/U1/thumbnail/2026-09-02-10-00.jpeg",
+				OriginalVideo: "U1/originals/serve/2026-09-02-10-00.mp4",
+				SkeletonVideo: "https://storage.googleapis.com/bucket/analyses/v1/U1/legacy/student_corrected.mp4?X-Goog-Expires=3600",
+				Expert:        commons.ExpertMatch{Video: commons.MediaRef{ObjectPath: "experts/v1/serve/videos/gone.mp4"}},
+			},
+			"2026-09-03-10-00": {
+				Thumbnail:     "U1/thumbnail/2026-09-03-10-00.jpeg",
+				SkeletonVideo: "https://storage.googleapis.com/bucket/analyses/v1/U1/expired/student_corrected.mp4",
+				StudentVideo:  commons.MediaRef{},
+			},
+		}},
+	}}
+	return bucket, users
+}
+
+func TestCheckReportsOrphansAndBrokenReferences(t *testing.T) {
+	bucket, users := newFixture()
+
+	report, err := integrity.NewChecker(bucket, "bucket").Check(context.Background(), users)
+
+	require.NoError(t, err)
+	orphans := make([]string, 0, len(report.Orphans))
+	for _, orphan := range report.Orphans {
+		orphans = append(orphans, orphan.Path)
+	}
+	require.Equal(t, []string{
+		"U1/thumbnail/failed-upload.jpeg",
+		"U1/thumbnail/just-uploaded.jpeg",
+		"analyses/v1/U1/replaced/student_corrected.mp4",
+	}, orphans)
+
+	type finding struct{ date, field, problem string }
+	var findings []finding
+	for _, f := range report.Findings {
+		findings = append(findings, finding{f.Date, f.Field, f.Problem})
+	}
+	require.Equal(t, []finding{
+		{"2026-09-01-10-00", integrity.FieldCueClip, integrity.ProblemMissing},
+		{"2026-09-02-10-00", integrity.FieldThumbnail, integrity.ProblemLegacyURL},
+		{"2026-09-02-10-00", integrity.FieldOriginalVideo, integrity.ProblemMissing},
+		{"2026-09-02-10-00", integrity.FieldExpertVideo, integrity.ProblemMissing},
+		{"2026-09-02-10-00", integrity.FieldSkeletonVideo, integrity.ProblemLegacyURL},
+		{"2026-09-03-10-00", integrity.FieldThumbnail, integrity.ProblemMissing},
+		{"2026-09-03-10-00", integrity.FieldSkeletonVideo, integrity.ProblemMissing},
+	}, findings)
+	require.Equal(t, 1, strings.Count(strings.Join(bucket.lists, ","), "experts/v1/serve/videos/e1.mp4"))
+}
+
+func TestCheckRepairsWhatItCan(t *testing.T) {
+	bucket, users := newFixture()
+
+	report, err := integrity.NewChecker(bucket, "bucket").Check(context.Background(), users)
+
+	require.NoError(t, err)
+	require.Len(t, report.Repairs, 2)
+	first := report.Repairs[0]
+	require.Equal(t, "2026-09-01-10-00", first.Date)
+	require.Equal(t, old, first.UpdateTime)
+	require.Len(t, first.Changes, 1, "only the repaired fields are written")
+	clips := first.Changes["cue_clips"].([]db.CueClip)
+	require.Len(t, clips, 1)
+	require.Equal(t, "ok", clips[0].Title)
+	require.Equal(t, map[string]any{
+		"thumbnail":                 "U1/thumbnail/2026-09-02-10-00.jpeg",
+		"original_video":            "",
+		"student_video.object_path": "analyses/v1/U1/legacy/student_corrected.mp4",
+	}, report.Repairs[1].Changes)
+	require.Equal(t, "U1/originals/serve/2026-09-02-10-00.mp4", users[0].Portfolio.Serve["2026-09-02-10-00"].OriginalVideo,
+		"the checked works must not change")
+}
+
+func TestFixKeepsRecentOrphans(t *testing.T) {
+	bucket, users := newFixture()
+	report, err := integrity.NewChecker(bucket, "bucket").Check(context.Background(), users)
+	require.NoError(t, err)
+	store := fakeStore{}
+
+	result, err := integrity.Fix(context.Background(), report, bucket, store, recent.Add(-time.Hour))
+
+	require.NoError(t, err)
+	require.Equal(t, integrity.FixResult{Deleted: 2, Kept: 1, Repaired: 2}, result)
+	require.ElementsMatch(t, []string{
+		"U1/thumbnail/failed-upload.jpeg", "analyses/v1/U1/replaced/student_corrected.mp4",
+	}, bucket.deleted)
+	require.Contains(t, bucket.objects, "U1/thumbnail/just-uploaded.jpeg")
+	require.Contains(t, store, "U1/serve/2026-09-02-10-00")
+	require.Equal(t, old, store["U1/serve/2026-09-02-10-00"].UpdateTime)
+}
@@ -0,0 +1,209 @@
+// Package logging writes structured JSON logs in the shape Cloud Logging
+// reads: each line has a severity, a message, its source location and the
+// fields of the webhook event being handled, which travel in the context.
+package logging
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Keys Cloud Logging gives meaning to.
+const (
+	severityKey       = "severity"
+	messageKey        = "message"
+	sourceLocationKey = "logging.googleapis.com/sourceLocation"
+)
+
+// redactedKeys name attributes whose values are never written: credentials,
+// and the text students send the bot.
+var redactedKeys = map[string]bool{
+	"token":         true,
+	"access_token":  true,
+	"api_key":       true,
+	"secret":        true,
+	"password":      true,
+	"authorization": true,
+	"text":          true,
+	"content":       true,
+}
+
+// userIDPattern matches LINE user IDs, which turn up inside error messages
+// such as storage object paths and Firestore document names.
+var userIDPattern = regexp.MustCompile(`U[0-9a-f]{32}`)
+
+// New returns a logger writing JSON lines at or above level to w.
+func New(w io.Writer, level slog.Level) *slog.Logger {
+	return slog.New(&handler{Handler: slog.NewJSONHandler(w, &slog.HandlerOptions{
+		AddSource:   true,
+		Level:       level,
+		ReplaceAttr: replaceAttr,
+	})})
+}
+
+// ParseLevel reads a level name such as "debug", "info", "warn" or "error".
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return 0, fmt.Errorf("invalid log level %q: %w", name, err)
+	}
+	return level, nil
+}
+
+// Severity names a level the way Cloud Logging does.
+func Severity(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return "ERROR"
+	case level >= slog.LevelWarn:
+		return "WARNING"
+	case level >= slog.LevelInfo:
+		return "INFO"
+	default:
+		return "DEBUG"
+	}
+}
+
+func replaceAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) == 0 {
+		switch a.Key {
+		case slog.LevelKey:
+			level, _ := a.Value.Any().(slog.Level)
+			return slog.String(severityKey, Severity(level))
+		case slog.MessageKey:
+			a.Key = messageKey
+			a.Value = slog.StringValue(hashUserIDs(a.Value.String()))
+			return a
+		case slog.SourceKey:
+			source, ok := a.Value.Any().(*slog.Source)
+			if !ok {
+				return a
+			}
+			// Cloud Logging wants the line number as a string.
+			return slog.Group(sourceLocationKey,
+				slog.String("file", source.File),
+				slog.String("line", strconv.Itoa(source.Line)),
+				slog.String("function", source.Function),
+			)
+		}
+	}
+	if redactedKeys[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, "[REDACTED]")
+	}
+	switch a.Value.Kind() {
+	case slog.KindString:
+		a.Value = slog.StringValue(hashUserIDs(a.Value.String()))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			a.Value = slog.StringValue(hashUserIDs(err.Error()))
+		}
+	}
+	return a
+}
+
+// hashUserIDs replaces the LINE user IDs in s with their HashUser pseudonyms.
+func hashUserIDs(s string) string {
+	return userIDPattern.ReplaceAllStringFunc(s, HashUser)
+}
+
+// handler adds the context's fields and trace ID to each record.
+type handler struct {
+	slog.Handler
+}
+
+func (h *handler) Handle(ctx context.Context, record slog.Record) error {
+	record.AddAttrs(fieldsFrom(ctx).attrs()...)
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		record.AddAttrs(slog.String("trace_id", span.TraceID().String()))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &handler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return &handler{Handler: h.Handler.WithGroup(name)}
+}
+
+// fields describe the work a context belongs to.
+type fields struct {
+	eventID   string
+	user      string
+	skill     string
+	state     string
+	requestID string
+}
+
+type fieldsKey struct{}
+
+func fieldsFrom(ctx context.Context) fields {
+	f, _ := ctx.Value(fieldsKey{}).(fields)
+	return f
+}
+
+func (f fields) attrs() []slog.Attr {
+	var attrs []slog.Attr
+	add := func(key, value string) {
+		if value != "" {
+			attrs = append(attrs, slog.String(key, value))
+		}
+	}
+	add("event_id", f.eventID)
+	add("user", f.user)
+	add("skill", f.skill)
+	add("state", f.state)
+	add("request_id", f.requestID)
+	return attrs
+}
+
+func with(ctx context.Context, update func(*fields)) context.Context {
+	f := fieldsFrom(ctx)
+	update(&f)
+	return context.WithValue(ctx, fieldsKey{}, f)
+}
+
+// WithEvent tags logs with the webhook event being handled.
+func WithEvent(ctx context.Context, eventID string) context.Context {
+	return with(ctx, func(f *fields) { f.eventID = eventID })
+}
+
+// WithUser tags logs with the user, whose ID is hashed first.
+func WithUser(ctx context.Context, userID string) context.Context {
+	return with(ctx, func(f *fields) { f.user = HashUser(userID) })
+}
+
+// WithSkill tags logs with the skill the user is working on.
+func WithSkill(ctx context.Context, skill string) context.Context {
+	return with(ctx, func(f *fields) { f.skill = skill })
+}
+
+// WithState tags logs with the user's session state.
+func WithState(ctx context.Context, state string) context.Context {
+	return with(ctx, func(f *fields) { f.state = state })
+}
+
+// WithRequest tags logs with the analysis request ID.
+func WithRequest(ctx context.Context, requestID string) context.Context {
+	return with(ctx, func(f *fields) { f.requestID = requestID })
+}
+
+// HashUser returns a stable pseudonym for a LINE user ID, so one user's
+// lines can be followed without the ID itself being logged.
+func HashUser(userID string) string {
+	if userID == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(userID))
+	return hex.EncodeToString(sum[:8])
+}
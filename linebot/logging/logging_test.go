@@ -0,0 +1,94 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func decode(t *testing.T, out *bytes.Buffer) map[string]any {
+	t.Helper()
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(out.Bytes(), &line))
+	return line
+}
+
+func TestLinesCarrySeverityAndContextFields(t *testing.T) {
+	var out bytes.Buffer
+	logger := New(&out, slog.LevelInfo)
+	ctx := WithEvent(context.Background(), "01H8EVENT")
+	ctx = WithUser(ctx, "U4af4980629c8a4f4e1e0e0c0f8d1e2a3")
+	ctx = WithState(WithSkill(ctx, "serve"), "analyzing_video/uploading_video")
+	ctx = WithRequest(ctx, "468789577898262530")
+
+	logger.WarnContext(ctx, "analysis rejected the video", "bytes", 1024)
+
+	line := decode(t, &out)
+	require.Equal(t, "WARNING", line["severity"])
+	require.Equal(t, "analysis rejected the video", line["message"])
+	require.Equal(t, "01H8EVENT", line["event_id"])
+	require.Equal(t, HashUser("U4af4980629c8a4f4e1e0e0c0f8d1e2a3"), line["user"])
+	require.Equal(t, "serve", line["skill"])
+	require.Equal(t, "analyzing_video/uploading_video", line["state"])
+	require.Equal(t, "468789577898262530", line["request_id"])
+	require.NotContains(t, out.String(), "U4af4980629c8a4f4e1e0e0c0f8d1e2a3")
+
+	source := line["logging.googleapis.com/sourceLocation"].(map[string]any)
+	require.True(t, strings.HasSuffix(source["file"].(string), "logging_test.go"))
+	require.IsType(t, "", source["line"])
+}
+
+func TestSecretsAndMessageTextAreRedacted(t *testing.T) {
+	var out bytes.Buffer
+	logger := New(&out, slog.LevelInfo)
+
+	logger.Info("request", "api_key", "sk-live", "Authorization", "Bearer abc", "text", "我的排名")
+
+	require.NotContains(t, out.String(), "sk-live")
+	require.NotContains(t, out.String(), "Bearer abc")
+	require.NotContains(t, out.String(), "我的排名")
+	require.Equal(t, "[REDACTED]", decode(t, &out)["api_key"])
+}
+
+func TestUserIDsInsideStringsAndErrorsAreHashed(t *testing.T) {
+	var out bytes.Buffer
+	logger := New(&out, slog.LevelInfo)
+	userID := "U4af4980629c8a4f4e1e0e0c0f8d1e2a3"
+	err := fmt.Errorf("failed to sign URL for analyses/v1/%s/serve/clip.mp4: denied", userID)
+
+	logger.Error("could not load user "+userID, "error", err, "path", "users/"+userID)
+
+	require.NotContains(t, out.String(), userID)
+	line := decode(t, &out)
+	require.Equal(t, "could not load user "+HashUser(userID), line["message"])
+	require.Equal(t, "failed to sign URL for analyses/v1/"+HashUser(userID)+"/serve/clip.mp4: denied", line["error"])
+	require.Equal(t, "users/"+HashUser(userID), line["path"])
+}
+
+func TestLevelFiltersLines(t *testing.T) {
+	level, err := ParseLevel("warn")
+	require.NoError(t, err)
+	var out bytes.Buffer
+	logger := New(&out, level)
+
+	logger.Info("skipped")
+	require.Zero(t, out.Len())
+	logger.Error("kept")
+	require.Equal(t, "ERROR", decode(t, &out)["severity"])
+
+	_, err = ParseLevel("loud")
+	require.Error(t, err)
+}
+
+func TestHashUserIsStableAndEmptyForNoUser(t *testing.T) {
+	require.Equal(t, HashUser("U1"), HashUser("U1"))
+	require.NotEqual(t, HashUser("U1"), HashUser("U2"))
+	require.Len(t, HashUser("U1"), 16)
+	require.Empty(t, HashUser(""))
+}
@@ -1,13 +1,27 @@
 package main
 
 import (
-	"log"
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/HeavenAQ/nstc-linebot-2025/api/db"
 	"github.com/HeavenAQ/nstc-linebot-2025/app"
+	"github.com/HeavenAQ/nstc-linebot-2025/gradebook"
+	"github.com/HeavenAQ/nstc-linebot-2025/logging"
+	"github.com/HeavenAQ/nstc-linebot-2025/metrics"
+	"github.com/HeavenAQ/nstc-linebot-2025/reanalysis"
+	"github.com/HeavenAQ/nstc-linebot-2025/scheduler"
+	"github.com/HeavenAQ/nstc-linebot-2025/tracing"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
@@ -21,6 +35,13 @@ func main() {
 	gin.SetMode(gin.ReleaseMode)
 	application := app.NewApp(".env")
 
+	shutdownTracing, err := tracing.Setup(context.Background(), application.Config.Tracing)
+	if err != nil {
+		application.Logger.Error("Setting up tracing failed", "error", err)
+		os.Exit(1)
+	}
+	defer shutdownTracing(context.Background())
+
 	r := gin.New()
 	r.Use(gin.Recovery())
 
@@ -41,20 +62,51 @@ func main() {
 	})
 	r.GET("/test", func(c *gin.Context) { c.String(http.StatusOK, "Hello, World!") })
 
+	r.GET("/metrics", requireAdmin(application.Config.Admin.ScrapeToken()), gin.WrapH(metrics.Handler()))
+
+	// Liveness answers while the process serves requests; readiness also
+	// needs every dependency. Readiness is public, so it names the failing
+	// checks without their errors.
+	r.GET("/healthz", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"status": "ok"}) })
+	r.GET("/readyz", func(c *gin.Context) {
+		statuses, ready := application.Health.Ready(c.Request.Context())
+		checks := make(map[string]bool, len(statuses))
+		for _, status := range statuses {
+			checks[status.Name] = status.OK
+			if !status.OK {
+				application.Logger.WarnContext(c.Request.Context(), "dependency not ready", "dependency", status.Name, "error", status.Error)
+			}
+		}
+		code := http.StatusOK
+		if !ready {
+			code = http.StatusServiceUnavailable
+		}
+		c.JSON(code, gin.H{"ready": ready, "checks": checks})
+	})
+
+	// Signed URLs of the local storage backend
+	if application.StorageClient != nil {
+		if files := application.StorageClient.FileServer(); files != nil {
+			serveFiles := gin.WrapH(http.StripPrefix("/storage", files))
+			r.GET("/storage/*object", serveFiles)
+			r.HEAD("/storage/*object", serveFiles)
+		}
+	}
+
 	// Backend APIs for chat history and summarization
 	r.GET("/api/chat/history", func(c *gin.Context) {
 		start := time.Now()
 		userID := c.Query("user_id")
 		if userID == "" {
-			application.Logger.Warn.Println("[chat.history] missing user_id")
+			application.Logger.WarnContext(c.Request.Context(), "chat history: missing user_id")
 			c.JSON(http.StatusBadRequest, gin.H{"error": "missing user_id"})
 			return
 		}
 		skill := strings.ToLower(strings.TrimSpace(c.Query("skill")))
-		application.Logger.Info.Printf("[chat.history] user_id=%s skill=%s", userID, skill)
-		history, err := application.FirestoreClient.GetChatHistory(userID)
+		ctx := logging.WithSkill(logging.WithUser(c.Request.Context(), userID), skill)
+		history, err := application.FirestoreClient.GetChatHistory(ctx, userID)
 		if err != nil {
-			application.Logger.Error.Printf("[chat.history] user_id=%s error=%v", userID, err)
+			application.Logger.ErrorContext(ctx, "chat history: fetch failed", "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch chat history"})
 			return
 		}
@@ -66,11 +118,11 @@ func main() {
 					filtered = append(filtered, m)
 				}
 			}
-			application.Logger.Info.Printf("[chat.history] user_id=%s skill=%s count=%d took=%s", userID, skill, len(filtered), time.Since(start))
+			application.Logger.InfoContext(ctx, "chat history served", "count", len(filtered), "took", time.Since(start).String())
 			c.JSON(http.StatusOK, gin.H{"data": filtered})
 			return
 		}
-		application.Logger.Info.Printf("[chat.history] user_id=%s count=%d took=%s", userID, len(messages), time.Since(start))
+		application.Logger.InfoContext(ctx, "chat history served", "count", len(messages), "took", time.Since(start).String())
 		c.JSON(http.StatusOK, gin.H{"data": messages})
 	})
 
@@ -83,22 +135,27 @@ func main() {
 		start := time.Now()
 		var req summarizeReq
 		if err := c.BindJSON(&req); err != nil || strings.TrimSpace(req.UserID) == "" || strings.TrimSpace(req.Skill) == "" {
-			application.Logger.Warn.Printf("[chat.summarize] invalid body content_len=%d user_id_present=%t skill_present=%t", len(req.Content), strings.TrimSpace(req.UserID) != "", strings.TrimSpace(req.Skill) != "")
+			application.Logger.WarnContext(c.Request.Context(), "summarize: invalid body",
+				"content_len", len(req.Content),
+				"user_id_present", strings.TrimSpace(req.UserID) != "",
+				"skill_present", strings.TrimSpace(req.Skill) != "",
+			)
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
 			return
 		}
 
 		// Determine today's date in server local time (YYYY-MM-DD)
 		today := time.Now().Format("2006-01-02")
+		skillLower := strings.ToLower(strings.TrimSpace(req.Skill))
+		ctx := logging.WithSkill(logging.WithUser(c.Request.Context(), req.UserID), skillLower)
 
 		// Compute current chat message count for the user+skill
-		history, err := application.FirestoreClient.GetChatHistory(req.UserID)
+		history, err := application.FirestoreClient.GetChatHistory(ctx, req.UserID)
 		if err != nil {
-			application.Logger.Error.Printf("[chat.summarize] user_id=%s history_error=%v", req.UserID, err)
+			application.Logger.ErrorContext(ctx, "summarize: fetching chat history failed", "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch chat history"})
 			return
 		}
-		skillLower := strings.ToLower(strings.TrimSpace(req.Skill))
 		currentCount := 0
 		if skillLower == "" {
 			currentCount = len(history.Messages)
@@ -112,42 +169,44 @@ func main() {
 
 		// The learner's recent grades ground the summary, so a summary is worth
 		// producing from scores alone even before they have chatted.
-		scores, err := application.FirestoreClient.GetRecentSkillScores(req.UserID, skillLower, recentScoreLimit)
+		scores, err := application.FirestoreClient.GetRecentSkillScores(ctx, req.UserID, skillLower, recentScoreLimit)
 		if err != nil {
-			application.Logger.Warn.Printf("[chat.summarize] user_id=%s skill=%s scores_error=%v", req.UserID, skillLower, err)
+			application.Logger.WarnContext(ctx, "summarize: fetching scores failed", "error", err)
 			scores = nil
 		}
 		scoreKey := db.ScoreFingerprint(scores)
 
 		if strings.TrimSpace(req.Content) == "" && len(scores) == 0 {
-			application.Logger.Info.Printf("[chat.summarize] nothing_to_summarize user_id=%s skill=%s took=%s", req.UserID, skillLower, time.Since(start))
+			application.Logger.InfoContext(ctx, "summarize: nothing to summarize", "took", time.Since(start).String())
 			c.JSON(http.StatusOK, gin.H{"summary": "", "cached": false})
 			return
 		}
 
 		// Try cache first
-		cached, err := application.FirestoreClient.GetDailySummary(req.UserID, today, skillLower)
+		cached, err := application.FirestoreClient.GetDailySummary(ctx, req.UserID, today, skillLower)
 		if err == nil && cached != nil && cached.LastCount == currentCount && cached.ScoreKey == scoreKey && strings.TrimSpace(cached.Summary) != "" {
-			application.Logger.Info.Printf("[chat.summarize] cache_hit user_id=%s date=%s skill=%s count=%d took=%s", req.UserID, today, skillLower, currentCount, time.Since(start))
+			application.Logger.InfoContext(ctx, "summarize: cache hit", "date", today, "count", currentCount, "took", time.Since(start).String())
 			c.JSON(http.StatusOK, gin.H{"summary": cached.Summary, "cached": true})
 			return
 		}
 
 		// Cache miss, or the message count or scores changed; generate new summary
-		application.Logger.Info.Printf("[chat.summarize] cache_miss user_id=%s date=%s skill=%s count=%d scores=%d content_len=%d", req.UserID, today, skillLower, currentCount, len(scores), len(req.Content))
-		sum, err := application.GPTClient.Summarize(req.Content, scores)
+		application.Logger.InfoContext(ctx, "summarize: cache miss",
+			"date", today, "count", currentCount, "scores", len(scores), "content_len", len(req.Content),
+		)
+		sum, err := application.GPTClient.Summarize(ctx, req.Content, scores)
 		if err != nil {
-			application.Logger.Error.Printf("[chat.summarize] error=%v took=%s", err, time.Since(start))
+			application.Logger.ErrorContext(ctx, "summarize failed", "error", err, "took", time.Since(start).String())
 			c.JSON(http.StatusBadGateway, gin.H{"error": "failed to summarize"})
 			return
 		}
 
 		// Store/Update cache
-		if err := application.FirestoreClient.SetDailySummary(req.UserID, today, skillLower, sum, currentCount, scoreKey); err != nil {
-			application.Logger.Warn.Printf("[chat.summarize] failed_cache_store user_id=%s date=%s skill=%s err=%v", req.UserID, today, skillLower, err)
+		if err := application.FirestoreClient.SetDailySummary(ctx, req.UserID, today, skillLower, sum, currentCount, scoreKey); err != nil {
+			application.Logger.WarnContext(ctx, "summarize: caching the summary failed", "date", today, "error", err)
 		}
 
-		application.Logger.Info.Printf("[chat.summarize] ok summary_len=%d took=%s", len(sum), time.Since(start))
+		application.Logger.InfoContext(ctx, "summarize: summary generated", "summary_len", len(sum), "took", time.Since(start).String())
 		c.JSON(http.StatusOK, gin.H{"summary": sum, "cached": false})
 	})
 
@@ -156,30 +215,35 @@ func main() {
 		start := time.Now()
 		userID := c.Query("user_id")
 		if userID == "" {
-			application.Logger.Warn.Println("[db.user] missing user_id")
+			application.Logger.WarnContext(c.Request.Context(), "user: missing user_id")
 			c.JSON(http.StatusBadRequest, gin.H{"error": "missing user_id"})
 			return
 		}
-		application.Logger.Info.Printf("[db.user] user_id=%s", userID)
-		user, err := application.FirestoreClient.GetUserData(userID)
+		ctx := logging.WithUser(c.Request.Context(), userID)
+		user, err := application.FirestoreClient.GetUserData(ctx, userID)
 		if err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
-			application.Logger.Warn.Printf("[db.user] user_id=%s not found took=%s", userID, time.Since(start))
+			application.Logger.WarnContext(ctx, "user not found", "took", time.Since(start).String())
 			return
 		}
-		application.Logger.Info.Printf("[db.user] user_id=%s ok took=%s", userID, time.Since(start))
+		application.SignThumbnails(ctx, user)
+		application.Logger.InfoContext(ctx, "user served", "took", time.Since(start).String())
 		c.JSON(http.StatusOK, user)
 	})
 
 	r.GET("/api/db/users", func(c *gin.Context) {
 		start := time.Now()
-		application.Logger.Info.Println("[db.users] list")
-		all, err := application.FirestoreClient.ListUsers()
+		all, err := application.FirestoreClient.ListUsers(c.Request.Context())
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
-		application.Logger.Info.Printf("[db.users] count=%d took=%s", len(*all), time.Since(start))
+		users := make([]*db.UserData, len(*all))
+		for i := range *all {
+			users[i] = &(*all)[i]
+		}
+		application.SignThumbnails(c.Request.Context(), users...)
+		application.Logger.InfoContext(c.Request.Context(), "users listed", "count", len(*all), "took", time.Since(start).String())
 		c.JSON(http.StatusOK, *all)
 	})
 
@@ -191,7 +255,7 @@ func main() {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "missing user_id, skill, or work_date"})
 			return
 		}
-		user, err := application.FirestoreClient.GetUserData(userID)
+		user, err := application.FirestoreClient.GetUserData(c.Request.Context(), userID)
 		if err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
 			return
@@ -206,20 +270,15 @@ func main() {
 			c.JSON(http.StatusConflict, gin.H{"error": "analysis predates synchronized playback"})
 			return
 		}
-		videos, err := application.AnalysisClient.RefreshPlaybackURLs(
-			c.Request.Context(),
-			work.StudentVideo.ObjectPath,
-			work.Expert.Video.ObjectPath,
-		)
-		if err != nil || len(videos) != 2 {
-			application.Logger.Error.Printf("[db.playback] refresh failed user=%s skill=%s date=%s err=%v", userID, skill, workDate, err)
+		videos, err := application.Playback.Resolve(c.Request.Context(), work.StudentVideo, work.Expert.Video)
+		if err != nil {
+			ctx := logging.WithSkill(logging.WithUser(c.Request.Context(), userID), skill)
+			application.Logger.ErrorContext(ctx, "playback: refreshing URLs failed", "date", workDate, "error", err)
 			c.JSON(http.StatusBadGateway, gin.H{"error": "failed to refresh playback URLs"})
 			return
 		}
-		work.StudentVideo.SignedURL = videos[0].SignedURL
-		work.StudentVideo.SignedURLExpires = videos[0].SignedURLExpires
-		work.Expert.Video.SignedURL = videos[1].SignedURL
-		work.Expert.Video.SignedURLExpires = videos[1].SignedURLExpires
+		work.StudentVideo = videos[0]
+		work.Expert.Video = videos[1]
 		c.JSON(http.StatusOK, gin.H{
 			"analysis_id":      work.AnalysisID,
 			"handedness":       work.Handedness,
@@ -232,6 +291,20 @@ func main() {
 		})
 	})
 
+	r.GET("/api/db/analysis", func(c *gin.Context) {
+		userID := strings.TrimSpace(c.Query("user_id"))
+		if userID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing user_id"})
+			return
+		}
+		job, err := application.FirestoreClient.GetAnalysisJob(c.Request.Context(), userID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "no analysis yet"})
+			return
+		}
+		c.JSON(http.StatusOK, job)
+	})
+
 	// Stats endpoints
 	r.GET("/api/db/stats/users/:id", func(c *gin.Context) {
 		start := time.Now()
@@ -241,16 +314,53 @@ func main() {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "missing id or skill"})
 			return
 		}
-		stats, err := application.FirestoreClient.GetUserSkillStats(id, skill)
+		ctx := logging.WithSkill(logging.WithUser(c.Request.Context(), id), skill)
+		stats, err := application.FirestoreClient.GetUserSkillStats(ctx, id, skill)
 		if err != nil {
-			application.Logger.Error.Printf("[db.stats.user] id=%s skill=%s err=%v", id, skill, err)
+			application.Logger.ErrorContext(ctx, "user stats failed", "error", err)
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		application.Logger.Info.Printf("[db.stats.user] id=%s skill=%s took=%s", id, skill, time.Since(start))
+		application.Logger.InfoContext(ctx, "user stats served", "took", time.Since(start).String())
 		c.JSON(http.StatusOK, stats)
 	})
 
+	r.GET("/api/db/stats/users/:id/rank", func(c *gin.Context) {
+		start := time.Now()
+		id := c.Param("id")
+		skills := db.BadmintonSkillNames()
+		if skill := strings.ToLower(strings.TrimSpace(c.Query("skill"))); skill != "" {
+			if db.SkillStrToEnum(skill) < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid skill"})
+				return
+			}
+			skills = []string{skill}
+		}
+		ctx := logging.WithUser(c.Request.Context(), id)
+		rank, err := application.FirestoreClient.GetUserRank(ctx, id, skills, application.Config.Ranking.MinCohort)
+		if err != nil {
+			application.Logger.ErrorContext(ctx, "user rank failed", "error", err)
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+		application.Logger.InfoContext(ctx, "user rank served", "skills", len(skills), "took", time.Since(start).String())
+		c.JSON(http.StatusOK, rank)
+	})
+
+	r.GET("/api/db/stats/users/:id/grade", func(c *gin.Context) {
+		start := time.Now()
+		id := c.Param("id")
+		ctx := logging.WithUser(c.Request.Context(), id)
+		grade, err := application.FirestoreClient.GetUserCourseGrade(ctx, id)
+		if err != nil {
+			application.Logger.ErrorContext(ctx, "course grade failed", "error", err)
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+		application.Logger.InfoContext(ctx, "course grade served", "total", grade.Total, "took", time.Since(start).String())
+		c.JSON(http.StatusOK, grade)
+	})
+
 	r.GET("/api/db/stats/class", func(c *gin.Context) {
 		start := time.Now()
 		skill := strings.ToLower(strings.TrimSpace(c.Query("skill")))
@@ -258,16 +368,222 @@ func main() {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "missing skill"})
 			return
 		}
-		stats, err := application.FirestoreClient.GetClassSkillStats(skill)
+		ctx := logging.WithSkill(c.Request.Context(), skill)
+		stats, err := application.FirestoreClient.GetClassSkillStats(ctx, skill)
 		if err != nil {
-			application.Logger.Error.Printf("[db.stats.class] skill=%s err=%v", skill, err)
+			application.Logger.ErrorContext(ctx, "class stats failed", "error", err)
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		application.Logger.Info.Printf("[db.stats.class] skill=%s took=%s", skill, time.Since(start))
+		application.Logger.InfoContext(ctx, "class stats served", "took", time.Since(start).String())
 		c.JSON(http.StatusOK, stats)
 	})
 
+	// Teacher-only endpoints
+	admin := r.Group("/api/admin", requireAdmin(application.Config.Admin.APIKey))
+
+	type classReq struct {
+		ClassID string `json:"class_id"`
+	}
+	admin.PUT("/users/:id/class", func(c *gin.Context) {
+		id := c.Param("id")
+		var req classReq
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+		if err := db.ValidateClassID(strings.TrimSpace(req.ClassID)); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx := logging.WithUser(c.Request.Context(), id)
+		if err := application.FirestoreClient.UpdateUserClass(ctx, id, strings.TrimSpace(req.ClassID)); err != nil {
+			application.Logger.ErrorContext(ctx, "updating class failed", "error", err)
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+		application.Logger.InfoContext(ctx, "class updated", "class", req.ClassID)
+		c.JSON(http.StatusOK, gin.H{"class_id": strings.TrimSpace(req.ClassID)})
+	})
+
+	admin.GET("/rubric", func(c *gin.Context) {
+		classID := strings.TrimSpace(c.Query("class"))
+		rubric, err := application.FirestoreClient.GetRubric(c.Request.Context(), classID)
+		if err != nil {
+			application.Logger.ErrorContext(c.Request.Context(), "getting rubric failed", "class", classID, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get rubric"})
+			return
+		}
+		c.JSON(http.StatusOK, rubric)
+	})
+
+	admin.PUT("/rubric", func(c *gin.Context) {
+		var rubric db.Rubric
+		if err := c.BindJSON(&rubric); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+		rubric.ClassID = strings.TrimSpace(c.Query("class"))
+		if err := rubric.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := application.FirestoreClient.SetRubric(c.Request.Context(), rubric); err != nil {
+			application.Logger.ErrorContext(c.Request.Context(), "saving rubric failed", "class", rubric.ClassID, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save rubric"})
+			return
+		}
+		application.Logger.InfoContext(c.Request.Context(), "rubric saved", "class", rubric.ClassID, "policy", rubric.AttemptPolicy)
+		c.JSON(http.StatusOK, rubric)
+	})
+
+	admin.POST("/assignments", func(c *gin.Context) {
+		var assignment db.Assignment
+		if err := c.BindJSON(&assignment); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+		assignment.ClassID = strings.TrimSpace(assignment.ClassID)
+		assignment.Skill = strings.ToLower(strings.TrimSpace(assignment.Skill))
+		if err := assignment.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		created, err := application.FirestoreClient.CreateAssignment(c.Request.Context(), assignment)
+		if err != nil {
+			application.Logger.ErrorContext(c.Request.Context(), "creating assignment failed", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create assignment"})
+			return
+		}
+		application.Logger.InfoContext(logging.WithSkill(c.Request.Context(), created.Skill), "assignment created",
+			"assignment", created.ID, "class", created.ClassID,
+		)
+		c.JSON(http.StatusCreated, created)
+	})
+
+	admin.GET("/assignments", func(c *gin.Context) {
+		classID := strings.TrimSpace(c.Query("class"))
+		assignments, err := application.FirestoreClient.ListAssignments(c.Request.Context(), classID)
+		if err != nil {
+			application.Logger.ErrorContext(c.Request.Context(), "listing assignments failed", "class", classID, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list assignments"})
+			return
+		}
+		c.JSON(http.StatusOK, assignments)
+	})
+
+	admin.GET("/assignments/:id/submissions", func(c *gin.Context) {
+		id := c.Param("id")
+		report, err := application.FirestoreClient.GetAssignmentReport(c.Request.Context(), id)
+		if err != nil {
+			application.Logger.ErrorContext(c.Request.Context(), "assignment report failed", "assignment", id, "error", err)
+			c.JSON(http.StatusNotFound, gin.H{"error": "assignment not found"})
+			return
+		}
+		application.Logger.InfoContext(c.Request.Context(), "assignment report served",
+			"assignment", id, "submitted", len(report.Submitted), "missing", len(report.Missing),
+		)
+		c.JSON(http.StatusOK, report)
+	})
+
+	admin.GET("/gradebook", func(c *gin.Context) {
+		start := time.Now()
+		filter, err := gradebook.NewFilter(c.Query("class"), c.Query("from"), c.Query("to"), c.Query("skill"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		format, err := gradebook.ParseFormat(c.Query("format"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		users, err := application.FirestoreClient.ListUsers(c.Request.Context())
+		if err != nil {
+			application.Logger.ErrorContext(c.Request.Context(), "gradebook: listing users failed", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list users"})
+			return
+		}
+		rows := gradebook.Build(*users, filter)
+		var out bytes.Buffer
+		if err := gradebook.Write(&out, format, rows); err != nil {
+			application.Logger.ErrorContext(c.Request.Context(), "gradebook: writing failed", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to write gradebook"})
+			return
+		}
+		filename := fmt.Sprintf("gradebook-%s.%s", time.Now().Format("20060102"), format)
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		application.Logger.InfoContext(c.Request.Context(), "gradebook exported",
+			"class", filter.ClassID, "format", string(format), "rows", len(rows), "took", time.Since(start).String(),
+		)
+		c.Data(http.StatusOK, format.ContentType(), out.Bytes())
+	})
+
+	admin.GET("/status", func(c *gin.Context) {
+		statuses, ready := application.Health.Run(c.Request.Context())
+		status := gin.H{"ready": ready, "dependencies": statuses}
+		if application.AnalysisClient != nil {
+			status["analysis_breaker"] = application.AnalysisClient.BreakerState()
+		}
+		c.JSON(http.StatusOK, status)
+	})
+
+	admin.GET("/jobs", func(c *gin.Context) {
+		jobs, err := application.Scheduler.Jobs(c.Request.Context())
+		if err != nil {
+			application.Logger.ErrorContext(c.Request.Context(), "listing jobs failed", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list jobs"})
+			return
+		}
+		c.JSON(http.StatusOK, jobs)
+	})
+
+	admin.POST("/jobs/:name/run", func(c *gin.Context) {
+		name := c.Param("name")
+		run, err := application.Scheduler.Trigger(application.Detach(c.Request.Context()), name)
+		switch {
+		case errors.Is(err, scheduler.ErrUnknownJob):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, scheduler.ErrJobRunning):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, scheduler.ErrStopped):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			application.Logger.ErrorContext(c.Request.Context(), "job trigger failed", "job", name, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start job"})
+			return
+		}
+		application.Logger.InfoContext(c.Request.Context(), "job triggered", "job", name, "run_id", run.ID)
+		c.JSON(http.StatusAccepted, gin.H{"status": "started", "run_id": run.ID})
+	})
+
+	admin.POST("/reanalysis", func(c *gin.Context) {
+		var scope reanalysis.Scope
+		if err := c.ShouldBindJSON(&scope); err != nil && !errors.Is(err, io.EOF) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+			return
+		}
+		err := application.Reanalysis.Start(application.Detach(c.Request.Context()), scope)
+		switch {
+		case errors.Is(err, reanalysis.ErrRunning):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ctx := logging.WithSkill(logging.WithUser(c.Request.Context(), scope.UserID), scope.Skill)
+		application.Logger.InfoContext(ctx, "re-analysis started")
+		c.JSON(http.StatusAccepted, gin.H{"status": "started", "scope": scope})
+	})
+
+	if application.Config.Scheduler.Enabled && application.Scheduler != nil {
+		application.Scheduler.Start(application.Context())
+	}
+
 	// HTTP server with timeouts
 	const (
 		DefaultReadTimeout  = 100 * time.Second
@@ -282,8 +598,46 @@ func main() {
 		IdleTimeout:  DefaultIdleTimeout,
 	}
 
-	application.Logger.Info.Println("\n\tServer started on port " + application.Config.Port)
-	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Fatal(err)
+	// On a signal the server stops taking webhooks, and analyses and jobs in
+	// progress get the grace period to finish before they are cancelled.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	serveErr := make(chan error, 1)
+	go func() {
+		application.Logger.Info("Server started", "port", application.Config.Port)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		if err != nil && err != http.ErrServerClosed {
+			application.Logger.Error("Server stopped", "error", err)
+			os.Exit(1)
+		}
+	case <-ctx.Done():
+		grace := application.Config.Shutdown.GracePeriod
+		application.Logger.Info("Shutting down", "grace_period", grace.String())
+		graceCtx, cancel := context.WithTimeout(context.Background(), grace)
+		defer cancel()
+		if err := srv.Shutdown(graceCtx); err != nil {
+			application.Logger.Warn("Requests still open after the grace period", "error", err)
+		}
+		if err := application.Shutdown(graceCtx); err != nil {
+			application.Logger.Error("Closing clients failed", "error", err)
+		}
+		application.Logger.Info("Shut down")
+	}
+}
+
+// requireAdmin rejects requests that do not carry apiKey as a bearer token.
+// An empty apiKey refuses every request rather than leaving the routes open.
+func requireAdmin(apiKey string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if apiKey == "" || subtle.ConstantTimeCompare([]byte(token), []byte(apiKey)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		c.Next()
 	}
 }
@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// FirestoreDialOptions count and time the RPCs of a Firestore client dialled
+// with them. A streaming RPC, such as a query, is timed until its last
+// response.
+func FirestoreDialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(firestoreUnary),
+		grpc.WithChainStreamInterceptor(firestoreStream),
+	}
+}
+
+func firestoreUnary(
+	ctx context.Context, method string, req, reply any, cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker, opts ...grpc.CallOption,
+) error {
+	start := time.Now()
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	observeFirestore(method, start, err)
+	return err
+}
+
+func firestoreStream(
+	ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string,
+	streamer grpc.Streamer, opts ...grpc.CallOption,
+) (grpc.ClientStream, error) {
+	start := time.Now()
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		observeFirestore(method, start, err)
+		return nil, err
+	}
+	return &firestoreClientStream{ClientStream: stream, method: method, start: start}, nil
+}
+
+// firestoreClientStream observes its RPC once, when a receive ends it.
+type firestoreClientStream struct {
+	grpc.ClientStream
+	method string
+	start  time.Time
+	once   sync.Once
+}
+
+func (s *firestoreClientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		s.once.Do(func() {
+			if errors.Is(err, io.EOF) {
+				observeFirestore(s.method, s.start, nil)
+			} else {
+				observeFirestore(s.method, s.start, err)
+			}
+		})
+	}
+	return err
+}
+
+// observeFirestore records an RPC by its method name, such as
+// /google.firestore.v1.Firestore/Commit.
+func observeFirestore(method string, start time.Time, err error) {
+	name := path.Base(method)
+	FirestoreDuration.WithLabelValues(name).Observe(Since(start))
+	FirestoreOperations.WithLabelValues(name, status.Code(err).String()).Inc()
+}
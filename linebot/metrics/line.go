@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lineID matches the path segments that name a user, group, room, message or
+// rich menu, which would make every request its own series.
+var lineID = regexp.MustCompile(`^([UCR][0-9a-f]{32}|[0-9]+|richmenu-[0-9a-f]+|richmenualias-.+)$`)
+
+// lineOperation names a LINE API request by its method and path, with IDs
+// replaced by {id}.
+func lineOperation(req *http.Request) string {
+	segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	for i, segment := range segments {
+		if lineID.MatchString(segment) {
+			segments[i] = "{id}"
+		}
+	}
+	return req.Method + " /" + strings.Join(segments, "/")
+}
+
+type lineTransport struct {
+	base http.RoundTripper
+}
+
+// LineTransport counts and times the LINE API requests made through base.
+// A request that gets no response is counted with code "error".
+func LineTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return lineTransport{base: base}
+}
+
+func (t lineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	operation := lineOperation(req)
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	LineAPIDuration.WithLabelValues(operation).Observe(Since(start))
+	code := OutcomeError
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+	LineAPICalls.WithLabelValues(operation, code).Inc()
+	return resp, err
+}
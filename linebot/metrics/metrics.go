@@ -0,0 +1,135 @@
+// Package metrics collects the bot's Prometheus metrics and serves them for
+// scraping. Collectors are registered on a registry of their own, so only
+// the families below and the Go runtime and process metrics are exported.
+package metrics
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "linebot"
+
+// Outcomes of a call.
+const (
+	OutcomeOK    = "ok"
+	OutcomeError = "error"
+)
+
+// Registry holds every collector this package defines.
+var Registry = prometheus.NewRegistry()
+
+// Buckets for calls that take milliseconds to seconds, and for analyses that
+// take seconds to minutes.
+var (
+	callBuckets     = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30}
+	analysisBuckets = []float64{1, 2.5, 5, 10, 20, 30, 45, 60, 90, 120, 180, 300, 600}
+)
+
+var (
+	WebhookEvents = counterVec("webhook_events_total",
+		"Webhook events received, by event type.", "type")
+	WebhookEventDuration = histogramVec("webhook_event_duration_seconds",
+		"Time to handle a webhook event, by event type.", callBuckets, "type")
+
+	StateTransitions = counterVec("state_transitions_total",
+		"Session state changes, as state/step before and after.", "from", "to")
+
+	AnalysisDuration = histogramVec("analysis_duration_seconds",
+		"Time for the analysis service to grade an upload, by skill and outcome.", analysisBuckets, "skill", "outcome")
+	AnalysesInFlight = gauge("analyses_in_flight",
+		"Analyses waiting on the analysis service.")
+	AnalysisStageDuration = histogramVec("analysis_stage_duration_seconds",
+		"Stage latencies the analysis service reports in its diagnostics, by stage.", analysisBuckets, "stage")
+
+	LineAPICalls = counterVec("line_api_calls_total",
+		"LINE API requests, by operation and HTTP status code.", "operation", "code")
+	LineAPIDuration = histogramVec("line_api_duration_seconds",
+		"LINE API request latency, by operation.", callBuckets, "operation")
+
+	GPTCalls = counterVec("gpt_calls_total",
+		"OpenAI requests, by operation and outcome.", "operation", "outcome")
+	GPTDuration = histogramVec("gpt_duration_seconds",
+		"OpenAI request latency, by operation.", analysisBuckets, "operation")
+	GPTTokens = counterVec("gpt_tokens_total",
+		"OpenAI tokens used, by operation and kind (input or output).", "operation", "kind")
+
+	FirestoreOperations = counterVec("firestore_operations_total",
+		"Firestore RPCs, by method and gRPC code.", "method", "code")
+	FirestoreDuration = histogramVec("firestore_operation_duration_seconds",
+		"Firestore RPC latency, by method.", callBuckets, "method")
+
+	SignedURLRefreshes = counterVec("signed_url_refreshes_total",
+		"Batches of playback URLs signed, by signer and outcome.", "signer", "outcome")
+	SignedURLRefreshDuration = histogramVec("signed_url_refresh_duration_seconds",
+		"Time to sign a batch of playback URLs, by signer.", callBuckets, "signer")
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// Outcome labels a call by whether it failed.
+func Outcome(err error) string {
+	if err != nil {
+		return OutcomeError
+	}
+	return OutcomeOK
+}
+
+// diagnosticPrefix and diagnosticSuffix mark the diagnostics that are stage
+// latencies, such as latency_pose_seconds.
+const (
+	diagnosticPrefix = "latency_"
+	diagnosticSuffix = "_seconds"
+)
+
+// ObserveDiagnostics records the stage latencies among an analysis's
+// diagnostics.
+func ObserveDiagnostics(diagnostics map[string]float64) {
+	for name, value := range diagnostics {
+		stage, ok := strings.CutPrefix(name, diagnosticPrefix)
+		if !ok || value < 0 {
+			continue
+		}
+		AnalysisStageDuration.WithLabelValues(strings.TrimSuffix(stage, diagnosticSuffix)).Observe(value)
+	}
+}
+
+// Since returns the seconds elapsed since start, for Observe.
+func Since(start time.Time) float64 {
+	return time.Since(start).Seconds()
+}
+
+func counterVec(name, help string, labels ...string) *prometheus.CounterVec {
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Namespace: namespace, Name: name, Help: help}, labels)
+	Registry.MustRegister(counter)
+	return counter
+}
+
+func histogramVec(name, help string, buckets []float64, labels ...string) *prometheus.HistogramVec {
+	histogram := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace, Name: name, Help: help, Buckets: buckets,
+	}, labels)
+	Registry.MustRegister(histogram)
+	return histogram
+}
+
+func gauge(name, help string) prometheus.Gauge {
+	g := prometheus.NewGauge(prometheus.GaugeOpts{Namespace: namespace, Name: name, Help: help})
+	Registry.MustRegister(g)
+	return g
+}
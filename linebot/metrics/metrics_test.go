@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestLineOperationHidesIDs(t *testing.T) {
+	for path, want := range map[string]string{
+		"/v2/bot/message/reply":                                   "POST /v2/bot/message/reply",
+		"/v2/bot/profile/U4af4980629c8a4f4e1e0e0c0f8d1e2a3":       "POST /v2/bot/profile/{id}",
+		"/v2/bot/message/468789577898262530/content":              "POST /v2/bot/message/{id}/content",
+		"/v2/bot/user/all/richmenu/richmenu-8dc2d1a2b0d0a4f4e1e0": "POST /v2/bot/user/all/richmenu/{id}",
+	} {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		require.Equal(t, want, lineOperation(req), path)
+	}
+}
+
+func TestLineTransportCountsByStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: LineTransport(server.Client().Transport)}
+	counter := LineAPICalls.WithLabelValues("POST /v2/bot/message/push", "429")
+	before := testutil.ToFloat64(counter)
+
+	resp, err := client.Post(server.URL+"/v2/bot/message/push", "application/json", strings.NewReader("{}"))
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	require.Equal(t, before+1, testutil.ToFloat64(counter))
+}
+
+func TestFirestoreUnaryRecordsCode(t *testing.T) {
+	counter := FirestoreOperations.WithLabelValues("GetDocument", codes.NotFound.String())
+	before := testutil.ToFloat64(counter)
+
+	err := firestoreUnary(context.Background(), "/google.firestore.v1.Firestore/GetDocument", nil, nil, nil,
+		func(context.Context, string, any, any, *grpc.ClientConn, ...grpc.CallOption) error {
+			return status.Error(codes.NotFound, "missing")
+		})
+
+	require.Equal(t, codes.NotFound, status.Code(err))
+	require.Equal(t, before+1, testutil.ToFloat64(counter))
+}
+
+type endingStream struct {
+	grpc.ClientStream
+	remaining int
+}
+
+func (s *endingStream) RecvMsg(any) error {
+	if s.remaining == 0 {
+		return io.EOF
+	}
+	s.remaining--
+	return nil
+}
+
+func TestFirestoreStreamRecordsOnceAtEnd(t *testing.T) {
+	counter := FirestoreOperations.WithLabelValues("RunQuery", codes.OK.String())
+	before := testutil.ToFloat64(counter)
+
+	stream, err := firestoreStream(context.Background(), &grpc.StreamDesc{}, nil, "/google.firestore.v1.Firestore/RunQuery",
+		func(context.Context, *grpc.StreamDesc, *grpc.ClientConn, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+			return &endingStream{remaining: 2}, nil
+		})
+	require.NoError(t, err)
+	for stream.RecvMsg(nil) == nil {
+	}
+	require.ErrorIs(t, stream.RecvMsg(nil), io.EOF)
+
+	require.Equal(t, before+1, testutil.ToFloat64(counter))
+}
+
+func TestObserveDiagnosticsRecordsLatencies(t *testing.T) {
+	before := testutil.CollectAndCount(AnalysisStageDuration)
+
+	ObserveDiagnostics(map[string]float64{
+		"latency_diagnostic_test_seconds": 1.5,
+		"expert_distance":                 0.3,
+	})
+
+	require.Equal(t, before+1, testutil.CollectAndCount(AnalysisStageDuration))
+}
+
+func TestHandlerServesFamilies(t *testing.T) {
+	WebhookEvents.WithLabelValues("message").Inc()
+	recorder := httptest.NewRecorder()
+
+	Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Contains(t, recorder.Body.String(), `linebot_webhook_events_total{type="message"}`)
+	require.Contains(t, recorder.Body.String(), "go_goroutines")
+}
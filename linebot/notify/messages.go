@@ -0,0 +1,117 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/HeavenAQ/nstc-linebot-2025/api/db"
+	"github.com/HeavenAQ/nstc-linebot-2025/commons"
+)
+
+// messageKey joins the parts into a Firestore document ID.
+func messageKey(kind Kind, parts ...string) string {
+	return string(kind) + "_" + strings.Join(parts, "_")
+}
+
+// dueReminders reminds the student of open assignments they have not
+// submitted that fall due within window.
+func dueReminders(user db.UserData, assignments []db.Assignment, now time.Time, window time.Duration) []Message {
+	var messages []Message
+	for _, assignment := range db.OpenAssignments(assignments, user, now) {
+		if assignment.Submitted || assignment.Overdue || assignment.DueAt.Sub(now) > window {
+			continue
+		}
+		messages = append(messages, Message{
+			Kind:   DueReminder,
+			Key:    messageKey(DueReminder, assignment.ID, user.ID),
+			UserID: user.ID,
+			Text: fmt.Sprintf(
+				"⏰ 作業提醒：【%s】%s 將於 %s 截止，記得透過「動作分析」上傳影片喔！",
+				db.SkillStrToEnum(assignment.Skill).ChnString(),
+				assignment.Title,
+				assignment.DueAt.Local().Format("01/02 15:04"),
+			),
+		})
+	}
+	return messages
+}
+
+// reflectionNudges asks for the reflection of every work uploaded between
+// within and after ago that still carries the placeholder.
+func reflectionNudges(user db.UserData, now time.Time, after, within time.Duration) []Message {
+	var messages []Message
+	for _, skill := range db.BadmintonSkillNames() {
+		for key, work := range user.Portfolio.GetSkillPortfolio(skill) {
+			uploaded, err := time.ParseInLocation(db.WorkKeyLayout, key, time.Local)
+			if err != nil || work.HasReflection() {
+				continue
+			}
+			if age := now.Sub(uploaded); age < after || age > within {
+				continue
+			}
+			messages = append(messages, Message{
+				Kind:   ReflectionNudge,
+				Key:    messageKey(ReflectionNudge, user.ID, skill, key),
+				UserID: user.ID,
+				Text: fmt.Sprintf(
+					"✏️ 您在 %s 上傳的【%s】影片還沒有學習反思，請到「預習及反思」補上心得喔！",
+					uploaded.Format("01/02"),
+					db.SkillStrToEnum(skill).ChnString(),
+				),
+			})
+		}
+	}
+	return messages
+}
+
+// weeklyDigest summarizes the past week per skill, newest scores first as
+// GetRecentSkillScores returns them. Students who have never uploaded get no
+// digest.
+func weeklyDigest(userID string, scores map[string][]commons.SkillScore, now time.Time) (Message, bool) {
+	weekStart := now.AddDate(0, 0, -7)
+	var b strings.Builder
+	b.WriteString("📊 本週學習摘要")
+	attempted := false
+	for _, skill := range db.BadmintonSkillNames() {
+		skillScores := scores[skill]
+		if len(skillScores) == 0 {
+			continue
+		}
+		attempted = true
+
+		thisWeek := 0
+		var previous *commons.SkillScore
+		for i, score := range skillScores {
+			date, err := time.ParseInLocation(db.WorkKeyLayout, score.Date, time.Local)
+			if err == nil && date.After(weekStart) {
+				thisWeek++
+				continue
+			}
+			previous = &skillScores[i]
+			break
+		}
+
+		b.WriteString(fmt.Sprintf("\n\n【%s】", db.SkillStrToEnum(skill).ChnString()))
+		latest := skillScores[0].TotalGrade
+		switch {
+		case thisWeek == 0:
+			b.WriteString(fmt.Sprintf("本週尚未上傳，最近一次 %.2f 分", latest))
+		case previous == nil:
+			b.WriteString(fmt.Sprintf("本週上傳 %d 次，最新 %.2f 分", thisWeek, latest))
+		default:
+			b.WriteString(fmt.Sprintf("本週上傳 %d 次，最新 %.2f 分（較先前 %+.2f）", thisWeek, latest, latest-previous.TotalGrade))
+		}
+	}
+	if !attempted {
+		return Message{}, false
+	}
+
+	year, week := now.Local().ISOWeek()
+	return Message{
+		Kind:   WeeklyDigest,
+		Key:    messageKey(WeeklyDigest, userID, fmt.Sprintf("%d-W%02d", year, week)),
+		UserID: userID,
+		Text:   b.String(),
+	}, true
+}
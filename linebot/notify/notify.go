@@ -0,0 +1,169 @@
+// Package notify works out which push messages students are due and sends
+// each of them at most once, however many instances run it.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/HeavenAQ/nstc-linebot-2025/api/db"
+	"github.com/HeavenAQ/nstc-linebot-2025/commons"
+	"github.com/HeavenAQ/nstc-linebot-2025/config"
+)
+
+// Kind is the kind of push message.
+type Kind string
+
+const (
+	DueReminder     Kind = "due_reminder"
+	ReflectionNudge Kind = "reflection_nudge"
+	WeeklyDigest    Kind = "weekly_digest"
+)
+
+// digestScoreLimit caps how many attempts per skill feed the weekly digest.
+const digestScoreLimit = 20
+
+// Message is one push message. Key is derived only from what the message is
+// about, never from when it was planned, so every run plans the same key for
+// the same reminder.
+type Message struct {
+	Kind   Kind
+	Key    string
+	UserID string
+	Text   string
+}
+
+// Store is the persistence the runner needs; *db.FirestoreClient satisfies it.
+type Store interface {
+	ListUsers(ctx context.Context) (*[]db.UserData, error)
+	ListAssignments(ctx context.Context, classID string) ([]db.Assignment, error)
+	GetRecentSkillScores(ctx context.Context, userID, skill string, limit int) ([]commons.SkillScore, error)
+	ClaimNotification(ctx context.Context, key, kind, userID string) (bool, error)
+	ReleaseNotification(ctx context.Context, key string) error
+}
+
+// Pusher delivers a text message to a user.
+type Pusher interface {
+	PushText(ctx context.Context, userID, text string) error
+}
+
+// Result summarizes a run.
+type Result struct {
+	Quiet   bool `json:"quiet"`
+	Planned int  `json:"planned"`
+	Sent    int  `json:"sent"`
+	Skipped int  `json:"skipped"`
+	Failed  int  `json:"failed"`
+}
+
+type Runner struct {
+	store  Store
+	pusher Pusher
+	cfg    config.NotificationConfig
+}
+
+func NewRunner(store Store, pusher Pusher, cfg config.NotificationConfig) *Runner {
+	return &Runner{store: store, pusher: pusher, cfg: cfg}
+}
+
+// Run plans and sends every message due at now. Each message is claimed
+// before it is pushed, so a restarted or duplicated instance skips what has
+// already gone out. A failed push releases its claim for the next run. Once
+// ctx is done no more messages are sent.
+func (r *Runner) Run(ctx context.Context, now time.Time) (Result, error) {
+	if r.quiet(now) {
+		return Result{Quiet: true}, nil
+	}
+	messages, err := r.plan(ctx, now)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := Result{Planned: len(messages)}
+	var errs []error
+	for _, msg := range messages {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		claimed, err := r.store.ClaimNotification(ctx, msg.Key, string(msg.Kind), msg.UserID)
+		if err != nil {
+			result.Failed++
+			errs = append(errs, err)
+			continue
+		}
+		if !claimed {
+			result.Skipped++
+			continue
+		}
+		if err := r.pusher.PushText(ctx, msg.UserID, msg.Text); err != nil {
+			result.Failed++
+			errs = append(errs, fmt.Errorf("push %s: %w", msg.Key, err))
+			if err := r.store.ReleaseNotification(ctx, msg.Key); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		result.Sent++
+	}
+	return result, errors.Join(errs...)
+}
+
+// quiet reports whether now falls within quiet hours. The range may wrap
+// past midnight, e.g. 22 to 8.
+func (r *Runner) quiet(now time.Time) bool {
+	start, end, hour := r.cfg.QuietStart, r.cfg.QuietEnd, now.Local().Hour()
+	switch {
+	case start == end:
+		return false
+	case start < end:
+		return hour >= start && hour < end
+	default:
+		return hour >= start || hour < end
+	}
+}
+
+func (r *Runner) plan(ctx context.Context, now time.Time) ([]Message, error) {
+	users, err := r.store.ListUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	assignments := map[string][]db.Assignment{}
+	var messages []Message
+	for _, user := range *users {
+		if user.NotificationsOff {
+			continue
+		}
+		classAssignments, ok := assignments[user.ClassID]
+		if !ok {
+			if classAssignments, err = r.store.ListAssignments(ctx, user.ClassID); err != nil {
+				return nil, err
+			}
+			assignments[user.ClassID] = classAssignments
+		}
+		messages = append(messages, dueReminders(user, classAssignments, now, r.cfg.DueWindow)...)
+		messages = append(messages, reflectionNudges(user, now, r.cfg.NudgeAfter, r.cfg.NudgeWithin)...)
+
+		if !r.digestDue(now) {
+			continue
+		}
+		scores := map[string][]commons.SkillScore{}
+		for _, skill := range db.BadmintonSkillNames() {
+			if scores[skill], err = r.store.GetRecentSkillScores(ctx, user.ID, skill, digestScoreLimit); err != nil {
+				return nil, err
+			}
+		}
+		if msg, ok := weeklyDigest(user.ID, scores, now); ok {
+			messages = append(messages, msg)
+		}
+	}
+	return messages, nil
+}
+
+func (r *Runner) digestDue(now time.Time) bool {
+	local := now.Local()
+	return int(local.Weekday()) == r.cfg.DigestWeekday && local.Hour() >= r.cfg.DigestHour
+}
@@ -0,0 +1,144 @@
+package notify_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/HeavenAQ/nstc-linebot-2025/api/db"
+	"github.com/HeavenAQ/nstc-linebot-2025/commons"
+	"github.com/HeavenAQ/nstc-linebot-2025/config"
+	"github.com/HeavenAQ/nstc-linebot-2025/notify"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStore struct {
+	users       []db.UserData
+	assignments []db.Assignment
+	claimed     map[string]bool
+}
+
+func (s *fakeStore) ListUsers(_ context.Context) (*[]db.UserData, error) { return &s.users, nil }
+
+func (s *fakeStore) ListAssignments(_ context.Context, classID string) ([]db.Assignment, error) {
+	return s.assignments, nil
+}
+
+func (s *fakeStore) GetRecentSkillScores(_ context.Context, userID, skill string, limit int) ([]commons.SkillScore, error) {
+	for _, user := range s.users {
+		if user.ID == userID {
+			return db.RecentSkillScores(user.Portfolio.GetSkillPortfolio(skill), limit), nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *fakeStore) ClaimNotification(_ context.Context, key, kind, userID string) (bool, error) {
+	if s.claimed[key] {
+		return false, nil
+	}
+	s.claimed[key] = true
+	return true, nil
+}
+
+func (s *fakeStore) ReleaseNotification(_ context.Context, key string) error {
+	delete(s.claimed, key)
+	return nil
+}
+
+type fakePusher struct {
+	sent []string
+	err  error
+}
+
+func (p *fakePusher) PushText(_ context.Context, userID, text string) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.sent = append(p.sent, userID+": "+text)
+	return nil
+}
+
+func settings() config.NotificationConfig {
+	return config.NotificationConfig{
+		QuietStart:    22,
+		QuietEnd:      8,
+		DueWindow:     24 * time.Hour,
+		NudgeAfter:    24 * time.Hour,
+		NudgeWithin:   7 * 24 * time.Hour,
+		DigestWeekday: int(time.Sunday),
+		DigestHour:    20,
+	}
+}
+
+func at(day, hour int) time.Time {
+	// 2026-09-13 is a Sunday.
+	return time.Date(2026, 9, day, hour, 0, 0, 0, time.Local)
+}
+
+func work(grade float64, reflection string) db.Work {
+	return db.Work{Reflection: reflection, GradingOutcome: commons.GradingOutcome{TotalGrade: grade}}
+}
+
+func newStore() *fakeStore {
+	serve := map[string]db.Work{
+		"2026-09-01-10-00": work(70, "已寫"),
+		"2026-09-11-10-00": work(80, db.DefaultReflection),
+	}
+	return &fakeStore{
+		users: []db.UserData{
+			{ID: "u1", ClassID: "A", Portfolio: db.Portfolios{Serve: serve}},
+			{ID: "u2", ClassID: "A", NotificationsOff: true, Portfolio: db.Portfolios{Serve: serve}},
+		},
+		assignments: []db.Assignment{{
+			ID: "w2", Title: "第二週", ClassID: "A", Skill: "smash",
+			OpensAt: at(7, 0), DueAt: at(14, 12),
+		}},
+		claimed: map[string]bool{},
+	}
+}
+
+func newRunner(store notify.Store, pusher notify.Pusher) *notify.Runner {
+	return notify.NewRunner(store, pusher, settings())
+}
+
+func TestRunSendsEachMessageOnce(t *testing.T) {
+	store, pusher := newStore(), &fakePusher{}
+	runner := newRunner(store, pusher)
+
+	result, err := runner.Run(context.Background(), at(13, 20))
+	require.NoError(t, err)
+	require.Equal(t, notify.Result{Planned: 3, Sent: 3}, result)
+	require.Len(t, pusher.sent, 3)
+	require.Contains(t, pusher.sent[0], "u1: ⏰ 作業提醒：【殺球】第二週 將於 09/14 12:00 截止")
+	require.Contains(t, pusher.sent[1], "u1: ✏️ 您在 09/11 上傳的【發球】影片還沒有學習反思")
+	require.Contains(t, pusher.sent[2], "【發球】本週上傳 1 次，最新 80.00 分（較先前 +10.00）")
+
+	// A second instance running the same schedule finds every message claimed.
+	result, err = newRunner(store, pusher).Run(context.Background(), at(13, 21))
+	require.NoError(t, err)
+	require.Equal(t, notify.Result{Planned: 3, Skipped: 3}, result)
+	require.Len(t, pusher.sent, 3)
+}
+
+func TestRunRespectsQuietHours(t *testing.T) {
+	store, pusher := newStore(), &fakePusher{}
+
+	result, err := newRunner(store, pusher).Run(context.Background(), at(13, 23))
+
+	require.NoError(t, err)
+	require.True(t, result.Quiet)
+	require.Empty(t, pusher.sent)
+	require.Empty(t, store.claimed)
+}
+
+func TestRunReleasesClaimWhenPushFails(t *testing.T) {
+	store, pusher := newStore(), &fakePusher{err: errors.New("line down")}
+
+	result, err := newRunner(store, pusher).Run(context.Background(), at(13, 13))
+
+	require.ErrorContains(t, err, "line down")
+	require.Equal(t, 2, result.Failed)
+	require.Empty(t, store.claimed)
+}
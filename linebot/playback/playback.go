@@ -0,0 +1,181 @@
+// Package playback hands out signed URLs for stored videos. URLs are cached
+// by object path and reused while they have enough lifetime left, so playing
+// a video rarely needs a signing round-trip.
+package playback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/HeavenAQ/nstc-linebot-2025/commons"
+)
+
+// maxBatch is the most object paths the analyzer signs per request.
+const maxBatch = 8
+
+// SignFunc signs object paths, returning one MediaRef per path in order.
+type SignFunc func(ctx context.Context, objectPaths ...string) ([]commons.MediaRef, error)
+
+// URLSigner signs a single object; *storage.BucketClient satisfies it.
+type URLSigner interface {
+	SignURL(ctx context.Context, objectPath string, lifetime time.Duration) (string, time.Time, error)
+}
+
+// LocalSigner signs V4 URLs lasting lifetime with the bot's own storage
+// credentials.
+func LocalSigner(signer URLSigner, lifetime time.Duration) SignFunc {
+	return func(ctx context.Context, objectPaths ...string) ([]commons.MediaRef, error) {
+		refs := make([]commons.MediaRef, 0, len(objectPaths))
+		for _, objectPath := range objectPaths {
+			url, expires, err := signer.SignURL(ctx, objectPath, lifetime)
+			if err != nil {
+				return nil, err
+			}
+			refs = append(refs, commons.MediaRef{
+				ObjectPath: objectPath, SignedURL: url, SignedURLExpires: expires.Unix(),
+			})
+		}
+		return refs, nil
+	}
+}
+
+type Cache struct {
+	signers      []SignFunc
+	minRemaining time.Duration
+	now          func() time.Time
+
+	mu   sync.Mutex
+	urls map[string]commons.MediaRef
+}
+
+// NewCache reuses a URL while at least minRemaining of it is left. Misses are
+// signed by the first signer that succeeds.
+func NewCache(minRemaining time.Duration, signers ...SignFunc) *Cache {
+	return &Cache{
+		signers:      signers,
+		minRemaining: minRemaining,
+		now:          time.Now,
+		urls:         map[string]commons.MediaRef{},
+	}
+}
+
+// Resolve fills in a fresh SignedURL and SignedURLExpires for each ref,
+// keeping its other fields. A ref's own URL is used when it is still fresh.
+func (c *Cache) Resolve(ctx context.Context, refs ...commons.MediaRef) ([]commons.MediaRef, error) {
+	resolved := make([]commons.MediaRef, len(refs))
+	copy(resolved, refs)
+
+	var missing []string
+	c.mu.Lock()
+	for _, ref := range refs {
+		if ref.ObjectPath == "" {
+			continue
+		}
+		if c.fresh(ref) {
+			c.urls[ref.ObjectPath] = ref
+			continue
+		}
+		if cached, ok := c.urls[ref.ObjectPath]; ok && c.fresh(cached) {
+			continue
+		}
+		missing = append(missing, ref.ObjectPath)
+	}
+	c.mu.Unlock()
+
+	signed, err := c.sign(ctx, unique(missing))
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.prune()
+	for _, ref := range signed {
+		c.urls[ref.ObjectPath] = ref
+	}
+	for i, ref := range resolved {
+		if ref.ObjectPath == "" || c.fresh(ref) {
+			continue
+		}
+		cached := c.urls[ref.ObjectPath]
+		resolved[i].SignedURL = cached.SignedURL
+		resolved[i].SignedURLExpires = cached.SignedURLExpires
+	}
+	return resolved, nil
+}
+
+// URLs resolves bare object paths.
+func (c *Cache) URLs(ctx context.Context, objectPaths ...string) ([]commons.MediaRef, error) {
+	refs := make([]commons.MediaRef, len(objectPaths))
+	for i, objectPath := range objectPaths {
+		refs[i].ObjectPath = objectPath
+	}
+	return c.Resolve(ctx, refs...)
+}
+
+func (c *Cache) fresh(ref commons.MediaRef) bool {
+	if ref.SignedURL == "" {
+		return false
+	}
+	return time.Unix(ref.SignedURLExpires, 0).Sub(c.now()) >= c.minRemaining
+}
+
+// prune drops expired URLs, so the cache only holds what could still play.
+func (c *Cache) prune() {
+	now := c.now().Unix()
+	for objectPath, ref := range c.urls {
+		if ref.SignedURLExpires <= now {
+			delete(c.urls, objectPath)
+		}
+	}
+}
+
+func (c *Cache) sign(ctx context.Context, objectPaths []string) ([]commons.MediaRef, error) {
+	var signed []commons.MediaRef
+	for start := 0; start < len(objectPaths); start += maxBatch {
+		batch := objectPaths[start:min(start+maxBatch, len(objectPaths))]
+		refs, err := c.signBatch(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		signed = append(signed, refs...)
+	}
+	return signed, nil
+}
+
+func (c *Cache) signBatch(ctx context.Context, objectPaths []string) ([]commons.MediaRef, error) {
+	var errs []error
+	for _, sign := range c.signers {
+		refs, err := sign(ctx, objectPaths...)
+		if err == nil && len(refs) != len(objectPaths) {
+			err = fmt.Errorf("signed %d of %d URLs", len(refs), len(objectPaths))
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for i := range refs {
+			refs[i].ObjectPath = objectPaths[i]
+		}
+		return refs, nil
+	}
+	if len(errs) == 0 {
+		return nil, errors.New("no URL signer configured")
+	}
+	return nil, fmt.Errorf("sign playback URLs: %w", errors.Join(errs...))
+}
+
+func unique(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	kept := values[:0]
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			kept = append(kept, value)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,144 @@
+package playback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/HeavenAQ/nstc-linebot-2025/commons"
+	"github.com/stretchr/testify/require"
+)
+
+var now = time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+// recordingSigner signs URLs lasting an hour and records each batch.
+type recordingSigner struct {
+	batches [][]string
+	err     error
+}
+
+func (s *recordingSigner) sign(_ context.Context, objectPaths ...string) ([]commons.MediaRef, error) {
+	s.batches = append(s.batches, append([]string(nil), objectPaths...))
+	if s.err != nil {
+		return nil, s.err
+	}
+	refs := make([]commons.MediaRef, 0, len(objectPaths))
+	for _, objectPath := range objectPaths {
+		refs = append(refs, commons.MediaRef{
+			SignedURL:        fmt.Sprintf("https://signed/%s#%d", objectPath, len(s.batches)),
+			SignedURLExpires: now.Add(time.Hour).Unix(),
+		})
+	}
+	return refs, nil
+}
+
+func newTestCache(signers ...SignFunc) (*Cache, *time.Time) {
+	clock := now
+	cache := NewCache(10*time.Minute, signers...)
+	cache.now = func() time.Time { return clock }
+	return cache, &clock
+}
+
+func TestResolveKeepsFreshStoredURLs(t *testing.T) {
+	signer := &recordingSigner{}
+	cache, _ := newTestCache(signer.sign)
+	stored := commons.MediaRef{
+		ObjectPath: "analyses/a1/student.mp4", SignedURL: "https://stored",
+		SignedURLExpires: now.Add(3 * time.Hour).Unix(), DurationSeconds: 4.5,
+	}
+
+	refs, err := cache.Resolve(context.Background(), stored)
+
+	require.NoError(t, err)
+	require.Equal(t, []commons.MediaRef{stored}, refs)
+	require.Empty(t, signer.batches)
+}
+
+func TestResolveSignsStaleURLsOnceAndKeepsOtherFields(t *testing.T) {
+	signer := &recordingSigner{}
+	cache, _ := newTestCache(signer.sign)
+	stale := commons.MediaRef{
+		ObjectPath: "analyses/a1/student.mp4", SignedURL: "https://stale",
+		SignedURLExpires: now.Add(5 * time.Minute).Unix(), DurationSeconds: 4.5,
+	}
+
+	first, err := cache.Resolve(context.Background(), stale)
+	require.NoError(t, err)
+	second, err := cache.Resolve(context.Background(), stale)
+	require.NoError(t, err)
+
+	require.Equal(t, "https://signed/analyses/a1/student.mp4#1", first[0].SignedURL)
+	require.Equal(t, now.Add(time.Hour).Unix(), first[0].SignedURLExpires)
+	require.InDelta(t, 4.5, first[0].DurationSeconds, 0.001)
+	require.Equal(t, first, second)
+	require.Len(t, signer.batches, 1)
+}
+
+func TestURLsRefreshInBatchesWhenNearExpiry(t *testing.T) {
+	signer := &recordingSigner{}
+	cache, clock := newTestCache(signer.sign)
+	paths := make([]string, 0, 10)
+	for i := range 10 {
+		paths = append(paths, fmt.Sprintf("analyses/a%d/student.mp4", i))
+	}
+
+	refs, err := cache.URLs(context.Background(), append(paths, paths[0])...)
+	require.NoError(t, err)
+	require.Len(t, refs, 11)
+	require.Equal(t, refs[0], refs[10])
+	require.Len(t, signer.batches, 2)
+	require.Len(t, signer.batches[0], 8)
+	require.Len(t, signer.batches[1], 2)
+
+	*clock = now.Add(55 * time.Minute)
+	refs, err = cache.URLs(context.Background(), paths[3])
+	require.NoError(t, err)
+	require.Equal(t, [][]string{paths[3:4]}, signer.batches[2:])
+	require.Equal(t, "https://signed/analyses/a3/student.mp4#3", refs[0].SignedURL)
+}
+
+func TestResolveFallsBackToTheNextSigner(t *testing.T) {
+	local := &recordingSigner{err: errors.New("permission denied")}
+	analyzer := &recordingSigner{}
+	cache, _ := newTestCache(local.sign, analyzer.sign)
+
+	refs, err := cache.URLs(context.Background(), "experts/v1/serve.mp4")
+
+	require.NoError(t, err)
+	require.Equal(t, "https://signed/experts/v1/serve.mp4#1", refs[0].SignedURL)
+	require.Len(t, local.batches, 1)
+	require.Len(t, analyzer.batches, 1)
+}
+
+func TestResolveReportsEverySignerError(t *testing.T) {
+	cache, _ := newTestCache(
+		(&recordingSigner{err: errors.New("permission denied")}).sign,
+		(&recordingSigner{err: errors.New("unavailable")}).sign,
+	)
+
+	_, err := cache.URLs(context.Background(), "analyses/a1/student.mp4")
+
+	require.ErrorContains(t, err, "permission denied")
+	require.ErrorContains(t, err, "unavailable")
+}
+
+func TestLocalSigner(t *testing.T) {
+	sign := LocalSigner(fakeURLSigner{}, time.Hour)
+
+	refs, err := sign(context.Background(), "analyses/a1/student.mp4")
+
+	require.NoError(t, err)
+	require.Equal(t, []commons.MediaRef{{
+		ObjectPath:       "analyses/a1/student.mp4",
+		SignedURL:        "https://local/analyses/a1/student.mp4",
+		SignedURLExpires: now.Add(time.Hour).Unix(),
+	}}, refs)
+}
+
+type fakeURLSigner struct{}
+
+func (fakeURLSigner) SignURL(_ context.Context, objectPath string, lifetime time.Duration) (string, time.Time, error) {
+	return "https://local/" + objectPath, now.Add(lifetime), nil
+}
@@ -0,0 +1,211 @@
+// Package reanalysis re-grades archived uploads, so past work benefits when
+// the analyzer's checkpoints improve. Each work keeps the gradings it had
+// before.
+package reanalysis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/HeavenAQ/nstc-linebot-2025/api/db"
+	"github.com/HeavenAQ/nstc-linebot-2025/commons"
+	"github.com/HeavenAQ/nstc-linebot-2025/logging"
+)
+
+var ErrRunning = errors.New("a re-analysis is already running")
+
+// Store is the persistence the runner needs; *db.FirestoreClient satisfies it.
+type Store interface {
+	ListUsers(ctx context.Context) (*[]db.UserData, error)
+	GetUserData(ctx context.Context, userID string) (*db.UserData, error)
+	UpdateWorkAnalysis(ctx context.Context, userID, skill, date string, work db.Work) error
+}
+
+// Archive opens archived uploads; *storage.BucketClient satisfies it.
+type Archive interface {
+	OpenVideo(ctx context.Context, filePath string) (io.ReadCloser, error)
+}
+
+// Analyzer grades a video; *analysis.Client satisfies it.
+type Analyzer interface {
+	AnalyzeVideo(
+		ctx context.Context,
+		requestID, userID, filename, skill, handedness string,
+		video io.Reader,
+	) (*commons.AnalysisOutcome, error)
+}
+
+// Scope narrows a re-analysis to one student and/or one skill. The zero
+// value re-analyzes everyone.
+type Scope struct {
+	UserID string `json:"user_id"`
+	Skill  string `json:"skill"`
+}
+
+func (s Scope) Validate() error {
+	if s.Skill != "" && !slices.Contains(db.BadmintonSkillNames(), s.Skill) {
+		return fmt.Errorf("unsupported skill: %s", s.Skill)
+	}
+	return nil
+}
+
+// Result summarizes a run. Skipped counts works uploaded before originals
+// were archived.
+type Result struct {
+	Reanalyzed int `json:"reanalyzed"`
+	Skipped    int `json:"skipped"`
+	Failed     int `json:"failed"`
+}
+
+type Runner struct {
+	store    Store
+	archive  Archive
+	analyzer Analyzer
+	logger   *slog.Logger
+	running  atomic.Bool
+	wg       sync.WaitGroup
+}
+
+func NewRunner(store Store, archive Archive, analyzer Analyzer, logger *slog.Logger) *Runner {
+	return &Runner{store: store, archive: archive, analyzer: analyzer, logger: logger}
+}
+
+// Start runs the re-analysis in the background and logs the outcome, for
+// callers that cannot wait the minutes each video takes. It stops when ctx is
+// done, so ctx should outlive the caller's request.
+func (r *Runner) Start(ctx context.Context, scope Scope) error {
+	if err := scope.Validate(); err != nil {
+		return err
+	}
+	if !r.running.CompareAndSwap(false, true) {
+		return ErrRunning
+	}
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer r.running.Store(false)
+		started := time.Now()
+		ctx := logging.WithSkill(logging.WithUser(ctx, scope.UserID), scope.Skill)
+		result, err := r.run(ctx, scope)
+		level := slog.LevelInfo
+		if err != nil {
+			level = slog.LevelError
+		}
+		r.logger.Log(ctx, level, "re-analysis finished",
+			"reanalyzed", result.Reanalyzed, "skipped", result.Skipped, "failed", result.Failed,
+			"took", time.Since(started).String(), "error", err,
+		)
+	}()
+	return nil
+}
+
+// Wait waits for the run started by Start to finish. A work the run was
+// cancelled in the middle of keeps its previous grading.
+func (r *Runner) Wait() {
+	r.wg.Wait()
+}
+
+// Run re-analyzes every archived work in scope and waits for it to finish.
+// A failed work is counted and does not stop the others.
+func (r *Runner) Run(ctx context.Context, scope Scope) (Result, error) {
+	if err := scope.Validate(); err != nil {
+		return Result{}, err
+	}
+	if !r.running.CompareAndSwap(false, true) {
+		return Result{}, ErrRunning
+	}
+	defer r.running.Store(false)
+	return r.run(ctx, scope)
+}
+
+func (r *Runner) run(ctx context.Context, scope Scope) (Result, error) {
+	users, err := r.users(ctx, scope)
+	if err != nil {
+		return Result{}, err
+	}
+	skills := db.BadmintonSkillNames()
+	if scope.Skill != "" {
+		skills = []string{scope.Skill}
+	}
+
+	var result Result
+	var errs []error
+	for _, user := range users {
+		for _, skill := range skills {
+			portfolio := user.Portfolio.GetSkillPortfolio(skill)
+			dates := make([]string, 0, len(portfolio))
+			for date := range portfolio {
+				dates = append(dates, date)
+			}
+			sort.Strings(dates)
+			for _, date := range dates {
+				if err := ctx.Err(); err != nil {
+					return result, errors.Join(append(errs, err)...)
+				}
+				work := portfolio[date]
+				if work.OriginalVideo == "" {
+					result.Skipped++
+					continue
+				}
+				if err := r.reanalyze(ctx, user.ID, skill, date, work); err != nil {
+					result.Failed++
+					errs = append(errs, fmt.Errorf("user %s %s %s: %w", logging.HashUser(user.ID), skill, date, err))
+					continue
+				}
+				result.Reanalyzed++
+			}
+		}
+	}
+	return result, errors.Join(errs...)
+}
+
+func (r *Runner) users(ctx context.Context, scope Scope) ([]db.UserData, error) {
+	if scope.UserID != "" {
+		user, err := r.store.GetUserData(ctx, scope.UserID)
+		if err != nil {
+			return nil, err
+		}
+		return []db.UserData{*user}, nil
+	}
+	users, err := r.store.ListUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return *users, nil
+}
+
+func (r *Runner) reanalyze(ctx context.Context, userID, skill, date string, work db.Work) error {
+	video, err := r.archive.OpenVideo(ctx, work.OriginalVideo)
+	if err != nil {
+		return err
+	}
+	defer video.Close()
+
+	handedness := work.Handedness
+	if handedness == "" {
+		handedness = "auto"
+	}
+	now := time.Now()
+	requestID := fmt.Sprintf("reanalysis-%s-%s-%d", skill, date, now.UnixNano())
+	outcome, err := r.analyzer.AnalyzeVideo(
+		ctx, requestID, userID, path.Base(work.OriginalVideo), skill, handedness, video,
+	)
+	if err != nil {
+		return err
+	}
+	ctx = logging.WithRequest(logging.WithSkill(logging.WithUser(ctx, userID), skill), requestID)
+	r.logger.InfoContext(ctx, "re-analyzed work", "date", date,
+		"previous_grade", work.GradingOutcome.TotalGrade, "grade", outcome.Grade.TotalGrade,
+		"previous_version", work.AnalyzerVersion, "version", outcome.AnalyzerVersion,
+	)
+	return r.store.UpdateWorkAnalysis(ctx, userID, skill, date, work.Reanalyzed(*outcome, now))
+}
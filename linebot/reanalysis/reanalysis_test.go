@@ -0,0 +1,174 @@
+package reanalysis_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/HeavenAQ/nstc-linebot-2025/api/db"
+	"github.com/HeavenAQ/nstc-linebot-2025/commons"
+	"github.com/HeavenAQ/nstc-linebot-2025/logging"
+	"github.com/HeavenAQ/nstc-linebot-2025/reanalysis"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStore struct {
+	users   []db.UserData
+	updated map[string]db.Work
+}
+
+func (s *fakeStore) ListUsers(_ context.Context) (*[]db.UserData, error) { return &s.users, nil }
+
+func (s *fakeStore) GetUserData(_ context.Context, userID string) (*db.UserData, error) {
+	for _, user := range s.users {
+		if user.ID == userID {
+			return &user, nil
+		}
+	}
+	return nil, errors.New("user not found")
+}
+
+func (s *fakeStore) UpdateWorkAnalysis(_ context.Context, userID, skill, date string, work db.Work) error {
+	s.updated[userID+"/"+skill+"/"+date] = work
+	return nil
+}
+
+type fakeArchive map[string]string
+
+func (a fakeArchive) OpenVideo(_ context.Context, filePath string) (io.ReadCloser, error) {
+	data, ok := a[filePath]
+	if !ok {
+		return nil, errors.New("object not found")
+	}
+	return io.NopCloser(strings.NewReader(data)), nil
+}
+
+// fakeAnalyzer echoes the video into the analysis ID, so tests can tell
+// which original was sent.
+type fakeAnalyzer struct {
+	handedness []string
+}
+
+func (a *fakeAnalyzer) AnalyzeVideo(
+	_ context.Context, _, _, _, skill, handedness string, video io.Reader,
+) (*commons.AnalysisOutcome, error) {
+	data, err := io.ReadAll(video)
+	if err != nil {
+		return nil, err
+	}
+	a.handedness = append(a.handedness, handedness)
+	return &commons.AnalysisOutcome{
+		AnalysisID:      "new-" + string(data),
+		Skill:           skill,
+		Handedness:      "right",
+		Grade:           commons.GradingOutcome{TotalGrade: float64(len(data))},
+		AnalyzerVersion: "v2",
+	}, nil
+}
+
+func archived(path string, grade float64) db.Work {
+	return db.Work{
+		OriginalVideo:   path,
+		AnalysisID:      "old",
+		AnalyzerVersion: "v1",
+		Handedness:      "right",
+		Reflection:      "心得",
+		GradingOutcome:  commons.GradingOutcome{TotalGrade: grade},
+	}
+}
+
+func newFixture() (*fakeStore, fakeArchive) {
+	store := &fakeStore{
+		updated: map[string]db.Work{},
+		users: []db.UserData{
+			{ID: "U1", Portfolio: db.Portfolios{
+				Serve: map[string]db.Work{
+					"2026-09-01-10-00": archived("U1/originals/serve/2026-09-01-10-00.mp4", 50),
+					"2026-08-01-10-00": {AnalysisID: "before-archiving"},
+				},
+				Smash: map[string]db.Work{
+					"2026-09-02-10-00": archived("U1/originals/smash/2026-09-02-10-00.mp4", 40),
+				},
+			}},
+			{ID: "U2", Portfolio: db.Portfolios{
+				Serve: map[string]db.Work{
+					"2026-09-03-10-00": archived("U2/originals/serve/2026-09-03-10-00.mp4", 30),
+				},
+			}},
+		},
+	}
+	archive := fakeArchive{
+		"U1/originals/serve/2026-09-01-10-00.mp4": "first-serve",
+		"U1/originals/smash/2026-09-02-10-00.mp4": "smash",
+		"U2/originals/serve/2026-09-03-10-00.mp4": "serve",
+	}
+	return store, archive
+}
+
+func newRunner(store *fakeStore, archive fakeArchive, analyzer *fakeAnalyzer) *reanalysis.Runner {
+	return reanalysis.NewRunner(store, archive, analyzer, slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestRunEveryone(t *testing.T) {
+	store, archive := newFixture()
+	analyzer := &fakeAnalyzer{}
+
+	result, err := newRunner(store, archive, analyzer).Run(context.Background(), reanalysis.Scope{})
+
+	require.NoError(t, err)
+	require.Equal(t, reanalysis.Result{Reanalyzed: 3, Skipped: 1}, result)
+	work := store.updated["U1/serve/2026-09-01-10-00"]
+	require.Equal(t, "new-first-serve", work.AnalysisID)
+	require.Equal(t, "v2", work.AnalyzerVersion)
+	require.Equal(t, "心得", work.Reflection)
+	require.Len(t, work.GradeHistory, 1)
+	require.Equal(t, "v1", work.GradeHistory[0].AnalyzerVersion)
+	require.InDelta(t, 50, work.GradeHistory[0].GradingOutcome.TotalGrade, 0.001)
+}
+
+func TestRunScopedToUserAndSkill(t *testing.T) {
+	store, archive := newFixture()
+
+	result, err := newRunner(store, archive, &fakeAnalyzer{}).Run(
+		context.Background(), reanalysis.Scope{UserID: "U1", Skill: "smash"},
+	)
+
+	require.NoError(t, err)
+	require.Equal(t, reanalysis.Result{Reanalyzed: 1}, result)
+	require.Contains(t, store.updated, "U1/smash/2026-09-02-10-00")
+	require.Len(t, store.updated, 1)
+}
+
+func TestRunCountsFailuresAndContinues(t *testing.T) {
+	store, archive := newFixture()
+	delete(archive, "U1/originals/smash/2026-09-02-10-00.mp4")
+
+	result, err := newRunner(store, archive, &fakeAnalyzer{}).Run(context.Background(), reanalysis.Scope{})
+
+	require.ErrorContains(t, err, "user "+logging.HashUser("U1")+" smash 2026-09-02-10-00: object not found")
+	require.Equal(t, reanalysis.Result{Reanalyzed: 2, Skipped: 1, Failed: 1}, result)
+}
+
+func TestRunDefaultsMissingHandednessToAuto(t *testing.T) {
+	store, archive := newFixture()
+	work := store.users[1].Portfolio.Serve["2026-09-03-10-00"]
+	work.Handedness = ""
+	store.users[1].Portfolio.Serve["2026-09-03-10-00"] = work
+	analyzer := &fakeAnalyzer{}
+
+	_, err := newRunner(store, archive, analyzer).Run(context.Background(), reanalysis.Scope{UserID: "U2"})
+
+	require.NoError(t, err)
+	require.Equal(t, []string{"auto"}, analyzer.handedness)
+}
+
+func TestRunRejectsUnknownSkill(t *testing.T) {
+	store, archive := newFixture()
+
+	_, err := newRunner(store, archive, &fakeAnalyzer{}).Run(context.Background(), reanalysis.Scope{Skill: "drop"})
+
+	require.EqualError(t, err, "unsupported skill: drop")
+}
@@ -0,0 +1,123 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron spec. Specs have the five standard fields
+// (minute, hour, day of month, month, day of week) and accept "*", lists,
+// ranges and steps, or one of the @hourly, @daily and @weekly shorthands.
+// Times are matched in server local time.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+	// domAny and dowAny record a day field starting with "*": as in cron,
+	// when both day fields are restricted a day matching either is enough.
+	domAny, dowAny bool
+}
+
+var shorthands = map[string]string{
+	"@hourly": "0 * * * *",
+	"@daily":  "0 0 * * *",
+	"@weekly": "0 0 * * 0",
+}
+
+type fieldRange struct{ min, max int }
+
+var fieldRanges = [5]fieldRange{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 6}}
+
+// ParseSchedule parses a cron spec.
+func ParseSchedule(spec string) (Schedule, error) {
+	if expanded, ok := shorthands[strings.TrimSpace(spec)]; ok {
+		spec = expanded
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return Schedule{}, fmt.Errorf("cron spec %q must have 5 fields", spec)
+	}
+
+	var bits [5]uint64
+	for i, field := range fields {
+		parsed, err := parseField(field, fieldRanges[i])
+		if err != nil {
+			return Schedule{}, fmt.Errorf("cron spec %q: %w", spec, err)
+		}
+		bits[i] = parsed
+	}
+	return Schedule{
+		minute: bits[0], hour: bits[1], dom: bits[2], month: bits[3], dow: bits[4],
+		domAny: strings.HasPrefix(fields[2], "*"), dowAny: strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+func parseField(field string, limits fieldRange) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if before, after, ok := strings.Cut(part, "/"); ok {
+			var err error
+			if step, err = strconv.Atoi(after); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			rangePart = before
+		}
+
+		low, high := limits.min, limits.max
+		if rangePart != "*" {
+			var err error
+			lowText, highText, isRange := strings.Cut(rangePart, "-")
+			if low, err = strconv.Atoi(lowText); err != nil {
+				return 0, fmt.Errorf("invalid value %q", part)
+			}
+			high = low
+			if isRange {
+				if high, err = strconv.Atoi(highText); err != nil {
+					return 0, fmt.Errorf("invalid value %q", part)
+				}
+			}
+		}
+		if low < limits.min || high > limits.max || low > high {
+			return 0, fmt.Errorf("%q is out of range %d-%d", part, limits.min, limits.max)
+		}
+		for value := low; value <= high; value += step {
+			bits |= 1 << value
+		}
+	}
+	return bits, nil
+}
+
+// maxSearch bounds Next for specs that never match, such as 31 February.
+const maxSearch = 5 * 366 * 24 * time.Hour
+
+// Next returns the first matching minute strictly after t, or the zero time
+// if the spec never matches.
+func (s Schedule) Next(t time.Time) time.Time {
+	t = t.Local().Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(maxSearch)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.Local)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.Local)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, time.Local)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (s Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
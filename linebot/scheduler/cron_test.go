@@ -0,0 +1,48 @@
+package scheduler_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/HeavenAQ/nstc-linebot-2025/scheduler"
+	"github.com/stretchr/testify/require"
+)
+
+func local(month time.Month, day, hour, minute int) time.Time {
+	return time.Date(2026, month, day, hour, minute, 0, 0, time.Local)
+}
+
+func TestScheduleNext(t *testing.T) {
+	cases := []struct {
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{"*/15 * * * *", local(9, 13, 10, 7), local(9, 13, 10, 15)},
+		{"*/15 * * * *", local(9, 13, 10, 15), local(9, 13, 10, 30)},
+		{"0 20 * * 0", local(9, 14, 9, 0), local(9, 20, 20, 0)},
+		{"30 8-10/2 * * *", local(9, 13, 9, 0), local(9, 13, 10, 30)},
+		{"0 0 1,15 * *", local(9, 2, 0, 0), local(9, 15, 0, 0)},
+		{"@daily", local(12, 31, 12, 0), time.Date(2027, 1, 1, 0, 0, 0, 0, time.Local)},
+		// Both day fields restricted: the 1st or any Monday.
+		{"0 0 1 * 1", local(9, 2, 0, 0), local(9, 7, 0, 0)},
+	}
+	for _, tc := range cases {
+		schedule, err := scheduler.ParseSchedule(tc.spec)
+		require.NoError(t, err, tc.spec)
+		require.Equal(t, tc.want, schedule.Next(tc.from), tc.spec)
+	}
+}
+
+func TestScheduleNeverMatching(t *testing.T) {
+	schedule, err := scheduler.ParseSchedule("0 0 31 2 *")
+	require.NoError(t, err)
+	require.True(t, schedule.Next(local(1, 1, 0, 0)).IsZero())
+}
+
+func TestParseScheduleRejectsInvalidSpecs(t *testing.T) {
+	for _, spec := range []string{"* * * *", "60 * * * *", "* 24 * * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		_, err := scheduler.ParseSchedule(spec)
+		require.Error(t, err, spec)
+	}
+}
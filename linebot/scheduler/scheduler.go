@@ -0,0 +1,313 @@
+// Package scheduler runs periodic jobs on exactly one instance at a time. The
+// instances elect a leader through a Firestore lease; only the leader runs
+// jobs on their schedule, and every run is recorded.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/HeavenAQ/nstc-linebot-2025/api/db"
+)
+
+// leaseName is the lease document the instances compete for.
+const leaseName = "scheduler"
+
+// How a run was started.
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+)
+
+var (
+	ErrUnknownJob = errors.New("unknown job")
+	ErrJobRunning = errors.New("job is already running")
+	ErrStopped    = errors.New("scheduler is stopped")
+)
+
+// releaseTimeout bounds giving up the lease when the scheduler stops.
+const releaseTimeout = 2 * time.Second
+
+// Store is the persistence the scheduler needs; *db.FirestoreClient
+// satisfies it.
+type Store interface {
+	AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error)
+	ReleaseLease(ctx context.Context, name, holder string) error
+	RecordJobRun(ctx context.Context, run db.JobRun) error
+	GetLatestJobRuns(ctx context.Context) (map[string]db.JobRun, error)
+}
+
+// Timing decides when a job runs. Next returns the first run strictly after
+// t, or the zero time if there is none.
+type Timing interface {
+	Next(t time.Time) time.Time
+}
+
+type job struct {
+	name     string
+	spec     string
+	schedule Timing
+	run      func(ctx context.Context) error
+	next     time.Time
+	running  atomic.Bool
+}
+
+// JobStatus describes a registered job for the admin listing. NextRun is
+// only known on the leader.
+type JobStatus struct {
+	Name    string     `json:"name"`
+	Spec    string     `json:"spec"`
+	NextRun *time.Time `json:"next_run,omitempty"`
+	Running bool       `json:"running"`
+	LastRun *db.JobRun `json:"last_run,omitempty"`
+}
+
+type Scheduler struct {
+	store    Store
+	holder   string
+	leaseTTL time.Duration
+	logger   *slog.Logger
+
+	mu     sync.Mutex
+	jobs   []*job
+	leader bool
+	wg     sync.WaitGroup
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// New creates a scheduler whose leadership lasts leaseTTL without renewal.
+// The lease is renewed three times per TTL, so a leader that stops renewing
+// is replaced within one TTL.
+func New(store Store, leaseTTL time.Duration, logger *slog.Logger) *Scheduler {
+	hostname, _ := os.Hostname()
+	return &Scheduler{
+		store:    store,
+		holder:   fmt.Sprintf("%s-%d-%d", hostname, os.Getpid(), time.Now().UnixNano()),
+		leaseTTL: leaseTTL,
+		logger:   logger,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Register adds a job run on a cron spec. It must be called before Start.
+func (s *Scheduler) Register(name, spec string, run func(ctx context.Context) error) error {
+	schedule, err := ParseSchedule(spec)
+	if err != nil {
+		return err
+	}
+	return s.RegisterTiming(name, spec, schedule, run)
+}
+
+// RegisterTiming adds a job run on any timing; spec describes the timing in
+// the job listing. It must be called before Start.
+func (s *Scheduler) RegisterTiming(name, spec string, schedule Timing, run func(ctx context.Context) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.jobs {
+		if existing.name == name {
+			return fmt.Errorf("job %s is already registered", name)
+		}
+	}
+	s.jobs = append(s.jobs, &job{name: name, spec: spec, schedule: schedule, run: run})
+	return nil
+}
+
+// Start competes for leadership and runs due jobs until ctx is done or Stop is
+// called. A leader then releases the lease, so after a deploy the next
+// instance takes over without waiting for it to expire.
+func (s *Scheduler) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.leaseTTL / 3)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer ticker.Stop()
+		defer s.release(ctx)
+		s.tick(ctx, time.Now())
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.stop:
+				return
+			case now := <-ticker.C:
+				s.tick(ctx, now)
+			}
+		}
+	}()
+}
+
+// Stop stops starting runs. Runs in progress carry on until their context is
+// done.
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopOnce.Do(func() { close(s.stop) })
+}
+
+// release gives up the lease if this instance leads.
+func (s *Scheduler) release(ctx context.Context) {
+	s.mu.Lock()
+	leader := s.leader
+	s.leader = false
+	s.mu.Unlock()
+	if !leader {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), releaseTimeout)
+	defer cancel()
+	if err := s.store.ReleaseLease(ctx, leaseName, s.holder); err != nil {
+		s.logger.WarnContext(ctx, "scheduler lease not released", "error", err)
+		return
+	}
+	s.logger.InfoContext(ctx, "scheduler released leadership", "holder", s.holder)
+}
+
+// Wait waits for a stopped scheduler and the runs it started to finish.
+func (s *Scheduler) Wait() {
+	s.wg.Wait()
+}
+
+// tick renews the lease and, while leading, starts every job that is due.
+// A new leader schedules from now on rather than catching up, so a run the
+// previous leader already made is not repeated.
+func (s *Scheduler) tick(ctx context.Context, now time.Time) {
+	leader, err := s.store.AcquireLease(ctx, leaseName, s.holder, s.leaseTTL)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "scheduler lease error", "error", err)
+		leader = false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if leader && !s.leader {
+		s.logger.InfoContext(ctx, "scheduler became leader", "holder", s.holder)
+		for _, job := range s.jobs {
+			job.next = job.schedule.Next(now)
+		}
+	}
+	if !leader && s.leader {
+		s.logger.InfoContext(ctx, "scheduler lost leadership", "holder", s.holder)
+	}
+	s.leader = leader
+	if !leader {
+		return
+	}
+
+	for _, job := range s.jobs {
+		if job.next.IsZero() || now.Before(job.next) {
+			continue
+		}
+		job.next = job.schedule.Next(now)
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			if _, err := s.execute(ctx, job, TriggerSchedule); err != nil {
+				s.logger.WarnContext(ctx, "job skipped", "job", job.name, "error", err)
+			}
+		}()
+	}
+}
+
+// Trigger starts the named job on this instance, whether or not it leads,
+// and returns its run, which is recorded once it finishes. The run lasts
+// until ctx is done, so ctx should outlive the request that triggered it;
+// Wait waits for it too.
+func (s *Scheduler) Trigger(ctx context.Context, name string) (db.JobRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-s.stop:
+		return db.JobRun{}, ErrStopped
+	default:
+	}
+	var target *job
+	for _, job := range s.jobs {
+		if job.name == name {
+			target = job
+		}
+	}
+	if target == nil {
+		return db.JobRun{}, ErrUnknownJob
+	}
+	if !target.running.CompareAndSwap(false, true) {
+		return db.JobRun{}, ErrJobRunning
+	}
+	run := s.newRun(target, TriggerManual)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.finish(ctx, target, run)
+	}()
+	return run, nil
+}
+
+// execute runs the job unless it is already running on this instance and
+// records the outcome.
+func (s *Scheduler) execute(ctx context.Context, job *job, trigger string) (db.JobRun, error) {
+	if !job.running.CompareAndSwap(false, true) {
+		return db.JobRun{}, ErrJobRunning
+	}
+	return s.finish(ctx, job, s.newRun(job, trigger)), nil
+}
+
+func (s *Scheduler) newRun(job *job, trigger string) db.JobRun {
+	started := time.Now().UTC()
+	return db.JobRun{
+		ID:  fmt.Sprintf("%s-%d", job.name, started.UnixNano()),
+		Job: job.name, Trigger: trigger, Holder: s.holder, StartedAt: started,
+	}
+}
+
+// finish runs a job claimed by setting running, records the run and frees
+// the job again.
+func (s *Scheduler) finish(ctx context.Context, job *job, run db.JobRun) db.JobRun {
+	defer job.running.Store(false)
+
+	err := job.run(ctx)
+	run.DurationMS = time.Since(run.StartedAt).Milliseconds()
+	run.Success = err == nil
+	if err != nil {
+		run.Error = err.Error()
+		s.logger.ErrorContext(ctx, "job failed", "job", job.name, "duration_ms", run.DurationMS, "error", err)
+	} else {
+		s.logger.InfoContext(ctx, "job finished", "job", job.name, "duration_ms", run.DurationMS)
+	}
+	// A run cut short by shutdown is still recorded.
+	if err := s.store.RecordJobRun(context.WithoutCancel(ctx), run); err != nil {
+		s.logger.WarnContext(ctx, "job run not recorded", "job", job.name, "error", err)
+	}
+	return run
+}
+
+// Jobs lists the registered jobs with their latest recorded run, which may
+// have been made by another instance.
+func (s *Scheduler) Jobs(ctx context.Context) ([]JobStatus, error) {
+	runs, err := s.store.GetLatestJobRuns(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	statuses := make([]JobStatus, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		status := JobStatus{Name: job.name, Spec: job.spec, Running: job.running.Load()}
+		if s.leader && !job.next.IsZero() {
+			next := job.next
+			status.NextRun = &next
+		}
+		if run, ok := runs[job.name]; ok {
+			status.LastRun = &run
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
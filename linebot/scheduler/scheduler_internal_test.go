@@ -0,0 +1,193 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/HeavenAQ/nstc-linebot-2025/api/db"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStore hands the lease to whichever holder asks first until it expires.
+type fakeStore struct {
+	mu      sync.Mutex
+	holder  string
+	expires time.Time
+	now     time.Time
+	runs    []db.JobRun
+}
+
+func (s *fakeStore) AcquireLease(_ context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.holder != holder && s.now.Before(s.expires) {
+		return false, nil
+	}
+	s.holder, s.expires = holder, s.now.Add(ttl)
+	return true, nil
+}
+
+func (s *fakeStore) ReleaseLease(_ context.Context, name, holder string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.holder == holder {
+		s.holder, s.expires = "", time.Time{}
+	}
+	return nil
+}
+
+func (s *fakeStore) RecordJobRun(_ context.Context, run db.JobRun) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.runs = append(s.runs, run)
+	return nil
+}
+
+func (s *fakeStore) GetLatestJobRuns(_ context.Context) (map[string]db.JobRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	latest := map[string]db.JobRun{}
+	for _, run := range s.runs {
+		latest[run.Job] = run
+	}
+	return latest, nil
+}
+
+func newScheduler(store Store, holder string) *Scheduler {
+	s := New(store, 90*time.Second, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	s.holder = holder
+	return s
+}
+
+func minute(m int) time.Time {
+	return time.Date(2026, 9, 13, 10, m, 0, 0, time.Local)
+}
+
+func TestOnlyTheLeaderRunsScheduledJobs(t *testing.T) {
+	store := &fakeStore{now: minute(0)}
+	counts := map[string]int{}
+	var mu sync.Mutex
+	instances := []*Scheduler{newScheduler(store, "a"), newScheduler(store, "b")}
+	for _, instance := range instances {
+		name := instance.holder
+		require.NoError(t, instance.Register("ping", "*/5 * * * *", func(ctx context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			counts[name]++
+			return nil
+		}))
+	}
+
+	for m := 0; m <= 10; m++ {
+		store.now = minute(m)
+		for _, instance := range instances {
+			instance.tick(context.Background(), minute(m))
+			instance.wg.Wait()
+		}
+	}
+
+	require.Equal(t, map[string]int{"a": 2}, counts)
+	require.Len(t, store.runs, 2)
+	require.Equal(t, TriggerSchedule, store.runs[0].Trigger)
+	require.True(t, store.runs[0].Success)
+}
+
+func TestLeadershipMovesWhenTheLeaseExpires(t *testing.T) {
+	store := &fakeStore{now: minute(0)}
+	a, b := newScheduler(store, "a"), newScheduler(store, "b")
+
+	a.tick(context.Background(), minute(0))
+	b.tick(context.Background(), minute(0))
+	require.True(t, a.leader)
+	require.False(t, b.leader)
+
+	// a stops renewing; once its lease lapses b takes over.
+	store.now = minute(2)
+	b.tick(context.Background(), minute(2))
+	require.True(t, b.leader)
+}
+
+func TestTriggerRecordsFailuresAndUnknownJobs(t *testing.T) {
+	store := &fakeStore{}
+	s := newScheduler(store, "a")
+	require.NoError(t, s.Register("cleanup", "@daily", func(ctx context.Context) error {
+		return errors.New("disk full")
+	}))
+	require.Error(t, s.Register("cleanup", "@daily", nil))
+
+	run, err := s.Trigger(context.Background(), "cleanup")
+	require.NoError(t, err)
+	require.Equal(t, TriggerManual, run.Trigger)
+	require.NotEmpty(t, run.ID)
+	s.Wait()
+
+	jobs, err := s.Jobs(context.Background())
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	require.Equal(t, run.ID, jobs[0].LastRun.ID)
+	require.False(t, jobs[0].LastRun.Success)
+	require.Equal(t, "disk full", jobs[0].LastRun.Error)
+
+	_, err = s.Trigger(context.Background(), "missing")
+	require.ErrorIs(t, err, ErrUnknownJob)
+}
+
+func TestStopEndsTheScheduler(t *testing.T) {
+	s := newScheduler(&fakeStore{now: minute(0)}, "a")
+	s.Start(context.Background())
+	s.Stop()
+
+	done := make(chan struct{})
+	go func() {
+		s.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("scheduler did not stop")
+	}
+}
+
+func TestTriggerReturnsWhileTheJobRuns(t *testing.T) {
+	s := newScheduler(&fakeStore{}, "a")
+	release := make(chan struct{})
+	require.NoError(t, s.Register("notifications", "@daily", func(ctx context.Context) error {
+		<-release
+		return nil
+	}))
+
+	_, err := s.Trigger(context.Background(), "notifications")
+	require.NoError(t, err)
+	_, err = s.Trigger(context.Background(), "notifications")
+	require.ErrorIs(t, err, ErrJobRunning)
+
+	close(release)
+	s.Wait()
+	s.Stop()
+	_, err = s.Trigger(context.Background(), "notifications")
+	require.ErrorIs(t, err, ErrStopped)
+}
+
+func TestStoppedLeaderReleasesTheLease(t *testing.T) {
+	store := &fakeStore{now: minute(0)}
+	a := newScheduler(store, "a")
+	a.Start(context.Background())
+	require.Eventually(t, func() bool {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		return a.leader
+	}, time.Second, time.Millisecond)
+
+	a.Stop()
+	a.Wait()
+
+	b := newScheduler(store, "b")
+	b.tick(context.Background(), minute(0))
+	require.True(t, b.leader)
+}
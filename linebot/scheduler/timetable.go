@@ -0,0 +1,148 @@
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Timetable is a weekly class timetable. A spec lists classes separated by
+// ";", each as days and a start and end time, such as
+// "mon,wed 08:10-10:00; fri 13:20-15:10". Days are sun to sat, and a range
+// such as mon-fri covers the days between. Times are in server local time.
+type Timetable struct {
+	classes []class
+}
+
+type class struct {
+	days       uint64
+	start, end time.Duration // since midnight
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// ParseTimetable parses a timetable spec. An empty spec has no classes.
+func ParseTimetable(spec string) (Timetable, error) {
+	var table Timetable
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parsed, err := parseClass(entry)
+		if err != nil {
+			return Timetable{}, fmt.Errorf("timetable %q: %w", spec, err)
+		}
+		table.classes = append(table.classes, parsed)
+	}
+	return table, nil
+}
+
+func parseClass(entry string) (class, error) {
+	fields := strings.Fields(entry)
+	if len(fields) != 2 {
+		return class{}, fmt.Errorf("class %q must be days and a time range", entry)
+	}
+	days, err := parseDays(fields[0])
+	if err != nil {
+		return class{}, err
+	}
+	startText, endText, ok := strings.Cut(fields[1], "-")
+	if !ok {
+		return class{}, fmt.Errorf("class %q must have a start and end time", entry)
+	}
+	start, err := parseClock(startText)
+	if err != nil {
+		return class{}, err
+	}
+	end, err := parseClock(endText)
+	if err != nil {
+		return class{}, err
+	}
+	if end <= start {
+		return class{}, fmt.Errorf("class %q ends before it starts", entry)
+	}
+	return class{days: days, start: start, end: end}, nil
+}
+
+func parseDays(field string) (uint64, error) {
+	var days uint64
+	for _, part := range strings.Split(strings.ToLower(field), ",") {
+		lowText, highText, isRange := strings.Cut(part, "-")
+		low, ok := weekdays[lowText]
+		if !ok {
+			return 0, fmt.Errorf("invalid day %q", part)
+		}
+		high := low
+		if isRange {
+			if high, ok = weekdays[highText]; !ok || high < low {
+				return 0, fmt.Errorf("invalid day range %q", part)
+			}
+		}
+		for day := low; day <= high; day++ {
+			days |= 1 << uint(day)
+		}
+	}
+	return days, nil
+}
+
+func parseClock(text string) (time.Duration, error) {
+	clock, err := time.Parse("15:04", text)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q", text)
+	}
+	return time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute, nil
+}
+
+// Empty reports whether the timetable has no classes.
+func (t Timetable) Empty() bool { return len(t.classes) == 0 }
+
+// Warmup returns the timing of pings that keep a service warm for class: the
+// first lead before each class starts, then every interval until it ends.
+func (t Timetable) Warmup(lead, interval time.Duration) Timing {
+	return warmup{table: t, lead: lead, interval: interval}
+}
+
+type warmup struct {
+	table          Timetable
+	lead, interval time.Duration
+}
+
+// Next returns the first ping strictly after t, or the zero time if the
+// timetable is empty.
+func (w warmup) Next(t time.Time) time.Time {
+	t = t.Local()
+	var next time.Time
+	// A window can open on the day before its class when lead crosses
+	// midnight, so the search starts a day early.
+	midnight := time.Date(t.Year(), t.Month(), t.Day()-1, 0, 0, 0, 0, time.Local)
+	for offset := 0; offset <= 8; offset++ {
+		day := midnight.AddDate(0, 0, offset)
+		for _, class := range w.table.classes {
+			if class.days&(1<<uint(day.Weekday())) == 0 {
+				continue
+			}
+			ping := w.nextPing(t, day.Add(class.start-w.lead), day.Add(class.end))
+			if !ping.IsZero() && (next.IsZero() || ping.Before(next)) {
+				next = ping
+			}
+		}
+	}
+	return next
+}
+
+// nextPing returns the first ping after t in the window from opens to
+// closes, or the zero time if the window has none left.
+func (w warmup) nextPing(t, opens, closes time.Time) time.Time {
+	if t.Before(opens) {
+		return opens
+	}
+	ping := opens.Add((t.Sub(opens)/w.interval + 1) * w.interval)
+	if ping.Before(closes) {
+		return ping
+	}
+	return time.Time{}
+}
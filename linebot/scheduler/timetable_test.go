@@ -0,0 +1,67 @@
+package scheduler_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/HeavenAQ/nstc-linebot-2025/scheduler"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWarmupNext(t *testing.T) {
+	// 14 September 2026 is a Monday.
+	table, err := scheduler.ParseTimetable("mon,wed 08:10-10:00; fri 13:20-15:10")
+	require.NoError(t, err)
+	warmup := table.Warmup(15*time.Minute, 5*time.Minute)
+
+	cases := []struct {
+		from time.Time
+		want time.Time
+	}{
+		{local(9, 13, 12, 0), local(9, 14, 7, 55)},
+		{local(9, 14, 7, 55), local(9, 14, 8, 0)},
+		{local(9, 14, 9, 2), local(9, 14, 9, 5)},
+		// The ping at 10:00 would come after class.
+		{local(9, 14, 9, 55), local(9, 16, 7, 55)},
+		{local(9, 16, 12, 0), local(9, 18, 13, 5)},
+		{local(9, 19, 9, 0), local(9, 21, 7, 55)},
+	}
+	for _, tc := range cases {
+		require.Equal(t, tc.want, warmup.Next(tc.from), tc.from.String())
+	}
+}
+
+func TestWarmupCrossesMidnight(t *testing.T) {
+	table, err := scheduler.ParseTimetable("tue 00:10-01:00")
+	require.NoError(t, err)
+	warmup := table.Warmup(30*time.Minute, 5*time.Minute)
+
+	require.Equal(t, local(9, 14, 23, 40), warmup.Next(local(9, 14, 12, 0)))
+	require.Equal(t, local(9, 14, 23, 45), warmup.Next(local(9, 14, 23, 40)))
+	require.Equal(t, local(9, 15, 0, 55), warmup.Next(local(9, 15, 0, 52)))
+}
+
+func TestWarmupDayRanges(t *testing.T) {
+	table, err := scheduler.ParseTimetable("mon-fri 08:00-09:00")
+	require.NoError(t, err)
+	warmup := table.Warmup(10*time.Minute, 5*time.Minute)
+
+	require.Equal(t, local(9, 17, 7, 50), warmup.Next(local(9, 16, 9, 0)))
+	require.Equal(t, local(9, 21, 7, 50), warmup.Next(local(9, 18, 9, 0)))
+}
+
+func TestEmptyTimetableNeverWarms(t *testing.T) {
+	table, err := scheduler.ParseTimetable(" ")
+	require.NoError(t, err)
+	require.True(t, table.Empty())
+	require.True(t, table.Warmup(time.Minute, time.Minute).Next(local(9, 14, 8, 0)).IsZero())
+}
+
+func TestParseTimetableRejectsInvalidSpecs(t *testing.T) {
+	for _, spec := range []string{
+		"mon", "mon 08:00", "xyz 08:00-09:00", "mon 10:00-09:00", "fri-mon 08:00-09:00", "mon 25:00-26:00",
+	} {
+		_, err := scheduler.ParseTimetable(spec)
+		require.Error(t, err, spec)
+	}
+}
@@ -0,0 +1,46 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/HeavenAQ/nstc-linebot-2025/config"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestRunNestsStepsAndRecordsFailures(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	failed := errors.New("upload failed")
+	err := Run(context.Background(), "upload", func(ctx context.Context) error {
+		return Run(ctx, "storage.upload_thumbnail", func(context.Context) error { return failed })
+	})
+	require.ErrorIs(t, err, failed)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	child, parent := spans[0], spans[1]
+	require.Equal(t, "storage.upload_thumbnail", child.Name())
+	require.Equal(t, parent.SpanContext().SpanID(), child.Parent().SpanID())
+	require.Equal(t, codes.Error, child.Status().Code)
+	require.Len(t, child.Events(), 1)
+}
+
+func TestSetupRejectsUnknownExporter(t *testing.T) {
+	_, err := Setup(context.Background(), config.TracingConfig{Exporter: "jaeger"})
+	require.Error(t, err)
+}
+
+func TestSetupWithoutExporterIsNoop(t *testing.T) {
+	shutdown, err := Setup(context.Background(), config.TracingConfig{Exporter: ExporterNone})
+	require.NoError(t, err)
+	require.NoError(t, shutdown(context.Background()))
+}